@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/util/pki"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 集群内置的CA，证书默认放在config.PKIDir下面
+// 程序的使用方法：
+//
+//	在master上初始化CA并签发APIServer的证书
+//	ca init [-hosts 192.168.1.5,127.0.0.1,localhost] [-force]
+//
+//	节点加入集群的时候，在master上给节点签发客户端证书，然后拷贝到节点的config.PKIDir下面
+//	ca issue -name node1 [-out /tmp/node1] [-user]
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "init":
+		err = initCA(os.Args[2:])
+	case "issue":
+		err = issue(os.Args[2:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Println("ca " + os.Args[1] + " failed: " + err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println("usage: ca init [-hosts hosts] [-force]")
+	fmt.Println("       ca issue -name name [-out dir] [-user]")
+}
+
+// 生成CA和APIServer的服务端证书，再给master上的组件签发一个客户端证书
+func initCA(args []string) error {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	hosts := flags.String("hosts", config.GetMasterIP()+","+config.Local_Server_IP+",localhost", "apiserver certificate hosts, separated by comma")
+	force := flags.Bool("force", false, "overwrite the existing CA")
+	flags.Parse(args)
+
+	if _, err := os.Stat(config.CACertFile); err == nil && !*force {
+		return errors.New(config.CACertFile + " already exists, use -force to overwrite")
+	}
+
+	caCert, caKey, err := pki.NewCA("minik8s-ca")
+	if err != nil {
+		return err
+	}
+	if err := writePair(config.CACertFile, caCert, config.CAKeyFile, caKey); err != nil {
+		return err
+	}
+
+	serverCert, serverKey, err := pki.IssueCert(caCert, caKey, &pki.CertRequest{
+		CommonName: "minik8s-apiserver",
+		Hosts:      splitHosts(*hosts),
+		Usage:      pki.UsageServer,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writePair(config.APIServerCertFile, serverCert, config.APIServerKeyFile, serverKey); err != nil {
+		return err
+	}
+
+	clientCert, clientKey, err := pki.IssueCert(caCert, caKey, &pki.CertRequest{
+		CommonName:   config.NodeTokenUser,
+		Organization: []string{apiObject.GroupNodes},
+		Usage:        pki.UsageClient,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writePair(config.ClientCertFile, clientCert, config.ClientKeyFile, clientKey); err != nil {
+		return err
+	}
+
+	fmt.Println("CA and certificates written to " + config.PKIDir)
+	return nil
+}
+
+// 用CA签发一个客户端证书，默认是节点组件的证书，-user的时候是普通用户的证书
+func issue(args []string) error {
+	flags := flag.NewFlagSet("issue", flag.ExitOnError)
+	name := flags.String("name", "", "node name or user name, used as the certificate common name")
+	out := flags.String("out", ".", "output directory")
+	user := flags.Bool("user", false, "issue a user certificate instead of a node certificate")
+	flags.Parse(args)
+
+	if *name == "" {
+		return errors.New("-name is required")
+	}
+
+	caCert, err := os.ReadFile(config.CACertFile)
+	if err != nil {
+		return err
+	}
+	caKey, err := os.ReadFile(config.CAKeyFile)
+	if err != nil {
+		return err
+	}
+
+	req := &pki.CertRequest{CommonName: *name, Usage: pki.UsageClient}
+	if !*user {
+		req.Organization = []string{apiObject.GroupNodes}
+	}
+	cert, key, err := pki.IssueCert(caCert, caKey, req)
+	if err != nil {
+		return err
+	}
+
+	certFile := filepath.Join(*out, filepath.Base(config.ClientCertFile))
+	keyFile := filepath.Join(*out, filepath.Base(config.ClientKeyFile))
+	if err := writePair(certFile, cert, keyFile, key); err != nil {
+		return err
+	}
+	// 节点还需要CA证书来验证APIServer
+	if err := writeFile(filepath.Join(*out, filepath.Base(config.CACertFile)), caCert, 0644); err != nil {
+		return err
+	}
+
+	fmt.Println("certificate for " + *name + " written to " + *out + ", copy them to " + config.PKIDir + " on the node")
+	return nil
+}
+
+func splitHosts(hosts string) []string {
+	result := make([]string, 0)
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			result = append(result, host)
+		}
+	}
+	return result
+}
+
+func writePair(certFile string, cert []byte, keyFile string, key []byte) error {
+	if err := writeFile(certFile, cert, 0644); err != nil {
+		return err
+	}
+	return writeFile(keyFile, key, 0600)
+}
+
+func writeFile(file string, content []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, content, perm)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"miniK8s/pkg/apiserver/app/backup"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/etcd"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// 集群的运维工具，目前用来备份和恢复etcd中的集群状态
+// 程序的使用方法：
+//
+//	备份一次，文件写到-dir下面
+//	minik8s backup [-dir /var/lib/minik8s/backup/] [-endpoints localhost:2379]
+//
+//	定时备份，只保留最新的-keep个备份
+//	minik8s backup -schedule 1h [-keep 24] [-dir dir]
+//
+//	从备份恢复，恢复之前需要先停止APIServer
+//	minik8s restore -file /var/lib/minik8s/backup/minik8s-backup-20230601-120000.json.gz
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Println("minik8s " + os.Args[1] + " failed: " + err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Println("usage: minik8s backup [-dir dir] [-schedule interval] [-keep n] [-endpoints endpoints]")
+	fmt.Println("       minik8s restore -file file [-endpoints endpoints]")
+}
+
+func runBackup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	dir := flags.String("dir", serverconfig.DefaultBackupDir, "directory to store the backups")
+	schedule := flags.Duration("schedule", 0, "backup periodically with this interval, 0 means backup once")
+	keep := flags.Int("keep", 7, "number of backups to keep, 0 means keep all")
+	endpoints := flags.String("endpoints", strings.Join(serverconfig.DefaultEtcdConfig().EtcdEndpoints, ","), "etcd endpoints, separated by comma")
+	flags.Parse(args)
+
+	store, err := newStore(*endpoints)
+	if err != nil {
+		return err
+	}
+
+	if *schedule <= 0 {
+		file, err := backup.BackupToDir(store, *dir)
+		if err != nil {
+			return err
+		}
+		if _, err := backup.Prune(*dir, *keep); err != nil {
+			return err
+		}
+		fmt.Println("backup written to " + file)
+		return nil
+	}
+
+	stopCh := make(chan struct{})
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		close(stopCh)
+	}()
+
+	fmt.Printf("backup to %s every %s, keep %d backups\n", *dir, schedule.String(), *keep)
+	backup.RunPeriodic(store, *dir, *schedule, *keep, stopCh)
+	return nil
+}
+
+func runRestore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	file := flags.String("file", "", "backup file to restore from")
+	endpoints := flags.String("endpoints", strings.Join(serverconfig.DefaultEtcdConfig().EtcdEndpoints, ","), "etcd endpoints, separated by comma")
+	flags.Parse(args)
+
+	if *file == "" {
+		return errors.New("-file is required")
+	}
+
+	archive, err := backup.ReadArchiveFile(*file)
+	if err != nil {
+		return err
+	}
+
+	store, err := newStore(*endpoints)
+	if err != nil {
+		return err
+	}
+	if err := backup.Restore(store, archive); err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %d objects from the backup created at %s, restart the apiserver and wait for the kubelets to report\n",
+		len(archive.Entries), archive.CreatedAt.Format(time.RFC3339))
+	return nil
+}
+
+func newStore(endpoints string) (*etcd.Store, error) {
+	list := make([]string, 0)
+	for _, endpoint := range strings.Split(endpoints, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			list = append(list, endpoint)
+		}
+	}
+	if len(list) == 0 {
+		return nil, errors.New("no etcd endpoints")
+	}
+	return etcd.NewEtcdStore(list, serverconfig.DefaultEtcdConfig().EtcdTimeout)
+}
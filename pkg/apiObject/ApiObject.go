@@ -15,12 +15,28 @@ const (
 	HpaKind        = "Hpa"
 	FunctionKind   = "Function"
 	WorkflowKind   = "Workflow"
+	NamespaceKind  = "Namespace"
+	CRDKind        = "CustomResourceDefinition"
+
+	MutatingWebhookConfigurationKind   = "MutatingWebhookConfiguration"
+	ValidatingWebhookConfigurationKind = "ValidatingWebhookConfiguration"
+
+	RoleKind               = "Role"
+	ClusterRoleKind        = "ClusterRole"
+	RoleBindingKind        = "RoleBinding"
+	ClusterRoleBindingKind = "ClusterRoleBinding"
 )
 
-var AllResourceKindSlice = []string{PodKind, ServiceKind, DnsKind, NodeKind, JobKind, ReplicaSetKind, HpaKind, FunctionKind, WorkflowKind}
+// APIServer的/metrics不是资源，这个kind只在权限控制里面使用
+const MetricsKind = "Metrics"
+
+var AllResourceKindSlice = []string{PodKind, ServiceKind, DnsKind, NodeKind, JobKind, ReplicaSetKind, HpaKind, FunctionKind, WorkflowKind, NamespaceKind, CRDKind,
+	MutatingWebhookConfigurationKind, ValidatingWebhookConfigurationKind, RoleKind, ClusterRoleKind, RoleBindingKind, ClusterRoleBindingKind}
 
 var AllResourceKind = strings.ToLower("[" + PodKind + "/" + ServiceKind + "/" + DnsKind + "/" + NodeKind + "/" + JobKind +
-	"/" + ReplicaSetKind + "/" + HpaKind + "/" + FunctionKind + "/" + WorkflowKind + "]")
+	"/" + ReplicaSetKind + "/" + HpaKind + "/" + FunctionKind + "/" + WorkflowKind + "/" + NamespaceKind + "/" + CRDKind +
+	"/" + MutatingWebhookConfigurationKind + "/" + ValidatingWebhookConfigurationKind +
+	"/" + RoleKind + "/" + ClusterRoleKind + "/" + RoleBindingKind + "/" + ClusterRoleBindingKind + "]")
 
 type APIObject interface {
 	// GetObjectName() string
@@ -40,4 +56,17 @@ var KindToStructType = map[string]reflect.Type{
 	HpaKind:        reflect.TypeOf(&HPA{}).Elem(),
 	FunctionKind:   reflect.TypeOf(&Function{}).Elem(),
 	WorkflowKind:   reflect.TypeOf(&Workflow{}).Elem(),
+	NamespaceKind:  reflect.TypeOf(&Namespace{}).Elem(),
+	CRDKind:        reflect.TypeOf(&CustomResourceDefinition{}).Elem(),
+
+	MutatingWebhookConfigurationKind:   reflect.TypeOf(&MutatingWebhookConfiguration{}).Elem(),
+	ValidatingWebhookConfigurationKind: reflect.TypeOf(&ValidatingWebhookConfiguration{}).Elem(),
+
+	RoleKind:               reflect.TypeOf(&Role{}).Elem(),
+	ClusterRoleKind:        reflect.TypeOf(&ClusterRole{}).Elem(),
+	RoleBindingKind:        reflect.TypeOf(&RoleBinding{}).Elem(),
+	ClusterRoleBindingKind: reflect.TypeOf(&ClusterRoleBinding{}).Elem(),
+
+	EventKind: reflect.TypeOf(&Event{}).Elem(),
+	LeaseKind: reflect.TypeOf(&Lease{}).Elem(),
 }
@@ -0,0 +1,142 @@
+package apiObject
+
+import "encoding/json"
+
+// 准入控制相关的对象
+// APIServer在创建、更新、删除对象的时候，先执行内置的准入插件，再调用用户注册的外部webhook
+// 参考https://kubernetes.io/docs/reference/access-authn-authz/extensible-admission-controllers/
+
+// webhook关心的操作
+const (
+	AdmissionOperationCreate = "CREATE"
+	AdmissionOperationUpdate = "UPDATE"
+	AdmissionOperationDelete = "DELETE"
+	// 匹配所有的操作或者所有的kind
+	AdmissionMatchAll = "*"
+)
+
+// 调用webhook失败(超时、无法连接、返回的数据格式不对)的时候的处理方式
+const (
+	// 拒绝请求，默认值
+	WebhookFailurePolicyFail = "Fail"
+	// 忽略这个webhook，继续处理请求
+	WebhookFailurePolicyIgnore = "Ignore"
+)
+
+// 修改型的webhook，可以修改请求中的对象，按照名字的顺序依次调用
+type MutatingWebhookConfiguration struct {
+	Basic    `json:",inline" yaml:",inline"`
+	Webhooks []Webhook `json:"webhooks" yaml:"webhooks"`
+}
+
+// 校验型的webhook，只能决定是否允许请求，在所有的修改型webhook之后调用
+type ValidatingWebhookConfiguration struct {
+	Basic    `json:",inline" yaml:",inline"`
+	Webhooks []Webhook `json:"webhooks" yaml:"webhooks"`
+}
+
+type Webhook struct {
+	// webhook的名字，出现在拒绝请求的错误信息里面
+	Name string `json:"name" yaml:"name"`
+	// webhook的地址，APIServer会POST一个AdmissionReview过去，比如http://192.168.1.10:9443/validate
+	URL string `json:"url" yaml:"url"`
+	// 哪些请求需要调用这个webhook，为空的时候不会调用
+	Rules []WebhookRule `json:"rules" yaml:"rules"`
+	// 调用失败的时候的处理方式，Fail或者Ignore，默认是Fail
+	FailurePolicy string `json:"failurePolicy" yaml:"failurePolicy" default:"Fail"`
+	// 调用的超时时间，单位是秒，默认是10秒
+	TimeoutSeconds int `json:"timeoutSeconds" yaml:"timeoutSeconds" default:"10"`
+}
+
+type WebhookRule struct {
+	// CREATE、UPDATE、DELETE或者*
+	Operations []string `json:"operations" yaml:"operations"`
+	// 对象的kind，比如Pod、Replicaset，*表示所有的kind
+	Kinds []string `json:"kinds" yaml:"kinds"`
+	// 只处理这些namespace里面的对象，为空的时候处理所有的namespace
+	Namespaces []string `json:"namespaces" yaml:"namespaces"`
+}
+
+// APIServer和webhook之间传递的数据，请求里面只有Request，返回的时候只需要填Response
+type AdmissionReview struct {
+	Request  *AdmissionRequest  `json:"request,omitempty"`
+	Response *AdmissionResponse `json:"response,omitempty"`
+}
+
+type AdmissionRequest struct {
+	// 每次请求唯一的ID，webhook返回的时候原样带回
+	UID       string `json:"uid"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Operation string `json:"operation"`
+	// 请求中的对象，删除的时候为空
+	Object json.RawMessage `json:"object,omitempty"`
+	// 更新和删除的时候是etcd中原来的对象
+	OldObject json.RawMessage `json:"oldObject,omitempty"`
+}
+
+type AdmissionResponse struct {
+	UID string `json:"uid"`
+	// 是否允许这个请求
+	Allowed bool `json:"allowed"`
+	// 不允许的时候返回给用户的原因
+	Message string `json:"message,omitempty"`
+	// 修改型的webhook可以返回修改之后的完整对象，为空的时候表示不修改
+	// 校验型的webhook返回的Object会被忽略
+	Object json.RawMessage `json:"object,omitempty"`
+}
+
+// 某个webhook是否需要处理这个请求
+func (w *Webhook) Match(operation string, kind string, namespace string) bool {
+	for _, rule := range w.Rules {
+		if rule.match(operation, kind, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *WebhookRule) match(operation string, kind string, namespace string) bool {
+	if !matchOrAll(r.Operations, operation) || !matchOrAll(r.Kinds, kind) {
+		return false
+	}
+	if len(r.Namespaces) == 0 {
+		return true
+	}
+	return matchOrAll(r.Namespaces, namespace)
+}
+
+func matchOrAll(list []string, target string) bool {
+	for _, item := range list {
+		if item == AdmissionMatchAll || item == target {
+			return true
+		}
+	}
+	return false
+}
+
+// 以下函数用来实现apiObject.Object接口
+func (m *MutatingWebhookConfiguration) GetObjectKind() string {
+	return m.Kind
+}
+
+func (m *MutatingWebhookConfiguration) GetObjectName() string {
+	return m.Metadata.Name
+}
+
+func (m *MutatingWebhookConfiguration) GetObjectNamespace() string {
+	return ""
+}
+
+func (v *ValidatingWebhookConfiguration) GetObjectKind() string {
+	return v.Kind
+}
+
+func (v *ValidatingWebhookConfiguration) GetObjectName() string {
+	return v.Metadata.Name
+}
+
+func (v *ValidatingWebhookConfiguration) GetObjectNamespace() string {
+	return ""
+}
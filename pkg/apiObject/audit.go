@@ -0,0 +1,74 @@
+package apiObject
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 审计日志不是通过通用的handler注册的资源，这个kind只在权限控制里面使用
+const AuditEventKind = "AuditEvent"
+
+// 审计的级别
+const (
+	// 不记录
+	AuditLevelNone = "None"
+	// 只记录谁在什么时候对哪个对象做了什么操作，以及返回的状态码
+	AuditLevelMetadata = "Metadata"
+	// 在Metadata的基础上记录请求的body
+	AuditLevelRequest = "Request"
+)
+
+// 审计日志里面的一条记录，按照JSON Lines的格式写到文件里面
+type AuditEvent struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	Level     string    `json:"level" yaml:"level"`
+	// 认证通过之后的用户名和token的种类，没有通过认证的时候为空
+	User      string `json:"user,omitempty" yaml:"user,omitempty"`
+	TokenType string `json:"tokenType,omitempty" yaml:"tokenType,omitempty"`
+	// token的sha256的前几位，可以用来区分同一个用户的不同token，不会泄露token本身
+	TokenID string `json:"tokenID,omitempty" yaml:"tokenID,omitempty"`
+	// 客户端的IP
+	SourceIP string `json:"sourceIP" yaml:"sourceIP"`
+
+	Verb      string `json:"verb" yaml:"verb"`
+	Method    string `json:"method" yaml:"method"`
+	URL       string `json:"url" yaml:"url"`
+	Kind      string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+
+	Code int `json:"code" yaml:"code"`
+	// 只有Request级别才会记录
+	RequestBody json.RawMessage `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+}
+
+// 审计策略，按照顺序匹配rules，第一条匹配的rule决定审计的级别
+// 没有匹配的rule的时候使用DefaultLevel，DefaultLevel为空的时候是Metadata
+type AuditPolicy struct {
+	DefaultLevel string            `json:"defaultLevel" yaml:"defaultLevel"`
+	Rules        []AuditPolicyRule `json:"rules" yaml:"rules"`
+}
+
+type AuditPolicyRule struct {
+	Level string `json:"level" yaml:"level"`
+	// 为空的时候匹配所有的操作或者kind，也可以写"*"
+	Verbs []string `json:"verbs" yaml:"verbs"`
+	Kinds []string `json:"kinds" yaml:"kinds"`
+}
+
+// 查询某个操作的审计级别
+func (p *AuditPolicy) LevelFor(verb string, kind string) string {
+	for _, rule := range p.Rules {
+		if len(rule.Verbs) != 0 && !matchOrAll(rule.Verbs, verb) {
+			continue
+		}
+		if len(rule.Kinds) != 0 && !matchOrAll(rule.Kinds, kind) {
+			continue
+		}
+		return rule.Level
+	}
+	if p.DefaultLevel == "" {
+		return AuditLevelMetadata
+	}
+	return p.DefaultLevel
+}
@@ -1,5 +1,7 @@
 package apiObject
 
+import "time"
+
 // 我们常见的API对象的yaml文件中，都有apiVersion、kind、metadata三个字段
 // Basic包含的是除了Spec的所有字段
 type Metadata struct {
@@ -8,6 +10,15 @@ type Metadata struct {
 	Namespace   string            `json:"namespace" yaml:"namespace" default:"default"`
 	Labels      map[string]string `json:"labels" yaml:"labels"`
 	Annotations map[string]string `json:"annotations" yaml:"annotations"`
+	// 对象的版本号，对应etcd中的ModRevision，由APIServer在读取的时候填充
+	// 更新的时候如果带上了这个字段，APIServer会检查版本是否一致，不一致返回409
+	ResourceVersion string `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	// 对象的所有者，所有者都被删除之后，GC会删除这个对象
+	OwnerReferences []OwnerReference `json:"ownerReferences,omitempty" yaml:"ownerReferences,omitempty"`
+	// finalizers不为空的时候，删除对象只会设置DeletionTimestamp，等finalizers都被移除之后才真正删除
+	Finalizers []string `json:"finalizers,omitempty" yaml:"finalizers,omitempty"`
+	// 对象开始删除的时间，由APIServer设置
+	DeletionTimestamp *time.Time `json:"deletionTimestamp,omitempty" yaml:"deletionTimestamp,omitempty"`
 }
 
 type Basic struct {
@@ -15,3 +26,96 @@ type Basic struct {
 	Kind       string   `json:"kind" yaml:"kind"`
 	Metadata   Metadata `json:"metadata" yaml:"metadata"`
 }
+
+// 指向对象的所有者，所有者和对象在同一个namespace里面(集群级别的所有者除外)
+type OwnerReference struct {
+	Kind string `json:"kind" yaml:"kind"`
+	Name string `json:"name" yaml:"name"`
+	// 同名的对象被删除之后重新创建，UUID会不一样，GC按照UUID判断所有者是否存在
+	UUID string `json:"uuid" yaml:"uuid"`
+	// 为true的时候，前台删除所有者需要等这个对象删除之后才能完成
+	BlockOwnerDeletion bool `json:"blockOwnerDeletion,omitempty" yaml:"blockOwnerDeletion,omitempty"`
+}
+
+// 删除对象时的级联策略，通过DELETE请求的propagationPolicy参数指定
+const (
+	// 立即删除对象，GC在后台删除依赖它的对象，默认的策略
+	DeletePropagationBackground = "Background"
+	// 先删除所有依赖它的对象，再删除对象本身
+	DeletePropagationForeground = "Foreground"
+	// 删除对象，但是保留依赖它的对象，只去掉它们的ownerReferences
+	DeletePropagationOrphan = "Orphan"
+)
+
+// GC使用的finalizer
+const (
+	FinalizerForegroundDeletion = "foregroundDeletion"
+	FinalizerOrphan             = "orphan"
+)
+
+// kubectl apply把这次apply的配置(json)保存在这个annotation里面，下次apply的时候用来计算需要删除的字段
+const LastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// kubectl apply把这次apply的所有对象的kind和namespace(kind/namespace，逗号分隔)保存在每个对象的这个annotation里面
+// prune的时候用来找到配置里面已经没有的kind和namespace下面的对象
+const AppliedScopesAnnotation = "kubectl.kubernetes.io/applied-scopes"
+
+// 通过metadata子资源更新对象的ownerReferences和finalizers
+// 带上resourceVersion的时候APIServer会检查版本是否一致
+type MetadataUpdate struct {
+	ResourceVersion string           `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
+	OwnerReferences []OwnerReference `json:"ownerReferences" yaml:"ownerReferences"`
+	Finalizers      []string         `json:"finalizers" yaml:"finalizers"`
+}
+
+// 生成一个指向这个对象的OwnerReference
+func NewOwnerReference(kind string, owner *Metadata) OwnerReference {
+	return OwnerReference{
+		Kind:               kind,
+		Name:               owner.Name,
+		UUID:               owner.UUID,
+		BlockOwnerDeletion: true,
+	}
+}
+
+// 对象是否正在删除
+func (m *Metadata) IsBeingDeleted() bool {
+	return m.DeletionTimestamp != nil
+}
+
+func (m *Metadata) HasFinalizer(finalizer string) bool {
+	for _, f := range m.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// 添加一个finalizer，已经存在的时候不重复添加
+func (m *Metadata) AddFinalizer(finalizer string) {
+	if !m.HasFinalizer(finalizer) {
+		m.Finalizers = append(m.Finalizers, finalizer)
+	}
+}
+
+// 删除一个finalizer，返回删除之后的finalizers，不修改原来的对象
+func (m *Metadata) WithoutFinalizer(finalizer string) []string {
+	finalizers := make([]string, 0, len(m.Finalizers))
+	for _, f := range m.Finalizers {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	return finalizers
+}
+
+// 对象是否属于uuid对应的所有者
+func (m *Metadata) IsOwnedBy(uuid string) bool {
+	for _, ref := range m.OwnerReferences {
+		if ref.UUID == uuid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package apiObject
+
+import "strings"
+
+// CustomResourceDefinition用来注册用户自定义的资源种类
+// CRD是集群级别的资源，名字必须是<plural>.<group>，比如trainingruns.ai.example.com
+// 注册之后APIServer会提供下面的接口
+// "/apis/<group>/<version>/namespaces/:namespace/<plural>"
+// "/apis/<group>/<version>/namespaces/:namespace/<plural>/:name"
+type CustomResourceDefinition struct {
+	Basic `json:",inline" yaml:",inline"`
+	Spec  CustomResourceDefinitionSpec `json:"spec" yaml:"spec"`
+}
+
+type CustomResourceDefinitionSpec struct {
+	// 资源所属的组，比如ai.example.com
+	Group string `json:"group" yaml:"group"`
+	// 资源的版本，比如v1
+	Version string                        `json:"version" yaml:"version"`
+	Names   CustomResourceDefinitionNames `json:"names" yaml:"names"`
+	// 可选的JSON Schema，用来校验自定义对象的spec字段
+	// 支持type、properties、required、items、enum、minimum、maximum等常用的关键字
+	Schema map[string]interface{} `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+type CustomResourceDefinitionNames struct {
+	// 自定义对象的kind，比如TrainingRun
+	Kind string `json:"kind" yaml:"kind"`
+	// URL中使用的复数形式，比如trainingruns
+	Plural string `json:"plural" yaml:"plural"`
+	// kubectl中使用的单数形式，比如trainingrun，为空的时候使用kind的小写
+	Singular string `json:"singular" yaml:"singular"`
+}
+
+// 自定义资源的对象，除了Basic之外所有的内容都放在spec里面
+type CustomObject struct {
+	Basic `json:",inline" yaml:",inline"`
+	Spec  map[string]interface{} `json:"spec" yaml:"spec"`
+}
+
+// CRD的名字应该是<plural>.<group>
+func (crd *CustomResourceDefinition) ExpectedName() string {
+	return crd.Spec.Names.Plural + "." + crd.Spec.Group
+}
+
+// 自定义对象的apiVersion，<group>/<version>
+func (crd *CustomResourceDefinition) APIVersion() string {
+	return crd.Spec.Group + "/" + crd.Spec.Version
+}
+
+// kubectl中使用的名字
+func (crd *CustomResourceDefinition) SingularName() string {
+	if crd.Spec.Names.Singular != "" {
+		return crd.Spec.Names.Singular
+	}
+	return strings.ToLower(crd.Spec.Names.Kind)
+}
+
+// kubectl里面可以用kind、单数或者复数形式指代自定义资源，大小写不敏感
+func (crd *CustomResourceDefinition) MatchName(name string) bool {
+	return strings.EqualFold(name, crd.Spec.Names.Kind) ||
+		strings.EqualFold(name, crd.Spec.Names.Plural) ||
+		strings.EqualFold(name, crd.SingularName())
+}
+
+// 以下函数用来实现apiObject.Object接口
+func (crd *CustomResourceDefinition) GetObjectKind() string {
+	return crd.Kind
+}
+
+func (crd *CustomResourceDefinition) GetObjectName() string {
+	return crd.Metadata.Name
+}
+
+func (crd *CustomResourceDefinition) GetObjectNamespace() string {
+	return ""
+}
+
+func (co *CustomObject) GetObjectKind() string {
+	return co.Kind
+}
+
+func (co *CustomObject) GetObjectName() string {
+	return co.Metadata.Name
+}
+
+func (co *CustomObject) GetObjectNamespace() string {
+	return co.Metadata.Namespace
+}
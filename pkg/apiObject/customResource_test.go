@@ -0,0 +1,49 @@
+package apiObject
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCustomResourceDefinitionYaml(t *testing.T) {
+	content, err := os.ReadFile("./testFile/yamlFile/CustomResourceDefinition.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crd := &CustomResourceDefinition{}
+	if err := yaml.Unmarshal(content, crd); err != nil {
+		t.Fatal(err)
+	}
+
+	if crd.Metadata.Name != crd.ExpectedName() {
+		t.Errorf("expected name %s but got %s", crd.ExpectedName(), crd.Metadata.Name)
+	}
+	if crd.APIVersion() != "ai.minik8s.io/v1" {
+		t.Errorf("unexpected apiVersion %s", crd.APIVersion())
+	}
+	for _, name := range []string{"TrainingRun", "trainingrun", "trainingruns"} {
+		if !crd.MatchName(name) {
+			t.Errorf("%s should match", name)
+		}
+	}
+	if crd.MatchName("pod") {
+		t.Errorf("pod should not match")
+	}
+
+	// schema需要能够转换为json发送给APIServer
+	data, err := json.Marshal(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := &CustomResourceDefinition{}
+	if err := json.Unmarshal(data, res); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.Spec.Schema["properties"].(map[string]interface{}); !ok {
+		t.Errorf("schema properties lost after json round trip: %v", res.Spec.Schema)
+	}
+}
@@ -0,0 +1,65 @@
+package apiObject
+
+import "time"
+
+// 事件记录了各个组件对某个对象做了什么、遇到了什么问题，比如Pod被调度到了哪个节点、镜像拉取失败
+// 由pkg/event里面的Recorder创建，过了serverconfig.EventTTL之后自动删除
+const EventKind = "Event"
+
+// 事件的类型
+const (
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+// 事件相关的对象
+type ObjectReference struct {
+	Kind      string `json:"kind" yaml:"kind"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Name      string `json:"name" yaml:"name"`
+	UUID      string `json:"uuid,omitempty" yaml:"uuid,omitempty"`
+}
+
+// 产生事件的组件
+type EventSource struct {
+	Component string `json:"component" yaml:"component"`
+	Host      string `json:"host,omitempty" yaml:"host,omitempty"`
+}
+
+type Event struct {
+	Basic          `yaml:",inline" json:",inline"`
+	InvolvedObject ObjectReference `json:"involvedObject" yaml:"involvedObject"`
+	// 简短的原因，比如FailedScheduling、Pulled，方便过滤
+	Reason string `json:"reason" yaml:"reason"`
+	// 给人看的详细信息
+	Message string      `json:"message" yaml:"message"`
+	Type    string      `json:"type" yaml:"type"`
+	Source  EventSource `json:"source" yaml:"source"`
+	// 同一个对象相同的事件重复发生的时候只增加次数，不创建新的事件
+	Count          int       `json:"count" yaml:"count"`
+	FirstTimestamp time.Time `json:"firstTimestamp" yaml:"firstTimestamp"`
+	LastTimestamp  time.Time `json:"lastTimestamp" yaml:"lastTimestamp"`
+}
+
+// 生成一个指向这个对象的ObjectReference
+func NewObjectReference(kind string, meta *Metadata) ObjectReference {
+	return ObjectReference{
+		Kind:      kind,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		UUID:      meta.UUID,
+	}
+}
+
+// 以下函数用来实现apiObject.Object接口
+func (e *Event) GetObjectKind() string {
+	return e.Kind
+}
+
+func (e *Event) GetObjectName() string {
+	return e.Metadata.Name
+}
+
+func (e *Event) GetObjectNamespace() string {
+	return e.Metadata.Namespace
+}
@@ -87,6 +87,24 @@ func (j *Job) GetObjectNamespace() string {
 	return j.Metadata.Namespace
 }
 
+///////////////////////// 以下是Job的凭证相关的内容 /////////////////////////
+
+// job-server访问APIServer使用的凭证，APIServer给每个Job单独签发
+// 只能读取自己的Job和JobFile，更新Job的状态和JobFile，kubelet把它写成文件挂载进容器
+type JobCredential struct {
+	Token string `json:"token" yaml:"token"`
+	// 开启TLS的时候有集群CA和用CA签发的客户端证书，证书的CN是JobCredentialUser，不属于任何组
+	CAData         string `json:"caData,omitempty" yaml:"caData,omitempty"`
+	ClientCertData string `json:"clientCertData,omitempty" yaml:"clientCertData,omitempty"`
+	ClientKeyData  string `json:"clientKeyData,omitempty" yaml:"clientKeyData,omitempty"`
+}
+
+// Job的凭证对应的用户名，Role和RoleBinding也用这个名字
+// 证书不能吊销，带上uuid之后删除Job再创建同名的Job也不能使用原来的证书
+func JobCredentialUser(namespace string, name string, uuid string) string {
+	return "system:job:" + namespace + ":" + name + ":" + uuid
+}
+
 ///////////////////////// 以下是JobFile相关的内容 /////////////////////////
 
 type JobFile struct {
@@ -0,0 +1,38 @@
+package apiObject
+
+import "time"
+
+// Lease用于选主，scheduler、controller manager和serverless的controller启动了多个副本的时候，只有持有Lease的副本工作
+// 由pkg/leaderelection创建和续约，etcd中的对象在LeaseDurationSeconds之后没有续约就会自动删除
+const LeaseKind = "Lease"
+
+type LeaseSpec struct {
+	// 当前持有者的标识，为空表示持有者已经主动释放
+	HolderIdentity string `json:"holderIdentity" yaml:"holderIdentity"`
+	// 持有者超过这个时间没有续约，其他副本就可以接管
+	LeaseDurationSeconds int `json:"leaseDurationSeconds" yaml:"leaseDurationSeconds"`
+	// 当前持有者获得Lease的时间
+	AcquireTime time.Time `json:"acquireTime" yaml:"acquireTime"`
+	// 最后一次续约的时间
+	RenewTime time.Time `json:"renewTime" yaml:"renewTime"`
+	// 持有者变化的次数
+	LeaseTransitions int `json:"leaseTransitions" yaml:"leaseTransitions"`
+}
+
+type Lease struct {
+	Basic `yaml:",inline" json:",inline"`
+	Spec  LeaseSpec `json:"spec" yaml:"spec"`
+}
+
+// 以下函数用来实现apiObject.Object接口
+func (l *Lease) GetObjectKind() string {
+	return l.Kind
+}
+
+func (l *Lease) GetObjectName() string {
+	return l.Metadata.Name
+}
+
+func (l *Lease) GetObjectNamespace() string {
+	return l.Metadata.Namespace
+}
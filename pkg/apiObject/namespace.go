@@ -0,0 +1,52 @@
+package apiObject
+
+import "time"
+
+// Namespace的阶段
+const (
+	NamespaceActive      = "Active"
+	NamespaceTerminating = "Terminating"
+)
+
+// Namespace是集群级别的资源，自身没有namespace
+// Metadata.Namespace字段不会被使用
+type Namespace struct {
+	Basic `json:",inline" yaml:",inline"`
+}
+
+type NamespaceStore struct {
+	Basic  `json:",inline" yaml:",inline"`
+	Status NamespaceStatus `json:"status" yaml:"status"`
+}
+
+type NamespaceStatus struct {
+	Phase string `json:"phase" yaml:"phase"`
+	// 最后一次更新状态的时间，Terminating的时候记录的是开始删除的时间
+	UpdateTime time.Time `json:"updateTime" yaml:"updateTime"`
+}
+
+func (n *Namespace) ToNamespaceStore() *NamespaceStore {
+	return &NamespaceStore{
+		Basic: n.Basic,
+	}
+}
+
+func (ns *NamespaceStore) ToNamespace() *Namespace {
+	return &Namespace{
+		Basic: ns.Basic,
+	}
+}
+
+// 以下函数用来实现apiObject.Object接口
+func (n *Namespace) GetObjectKind() string {
+	return n.Kind
+}
+
+func (n *Namespace) GetObjectName() string {
+	return n.Metadata.Name
+}
+
+// Namespace的URL里面用的是:namespace参数，所以这里返回自己的名字
+func (n *Namespace) GetObjectNamespace() string {
+	return n.Metadata.Name
+}
@@ -17,14 +17,10 @@ import (
 // 除非重新创建了一个新的对象，这个对象的UUID才会变
 
 // Node
-// Node没有Namespace字段，因为Node是集群级别的资源，不属于任何Namespace
+// Node是集群级别的资源，不属于任何Namespace，Metadata.Namespace字段不会被使用
 // 下面定义的这个Node是面向用户的，而不是存贮到etcd里面的Node
-type NodeMetadata struct {
-	UUID        string            `json:"uuid" yaml:"uuid"`
-	Name        string            `json:"name" yaml:"name"`
-	Labels      map[string]string `json:"labels" yaml:"labels"`
-	Annotations map[string]string `json:"annotations" yaml:"annotations"`
-}
+// NodeMetadata和其他对象的Metadata是同一个类型，APIServer可以用通用的handler处理Node
+type NodeMetadata = Metadata
 
 type NodeBasic struct {
 	APIVersion   string       `json:"apiVersion" yaml:"apiVersion"`
@@ -139,6 +139,14 @@ type HostPath struct {
 type Volume struct {
 	Name     string   `json:"name" yaml:"name"`
 	HostPath HostPath `json:"hostPath" yaml:"hostPath"`
+	// Job的凭证，JobName为空的时候不是这种volume
+	JobCredential JobCredentialVolumeSource `json:"jobCredential" yaml:"jobCredential"`
+}
+
+// kubelet向APIServer申请Job的凭证，写到节点上的目录里面再挂载进容器，凭证不会出现在Pod的spec里面
+// 只有ownerReferences里面有这个Job的Pod才能使用
+type JobCredentialVolumeSource struct {
+	JobName string `json:"jobName" yaml:"jobName"`
 }
 
 // 参考Kubernetes API文档
@@ -0,0 +1,201 @@
+package apiObject
+
+// 基于角色的权限控制(RBAC)相关的对象
+// Role/ClusterRole列出允许对哪些kind执行哪些操作，RoleBinding/ClusterRoleBinding把角色授予token对应的用户或者组
+// 参考https://kubernetes.io/docs/reference/access-authn-authz/rbac/
+
+// 对资源的操作，和HTTP请求的对应关系是
+// GET单个对象->get，GET列表->list，GET列表并且watch=true->watch，POST->create，PUT->update，PATCH->patch，DELETE->delete
+const (
+	VerbGet    = "get"
+	VerbList   = "list"
+	VerbWatch  = "watch"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbPatch  = "patch"
+	VerbDelete = "delete"
+	// 匹配所有的操作或者所有的kind
+	VerbAll = "*"
+)
+
+var AllVerbs = []string{VerbGet, VerbList, VerbWatch, VerbCreate, VerbUpdate, VerbPatch, VerbDelete, VerbAll}
+
+// 子资源，Role的kinds里面写成Kind/subresource，比如Job/status
+const (
+	StatusSubresource   = "status"
+	MetadataSubresource = "metadata"
+	FinalizeSubresource = "finalize"
+	// Job的文件和job-server使用的凭证
+	JobFileSubresource       = "file"
+	JobCredentialSubresource = "credential"
+)
+
+// RoleBinding里面的subject的种类
+const (
+	// 匹配token里面的用户名
+	SubjectKindUser = "User"
+	// 匹配token所属的组
+	SubjectKindGroup = "Group"
+)
+
+// token所属的组
+const (
+	// 所有的节点组件的token
+	GroupNodes = "system:nodes"
+	// 所有认证通过的token
+	GroupAuthenticated = "system:authenticated"
+)
+
+// APIServer第一次启动的时候创建的ClusterRole和ClusterRoleBinding，拥有所有的权限
+const ClusterAdminName = "cluster-admin"
+
+// Role只在自己的namespace里面生效
+type Role struct {
+	Basic `json:",inline" yaml:",inline"`
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+// ClusterRole没有namespace，通过ClusterRoleBinding授予的时候对所有namespace和集群级别的资源生效
+// 通过RoleBinding授予的时候只在RoleBinding的namespace里面生效
+type ClusterRole struct {
+	Basic `json:",inline" yaml:",inline"`
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+}
+
+type PolicyRule struct {
+	// get、list、watch、create、update、delete或者*
+	Verbs []string `json:"verbs" yaml:"verbs"`
+	// 对象的kind，比如Pod、Function，*表示所有的kind
+	// Kind匹配这个kind和它所有的子资源，Kind/subresource只匹配这个子资源，比如Job/status
+	Kinds []string `json:"kinds" yaml:"kinds"`
+	// 不为空的时候只匹配这些名字的对象，list、watch、create这样不带名字的请求都不匹配
+	ResourceNames []string `json:"resourceNames,omitempty" yaml:"resourceNames,omitempty"`
+}
+
+// 把Role或者ClusterRole授予subjects，只在自己的namespace里面生效
+type RoleBinding struct {
+	Basic    `json:",inline" yaml:",inline"`
+	Subjects []Subject `json:"subjects" yaml:"subjects"`
+	RoleRef  RoleRef   `json:"roleRef" yaml:"roleRef"`
+}
+
+// 把ClusterRole授予subjects，对整个集群生效
+type ClusterRoleBinding struct {
+	Basic    `json:",inline" yaml:",inline"`
+	Subjects []Subject `json:"subjects" yaml:"subjects"`
+	RoleRef  RoleRef   `json:"roleRef" yaml:"roleRef"`
+}
+
+type Subject struct {
+	// User或者Group
+	Kind string `json:"kind" yaml:"kind"`
+	Name string `json:"name" yaml:"name"`
+}
+
+type RoleRef struct {
+	// Role或者ClusterRole，ClusterRoleBinding只能引用ClusterRole
+	Kind string `json:"kind" yaml:"kind"`
+	Name string `json:"name" yaml:"name"`
+}
+
+// token所属的组，节点组件的token属于system:nodes
+func (t *Token) Groups() []string {
+	if t.IsNode() {
+		return []string{GroupNodes, GroupAuthenticated}
+	}
+	return []string{GroupAuthenticated}
+}
+
+// subject是否指向这个token
+func (s *Subject) Matches(token *Token) bool {
+	switch s.Kind {
+	case SubjectKindUser:
+		return s.Name == token.User
+	case SubjectKindGroup:
+		for _, group := range token.Groups() {
+			if s.Name == group {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 规则是否允许对名字为name的kind(或者它的子资源subresource)执行verb
+// subresource为空的时候是对象本身，name为空的时候是不带名字的请求
+func (r *PolicyRule) Allows(verb string, kind string, subresource string, name string) bool {
+	if !matchOrAll(r.Verbs, verb) {
+		return false
+	}
+	if !matchOrAll(r.Kinds, kind) && (subresource == "" || !matchOrAll(r.Kinds, kind+"/"+subresource)) {
+		return false
+	}
+	if len(r.ResourceNames) == 0 {
+		return true
+	}
+	for _, resourceName := range r.ResourceNames {
+		if name != "" && resourceName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// 任意一条规则允许即可
+func RulesAllow(rules []PolicyRule, verb string, kind string, subresource string, name string) bool {
+	for i := range rules {
+		if rules[i].Allows(verb, kind, subresource, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// 以下函数用来实现apiObject.Object接口
+func (r *Role) GetObjectKind() string {
+	return r.Kind
+}
+
+func (r *Role) GetObjectName() string {
+	return r.Metadata.Name
+}
+
+func (r *Role) GetObjectNamespace() string {
+	return r.Metadata.Namespace
+}
+
+func (r *ClusterRole) GetObjectKind() string {
+	return r.Kind
+}
+
+func (r *ClusterRole) GetObjectName() string {
+	return r.Metadata.Name
+}
+
+func (r *ClusterRole) GetObjectNamespace() string {
+	return ""
+}
+
+func (r *RoleBinding) GetObjectKind() string {
+	return r.Kind
+}
+
+func (r *RoleBinding) GetObjectName() string {
+	return r.Metadata.Name
+}
+
+func (r *RoleBinding) GetObjectNamespace() string {
+	return r.Metadata.Namespace
+}
+
+func (r *ClusterRoleBinding) GetObjectKind() string {
+	return r.Kind
+}
+
+func (r *ClusterRoleBinding) GetObjectName() string {
+	return r.Metadata.Name
+}
+
+func (r *ClusterRoleBinding) GetObjectNamespace() string {
+	return ""
+}
@@ -0,0 +1,31 @@
+package apiObject
+
+import "time"
+
+// token不是通过通用的handler注册的资源，这个kind只在权限控制里面使用
+const TokenKind = "Token"
+
+// token的种类，节点组件(kubelet、kube-proxy等)和用户(kubectl)使用不同的token
+const (
+	TokenTypeNode = "node"
+	TokenTypeUser = "user"
+)
+
+// 存放在etcd里面的token记录，请求头里面带上Authorization: Bearer <token>
+type Token struct {
+	Token string `json:"token" yaml:"token"`
+	Type  string `json:"type" yaml:"type"`
+	// token对应的用户名或者节点组件的名字，后面做权限控制的时候使用
+	User              string    `json:"user" yaml:"user"`
+	CreationTimestamp time.Time `json:"creationTimestamp" yaml:"creationTimestamp"`
+}
+
+func (t *Token) IsNode() bool {
+	return t.Type == TokenTypeNode
+}
+
+// 创建token时请求的body，token本身由APIServer生成
+type TokenRequest struct {
+	Type string `json:"type" yaml:"type"`
+	User string `json:"user" yaml:"user"`
+}
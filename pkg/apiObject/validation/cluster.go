@@ -0,0 +1,123 @@
+package validation
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/util/jsonschema"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// CustomResourceDefinition、webhook配置、token的检查，这些都是集群级别的对象
+
+// plural只能是小写字母、数字和-
+var crdPluralRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+func ValidateCRDSpec(crd *apiObject.CustomResourceDefinition) ErrorList {
+	errs := ErrorList{}
+	spec := crd.Spec
+
+	// group里面必须带有.，这样不会和/apis/v1下面内置的资源冲突
+	if spec.Group == "" || !strings.Contains(spec.Group, ".") || strings.Contains(spec.Group, "/") {
+		errs.Add("spec.group", "%q is invalid, should be like example.com", spec.Group)
+	}
+	if spec.Version == "" || strings.Contains(spec.Version, "/") {
+		errs.Add("spec.version", "%q is invalid", spec.Version)
+	}
+	errs.Required("spec.names.kind", spec.Names.Kind)
+	if !crdPluralRegexp.MatchString(spec.Names.Plural) {
+		errs.Add("spec.names.plural", "%q is invalid, should only contain lowercase letters, digits and '-'", spec.Names.Plural)
+	}
+	if crd.Metadata.Name != "" && crd.Metadata.Name != crd.ExpectedName() {
+		errs.Add("metadata.name", "should be %s", crd.ExpectedName())
+	}
+
+	if spec.Schema != nil {
+		if err := jsonschema.CheckSchema(spec.Schema); err != nil {
+			errs.Add("spec.schema", "%s", err.Error())
+		}
+	}
+
+	return errs
+}
+
+// 用CRD里面的schema检查自定义对象的spec，metadata由Validate检查
+func ValidateCustomObject(crd *apiObject.CustomResourceDefinition, obj *apiObject.CustomObject) ErrorList {
+	errs := ErrorList{}
+	if crd.Spec.Schema == nil {
+		return errs
+	}
+
+	var spec interface{} = obj.Spec
+	if obj.Spec == nil {
+		spec = map[string]interface{}{}
+	}
+
+	for _, e := range jsonschema.Validate(crd.Spec.Schema, spec, "spec") {
+		errs = append(errs, FieldError{Field: e.Field, Reason: e.Reason})
+	}
+	return errs
+}
+
+var webhookFailurePolicies = []string{apiObject.WebhookFailurePolicyFail, apiObject.WebhookFailurePolicyIgnore}
+
+var webhookOperations = []string{
+	apiObject.AdmissionOperationCreate,
+	apiObject.AdmissionOperationUpdate,
+	apiObject.AdmissionOperationDelete,
+	apiObject.AdmissionMatchAll,
+}
+
+func ValidateWebhooks(webhooks []apiObject.Webhook, field string) ErrorList {
+	errs := ErrorList{}
+
+	names := make(map[string]bool)
+	for i, webhook := range webhooks {
+		webhookField := fmt.Sprintf("%s[%d]", field, i)
+		if webhook.Name == "" {
+			errs.Add(webhookField+".name", "required")
+		} else if names[webhook.Name] {
+			errs.Add(webhookField+".name", "duplicate webhook name %q", webhook.Name)
+		}
+		names[webhook.Name] = true
+
+		u, err := url.Parse(webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs.Add(webhookField+".url", "%q is invalid, should be like http://host:port/path", webhook.URL)
+		}
+
+		validateEnum(&errs, webhook.FailurePolicy, webhookFailurePolicies, webhookField+".failurePolicy")
+		validateNonNegative(&errs, webhook.TimeoutSeconds, webhookField+".timeoutSeconds")
+
+		if len(webhook.Rules) == 0 {
+			errs.Add(webhookField+".rules", "must have at least one rule")
+		}
+		for j, rule := range webhook.Rules {
+			ruleField := fmt.Sprintf("%s.rules[%d]", webhookField, j)
+			if len(rule.Operations) == 0 {
+				errs.Add(ruleField+".operations", "must not be empty")
+			}
+			for k, operation := range rule.Operations {
+				validateEnum(&errs, operation, webhookOperations, fmt.Sprintf("%s.operations[%d]", ruleField, k))
+			}
+			if len(rule.Kinds) == 0 {
+				errs.Add(ruleField+".kinds", "must not be empty")
+			}
+		}
+	}
+
+	return errs
+}
+
+var tokenTypes = []string{apiObject.TokenTypeNode, apiObject.TokenTypeUser}
+
+func ValidateTokenRequest(req *apiObject.TokenRequest) ErrorList {
+	errs := ErrorList{}
+
+	errs.Required("type", req.Type)
+	validateEnum(&errs, req.Type, tokenTypes, "type")
+	validateName(&errs, req.User, "user")
+
+	return errs
+}
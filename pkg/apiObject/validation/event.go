@@ -0,0 +1,18 @@
+package validation
+
+import "miniK8s/pkg/apiObject"
+
+// 事件的检查，事件由各个组件的Recorder创建
+
+func ValidateEvent(event *apiObject.Event) ErrorList {
+	errs := ErrorList{}
+	errs.Required("involvedObject.kind", event.InvolvedObject.Kind)
+	errs.Required("involvedObject.name", event.InvolvedObject.Name)
+	errs.Required("reason", event.Reason)
+	errs.Required("type", event.Type)
+	validateEnum(&errs, event.Type, []string{apiObject.EventTypeNormal, apiObject.EventTypeWarning}, "type")
+	if event.Count < 1 {
+		errs.Add("count", "%d must be greater than 0", event.Count)
+	}
+	return errs
+}
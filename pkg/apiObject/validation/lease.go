@@ -0,0 +1,16 @@
+package validation
+
+import "miniK8s/pkg/apiObject"
+
+// Lease的检查，Lease由pkg/leaderelection创建和续约
+
+func ValidateLease(lease *apiObject.Lease) ErrorList {
+	errs := ErrorList{}
+	if lease.Spec.LeaseDurationSeconds < 1 {
+		errs.Add("spec.leaseDurationSeconds", "%d must be greater than 0", lease.Spec.LeaseDurationSeconds)
+	}
+	if lease.Spec.LeaseTransitions < 0 {
+		errs.Add("spec.leaseTransitions", "%d must be greater than or equal to 0", lease.Spec.LeaseTransitions)
+	}
+	return errs
+}
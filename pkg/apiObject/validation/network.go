@@ -0,0 +1,95 @@
+package validation
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"net"
+	"strings"
+)
+
+// Service、Dns、Node的检查
+
+var serviceTypes = []string{"ClusterIP", "NodePort", "LoadBalancer", "ExternalName"}
+
+func ValidateServiceSpec(spec *apiObject.ServiceSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	validateEnum(&errs, spec.Type, serviceTypes, field+".type")
+
+	if spec.ClusterIP != "" && net.ParseIP(spec.ClusterIP) == nil {
+		errs.Add(field+".clusterIP", "%q is not a valid IP address", spec.ClusterIP)
+	}
+
+	if len(spec.Ports) == 0 {
+		errs.Add(field+".ports", "must have at least one port")
+	}
+
+	names := make(map[string]bool)
+	for i, port := range spec.Ports {
+		portField := fmt.Sprintf("%s.ports[%d]", field, i)
+		// 多个端口的时候需要用名字区分
+		if len(spec.Ports) > 1 {
+			if port.Name == "" {
+				errs.Add(portField+".name", "required when there are multiple ports")
+			} else if names[port.Name] {
+				errs.Add(portField+".name", "duplicate port name %q", port.Name)
+			}
+		}
+		names[port.Name] = true
+
+		validatePort(&errs, port.Port, portField+".port")
+		validatePort(&errs, port.TargetPort, portField+".targetPort")
+		if port.NodePort != 0 {
+			validatePort(&errs, port.NodePort, portField+".nodePort")
+		}
+		validateProtocol(&errs, port.Protocol, portField+".protocol")
+	}
+
+	validateLabels(&errs, spec.Selector, field+".selector")
+
+	return errs
+}
+
+func ValidateDnsSpec(spec *apiObject.DnsSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	errs.Required(field+".host", spec.Host)
+	if strings.ContainsAny(spec.Host, "/: ") {
+		errs.Add(field+".host", "%q should be a domain name without scheme, port or path", spec.Host)
+	}
+
+	if len(spec.Paths) == 0 {
+		errs.Add(field+".paths", "must have at least one path")
+	}
+
+	subPaths := make(map[string]bool)
+	for i, path := range spec.Paths {
+		pathField := fmt.Sprintf("%s.paths[%d]", field, i)
+		if !strings.HasPrefix(path.SubPath, "/") {
+			errs.Add(pathField+".subPath", "%q should start with /", path.SubPath)
+		} else if subPaths[path.SubPath] {
+			errs.Add(pathField+".subPath", "duplicate subPath %q", path.SubPath)
+		}
+		subPaths[path.SubPath] = true
+
+		errs.Required(pathField+".svcName", path.SvcName)
+		errs.Required(pathField+".svcPort", path.SvcPort)
+		validatePortString(&errs, path.SvcPort, pathField+".svcPort")
+	}
+
+	return errs
+}
+
+func ValidateNode(node *apiObject.Node) ErrorList {
+	errs := ErrorList{}
+
+	validateName(&errs, node.NodeMetadata.Name, "metadata.name")
+	validateLabels(&errs, node.NodeMetadata.Labels, "metadata.labels")
+
+	errs.Required("ip", node.IP)
+	if node.IP != "" && net.ParseIP(node.IP) == nil {
+		errs.Add("ip", "%q is not a valid IP address", node.IP)
+	}
+
+	return errs
+}
@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"strings"
+)
+
+// Role、ClusterRole、RoleBinding、ClusterRoleBinding的检查
+
+var subjectKinds = []string{apiObject.SubjectKindUser, apiObject.SubjectKindGroup}
+
+func ValidatePolicyRules(rules []apiObject.PolicyRule, field string) ErrorList {
+	errs := ErrorList{}
+
+	for i, rule := range rules {
+		ruleField := fmt.Sprintf("%s[%d]", field, i)
+		if len(rule.Verbs) == 0 {
+			errs.Add(ruleField+".verbs", "must not be empty")
+		}
+		for j, verb := range rule.Verbs {
+			errs.Required(fmt.Sprintf("%s.verbs[%d]", ruleField, j), verb)
+			validateEnum(&errs, verb, apiObject.AllVerbs, fmt.Sprintf("%s.verbs[%d]", ruleField, j))
+		}
+		if len(rule.Kinds) == 0 {
+			errs.Add(ruleField+".kinds", "must not be empty")
+		}
+		for j, kind := range rule.Kinds {
+			kindField := fmt.Sprintf("%s.kinds[%d]", ruleField, j)
+			errs.Required(kindField, kind)
+			// Kind/subresource的两部分都不能为空
+			if parts := strings.Split(kind, "/"); len(parts) > 2 || (len(parts) == 2 && (parts[0] == "" || parts[1] == "")) {
+				errs.Add(kindField, "must be a kind or kind/subresource, got %q", kind)
+			}
+		}
+		for j, name := range rule.ResourceNames {
+			errs.Required(fmt.Sprintf("%s.resourceNames[%d]", ruleField, j), name)
+		}
+	}
+
+	return errs
+}
+
+// clusterScoped为true的时候是ClusterRoleBinding，只能引用ClusterRole
+func ValidateRoleBinding(subjects []apiObject.Subject, roleRef *apiObject.RoleRef, clusterScoped bool) ErrorList {
+	errs := ErrorList{}
+
+	if len(subjects) == 0 {
+		errs.Add("subjects", "must have at least one subject")
+	}
+	for i, subject := range subjects {
+		subjectField := fmt.Sprintf("subjects[%d]", i)
+		errs.Required(subjectField+".kind", subject.Kind)
+		validateEnum(&errs, subject.Kind, subjectKinds, subjectField+".kind")
+		errs.Required(subjectField+".name", subject.Name)
+	}
+
+	roleKinds := []string{apiObject.RoleKind, apiObject.ClusterRoleKind}
+	if clusterScoped {
+		roleKinds = []string{apiObject.ClusterRoleKind}
+	}
+	errs.Required("roleRef.kind", roleRef.Kind)
+	validateEnum(&errs, roleRef.Kind, roleKinds, "roleRef.kind")
+	errs.Required("roleRef.name", roleRef.Name)
+
+	return errs
+}
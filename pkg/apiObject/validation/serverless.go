@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"strings"
+)
+
+// Function、Workflow的检查
+
+func ValidateFunctionSpec(spec *apiObject.FunctionSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	// kubectl会把userUploadFilePath目录压缩之后放到userUploadFile里面
+	if len(spec.UserUploadFile) == 0 && spec.UserUploadFilePath == "" {
+		errs.Add(field+".userUploadFile", "required, please specify userUploadFilePath")
+	}
+
+	return errs
+}
+
+var workflowNodeTypes = []string{string(apiObject.WorkflowNodeTypeFunc), string(apiObject.WorkflowNodeTypeChoice)}
+
+func ValidateWorkflowSpec(spec *apiObject.WorkflowSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	if len(spec.WorkflowNodes) == 0 {
+		errs.Add(field+".workflowNodes", "must have at least one node")
+	}
+
+	// 先收集所有节点的名字，检查跳转的时候使用
+	nodes := make(map[string]bool)
+	for i, node := range spec.WorkflowNodes {
+		nameField := fmt.Sprintf("%s.workflowNodes[%d].name", field, i)
+		if node.Name == "" {
+			errs.Add(nameField, "required")
+		} else if nodes[node.Name] {
+			errs.Add(nameField, "duplicate node name %q", node.Name)
+		}
+		nodes[node.Name] = true
+	}
+
+	errs.Required(field+".entryNodeName", spec.EntryNodeName)
+	if spec.EntryNodeName != "" && !nodes[spec.EntryNodeName] {
+		errs.Add(field+".entryNodeName", "node %q not found in workflowNodes", spec.EntryNodeName)
+	}
+
+	for i := range spec.WorkflowNodes {
+		errs = append(errs, validateWorkflowNode(&spec.WorkflowNodes[i], nodes, fmt.Sprintf("%s.workflowNodes[%d]", field, i))...)
+	}
+
+	return errs
+}
+
+func validateWorkflowNode(node *apiObject.WorkflowNode, nodes map[string]bool, field string) ErrorList {
+	errs := ErrorList{}
+
+	// 跳转的目标为空表示流程结束，不为空的时候必须存在
+	checkNext := func(next string, nextField string) {
+		if next != "" && !nodes[next] {
+			errs.Add(nextField, "node %q not found in workflowNodes", next)
+		}
+		if next != "" && next == node.Name {
+			errs.Add(nextField, "node %q can not jump to itself", next)
+		}
+	}
+
+	switch node.Type {
+	case apiObject.WorkflowNodeTypeFunc:
+		errs.Required(field+".funcData.funcName", node.FuncData.FuncName)
+		checkNext(node.FuncData.NextNodeName, field+".funcData.nextNodeName")
+	case apiObject.WorkflowNodeTypeChoice:
+		data := node.ChoiceData
+		checkNext(data.TrueNextNodeName, field+".choiceData.trueNextNodeName")
+		checkNext(data.FalseNextNodeName, field+".choiceData.falseNextNodeName")
+		errs.Required(field+".choiceData.checkVarName", data.CheckVarName)
+		errs.Required(field+".choiceData.checkType", string(data.CheckType))
+		checkTypes := append(append([]string{}, apiObject.ChoiceCheckNumTypeList...), apiObject.ChoiceCheckStrTypeList...)
+		validateEnum(&errs, string(data.CheckType), checkTypes, field+".choiceData.checkType")
+	case "":
+		errs.Add(field+".type", "required")
+	default:
+		errs.Add(field+".type", "%q is not supported, should be one of %s", node.Type, strings.Join(workflowNodeTypes, ", "))
+	}
+
+	return errs
+}
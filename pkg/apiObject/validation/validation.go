@@ -0,0 +1,267 @@
+package validation
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"strconv"
+	"strings"
+)
+
+// 每种API对象的合法性检查，APIServer在写入etcd之前调用
+// 检查不通过的时候返回所有不合法的字段，而不是遇到第一个就返回，方便用户一次性改完
+
+// 某个字段不合法
+type FieldError struct {
+	// 字段的路径，和yaml里面的写法一致，比如spec.template.spec.containers[0].image
+	Field string `json:"field" yaml:"field"`
+	// 不合法的原因
+	Reason string `json:"reason" yaml:"reason"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+type ErrorList []FieldError
+
+// 添加一个字段错误
+func (list *ErrorList) Add(field string, format string, args ...interface{}) {
+	*list = append(*list, FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+// 字段为空的时候添加一个required错误
+func (list *ErrorList) Required(field string, value string) {
+	if value == "" {
+		list.Add(field, "required")
+	}
+}
+
+func (list ErrorList) Error() string {
+	reasons := make([]string, 0, len(list))
+	for _, e := range list {
+		reasons = append(reasons, e.Error())
+	}
+	return strings.Join(reasons, "; ")
+}
+
+// 对象不合法的时候返回的错误，APIServer返回422
+type InvalidError struct {
+	Kind   string
+	Name   string
+	Errors ErrorList
+}
+
+func NewInvalidError(kind string, name string, errs ErrorList) *InvalidError {
+	return &InvalidError{
+		Kind:   kind,
+		Name:   name,
+		Errors: errs,
+	}
+}
+
+func (e *InvalidError) Error() string {
+	return fmt.Sprintf("%s %q is invalid: %s", e.Kind, e.Name, e.Errors.Error())
+}
+
+// 检查一个API对象，obj是请求对象或者存储对象的指针，比如*apiObject.Pod或者*apiObject.PodStore
+// 不认识的类型不做检查，返回nil
+func Validate(obj interface{}) ErrorList {
+	switch o := obj.(type) {
+	case *apiObject.Pod:
+		return validateObject(&o.Basic, true, ValidatePodSpec(&o.Spec, "spec"))
+	case *apiObject.PodStore:
+		return validateObject(&o.Basic, true, ValidatePodSpec(&o.Spec, "spec"))
+	case *apiObject.ReplicaSet:
+		return validateObject(&o.Basic, true, ValidateReplicaSetSpec(&o.Spec, "spec"))
+	case *apiObject.ReplicaSetStore:
+		return validateObject(&o.Basic, true, ValidateReplicaSetSpec(&o.Spec, "spec"))
+	case *apiObject.HPA:
+		return validateObject(&o.Basic, true, ValidateHPASpec(&o.Spec, "spec"))
+	case *apiObject.HPAStore:
+		return validateObject(&o.Basic, true, ValidateHPASpec(&o.Spec, "spec"))
+	case *apiObject.Job:
+		return validateObject(&o.Basic, true, ValidateJobSpec(&o.Spec, "spec"))
+	case *apiObject.JobStore:
+		return validateObject(&o.Basic, true, ValidateJobSpec(&o.Spec, "spec"))
+	case *apiObject.Service:
+		return validateObject(&o.Basic, true, ValidateServiceSpec(&o.Spec, "spec"))
+	case *apiObject.ServiceStore:
+		return validateObject(&o.Basic, true, ValidateServiceSpec(&o.Spec, "spec"))
+	case *apiObject.Dns:
+		return validateObject(&o.Basic, true, ValidateDnsSpec(&o.Spec, "spec"))
+	case *apiObject.HpaStore:
+		// HpaStore是Dns的存储对象
+		return validateObject(&o.Basic, true, ValidateDnsSpec(&o.Spec, "spec"))
+	case *apiObject.Function:
+		return validateObject(&o.Basic, true, ValidateFunctionSpec(&o.Spec, "spec"))
+	case *apiObject.Workflow:
+		return validateObject(&o.Basic, true, ValidateWorkflowSpec(&o.Spec, "spec"))
+	case *apiObject.WorkflowStore:
+		return validateObject(&o.Basic, true, ValidateWorkflowSpec(&o.Spec, "spec"))
+	case *apiObject.Node:
+		return ValidateNode(o)
+	case *apiObject.NodeStore:
+		return ValidateNode(o.ToNode())
+	case *apiObject.Namespace:
+		return validateObject(&o.Basic, false, nil)
+	case *apiObject.NamespaceStore:
+		return validateObject(&o.Basic, false, nil)
+	case *apiObject.CustomResourceDefinition:
+		return validateObject(&o.Basic, false, ValidateCRDSpec(o))
+	case *apiObject.CustomObject:
+		return validateObject(&o.Basic, true, nil)
+	case *apiObject.MutatingWebhookConfiguration:
+		return validateObject(&o.Basic, false, ValidateWebhooks(o.Webhooks, "webhooks"))
+	case *apiObject.ValidatingWebhookConfiguration:
+		return validateObject(&o.Basic, false, ValidateWebhooks(o.Webhooks, "webhooks"))
+	case *apiObject.Role:
+		return validateObject(&o.Basic, true, ValidatePolicyRules(o.Rules, "rules"))
+	case *apiObject.ClusterRole:
+		return validateObject(&o.Basic, false, ValidatePolicyRules(o.Rules, "rules"))
+	case *apiObject.RoleBinding:
+		return validateObject(&o.Basic, true, ValidateRoleBinding(o.Subjects, &o.RoleRef, false))
+	case *apiObject.ClusterRoleBinding:
+		return validateObject(&o.Basic, false, ValidateRoleBinding(o.Subjects, &o.RoleRef, true))
+	case *apiObject.Event:
+		return validateObject(&o.Basic, true, ValidateEvent(o))
+	case *apiObject.Lease:
+		return validateObject(&o.Basic, true, ValidateLease(o))
+	}
+	return nil
+}
+
+// 检查metadata，再合并spec的检查结果
+func validateObject(basic *apiObject.Basic, namespaced bool, specErrs ErrorList) ErrorList {
+	errs := ValidateMetadata(&basic.Metadata, namespaced, "metadata")
+	return append(errs, specErrs...)
+}
+
+// name和namespace会出现在etcd的key和url里面，不能带有/，长度不能超过253
+func ValidateMetadata(meta *apiObject.Metadata, namespaced bool, field string) ErrorList {
+	errs := ErrorList{}
+	validateName(&errs, meta.Name, field+".name")
+	if namespaced && meta.Namespace != "" {
+		validateName(&errs, meta.Namespace, field+".namespace")
+	}
+	validateLabels(&errs, meta.Labels, field+".labels")
+	validateOwnerReferences(&errs, meta.OwnerReferences, field+".ownerReferences")
+	validateFinalizers(&errs, meta.Finalizers, field+".finalizers")
+	return errs
+}
+
+// 通过metadata子资源更新ownerReferences和finalizers
+// 正在删除的对象只能移除finalizers，不能添加新的
+func ValidateMetadataUpdate(old *apiObject.Metadata, update *apiObject.MetadataUpdate, field string) ErrorList {
+	errs := ErrorList{}
+	validateOwnerReferences(&errs, update.OwnerReferences, field+".ownerReferences")
+	validateFinalizers(&errs, update.Finalizers, field+".finalizers")
+	for i, ref := range update.OwnerReferences {
+		if ref.UUID != "" && ref.UUID == old.UUID {
+			errs.Add(fmt.Sprintf("%s.ownerReferences[%d].uuid", field, i), "object can not own itself")
+		}
+	}
+	if old.IsBeingDeleted() {
+		for i, f := range update.Finalizers {
+			if !old.HasFinalizer(f) {
+				errs.Add(fmt.Sprintf("%s.finalizers[%d]", field, i), "can not add finalizer %q to an object being deleted", f)
+			}
+		}
+	}
+	return errs
+}
+
+// 所有者的kind、name和uuid都不能为空
+func validateOwnerReferences(errs *ErrorList, refs []apiObject.OwnerReference, field string) {
+	for i, ref := range refs {
+		refField := fmt.Sprintf("%s[%d]", field, i)
+		errs.Required(refField+".kind", ref.Kind)
+		errs.Required(refField+".name", ref.Name)
+		errs.Required(refField+".uuid", ref.UUID)
+	}
+}
+
+func validateFinalizers(errs *ErrorList, finalizers []string, field string) {
+	for i, f := range finalizers {
+		if f == "" {
+			errs.Add(fmt.Sprintf("%s[%d]", field, i), "finalizer must not be empty")
+		}
+	}
+}
+
+const maxNameLength = 253
+
+func validateName(errs *ErrorList, name string, field string) {
+	if name == "" {
+		errs.Add(field, "required")
+		return
+	}
+	if len(name) > maxNameLength {
+		errs.Add(field, "must be no more than %d characters", maxNameLength)
+	}
+	if strings.ContainsAny(name, "/ \t\n") {
+		errs.Add(field, "%q must not contain '/' or whitespace", name)
+	}
+}
+
+func validateLabels(errs *ErrorList, labels map[string]string, field string) {
+	for key := range labels {
+		if key == "" {
+			errs.Add(field, "label key must not be empty")
+		}
+		// labelSelector用,和=分隔，key里面不能出现
+		if strings.ContainsAny(key, ",= ") {
+			errs.Add(field+"."+key, "label key must not contain ',', '=' or space")
+		}
+	}
+}
+
+// 端口号必须在1-65535之间
+func validatePort(errs *ErrorList, port int, field string) {
+	if port < 1 || port > 65535 {
+		errs.Add(field, "%d must be between 1 and 65535", port)
+	}
+}
+
+// 字符串形式的端口号，为空的时候不检查
+func validatePortString(errs *ErrorList, port string, field string) {
+	if port == "" {
+		return
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		errs.Add(field, "%q is not a number", port)
+		return
+	}
+	validatePort(errs, p, field)
+}
+
+// 协议可以为空，否则只能是TCP或者UDP，大小写不敏感
+func validateProtocol(errs *ErrorList, protocol string, field string) {
+	if protocol == "" {
+		return
+	}
+	switch strings.ToUpper(protocol) {
+	case "TCP", "UDP":
+	default:
+		errs.Add(field, "%q is not supported, should be TCP or UDP", protocol)
+	}
+}
+
+func validateNonNegative(errs *ErrorList, value int, field string) {
+	if value < 0 {
+		errs.Add(field, "%d must be greater than or equal to 0", value)
+	}
+}
+
+// value为空或者在list里面
+func validateEnum(errs *ErrorList, value string, list []string, field string) {
+	if value == "" {
+		return
+	}
+	for _, v := range list {
+		if v == value {
+			return
+		}
+	}
+	errs.Add(field, "%q is not supported, should be one of %s", value, strings.Join(list, ", "))
+}
@@ -0,0 +1,318 @@
+package validation
+
+import (
+	"miniK8s/pkg/apiObject"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 检查errs里面是否有某个字段的错误
+func hasField(errs ErrorList, field string) bool {
+	for _, e := range errs {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func newValidPodSpec() apiObject.PodSpec {
+	return apiObject.PodSpec{
+		RestartPolicy: "Always",
+		Containers: []apiObject.Container{
+			{
+				Name:  "nginx",
+				Image: "nginx:latest",
+				Ports: []apiObject.ContainerPort{{ContainerPort: "80"}},
+			},
+		},
+	}
+}
+
+func TestValidatePod(t *testing.T) {
+	pod := &apiObject.Pod{}
+	pod.Metadata.Name = "pod-example"
+	pod.Metadata.Namespace = "default"
+	pod.Spec = newValidPodSpec()
+
+	if errs := Validate(pod); len(errs) != 0 {
+		t.Fatalf("valid pod should pass, got %v", errs)
+	}
+
+	pod.Spec.Containers[0].Image = ""
+	pod.Spec.Containers[0].Ports[0].ContainerPort = "http"
+	pod.Spec.Containers[0].VolumeMounts = []apiObject.VolumeMount{{Name: "data", MountPath: "/data"}}
+	pod.Spec.RestartPolicy = "Sometimes"
+
+	errs := Validate(pod)
+	for _, field := range []string{
+		"spec.containers[0].image",
+		"spec.containers[0].ports[0].containerPort",
+		"spec.containers[0].volumeMounts[0].name",
+		"spec.restartPolicy",
+	} {
+		if !hasField(errs, field) {
+			t.Errorf("should report %s, got %v", field, errs)
+		}
+	}
+
+	pod.Spec.Containers = nil
+	if !hasField(Validate(pod), "spec.containers") {
+		t.Errorf("pod without containers should be rejected")
+	}
+}
+
+func TestValidateReplicaSet(t *testing.T) {
+	rs := &apiObject.ReplicaSet{}
+	rs.Metadata.Name = "replica-example"
+	rs.Spec.Replicas = 3
+	rs.Spec.Selector.MatchLabels = map[string]string{"app": "web"}
+	rs.Spec.Template.Metadata.Labels = map[string]string{"app": "web"}
+	rs.Spec.Template.Spec = newValidPodSpec()
+
+	if errs := Validate(rs); len(errs) != 0 {
+		t.Fatalf("valid replicaset should pass, got %v", errs)
+	}
+
+	rs.Spec.Replicas = -1
+	rs.Spec.Template.Metadata.Labels["app"] = "db"
+	rs.Spec.Template.Spec.Containers[0].Image = ""
+
+	errs := Validate(rs)
+	for _, field := range []string{
+		"spec.replicas",
+		"spec.template.metadata.labels",
+		"spec.template.spec.containers[0].image",
+	} {
+		if !hasField(errs, field) {
+			t.Errorf("should report %s, got %v", field, errs)
+		}
+	}
+}
+
+func TestValidateHPA(t *testing.T) {
+	hpa := &apiObject.HPAStore{}
+	hpa.Metadata.Name = "hpa-example"
+	hpa.Spec.MinReplicas = 2
+	hpa.Spec.MaxReplicas = 5
+	hpa.Spec.Workload.Kind = apiObject.PodKind
+	hpa.Spec.Workload.Metadata.Name = "pod-example"
+	hpa.Spec.AdjustInterval = 15 * time.Second
+	hpa.Spec.Selector.MatchLabels = map[string]string{"app": "hpa"}
+
+	if errs := Validate(hpa); len(errs) != 0 {
+		t.Fatalf("valid hpa should pass, got %v", errs)
+	}
+
+	hpa.Spec.MinReplicas = 6
+	if !hasField(Validate(hpa), "spec.maxReplicas") {
+		t.Errorf("minReplicas > maxReplicas should be rejected")
+	}
+}
+
+func TestValidateService(t *testing.T) {
+	service := &apiObject.Service{}
+	service.Metadata.Name = "service-example"
+	service.Spec.Selector = map[string]string{"app": "web"}
+	service.Spec.Ports = []apiObject.ServicePort{{Name: "http", Port: 88, TargetPort: 80, Protocol: "tcp"}}
+
+	if errs := Validate(service); len(errs) != 0 {
+		t.Fatalf("valid service should pass, got %v", errs)
+	}
+
+	service.Spec.Ports = nil
+	if !hasField(Validate(service), "spec.ports") {
+		t.Errorf("service without ports should be rejected")
+	}
+
+	service.Spec.Ports = []apiObject.ServicePort{{Port: 70000, TargetPort: 80}}
+	if !hasField(Validate(service), "spec.ports[0].port") {
+		t.Errorf("port out of range should be rejected")
+	}
+}
+
+func TestValidateWorkflow(t *testing.T) {
+	workflow := &apiObject.Workflow{}
+	workflow.Metadata.Name = "workflow-example"
+	workflow.Spec.EntryNodeName = "node1"
+	workflow.Spec.WorkflowNodes = []apiObject.WorkflowNode{
+		{
+			Name:     "node1",
+			Type:     apiObject.WorkflowNodeTypeFunc,
+			FuncData: apiObject.WorkflowFuncData{FuncName: "func1", NextNodeName: "node2"},
+		},
+		{
+			Name: "node2",
+			Type: apiObject.WorkflowNodeTypeChoice,
+			ChoiceData: apiObject.WorkflowChoiceData{
+				TrueNextNodeName:  "node3",
+				FalseNextNodeName: "node1",
+				CheckType:         apiObject.ChoiceCheckTypeNumGreaterThan,
+				CheckVarName:      "y",
+				CompareValue:      "0",
+			},
+		},
+		{
+			Name:     "node3",
+			Type:     apiObject.WorkflowNodeTypeFunc,
+			FuncData: apiObject.WorkflowFuncData{FuncName: "func3"},
+		},
+	}
+
+	if errs := Validate(workflow); len(errs) != 0 {
+		t.Fatalf("valid workflow should pass, got %v", errs)
+	}
+
+	workflow.Spec.WorkflowNodes[1].ChoiceData.TrueNextNodeName = "node4"
+	workflow.Spec.WorkflowNodes[2].Type = "loop"
+	workflow.Spec.EntryNodeName = "start"
+
+	errs := Validate(workflow)
+	for _, field := range []string{
+		"spec.workflowNodes[1].choiceData.trueNextNodeName",
+		"spec.workflowNodes[2].type",
+		"spec.entryNodeName",
+	} {
+		if !hasField(errs, field) {
+			t.Errorf("should report %s, got %v", field, errs)
+		}
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	ns := &apiObject.Namespace{}
+	if !hasField(Validate(ns), "metadata.name") {
+		t.Errorf("empty name should be rejected")
+	}
+
+	ns.Metadata.Name = "a/b"
+	if !hasField(Validate(ns), "metadata.name") {
+		t.Errorf("name with / should be rejected")
+	}
+}
+
+func TestValidateMetadataUpdate(t *testing.T) {
+	old := &apiObject.Metadata{UUID: "uuid-1", Finalizers: []string{apiObject.FinalizerForegroundDeletion}}
+
+	update := &apiObject.MetadataUpdate{
+		OwnerReferences: []apiObject.OwnerReference{{Kind: apiObject.ReplicaSetKind, Name: "rs1"}},
+		Finalizers:      []string{""},
+	}
+	errs := ValidateMetadataUpdate(old, update, "metadata")
+	if !hasField(errs, "metadata.ownerReferences[0].uuid") || !hasField(errs, "metadata.finalizers[0]") {
+		t.Errorf("owner without uuid and empty finalizer should be rejected, got %v", errs)
+	}
+
+	update = &apiObject.MetadataUpdate{
+		OwnerReferences: []apiObject.OwnerReference{{Kind: apiObject.ReplicaSetKind, Name: "rs1", UUID: "uuid-1"}},
+	}
+	if !hasField(ValidateMetadataUpdate(old, update, "metadata"), "metadata.ownerReferences[0].uuid") {
+		t.Errorf("object owning itself should be rejected")
+	}
+
+	// 正在删除的对象只能移除finalizers
+	now := time.Now()
+	old.DeletionTimestamp = &now
+	update = &apiObject.MetadataUpdate{Finalizers: []string{"custom"}}
+	if !hasField(ValidateMetadataUpdate(old, update, "metadata"), "metadata.finalizers[0]") {
+		t.Errorf("adding finalizer to an object being deleted should be rejected")
+	}
+	update = &apiObject.MetadataUpdate{}
+	if errs := ValidateMetadataUpdate(old, update, "metadata"); len(errs) != 0 {
+		t.Errorf("removing finalizers should be allowed, got %v", errs)
+	}
+}
+
+func TestValidateEvent(t *testing.T) {
+	event := &apiObject.Event{
+		InvolvedObject: apiObject.ObjectReference{Kind: apiObject.PodKind, Name: "pod1"},
+		Reason:         "Pulled",
+		Type:           "Error",
+	}
+	event.Metadata.Name = "pod1.abc"
+	errs := Validate(event)
+	if !hasField(errs, "type") || !hasField(errs, "count") {
+		t.Errorf("unknown type and zero count should be rejected, got %v", errs)
+	}
+
+	event.Type = apiObject.EventTypeNormal
+	event.Count = 1
+	if errs := Validate(event); len(errs) != 0 {
+		t.Errorf("expected valid event, got %v", errs)
+	}
+}
+
+func TestValidateLease(t *testing.T) {
+	lease := &apiObject.Lease{}
+	lease.Metadata.Name = "kube-scheduler"
+	errs := Validate(lease)
+	if !hasField(errs, "spec.leaseDurationSeconds") {
+		t.Errorf("zero lease duration should be rejected, got %v", errs)
+	}
+
+	// 释放之后holderIdentity为空
+	lease.Spec.LeaseDurationSeconds = 15
+	if errs := Validate(lease); len(errs) != 0 {
+		t.Errorf("expected valid lease, got %v", errs)
+	}
+}
+
+func TestValidateRBAC(t *testing.T) {
+	role := &apiObject.Role{}
+	role.Metadata.Name = "function-developer"
+	role.Metadata.Namespace = "interns"
+	role.Rules = []apiObject.PolicyRule{{Verbs: []string{"get", "list", "create"}, Kinds: []string{apiObject.FunctionKind}}}
+	if errs := Validate(role); len(errs) != 0 {
+		t.Fatalf("valid role should pass, got %v", errs)
+	}
+
+	role.Rules = append(role.Rules, apiObject.PolicyRule{Verbs: []string{"update"}, Kinds: []string{"Job/status", "/status"}, ResourceNames: []string{"train", ""}})
+	errs := Validate(role)
+	if !hasField(errs, "rules[1].kinds[1]") || !hasField(errs, "rules[1].resourceNames[1]") || hasField(errs, "rules[1].kinds[0]") {
+		t.Errorf("should report rules[1].kinds[1] and rules[1].resourceNames[1], got %v", errs)
+	}
+	role.Rules = role.Rules[:1]
+
+	role.Rules[0].Verbs = append(role.Rules[0].Verbs, "escalate")
+	role.Rules[0].Kinds = nil
+	errs = Validate(role)
+	if !hasField(errs, "rules[0].verbs[3]") || !hasField(errs, "rules[0].kinds") {
+		t.Errorf("should report rules[0].verbs[3] and rules[0].kinds, got %v", errs)
+	}
+
+	binding := &apiObject.ClusterRoleBinding{}
+	binding.Metadata.Name = "interns"
+	binding.Subjects = []apiObject.Subject{{Kind: "ServiceAccount", Name: "intern"}}
+	binding.RoleRef = apiObject.RoleRef{Kind: apiObject.RoleKind, Name: "function-developer"}
+	errs = Validate(binding)
+	if !hasField(errs, "subjects[0].kind") || !hasField(errs, "roleRef.kind") {
+		t.Errorf("should report subjects[0].kind and roleRef.kind, got %v", errs)
+	}
+}
+
+func TestValidateTokenRequest(t *testing.T) {
+	req := &apiObject.TokenRequest{Type: apiObject.TokenTypeUser, User: "alice"}
+	if errs := ValidateTokenRequest(req); len(errs) != 0 {
+		t.Fatalf("valid token request should pass, got %v", errs)
+	}
+
+	req = &apiObject.TokenRequest{Type: "admin"}
+	errs := ValidateTokenRequest(req)
+	if !hasField(errs, "type") || !hasField(errs, "user") {
+		t.Errorf("should report type and user, got %v", errs)
+	}
+}
+
+func TestInvalidError(t *testing.T) {
+	errs := ErrorList{}
+	errs.Add("spec.replicas", "%d must be greater than or equal to 0", -1)
+	errs.Required("spec.template.spec.containers[0].image", "")
+
+	err := NewInvalidError(apiObject.ReplicaSetKind, "replica-example", errs)
+	msg := err.Error()
+	if !strings.Contains(msg, "spec.replicas: -1 must be greater than or equal to 0") ||
+		!strings.Contains(msg, "spec.template.spec.containers[0].image: required") {
+		t.Errorf("unexpected error message %s", msg)
+	}
+}
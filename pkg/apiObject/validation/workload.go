@@ -0,0 +1,155 @@
+package validation
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+)
+
+// Pod、Replicaset、Hpa、Job的检查
+
+var restartPolicies = []string{"Always", "OnFailure", "Never"}
+
+var imagePullPolicies = []string{"Always", "IfNotPresent", "Never"}
+
+func ValidatePodSpec(spec *apiObject.PodSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	validateEnum(&errs, spec.RestartPolicy, restartPolicies, field+".restartPolicy")
+
+	// 先检查volume，容器挂载的时候需要用到
+	volumes := make(map[string]bool)
+	for i, volume := range spec.Volumes {
+		volumeField := fmt.Sprintf("%s.volumes[%d]", field, i)
+		if volume.Name == "" {
+			errs.Add(volumeField+".name", "required")
+		} else if volumes[volume.Name] {
+			errs.Add(volumeField+".name", "duplicate volume name %q", volume.Name)
+		}
+		volumes[volume.Name] = true
+		errs.Required(volumeField+".hostPath.path", volume.HostPath.Path)
+	}
+
+	if len(spec.Containers) == 0 {
+		errs.Add(field+".containers", "must have at least one container")
+	}
+	for i := range spec.Containers {
+		errs = append(errs, validateContainer(&spec.Containers[i], volumes, fmt.Sprintf("%s.containers[%d]", field, i))...)
+	}
+
+	return errs
+}
+
+func validateContainer(container *apiObject.Container, volumes map[string]bool, field string) ErrorList {
+	errs := ErrorList{}
+
+	errs.Required(field+".name", container.Name)
+	errs.Required(field+".image", container.Image)
+	validateEnum(&errs, container.ImagePullPolicy, imagePullPolicies, field+".imagePullPolicy")
+
+	for i, port := range container.Ports {
+		portField := fmt.Sprintf("%s.ports[%d]", field, i)
+		validatePortString(&errs, port.ContainerPort, portField+".containerPort")
+		validatePortString(&errs, port.HostPort, portField+".hostPort")
+		validateProtocol(&errs, port.Protocol, portField+".protocol")
+	}
+
+	for i, env := range container.Env {
+		errs.Required(fmt.Sprintf("%s.env[%d].name", field, i), env.Name)
+	}
+
+	validateNonNegative(&errs, container.Resources.Limits.CPU, field+".resources.limits.cpu")
+	validateNonNegative(&errs, container.Resources.Limits.Memory, field+".resources.limits.memory")
+	validateNonNegative(&errs, container.Resources.Requests.CPU, field+".resources.requests.cpu")
+	validateNonNegative(&errs, container.Resources.Requests.Memory, field+".resources.requests.memory")
+
+	for i, mount := range container.VolumeMounts {
+		mountField := fmt.Sprintf("%s.volumeMounts[%d]", field, i)
+		if !volumes[mount.Name] {
+			errs.Add(mountField+".name", "volume %q not found in volumes", mount.Name)
+		}
+		errs.Required(mountField+".mountPath", mount.MountPath)
+	}
+
+	probe := container.LivenessProbe
+	if probe.HttpGet.Port != 0 {
+		validatePort(&errs, probe.HttpGet.Port, field+".livenessProbe.httpGet.port")
+	}
+	validateNonNegative(&errs, probe.InitialDelaySeconds, field+".livenessProbe.initialDelaySeconds")
+	validateNonNegative(&errs, probe.TimeoutSeconds, field+".livenessProbe.timeoutSeconds")
+	validateNonNegative(&errs, probe.PeriodSeconds, field+".livenessProbe.periodSeconds")
+
+	return errs
+}
+
+func ValidateReplicaSetSpec(spec *apiObject.ReplicaSetSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	validateNonNegative(&errs, spec.Replicas, field+".replicas")
+
+	if len(spec.Selector.MatchLabels) == 0 {
+		errs.Add(field+".selector.matchLabels", "must not be empty")
+	}
+	validateLabels(&errs, spec.Template.Metadata.Labels, field+".template.metadata.labels")
+
+	// template的label必须能被selector选中，否则创建出来的Pod不归这个Replicaset管理
+	for key, value := range spec.Selector.MatchLabels {
+		if spec.Template.Metadata.Labels[key] != value {
+			errs.Add(field+".template.metadata.labels", "does not match selector %s=%s", key, value)
+		}
+	}
+
+	return append(errs, ValidatePodSpec(&spec.Template.Spec, field+".template.spec")...)
+}
+
+// Hpa可以控制的workload
+var hpaWorkloadKinds = []string{apiObject.PodKind, apiObject.ReplicaSetKind}
+
+func ValidateHPASpec(spec *apiObject.HPASpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	if spec.MinReplicas < 1 {
+		errs.Add(field+".minReplicas", "%d must be greater than or equal to 1", spec.MinReplicas)
+	}
+	if spec.MaxReplicas < spec.MinReplicas {
+		errs.Add(field+".maxReplicas", "%d must be greater than or equal to minReplicas %d", spec.MaxReplicas, spec.MinReplicas)
+	}
+
+	errs.Required(field+".workload.kind", spec.Workload.Kind)
+	validateEnum(&errs, spec.Workload.Kind, hpaWorkloadKinds, field+".workload.kind")
+	errs.Required(field+".workload.metadata.name", spec.Workload.Metadata.Name)
+
+	// hpa controller通过selector找到自己管理的Pod
+	if len(spec.Selector.MatchLabels) == 0 {
+		errs.Add(field+".selector.matchLabels", "must not be empty")
+	}
+
+	if spec.AdjustInterval < 0 {
+		errs.Add(field+".adjustInterval", "%s must not be negative", spec.AdjustInterval)
+	}
+	if spec.Metrics.CPUPercent < 0 {
+		errs.Add(field+".metrics.cpuPercent", "%v must not be negative", spec.Metrics.CPUPercent)
+	}
+	if spec.Metrics.MemPercent < 0 {
+		errs.Add(field+".metrics.memPercent", "%v must not be negative", spec.Metrics.MemPercent)
+	}
+
+	return errs
+}
+
+func ValidateJobSpec(spec *apiObject.JobSpec, field string) ErrorList {
+	errs := ErrorList{}
+
+	if spec.NTasks < 1 {
+		errs.Add(field+".nTasks", "%d must be greater than or equal to 1", spec.NTasks)
+	}
+	validateNonNegative(&errs, spec.NTasksPerNode, field+".nTasksPerNode")
+	validateNonNegative(&errs, spec.GPUNums, field+".gpuNums")
+
+	if len(spec.RunCommands) == 0 {
+		errs.Add(field+".runCommands", "must have at least one command")
+	}
+	errs.Required(field+".outputFile", spec.OutputFile)
+	errs.Required(field+".errorFile", spec.ErrorFile)
+
+	return errs
+}
@@ -0,0 +1,29 @@
+package apiObject
+
+import "encoding/json"
+
+// watch接口推送的事件类型
+const (
+	WatchEventAdded    = "ADDED"
+	WatchEventModified = "MODIFIED"
+	WatchEventDeleted  = "DELETED"
+	// watch失败，Object是WatchStatus，之后连接会被关闭
+	WatchEventError = "ERROR"
+)
+
+// 开始监听的resourceVersion已经被压缩，客户端需要重新list
+const WatchReasonExpired = "Expired"
+
+// watch接口每次推送的一个事件，一行一个json
+// Object是对象完整的json，metadata里面带有resourceVersion
+type WatchEvent struct {
+	Type   string          `json:"type" yaml:"type"`
+	Object json.RawMessage `json:"object" yaml:"object"`
+}
+
+// ERROR事件的Object，Code和HTTP的状态码一样，比如410表示resourceVersion已经过期
+type WatchStatus struct {
+	Code    int    `json:"code" yaml:"code"`
+	Reason  string `json:"reason" yaml:"reason"`
+	Message string `json:"message" yaml:"message"`
+}
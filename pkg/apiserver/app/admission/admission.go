@@ -0,0 +1,106 @@
+package admission
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 准入控制
+// APIServer把请求中的对象写入etcd之前，先依次执行修改型的插件(Admit)，再依次执行校验型的插件(Validate)
+// 内置的插件在前面，外部的webhook在后面，任何一个插件返回错误都会拒绝这个请求
+
+// 一次请求的信息，交给每个插件处理
+type Attributes struct {
+	// 对象的kind，比如Pod
+	Kind string
+	// 对象所在的namespace，集群级别的对象为空
+	Namespace string
+	Name      string
+	// apiObject.AdmissionOperationCreate等
+	Operation string
+	// 请求中的对象，是存储对象的指针，比如*apiObject.PodStore，删除的时候为nil
+	// 修改型的插件可以直接修改它
+	Object interface{}
+	// etcd中原来的对象，创建的时候为nil
+	OldObject interface{}
+}
+
+// 修改型的插件，可以修改Attributes.Object
+type MutationInterface interface {
+	Name() string
+	Admit(a *Attributes) error
+}
+
+// 校验型的插件，不能修改对象
+type ValidationInterface interface {
+	Name() string
+	Validate(a *Attributes) error
+}
+
+// 插件拒绝请求的时候返回的错误，Code是返回给客户端的状态码
+type Error struct {
+	Code    int
+	Plugin  string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return "admission plugin " + e.Plugin + " denied the request: " + e.Message
+}
+
+func newError(code int, plugin string, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Plugin:  plugin,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// 按照添加的顺序执行的插件链
+type Chain struct {
+	mutators   []MutationInterface
+	validators []ValidationInterface
+}
+
+func NewChain() *Chain {
+	return &Chain{
+		mutators:   make([]MutationInterface, 0),
+		validators: make([]ValidationInterface, 0),
+	}
+}
+
+func (c *Chain) AddMutator(m MutationInterface) {
+	c.mutators = append(c.mutators, m)
+}
+
+func (c *Chain) AddValidator(v ValidationInterface) {
+	c.validators = append(c.validators, v)
+}
+
+// 依次执行所有修改型的插件
+func (c *Chain) Admit(a *Attributes) error {
+	for _, m := range c.mutators {
+		if err := m.Admit(a); err != nil {
+			return wrapError(m.Name(), err)
+		}
+	}
+	return nil
+}
+
+// 依次执行所有校验型的插件
+func (c *Chain) Validate(a *Attributes) error {
+	for _, v := range c.validators {
+		if err := v.Validate(a); err != nil {
+			return wrapError(v.Name(), err)
+		}
+	}
+	return nil
+}
+
+// 插件返回的普通错误统一转换为403
+func wrapError(plugin string, err error) error {
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	return newError(http.StatusForbidden, plugin, "%s", err.Error())
+}
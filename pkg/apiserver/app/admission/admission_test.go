@@ -0,0 +1,266 @@
+package admission
+
+import (
+	"encoding/json"
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestPod() *apiObject.PodStore {
+	pod := &apiObject.PodStore{}
+	pod.Kind = apiObject.PodKind
+	pod.Metadata.Name = "test-pod"
+	pod.Metadata.Namespace = "default"
+	pod.Spec.Containers = []apiObject.Container{
+		{Name: "nginx", Image: "nginx:latest"},
+		{Name: "busybox", Image: "busybox:latest", ImagePullPolicy: "Always"},
+	}
+	return pod
+}
+
+func newTestAttributes(pod *apiObject.PodStore) *Attributes {
+	return &Attributes{
+		Kind:      apiObject.PodKind,
+		Namespace: pod.Metadata.Namespace,
+		Name:      pod.Metadata.Name,
+		Operation: apiObject.AdmissionOperationCreate,
+		Object:    pod,
+	}
+}
+
+func TestDefaultValues(t *testing.T) {
+	pod := newTestPod()
+	pod.Metadata.Namespace = ""
+
+	if err := NewDefaultValues().Admit(newTestAttributes(pod)); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+
+	if pod.Spec.RestartPolicy != "Always" {
+		t.Errorf("RestartPolicy should be Always, got %s", pod.Spec.RestartPolicy)
+	}
+	if pod.Spec.Containers[0].ImagePullPolicy != "IfNotPresent" {
+		t.Errorf("ImagePullPolicy should be IfNotPresent, got %s", pod.Spec.Containers[0].ImagePullPolicy)
+	}
+	// 用户设置的值不能被覆盖
+	if pod.Spec.Containers[1].ImagePullPolicy != "Always" {
+		t.Errorf("ImagePullPolicy should stay Always, got %s", pod.Spec.Containers[1].ImagePullPolicy)
+	}
+	if pod.Metadata.Namespace != "default" {
+		t.Errorf("Namespace should be default, got %s", pod.Metadata.Namespace)
+	}
+}
+
+func TestDuplicateContainerNames(t *testing.T) {
+	plugin := NewDuplicateContainerNames()
+
+	pod := newTestPod()
+	if err := plugin.Validate(newTestAttributes(pod)); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	pod.Spec.Containers[1].Name = "nginx"
+	err := plugin.Validate(newTestAttributes(pod))
+	if err == nil {
+		t.Fatalf("duplicate container names should be rejected")
+	}
+	if err.(*Error).Code != http.StatusUnprocessableEntity {
+		t.Errorf("code should be 422, got %d", err.(*Error).Code)
+	}
+	if !strings.Contains(err.Error(), "spec.containers[1].name") {
+		t.Errorf("error should contain the field, got %s", err.Error())
+	}
+
+	// Replicaset的template里面的容器也要检查
+	rs := &apiObject.ReplicaSet{}
+	rs.Metadata.Name = "test-rs"
+	rs.Spec.Template.Spec.Containers = pod.Spec.Containers
+	err = plugin.Validate(&Attributes{Kind: apiObject.ReplicaSetKind, Operation: apiObject.AdmissionOperationCreate, Object: rs})
+	if err == nil || !strings.Contains(err.Error(), "spec.template.spec.containers[1].name") {
+		t.Errorf("duplicate container names in template should be rejected, got %v", err)
+	}
+}
+
+func TestNamespaceLifecycle(t *testing.T) {
+	plugin := NewNamespaceLifecycle(func(namespace string) (int, error) {
+		if namespace == "default" {
+			return http.StatusOK, nil
+		}
+		return http.StatusNotFound, errors.New("namespace " + namespace + " not found")
+	})
+
+	pod := newTestPod()
+	if err := plugin.Validate(newTestAttributes(pod)); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	pod.Metadata.Namespace = "missing"
+	err := plugin.Validate(newTestAttributes(pod))
+	if err == nil || err.(*Error).Code != http.StatusNotFound {
+		t.Fatalf("missing namespace should be rejected with 404, got %v", err)
+	}
+
+	// 删除的时候不检查
+	attr := newTestAttributes(pod)
+	attr.Operation = apiObject.AdmissionOperationDelete
+	if err := plugin.Validate(attr); err != nil {
+		t.Errorf("delete should not be checked, got %v", err)
+	}
+}
+
+// 启动一个测试用的webhook，handler根据请求生成返回
+func newTestWebhookServer(t *testing.T, handler func(request *apiObject.AdmissionRequest) *apiObject.AdmissionResponse) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := &apiObject.AdmissionReview{}
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			t.Errorf("decode review failed: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := handler(review.Request)
+		response.UID = review.Request.UID
+		json.NewEncoder(w).Encode(&apiObject.AdmissionReview{Response: response})
+	}))
+}
+
+func TestMutatingWebhook(t *testing.T) {
+	server := newTestWebhookServer(t, func(request *apiObject.AdmissionRequest) *apiObject.AdmissionResponse {
+		pod := &apiObject.PodStore{}
+		json.Unmarshal(request.Object, pod)
+		if pod.Metadata.Labels == nil {
+			pod.Metadata.Labels = map[string]string{}
+		}
+		pod.Metadata.Labels["team"] = "ai"
+		object, _ := json.Marshal(pod)
+		return &apiObject.AdmissionResponse{Allowed: true, Object: object}
+	})
+	defer server.Close()
+
+	configs := []apiObject.MutatingWebhookConfiguration{{
+		Webhooks: []apiObject.Webhook{{
+			Name: "add-label",
+			URL:  server.URL,
+			Rules: []apiObject.WebhookRule{{
+				Operations: []string{apiObject.AdmissionOperationCreate},
+				Kinds:      []string{apiObject.PodKind},
+			}},
+		}},
+	}}
+	plugin := NewMutatingWebhook(func() ([]apiObject.MutatingWebhookConfiguration, error) {
+		return configs, nil
+	})
+
+	pod := newTestPod()
+	if err := plugin.Admit(newTestAttributes(pod)); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if pod.Metadata.Labels["team"] != "ai" {
+		t.Errorf("label should be added by webhook, got %v", pod.Metadata.Labels)
+	}
+
+	// 不匹配的kind不会调用webhook
+	rs := &apiObject.ReplicaSet{}
+	rs.Metadata.Name = "test-rs"
+	attr := &Attributes{Kind: apiObject.ReplicaSetKind, Name: "test-rs", Operation: apiObject.AdmissionOperationCreate, Object: rs}
+	if err := plugin.Admit(attr); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if len(rs.Metadata.Labels) != 0 {
+		t.Errorf("webhook should not be called for Replicaset")
+	}
+}
+
+func TestValidatingWebhook(t *testing.T) {
+	server := newTestWebhookServer(t, func(request *apiObject.AdmissionRequest) *apiObject.AdmissionResponse {
+		pod := &apiObject.PodStore{}
+		json.Unmarshal(request.Object, pod)
+		for _, container := range pod.Spec.Containers {
+			if strings.HasSuffix(container.Image, ":latest") {
+				return &apiObject.AdmissionResponse{Allowed: false, Message: "image tag latest is not allowed"}
+			}
+		}
+		return &apiObject.AdmissionResponse{Allowed: true}
+	})
+	defer server.Close()
+
+	webhook := apiObject.Webhook{
+		Name: "no-latest",
+		URL:  server.URL,
+		Rules: []apiObject.WebhookRule{{
+			Operations: []string{apiObject.AdmissionMatchAll},
+			Kinds:      []string{apiObject.AdmissionMatchAll},
+		}},
+	}
+	configs := []apiObject.ValidatingWebhookConfiguration{{Webhooks: []apiObject.Webhook{webhook}}}
+	plugin := NewValidatingWebhook(func() ([]apiObject.ValidatingWebhookConfiguration, error) {
+		return configs, nil
+	})
+
+	pod := newTestPod()
+	err := plugin.Validate(newTestAttributes(pod))
+	if err == nil || err.(*Error).Code != http.StatusForbidden {
+		t.Fatalf("request should be denied with 403, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "image tag latest is not allowed") {
+		t.Errorf("error should contain the message from webhook, got %s", err.Error())
+	}
+
+	pod.Spec.Containers = pod.Spec.Containers[:1]
+	pod.Spec.Containers[0].Image = "nginx:1.25"
+	if err := plugin.Validate(newTestAttributes(pod)); err != nil {
+		t.Errorf("request should be allowed, got %v", err)
+	}
+}
+
+func TestWebhookFailurePolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := apiObject.Webhook{
+		Name: "broken",
+		URL:  server.URL,
+		Rules: []apiObject.WebhookRule{{
+			Operations: []string{apiObject.AdmissionMatchAll},
+			Kinds:      []string{apiObject.AdmissionMatchAll},
+		}},
+	}
+	configs := []apiObject.ValidatingWebhookConfiguration{{Webhooks: []apiObject.Webhook{webhook}}}
+	plugin := NewValidatingWebhook(func() ([]apiObject.ValidatingWebhookConfiguration, error) {
+		return configs, nil
+	})
+
+	if err := plugin.Validate(newTestAttributes(newTestPod())); err == nil {
+		t.Errorf("request should be denied when webhook fails")
+	}
+
+	configs[0].Webhooks[0].FailurePolicy = apiObject.WebhookFailurePolicyIgnore
+	if err := plugin.Validate(newTestAttributes(newTestPod())); err != nil {
+		t.Errorf("failed webhook should be ignored, got %v", err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	chain := NewChain()
+	chain.AddMutator(NewDefaultValues())
+	chain.AddValidator(NewDuplicateContainerNames())
+
+	pod := newTestPod()
+	attr := newTestAttributes(pod)
+	if err := chain.Admit(attr); err != nil {
+		t.Fatalf("Admit failed: %v", err)
+	}
+	if err := chain.Validate(attr); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	pod.Spec.Containers[1].Name = pod.Spec.Containers[0].Name
+	if err := chain.Validate(attr); err == nil {
+		t.Errorf("duplicate container names should be rejected by the chain")
+	}
+}
@@ -0,0 +1,235 @@
+package admission
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// 内置的准入插件
+
+// ==============================================
+//
+// DefaultValues: 按照结构体里面的default标签填充默认值
+// 比如Container的ImagePullPolicy默认是IfNotPresent，PodSpec的RestartPolicy默认是Always
+//
+// ==============================================
+
+type DefaultValues struct{}
+
+func NewDefaultValues() *DefaultValues {
+	return &DefaultValues{}
+}
+
+func (d *DefaultValues) Name() string {
+	return "DefaultValues"
+}
+
+func (d *DefaultValues) Admit(a *Attributes) error {
+	if a.Operation == apiObject.AdmissionOperationDelete || a.Object == nil {
+		return nil
+	}
+	return SetDefaults(a.Object)
+}
+
+// 递归地给obj里面所有为零值并且带有default标签的字段设置默认值，obj必须是指针
+func SetDefaults(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr {
+		return fmt.Errorf("SetDefaults: %s is not a pointer", v.Type())
+	}
+	return setDefaults(v.Elem())
+}
+
+func setDefaults(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			return setDefaults(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := setDefaults(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if tag, ok := t.Field(i).Tag.Lookup("default"); ok && field.IsZero() {
+				if err := setValue(field, tag); err != nil {
+					return fmt.Errorf("field %s: %s", t.Field(i).Name, err.Error())
+				}
+			}
+			if err := setDefaults(field); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 把default标签里面的字符串转换为字段的类型
+func setValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported default value for %s", field.Type())
+	}
+	return nil
+}
+
+// ==============================================
+//
+// DuplicateContainerNames: 同一个Pod里面的容器不能重名
+// Pod、Replicaset的template等所有包含[]apiObject.Container的对象都会检查
+//
+// ==============================================
+
+type DuplicateContainerNames struct{}
+
+func NewDuplicateContainerNames() *DuplicateContainerNames {
+	return &DuplicateContainerNames{}
+}
+
+func (d *DuplicateContainerNames) Name() string {
+	return "DuplicateContainerNames"
+}
+
+func (d *DuplicateContainerNames) Validate(a *Attributes) error {
+	if a.Operation == apiObject.AdmissionOperationDelete || a.Object == nil {
+		return nil
+	}
+
+	var err error
+	findContainers(reflect.ValueOf(a.Object), "", func(field string, containers []apiObject.Container) {
+		names := make(map[string]int)
+		for i, container := range containers {
+			if j, ok := names[container.Name]; ok && err == nil {
+				err = newError(http.StatusUnprocessableEntity, d.Name(), "%s[%d].name: duplicate container name %q, same as %s[%d]", field, i, container.Name, field, j)
+			}
+			names[container.Name] = i
+		}
+	})
+	return err
+}
+
+var containerSliceType = reflect.TypeOf([]apiObject.Container{})
+
+// 找到v里面所有的[]apiObject.Container，field是json中的字段路径，比如spec.template.spec.containers
+func findContainers(v reflect.Value, field string, fn func(field string, containers []apiObject.Container)) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			findContainers(v.Elem(), field, fn)
+		}
+	case reflect.Slice:
+		if v.Type() == containerSliceType {
+			fn(field, v.Interface().([]apiObject.Container))
+			return
+		}
+		if v.Type().Elem().Kind() == reflect.Struct {
+			for i := 0; i < v.Len(); i++ {
+				findContainers(v.Index(i), fmt.Sprintf("%s[%d]", field, i), fn)
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			name := jsonFieldName(t.Field(i))
+			sub := field
+			if name != "" {
+				if sub != "" {
+					sub += "."
+				}
+				sub += name
+			}
+			findContainers(v.Field(i), sub, fn)
+		}
+	}
+}
+
+// 字段在json中的名字，内嵌的字段返回空字符串
+func jsonFieldName(f reflect.StructField) string {
+	if f.Anonymous {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	for i := 0; i < len(tag); i++ {
+		if tag[i] == ',' {
+			tag = tag[:i]
+			break
+		}
+	}
+	if tag == "" || tag == "-" {
+		return f.Name
+	}
+	return tag
+}
+
+// ==============================================
+//
+// NamespaceLifecycle: 只能在存在并且没有在删除中的namespace里面创建对象
+//
+// ==============================================
+
+type NamespaceLifecycle struct {
+	// 检查namespace，不通过的时候返回状态码和原因
+	check func(namespace string) (int, error)
+}
+
+func NewNamespaceLifecycle(check func(namespace string) (int, error)) *NamespaceLifecycle {
+	return &NamespaceLifecycle{
+		check: check,
+	}
+}
+
+func (n *NamespaceLifecycle) Name() string {
+	return "NamespaceLifecycle"
+}
+
+func (n *NamespaceLifecycle) Validate(a *Attributes) error {
+	// 集群级别的对象没有namespace
+	if a.Operation != apiObject.AdmissionOperationCreate || a.Namespace == "" {
+		return nil
+	}
+
+	if code, err := n.check(a.Namespace); err != nil {
+		return newError(code, n.Name(), "%s", err.Error())
+	}
+	return nil
+}
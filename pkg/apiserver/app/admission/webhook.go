@@ -0,0 +1,231 @@
+package admission
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/uuid"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// 调用外部的webhook
+// 用户通过MutatingWebhookConfiguration和ValidatingWebhookConfiguration注册webhook
+// APIServer把AdmissionReview POST给webhook，webhook返回是否允许，修改型的webhook还可以返回修改之后的对象
+
+// webhook没有设置超时时间的时候使用的默认值
+const defaultWebhookTimeout = 10 * time.Second
+
+// ==============================================
+//
+// MutatingWebhook: 调用所有匹配的修改型webhook
+//
+// ==============================================
+
+type MutatingWebhook struct {
+	// 获取所有的MutatingWebhookConfiguration
+	list func() ([]apiObject.MutatingWebhookConfiguration, error)
+}
+
+func NewMutatingWebhook(list func() ([]apiObject.MutatingWebhookConfiguration, error)) *MutatingWebhook {
+	return &MutatingWebhook{
+		list: list,
+	}
+}
+
+func (m *MutatingWebhook) Name() string {
+	return "MutatingAdmissionWebhook"
+}
+
+func (m *MutatingWebhook) Admit(a *Attributes) error {
+	if skipWebhook(a) {
+		return nil
+	}
+
+	configs, err := m.list()
+	if err != nil {
+		return newError(http.StatusInternalServerError, m.Name(), "list mutating webhook configurations failed %s", err.Error())
+	}
+
+	// 按照名字的顺序调用，保证每次调用的顺序一样
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Metadata.Name < configs[j].Metadata.Name
+	})
+
+	for _, config := range configs {
+		for _, webhook := range config.Webhooks {
+			if !webhook.Match(a.Operation, a.Kind, a.Namespace) {
+				continue
+			}
+
+			response, err := callWebhook(&webhook, a)
+			if err != nil {
+				if webhook.FailurePolicy == apiObject.WebhookFailurePolicyIgnore {
+					k8log.WarnLog("APIServer", "MutatingWebhook: ignore webhook "+webhook.Name+" "+err.Error())
+					continue
+				}
+				return newError(http.StatusInternalServerError, m.Name(), "failed calling webhook %s: %s", webhook.Name, err.Error())
+			}
+
+			if !response.Allowed {
+				return newError(http.StatusForbidden, m.Name(), "webhook %s denied the request: %s", webhook.Name, response.Message)
+			}
+
+			if len(response.Object) != 0 {
+				if err := applyMutation(a, response.Object); err != nil {
+					return newError(http.StatusInternalServerError, m.Name(), "webhook %s returned an invalid object: %s", webhook.Name, err.Error())
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// 用webhook返回的对象替换请求中的对象，kind、namespace和name不能被修改
+func applyMutation(a *Attributes, object json.RawMessage) error {
+	mutated := reflect.New(reflect.TypeOf(a.Object).Elem()).Interface()
+	if err := json.Unmarshal(object, mutated); err != nil {
+		return err
+	}
+
+	if o, ok := mutated.(apiObject.APIObject); ok {
+		if o.GetObjectName() != a.Name {
+			return errors.New("name can not be changed")
+		}
+		// 集群级别的对象的namespace由APIServer清空，不需要检查
+		if a.Namespace != "" && o.GetObjectNamespace() != a.Namespace {
+			return errors.New("namespace can not be changed")
+		}
+		if o.GetObjectKind() != "" && o.GetObjectKind() != a.Kind {
+			return errors.New("kind can not be changed")
+		}
+	}
+
+	reflect.ValueOf(a.Object).Elem().Set(reflect.ValueOf(mutated).Elem())
+	return nil
+}
+
+// ==============================================
+//
+// ValidatingWebhook: 调用所有匹配的校验型webhook
+//
+// ==============================================
+
+type ValidatingWebhook struct {
+	// 获取所有的ValidatingWebhookConfiguration
+	list func() ([]apiObject.ValidatingWebhookConfiguration, error)
+}
+
+func NewValidatingWebhook(list func() ([]apiObject.ValidatingWebhookConfiguration, error)) *ValidatingWebhook {
+	return &ValidatingWebhook{
+		list: list,
+	}
+}
+
+func (v *ValidatingWebhook) Name() string {
+	return "ValidatingAdmissionWebhook"
+}
+
+func (v *ValidatingWebhook) Validate(a *Attributes) error {
+	if skipWebhook(a) {
+		return nil
+	}
+
+	configs, err := v.list()
+	if err != nil {
+		return newError(http.StatusInternalServerError, v.Name(), "list validating webhook configurations failed %s", err.Error())
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Metadata.Name < configs[j].Metadata.Name
+	})
+
+	for _, config := range configs {
+		for _, webhook := range config.Webhooks {
+			if !webhook.Match(a.Operation, a.Kind, a.Namespace) {
+				continue
+			}
+
+			response, err := callWebhook(&webhook, a)
+			if err != nil {
+				if webhook.FailurePolicy == apiObject.WebhookFailurePolicyIgnore {
+					k8log.WarnLog("APIServer", "ValidatingWebhook: ignore webhook "+webhook.Name+" "+err.Error())
+					continue
+				}
+				return newError(http.StatusInternalServerError, v.Name(), "failed calling webhook %s: %s", webhook.Name, err.Error())
+			}
+
+			if !response.Allowed {
+				return newError(http.StatusForbidden, v.Name(), "webhook %s denied the request: %s", webhook.Name, response.Message)
+			}
+		}
+	}
+	return nil
+}
+
+// webhook的配置本身不经过webhook，避免webhook出问题之后没有办法删除配置
+func skipWebhook(a *Attributes) bool {
+	return a.Kind == apiObject.MutatingWebhookConfigurationKind || a.Kind == apiObject.ValidatingWebhookConfigurationKind
+}
+
+// 把请求发送给webhook，返回webhook的AdmissionResponse
+func callWebhook(webhook *apiObject.Webhook, a *Attributes) (*apiObject.AdmissionResponse, error) {
+	request := &apiObject.AdmissionRequest{
+		UID:       uuid.NewUUID(),
+		Kind:      a.Kind,
+		Namespace: a.Namespace,
+		Name:      a.Name,
+		Operation: a.Operation,
+	}
+
+	var err error
+	if a.Object != nil {
+		if request.Object, err = json.Marshal(a.Object); err != nil {
+			return nil, err
+		}
+	}
+	if a.OldObject != nil {
+		if request.OldObject, err = json.Marshal(a.OldObject); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(&apiObject.AdmissionReview{Request: request})
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := defaultWebhookTimeout
+	if webhook.TimeoutSeconds > 0 {
+		timeout = time.Duration(webhook.TimeoutSeconds) * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	resp, err := client.Post(webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	review := &apiObject.AdmissionReview{}
+	if err := json.NewDecoder(resp.Body).Decode(review); err != nil {
+		return nil, err
+	}
+
+	if review.Response == nil {
+		return nil, errors.New("response is empty")
+	}
+	if review.Response.UID != request.UID {
+		return nil, fmt.Errorf("response uid %s does not match request uid %s", review.Response.UID, request.UID)
+	}
+	return review.Response, nil
+}
@@ -0,0 +1,203 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/auth"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// 审计日志
+// 所有修改类的请求(POST、PUT、DELETE)处理完之后，按照审计策略写一条记录到轮转的JSON Lines文件里面
+// 没有通过认证和权限控制的请求也会被记录
+
+// 超过这个大小的请求body不记录，比如JobFile上传的压缩包
+const maxRequestBodySize = 64 * 1024
+
+type Logger struct {
+	writer *RotatingWriter
+	policy *apiObject.AuditPolicy
+}
+
+func NewLogger(writer *RotatingWriter, policy *apiObject.AuditPolicy) *Logger {
+	return &Logger{
+		writer: writer,
+		policy: policy,
+	}
+}
+
+// 读取审计策略，文件不存在的时候所有请求都按照Metadata级别记录
+func LoadPolicy(file string) (*apiObject.AuditPolicy, error) {
+	policy := &apiObject.AuditPolicy{}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return policy, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, policy); err != nil {
+		return nil, err
+	}
+	if err := validatePolicy(policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func validatePolicy(policy *apiObject.AuditPolicy) error {
+	levels := []string{policy.DefaultLevel}
+	for _, rule := range policy.Rules {
+		levels = append(levels, rule.Level)
+	}
+	for i, level := range levels {
+		// DefaultLevel可以不写
+		if i == 0 && level == "" {
+			continue
+		}
+		switch level {
+		case apiObject.AuditLevelNone, apiObject.AuditLevelMetadata, apiObject.AuditLevelRequest:
+		default:
+			return errors.New("unknown audit level " + level)
+		}
+	}
+	return nil
+}
+
+// 写入一条记录
+func (l *Logger) Log(event *apiObject.AuditEvent) error {
+	eventJson, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = l.writer.Write(append(eventJson, '\n'))
+	return err
+}
+
+// 记录修改类请求的中间件，需要注册在认证和权限控制的中间件之前，这样被拒绝的请求也能记录下来
+func Audit(resolve auth.RequestResolver, logger *Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verb, mutating := methodVerbs[c.Request.Method]
+		if !mutating {
+			c.Next()
+			return
+		}
+
+		body := readRequestBody(c)
+		c.Next()
+
+		event := &apiObject.AuditEvent{
+			Timestamp: time.Now(),
+			SourceIP:  c.ClientIP(),
+			Verb:      verb,
+			Method:    c.Request.Method,
+			URL:       c.Request.URL.RequestURI(),
+			Name:      c.Param(config.URL_PARAM_NAME),
+			Code:      c.Writer.Status(),
+		}
+		if info, ok := resolve(c); ok {
+			event.Verb = info.Verb
+			event.Kind = info.Kind
+			event.Namespace = info.Namespace
+		}
+
+		event.Level = logger.policy.LevelFor(event.Verb, event.Kind)
+		if event.Level == apiObject.AuditLevelNone {
+			return
+		}
+
+		if user, ok := auth.UserFromContext(c); ok {
+			event.User = user.User
+			event.TokenType = user.Type
+			if user.Token != "" {
+				event.TokenID = tokenID(user.Token)
+			}
+		}
+
+		// 创建对象的时候URL里面没有名字，从body里面读取
+		fillObjectMeta(event, body)
+
+		// 删除token的URL里面就是token本身，不能写到日志里面
+		if event.Kind == apiObject.TokenKind && event.Name != "" {
+			id := tokenID(event.Name)
+			event.URL = strings.Replace(event.URL, event.Name, id, 1)
+			event.Name = id
+		}
+
+		if event.Level == apiObject.AuditLevelRequest && len(body) != 0 {
+			if json.Valid(body) {
+				event.RequestBody = body
+			} else {
+				event.RequestBody, _ = json.Marshal(string(body))
+			}
+		}
+
+		if err := logger.Log(event); err != nil {
+			k8log.ErrorLog("APIServer", "write audit event failed, "+err.Error())
+		}
+	}
+}
+
+// 只记录修改类的请求，没有匹配到路由的时候用请求方法推断操作
+var methodVerbs = map[string]string{
+	http.MethodPost:   apiObject.VerbCreate,
+	http.MethodPut:    apiObject.VerbUpdate,
+	http.MethodPatch:  apiObject.VerbPatch,
+	http.MethodDelete: apiObject.VerbDelete,
+}
+
+// 读取请求的body之后再放回去，后面的handler还要用
+func readRequestBody(c *gin.Context) []byte {
+	if c.Request.Body == nil || c.Request.ContentLength > maxRequestBodySize {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRequestBodySize+1))
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if len(body) > maxRequestBodySize {
+		return nil
+	}
+	return body
+}
+
+func fillObjectMeta(event *apiObject.AuditEvent, body []byte) {
+	if event.Name != "" && event.Namespace != "" {
+		return
+	}
+	object := struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+	}{}
+	if len(body) == 0 || json.Unmarshal(body, &object) != nil {
+		return
+	}
+	if event.Name == "" {
+		event.Name = object.Metadata.Name
+	}
+	if event.Namespace == "" && event.Kind != apiObject.NamespaceKind {
+		event.Namespace = object.Metadata.Namespace
+	}
+}
+
+// token的sha256的前12位
+func tokenID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,179 @@
+package audit
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/auth"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	writer, err := NewRotatingWriter(path, 10, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter failed: %v", err)
+	}
+	defer writer.Close()
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := writer.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	// 最旧的aaaaaaaa被覆盖掉了
+	expected := []string{"bbbbbbbb\n", "cccccccc\n", "dddddddd\n"}
+	files := writer.Files()
+	if len(files) != len(expected) {
+		t.Fatalf("expected %d files but got %v", len(expected), files)
+	}
+	for i, file := range files {
+		content, _ := os.ReadFile(file)
+		if string(content) != expected[i] {
+			t.Errorf("file %s: expected %q but got %q", file, expected[i], string(content))
+		}
+	}
+}
+
+func TestAuditPolicy(t *testing.T) {
+	policy := &apiObject.AuditPolicy{
+		Rules: []apiObject.AuditPolicyRule{
+			{Level: apiObject.AuditLevelNone, Kinds: []string{apiObject.NodeKind}, Verbs: []string{apiObject.VerbUpdate}},
+			{Level: apiObject.AuditLevelRequest, Kinds: []string{apiObject.FunctionKind}},
+		},
+	}
+
+	cases := []struct {
+		verb  string
+		kind  string
+		level string
+	}{
+		{apiObject.VerbUpdate, apiObject.NodeKind, apiObject.AuditLevelNone},
+		{apiObject.VerbDelete, apiObject.NodeKind, apiObject.AuditLevelMetadata},
+		{apiObject.VerbUpdate, apiObject.FunctionKind, apiObject.AuditLevelRequest},
+		{apiObject.VerbDelete, apiObject.ReplicaSetKind, apiObject.AuditLevelMetadata},
+	}
+	for _, tc := range cases {
+		if level := policy.LevelFor(tc.verb, tc.kind); level != tc.level {
+			t.Errorf("%s %s: expected level %s but got %s", tc.verb, tc.kind, tc.level, level)
+		}
+	}
+
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(file, []byte("rules:\n  - level: Verbose\n"), 0600)
+	if _, err := LoadPolicy(file); err == nil {
+		t.Errorf("expected error for unknown audit level")
+	}
+	if policy, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml")); err != nil || policy.LevelFor(apiObject.VerbCreate, apiObject.PodKind) != apiObject.AuditLevelMetadata {
+		t.Errorf("missing policy file should audit everything at Metadata level, err %v", err)
+	}
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	writer, err := NewRotatingWriter(filepath.Join(t.TempDir(), "audit.log"), 0, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter failed: %v", err)
+	}
+	defer writer.Close()
+	logger := NewLogger(writer, &apiObject.AuditPolicy{
+		Rules: []apiObject.AuditPolicyRule{{Level: apiObject.AuditLevelRequest, Kinds: []string{apiObject.FunctionKind}}},
+	})
+
+	resolve := func(c *gin.Context) (*auth.RequestInfo, bool) {
+		info := &auth.RequestInfo{Namespace: c.Param("namespace")}
+		switch c.FullPath() {
+		case "/apis/v1/namespaces/:namespace/replicasets/:name":
+			info.Kind = apiObject.ReplicaSetKind
+		case "/apis/v1/namespaces/:namespace/functions":
+			info.Kind = apiObject.FunctionKind
+		case "/api/v1/tokens/:name":
+			info.Kind = apiObject.TokenKind
+		default:
+			return nil, false
+		}
+		info.Verb = methodVerbs[c.Request.Method]
+		return info, true
+	}
+	getToken := func(token string) (*apiObject.Token, error) {
+		if token == "admin-token" {
+			return &apiObject.Token{Token: token, Type: apiObject.TokenTypeUser, User: "admin"}, nil
+		}
+		return nil, nil
+	}
+
+	router := gin.New()
+	router.Use(Audit(resolve, logger), auth.Authenticate(getToken))
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{})
+	}
+	router.DELETE("/apis/v1/namespaces/:namespace/replicasets/:name", handler)
+	router.POST("/apis/v1/namespaces/:namespace/functions", handler)
+	router.GET("/apis/v1/namespaces/:namespace/functions", handler)
+	router.DELETE("/api/v1/tokens/:name", handler)
+
+	requests := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+	}{
+		{http.MethodDelete, "/apis/v1/namespaces/default/replicasets/rs1", "admin-token", ""},
+		{http.MethodPost, "/apis/v1/namespaces/default/functions", "admin-token", `{"metadata":{"name":"func1"}}`},
+		// GET不记录
+		{http.MethodGet, "/apis/v1/namespaces/default/functions", "admin-token", ""},
+		// 没有通过认证的请求也记录
+		{http.MethodDelete, "/apis/v1/namespaces/default/replicasets/rs2", "wrong-token", ""},
+		{http.MethodDelete, "/api/v1/tokens/secret-token", "admin-token", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set("Authorization", "Bearer "+r.token)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	events, err := logger.Query(&Filter{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events but got %d", len(events))
+	}
+
+	deleted := events[0]
+	if deleted.User != "admin" || deleted.TokenID == "" || deleted.Verb != apiObject.VerbDelete || deleted.Kind != apiObject.ReplicaSetKind ||
+		deleted.Namespace != "default" || deleted.Name != "rs1" || deleted.Code != http.StatusOK || deleted.RequestBody != nil {
+		t.Errorf("unexpected event %+v", deleted)
+	}
+
+	created := events[1]
+	if created.Name != "func1" || created.Level != apiObject.AuditLevelRequest || string(created.RequestBody) != `{"metadata":{"name":"func1"}}` {
+		t.Errorf("unexpected event %+v", created)
+	}
+
+	if denied := events[2]; denied.User != "" || denied.Code != http.StatusUnauthorized || denied.Name != "rs2" {
+		t.Errorf("unexpected event %+v", denied)
+	}
+
+	if token := events[3]; strings.Contains(token.URL, "secret-token") || token.Name == "secret-token" {
+		t.Errorf("token should not be written to the audit log, got %+v", token)
+	}
+
+	// 按照对象和时间过滤
+	events, _ = logger.Query(&Filter{Kind: apiObject.ReplicaSetKind, Name: "rs1"})
+	if len(events) != 1 {
+		t.Errorf("expected 1 event for rs1 but got %d", len(events))
+	}
+	events, _ = logger.Query(&Filter{Since: time.Now().Add(time.Hour)})
+	if len(events) != 0 {
+		t.Errorf("expected no event in the future but got %d", len(events))
+	}
+}
@@ -0,0 +1,78 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"miniK8s/pkg/apiObject"
+	"os"
+	"time"
+)
+
+// 查询审计日志的条件，为空的条件不参与过滤
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	Kind      string
+	Namespace string
+	Name      string
+	User      string
+	Verb      string
+}
+
+func (f *Filter) Match(event *apiObject.AuditEvent) bool {
+	if !f.Since.IsZero() && event.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && event.Timestamp.After(f.Until) {
+		return false
+	}
+	return matchField(f.Kind, event.Kind) &&
+		matchField(f.Namespace, event.Namespace) &&
+		matchField(f.Name, event.Name) &&
+		matchField(f.User, event.User) &&
+		matchField(f.Verb, event.Verb)
+}
+
+func matchField(want string, got string) bool {
+	return want == "" || want == got
+}
+
+// 从旧到新读取所有的日志文件，返回满足条件的记录
+func (l *Logger) Query(filter *Filter) ([]apiObject.AuditEvent, error) {
+	events := make([]apiObject.AuditEvent, 0)
+	for _, file := range l.writer.Files() {
+		fileEvents, err := readEvents(file, filter)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, fileEvents...)
+	}
+	return events, nil
+}
+
+func readEvents(file string, filter *Filter) ([]apiObject.AuditEvent, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	events := make([]apiObject.AuditEvent, 0)
+	scanner := bufio.NewScanner(f)
+	// 记录Request级别的body的时候一行可能比较长
+	scanner.Buffer(make([]byte, 0, 64*1024), 8*maxRequestBodySize)
+	for scanner.Scan() {
+		event := apiObject.AuditEvent{}
+		// 写到一半的行直接跳过
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if filter.Match(&event) {
+			events = append(events, event)
+		}
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 认证通过之后，token记录会放到gin.Context里面，后面的handler用UserFromContext读取
+const userContextKey = "minik8s/user"
+
+// 根据token查询etcd里面的记录，不存在的时候返回nil, nil
+type TokenGetter func(token string) (*apiObject.Token, error)
+
+// 所有的请求都必须带上Authorization: Bearer <token>，否则返回401
+// 开启TLS之后，没有token但是带了集群CA签发的客户端证书的请求也可以通过认证
+func Authenticate(getToken TokenGetter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			if record, ok := userFromClientCert(c.Request); ok {
+				c.Set(userContextKey, record)
+				c.Next()
+				return
+			}
+		}
+
+		token, ok := parseBearerToken(header)
+		if !ok {
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+
+		record, err := getToken(token)
+		if err != nil {
+			k8log.ErrorLog("APIServer", "get token failed, "+err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authenticate failed: " + err.Error()})
+			return
+		}
+		if record == nil {
+			abortUnauthorized(c, "invalid bearer token")
+			return
+		}
+
+		c.Set(userContextKey, record)
+		c.Next()
+	}
+}
+
+// 读取认证中间件放进去的token记录
+func UserFromContext(c *gin.Context) (*apiObject.Token, bool) {
+	value, ok := c.Get(userContextKey)
+	if !ok {
+		return nil, false
+	}
+	record, ok := value.(*apiObject.Token)
+	return record, ok
+}
+
+// 客户端证书的CN是用户名，O里面有system:nodes的时候是节点组件
+// 证书链已经在TLS握手的时候验证过，只有VerifiedChains不为空的证书才会被使用
+func userFromClientCert(req *http.Request) (*apiObject.Token, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	cert := req.TLS.VerifiedChains[0][0]
+	if cert.Subject.CommonName == "" {
+		return nil, false
+	}
+
+	record := &apiObject.Token{Type: apiObject.TokenTypeUser, User: cert.Subject.CommonName}
+	for _, org := range cert.Subject.Organization {
+		if org == apiObject.GroupNodes {
+			record.Type = apiObject.TokenTypeNode
+		}
+	}
+	return record, true
+}
+
+// 解析Authorization请求头，格式是Bearer <token>，Bearer不区分大小写
+func parseBearerToken(header string) (string, bool) {
+	parts := strings.Fields(header)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func abortUnauthorized(c *gin.Context, reason string) {
+	k8log.WarnLog("APIServer", "unauthorized request "+c.Request.Method+" "+c.Request.URL.Path+", "+reason)
+	c.Header("WWW-Authenticate", "Bearer")
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized: " + reason})
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	tokens := map[string]*apiObject.Token{
+		"user-token": {Token: "user-token", Type: apiObject.TokenTypeUser, User: "alice"},
+		"node-token": {Token: "node-token", Type: apiObject.TokenTypeNode, User: "system:node"},
+	}
+	getToken := func(token string) (*apiObject.Token, error) {
+		if token == "broken" {
+			return nil, errors.New("etcd unavailable")
+		}
+		return tokens[token], nil
+	}
+
+	router := gin.New()
+	router.Use(Authenticate(getToken))
+	router.GET("/api/v1/pods", func(c *gin.Context) {
+		user, ok := UserFromContext(c)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "no user"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": user.User})
+	})
+	return router
+}
+
+func TestAuthenticate(t *testing.T) {
+	router := newTestRouter()
+
+	cases := []struct {
+		header string
+		code   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+		{"Bearer", http.StatusUnauthorized},
+		{"Bearer unknown-token", http.StatusUnauthorized},
+		{"Bearer broken", http.StatusInternalServerError},
+		{"Bearer user-token", http.StatusOK},
+		{"bearer node-token", http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("Authorization %q: expected status %v but got %v", tc.header, tc.code, w.Code)
+		}
+	}
+}
+
+func TestAuthenticateClientCert(t *testing.T) {
+	router := newTestRouter()
+
+	cases := []struct {
+		state *tls.ConnectionState
+		code  int
+	}{
+		{nil, http.StatusUnauthorized},
+		// 证书没有通过验证
+		{&tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "node1"}}}}, http.StatusUnauthorized},
+		{&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "node1", Organization: []string{apiObject.GroupNodes}}}}}}, http.StatusOK},
+	}
+
+	for i, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/pods", nil)
+		req.TLS = tc.state
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("case %d: expected status %v but got %v", i, tc.code, w.Code)
+		}
+	}
+
+	if got := userOf(t, &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "node1", Organization: []string{apiObject.GroupNodes}}}}}}); got == nil || !got.IsNode() || got.User != "node1" {
+		t.Errorf("expected node user node1 but got %v", got)
+	}
+	if got := userOf(t, &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "alice"}}}}}); got == nil || got.IsNode() || got.User != "alice" {
+		t.Errorf("expected user alice but got %v", got)
+	}
+}
+
+func userOf(t *testing.T, state *tls.ConnectionState) *apiObject.Token {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.TLS = state
+	record, _ := userFromClientCert(req)
+	return record
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/k8log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 一个请求要对什么对象执行什么操作
+type RequestInfo struct {
+	// get、list、watch、create、update、delete
+	Verb string
+	Kind string
+	// 集群级别的资源和全局的list为空
+	Namespace string
+	// 请求的对象的名字，list、watch、create为空
+	Name string
+	// 请求的子资源，比如status，请求对象本身的时候为空
+	Subresource string
+}
+
+// 根据路由解析请求，不认识的路由返回false
+type RequestResolver func(c *gin.Context) (*RequestInfo, bool)
+
+// 读取etcd里面的权限控制对象，不存在的时候返回nil, nil
+type RBACLister interface {
+	ListClusterRoleBindings() ([]apiObject.ClusterRoleBinding, error)
+	ListRoleBindings(namespace string) ([]apiObject.RoleBinding, error)
+	GetClusterRole(name string) (*apiObject.ClusterRole, error)
+	GetRole(namespace string, name string) (*apiObject.Role, error)
+}
+
+type RBACAuthorizer struct {
+	lister RBACLister
+}
+
+func NewRBACAuthorizer(lister RBACLister) *RBACAuthorizer {
+	return &RBACAuthorizer{lister: lister}
+}
+
+// 先检查ClusterRoleBinding，再检查请求的namespace里面的RoleBinding，任意一个允许即可
+func (a *RBACAuthorizer) Authorize(user *apiObject.Token, info *RequestInfo) (bool, error) {
+	clusterBindings, err := a.lister.ListClusterRoleBindings()
+	if err != nil {
+		return false, err
+	}
+	for _, binding := range clusterBindings {
+		if !subjectsMatch(binding.Subjects, user) {
+			continue
+		}
+		role, err := a.lister.GetClusterRole(binding.RoleRef.Name)
+		if err != nil {
+			return false, err
+		}
+		if role != nil && apiObject.RulesAllow(role.Rules, info.Verb, info.Kind, info.Subresource, info.Name) {
+			return true, nil
+		}
+	}
+
+	// 集群级别的资源只能通过ClusterRoleBinding授权
+	if info.Namespace == "" {
+		return false, nil
+	}
+
+	bindings, err := a.lister.ListRoleBindings(info.Namespace)
+	if err != nil {
+		return false, err
+	}
+	for _, binding := range bindings {
+		if !subjectsMatch(binding.Subjects, user) {
+			continue
+		}
+		rules, err := a.roleRefRules(info.Namespace, &binding.RoleRef)
+		if err != nil {
+			return false, err
+		}
+		if apiObject.RulesAllow(rules, info.Verb, info.Kind, info.Subresource, info.Name) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// RoleBinding可以引用同一个namespace里面的Role，也可以引用ClusterRole
+func (a *RBACAuthorizer) roleRefRules(namespace string, ref *apiObject.RoleRef) ([]apiObject.PolicyRule, error) {
+	switch ref.Kind {
+	case apiObject.RoleKind:
+		role, err := a.lister.GetRole(namespace, ref.Name)
+		if err != nil || role == nil {
+			return nil, err
+		}
+		return role.Rules, nil
+	case apiObject.ClusterRoleKind:
+		role, err := a.lister.GetClusterRole(ref.Name)
+		if err != nil || role == nil {
+			return nil, err
+		}
+		return role.Rules, nil
+	}
+	return nil, nil
+}
+
+func subjectsMatch(subjects []apiObject.Subject, user *apiObject.Token) bool {
+	for i := range subjects {
+		if subjects[i].Matches(user) {
+			return true
+		}
+	}
+	return false
+}
+
+// 必须放在Authenticate之后，没有权限的时候返回403
+func Authorize(resolve RequestResolver, authorizer *RBACAuthorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := UserFromContext(c)
+		if !ok {
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+
+		info, ok := resolve(c)
+		if !ok {
+			// 不认识的路由一律拒绝，新增路由的时候需要同时登记对应的kind
+			k8log.WarnLog("APIServer", "no authorization rule for "+c.Request.Method+" "+c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden: unknown resource " + c.Request.URL.Path})
+			return
+		}
+
+		allowed, err := authorizer.Authorize(user, info)
+		if err != nil {
+			k8log.ErrorLog("APIServer", "authorize failed, "+err.Error())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "authorize failed: " + err.Error()})
+			return
+		}
+		if !allowed {
+			reason := forbiddenReason(user, info)
+			k8log.WarnLog("APIServer", reason)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": reason})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func forbiddenReason(user *apiObject.Token, info *RequestInfo) string {
+	scope := "at the cluster scope"
+	if info.Namespace != "" {
+		scope = fmt.Sprintf("in the namespace %q", info.Namespace)
+	}
+	return fmt.Sprintf("forbidden: user %q cannot %s %s %s", user.User, info.Verb, info.Kind, scope)
+}
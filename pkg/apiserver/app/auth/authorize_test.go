@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeRBACLister struct {
+	clusterRoles        map[string]*apiObject.ClusterRole
+	roles               map[string]*apiObject.Role
+	clusterRoleBindings []apiObject.ClusterRoleBinding
+	roleBindings        []apiObject.RoleBinding
+}
+
+func (f *fakeRBACLister) ListClusterRoleBindings() ([]apiObject.ClusterRoleBinding, error) {
+	return f.clusterRoleBindings, nil
+}
+
+func (f *fakeRBACLister) ListRoleBindings(namespace string) ([]apiObject.RoleBinding, error) {
+	bindings := make([]apiObject.RoleBinding, 0)
+	for _, binding := range f.roleBindings {
+		if binding.Metadata.Namespace == namespace {
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings, nil
+}
+
+func (f *fakeRBACLister) GetClusterRole(name string) (*apiObject.ClusterRole, error) {
+	return f.clusterRoles[name], nil
+}
+
+func (f *fakeRBACLister) GetRole(namespace string, name string) (*apiObject.Role, error) {
+	return f.roles[namespace+"/"+name], nil
+}
+
+func newFakeRBACLister() *fakeRBACLister {
+	clusterAdmin := &apiObject.ClusterRole{Rules: []apiObject.PolicyRule{{Verbs: []string{"*"}, Kinds: []string{"*"}}}}
+	viewer := &apiObject.ClusterRole{Rules: []apiObject.PolicyRule{{Verbs: []string{"get", "list", "watch"}, Kinds: []string{"*"}}}}
+	developer := &apiObject.Role{Rules: []apiObject.PolicyRule{{Verbs: []string{"*"}, Kinds: []string{apiObject.FunctionKind}}}}
+	// 只能读取train这个Job，更新它的状态
+	jobServer := &apiObject.Role{Rules: []apiObject.PolicyRule{
+		{Verbs: []string{"get"}, Kinds: []string{apiObject.JobKind}, ResourceNames: []string{"train"}},
+		{Verbs: []string{"update"}, Kinds: []string{apiObject.JobKind + "/status"}, ResourceNames: []string{"train"}},
+	}}
+
+	adminBinding := apiObject.ClusterRoleBinding{
+		Subjects: []apiObject.Subject{
+			{Kind: apiObject.SubjectKindUser, Name: "admin"},
+			{Kind: apiObject.SubjectKindGroup, Name: apiObject.GroupNodes},
+		},
+		RoleRef: apiObject.RoleRef{Kind: apiObject.ClusterRoleKind, Name: apiObject.ClusterAdminName},
+	}
+	developerBinding := apiObject.RoleBinding{
+		Subjects: []apiObject.Subject{{Kind: apiObject.SubjectKindUser, Name: "intern"}},
+		RoleRef:  apiObject.RoleRef{Kind: apiObject.RoleKind, Name: "function-developer"},
+	}
+	developerBinding.Metadata.Namespace = "interns"
+	viewerBinding := apiObject.RoleBinding{
+		Subjects: []apiObject.Subject{{Kind: apiObject.SubjectKindUser, Name: "intern"}},
+		RoleRef:  apiObject.RoleRef{Kind: apiObject.ClusterRoleKind, Name: "viewer"},
+	}
+	viewerBinding.Metadata.Namespace = "interns"
+	jobServerBinding := apiObject.RoleBinding{
+		Subjects: []apiObject.Subject{{Kind: apiObject.SubjectKindUser, Name: "job-server"}},
+		RoleRef:  apiObject.RoleRef{Kind: apiObject.RoleKind, Name: "job-server"},
+	}
+	jobServerBinding.Metadata.Namespace = "interns"
+
+	return &fakeRBACLister{
+		clusterRoles: map[string]*apiObject.ClusterRole{
+			apiObject.ClusterAdminName: clusterAdmin,
+			"viewer":                   viewer,
+		},
+		roles:               map[string]*apiObject.Role{"interns/function-developer": developer, "interns/job-server": jobServer},
+		clusterRoleBindings: []apiObject.ClusterRoleBinding{adminBinding},
+		roleBindings:        []apiObject.RoleBinding{developerBinding, viewerBinding, jobServerBinding},
+	}
+}
+
+func TestRBACAuthorizer(t *testing.T) {
+	authorizer := NewRBACAuthorizer(newFakeRBACLister())
+
+	admin := &apiObject.Token{Type: apiObject.TokenTypeUser, User: "admin"}
+	node := &apiObject.Token{Type: apiObject.TokenTypeNode, User: "system:node"}
+	intern := &apiObject.Token{Type: apiObject.TokenTypeUser, User: "intern"}
+	jobServer := &apiObject.Token{Type: apiObject.TokenTypeUser, User: "job-server"}
+
+	cases := []struct {
+		user    *apiObject.Token
+		info    RequestInfo
+		allowed bool
+	}{
+		{admin, RequestInfo{Verb: apiObject.VerbDelete, Kind: apiObject.NodeKind, Namespace: ""}, true},
+		{node, RequestInfo{Verb: apiObject.VerbUpdate, Kind: apiObject.PodKind, Namespace: "team-b"}, true},
+		{intern, RequestInfo{Verb: apiObject.VerbCreate, Kind: apiObject.FunctionKind, Namespace: "interns"}, true},
+		{intern, RequestInfo{Verb: apiObject.VerbDelete, Kind: apiObject.FunctionKind, Namespace: "interns"}, true},
+		// 通过RoleBinding引用的ClusterRole只在interns里面生效
+		{intern, RequestInfo{Verb: apiObject.VerbList, Kind: apiObject.PodKind, Namespace: "interns"}, true},
+		{intern, RequestInfo{Verb: apiObject.VerbDelete, Kind: apiObject.PodKind, Namespace: "interns"}, false},
+		{intern, RequestInfo{Verb: apiObject.VerbCreate, Kind: apiObject.FunctionKind, Namespace: "team-b"}, false},
+		{intern, RequestInfo{Verb: apiObject.VerbDelete, Kind: apiObject.ReplicaSetKind, Namespace: "team-b"}, false},
+		{intern, RequestInfo{Verb: apiObject.VerbDelete, Kind: apiObject.NodeKind, Namespace: ""}, false},
+		{intern, RequestInfo{Verb: apiObject.VerbList, Kind: apiObject.PodKind, Namespace: ""}, false},
+		// Kind匹配所有的子资源
+		{intern, RequestInfo{Verb: apiObject.VerbUpdate, Kind: apiObject.FunctionKind, Namespace: "interns", Name: "f", Subresource: "status"}, true},
+		// resourceNames和子资源
+		{jobServer, RequestInfo{Verb: apiObject.VerbGet, Kind: apiObject.JobKind, Namespace: "interns", Name: "train"}, true},
+		{jobServer, RequestInfo{Verb: apiObject.VerbUpdate, Kind: apiObject.JobKind, Namespace: "interns", Name: "train", Subresource: "status"}, true},
+		{jobServer, RequestInfo{Verb: apiObject.VerbUpdate, Kind: apiObject.JobKind, Namespace: "interns", Name: "train"}, false},
+		{jobServer, RequestInfo{Verb: apiObject.VerbUpdate, Kind: apiObject.JobKind, Namespace: "interns", Name: "other", Subresource: "status"}, false},
+		{jobServer, RequestInfo{Verb: apiObject.VerbGet, Kind: apiObject.JobKind, Namespace: "interns", Name: "other"}, false},
+		{jobServer, RequestInfo{Verb: apiObject.VerbList, Kind: apiObject.JobKind, Namespace: "interns"}, false},
+		{jobServer, RequestInfo{Verb: apiObject.VerbGet, Kind: apiObject.JobKind, Namespace: "team-b", Name: "train"}, false},
+	}
+
+	for _, tc := range cases {
+		allowed, err := authorizer.Authorize(tc.user, &tc.info)
+		if err != nil {
+			t.Fatalf("Authorize failed: %v", err)
+		}
+		if allowed != tc.allowed {
+			t.Errorf("%s %s %s in %q: expected %v but got %v", tc.user.User, tc.info.Verb, tc.info.Kind, tc.info.Namespace, tc.allowed, allowed)
+		}
+	}
+}
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tokens := map[string]*apiObject.Token{
+		"admin-token":  {Type: apiObject.TokenTypeUser, User: "admin"},
+		"intern-token": {Type: apiObject.TokenTypeUser, User: "intern"},
+	}
+	getToken := func(token string) (*apiObject.Token, error) {
+		return tokens[token], nil
+	}
+	resolve := func(c *gin.Context) (*RequestInfo, bool) {
+		if c.FullPath() != "/api/v1/nodes/:name" {
+			return nil, false
+		}
+		return &RequestInfo{Verb: apiObject.VerbDelete, Kind: apiObject.NodeKind}, true
+	}
+
+	router := gin.New()
+	router.Use(Authenticate(getToken), Authorize(resolve, NewRBACAuthorizer(newFakeRBACLister())))
+	router.DELETE("/api/v1/nodes/:name", func(c *gin.Context) {
+		c.JSON(http.StatusNoContent, gin.H{})
+	})
+	router.DELETE("/api/v1/unknown/:name", func(c *gin.Context) {
+		c.JSON(http.StatusNoContent, gin.H{})
+	})
+
+	cases := []struct {
+		token string
+		path  string
+		code  int
+	}{
+		{"admin-token", "/api/v1/nodes/node1", http.StatusNoContent},
+		{"intern-token", "/api/v1/nodes/node1", http.StatusForbidden},
+		// 没有登记的路由，哪怕是管理员也拒绝
+		{"admin-token", "/api/v1/unknown/node1", http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s %s: expected status %v but got %v", tc.token, tc.path, tc.code, w.Code)
+		}
+	}
+}
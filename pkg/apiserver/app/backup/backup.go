@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/etcd"
+	"miniK8s/pkg/k8log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 备份文件的格式版本，格式不兼容的时候需要增加，恢复的时候只接受认识的版本
+const ArchiveVersion = 1
+
+// 所有的集群状态都在这个前缀下面
+const RegistryPrefix = "/registry/"
+
+// 备份文件的名字是minik8s-backup-<时间>.json.gz，按照名字排序就是按照时间排序
+const (
+	archivePrefix     = "minik8s-backup-"
+	archiveSuffix     = ".json.gz"
+	archiveTimeFormat = "20060102-150405"
+)
+
+// 不需要备份的前缀，事件和选主的Lease带有租约，恢复之后不会过期
+// token和job的凭证是明文保存的，拿到备份文件就可以访问集群，所以也不备份，见secretPrefixes
+var excludedPrefixes = append([]string{
+	serverconfig.EtcdEventPath,
+	serverconfig.EtcdLeasePath,
+}, secretPrefixes...)
+
+// 保存凭证的前缀，不会写到备份文件里面，恢复的时候也保留etcd中原来的数据
+var secretPrefixes = []string{
+	serverconfig.EtcdTokenPath,
+	serverconfig.EtcdJobCredentialPath,
+}
+
+// 备份和恢复用到的etcd操作，*etcd.Store实现了这个接口
+type Store interface {
+	PrefixGetWithRevision(key string) ([]etcd.ListRes, int64, error)
+	Put(key string, val []byte) error
+	PrefixDel(key string) error
+}
+
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// 一个备份文件，所有的key都是在同一个revision读取的
+type Archive struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"createdAt"`
+	// 备份时etcd的revision
+	Revision int64   `json:"revision"`
+	Entries  []Entry `json:"entries"`
+}
+
+// 把RegistryPrefix下面的所有对象导出到w，格式是gzip压缩的json
+func Backup(store Store, w io.Writer) (*Archive, error) {
+	res, revision, err := store.PrefixGetWithRevision(RegistryPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		Version:   ArchiveVersion,
+		CreatedAt: time.Now(),
+		Revision:  revision,
+		Entries:   make([]Entry, 0, len(res)),
+	}
+	for _, kv := range res {
+		if excluded(kv.Key) {
+			continue
+		}
+		archive.Entries = append(archive.Entries, Entry{Key: kv.Key, Value: kv.Value})
+	}
+
+	gw := gzip.NewWriter(w)
+	if err := json.NewEncoder(gw).Encode(archive); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// 在dir下面生成一个新的备份文件，返回文件的路径
+// 先写到临时文件里面再重命名，避免留下不完整的备份
+func BackupToDir(store Store, dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(dir, ".tmp-"+archivePrefix)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	archive, err := Backup(store, f)
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	file := filepath.Join(dir, archiveName(archive.CreatedAt))
+	if err := os.Rename(f.Name(), file); err != nil {
+		return "", err
+	}
+	k8log.InfoLog("Backup", fmt.Sprintf("backup %d objects at revision %d to %s", len(archive.Entries), archive.Revision, file))
+	return file, nil
+}
+
+// 读取备份文件，版本不认识的时候返回错误
+func ReadArchive(r io.Reader) (*Archive, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	archive := &Archive{}
+	if err := json.NewDecoder(gr).Decode(archive); err != nil {
+		return nil, err
+	}
+	if archive.Version != ArchiveVersion {
+		return nil, fmt.Errorf("unsupported backup version %d, expected %d", archive.Version, ArchiveVersion)
+	}
+	return archive, nil
+}
+
+func ReadArchiveFile(file string) (*Archive, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadArchive(f)
+}
+
+func archiveName(t time.Time) string {
+	return archivePrefix + t.UTC().Format(archiveTimeFormat) + archiveSuffix
+}
+
+func isArchiveName(name string) bool {
+	return strings.HasPrefix(name, archivePrefix) && strings.HasSuffix(name, archiveSuffix)
+}
+
+func excluded(key string) bool {
+	for _, prefix := range excludedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
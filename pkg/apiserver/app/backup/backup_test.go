@@ -0,0 +1,236 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/etcd"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeStore struct {
+	data     map[string]string
+	revision int64
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{data: make(map[string]string)}
+}
+
+func (s *fakeStore) PrefixGetWithRevision(key string) ([]etcd.ListRes, int64, error) {
+	keys := make([]string, 0)
+	for k := range s.data {
+		if strings.HasPrefix(k, key) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	res := make([]etcd.ListRes, 0)
+	for _, k := range keys {
+		res = append(res, etcd.ListRes{Key: k, Value: s.data[k]})
+	}
+	return res, s.revision, nil
+}
+
+func (s *fakeStore) Put(key string, val []byte) error {
+	s.revision++
+	s.data[key] = string(val)
+	return nil
+}
+
+func (s *fakeStore) PrefixDel(key string) error {
+	for k := range s.data {
+		if strings.HasPrefix(k, key) {
+			delete(s.data, k)
+		}
+	}
+	return nil
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	src := newFakeStore()
+	node := apiObject.NodeStore{Status: apiObject.NodeStatus{Condition: apiObject.Ready}}
+	node.NodeMetadata.Name = "node1"
+	service := apiObject.ServiceStore{}
+	service.Metadata.Name = "svc1"
+	service.Spec.ClusterIP = "192.168.1.2"
+
+	src.Put(serverconfig.EtcdNodePath+"node1", []byte(mustJSON(t, node)))
+	src.Put(serverconfig.EtcdServicePath+"default/svc1", []byte(mustJSON(t, service)))
+	src.Put(serverconfig.EtcdPodPath+"default/pod1", []byte(`{"metadata":{"name":"pod1"}}`))
+	src.Put(serverconfig.EtcdEventPath+"default/pod1.1", []byte(`{}`))
+	src.Put(serverconfig.EtcdLeasePath+"default/kube-scheduler", []byte(`{}`))
+	// 有一个已经删除的Service泄露的IP
+	src.Put(serverconfig.EtcdIPPath, []byte(`{"258":true,"3":true}`))
+	src.Put("/other/key", []byte(`{}`))
+
+	buf := &bytes.Buffer{}
+	archive, err := Backup(src, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 事件、Lease和registry之外的key不会备份
+	if len(archive.Entries) != 4 {
+		t.Fatalf("expected 4 entries but got %d", len(archive.Entries))
+	}
+
+	restored, err := ReadArchive(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := newFakeStore()
+	dst.Put(serverconfig.EtcdPodPath+"default/stale", []byte(`{}`))
+	if err := Restore(dst, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := dst.data[serverconfig.EtcdPodPath+"default/stale"]; ok {
+		t.Errorf("objects not in the backup should be removed")
+	}
+	if dst.data[serverconfig.EtcdPodPath+"default/pod1"] != `{"metadata":{"name":"pod1"}}` {
+		t.Errorf("pod is not restored")
+	}
+	if dst.data[serverconfig.EtcdIPPath] != `{"258":true}` {
+		t.Errorf("expected allocated ip {\"258\":true} but got %s", dst.data[serverconfig.EtcdIPPath])
+	}
+
+	restoredNode := apiObject.NodeStore{}
+	if err := json.Unmarshal([]byte(dst.data[serverconfig.EtcdNodePath+"node1"]), &restoredNode); err != nil {
+		t.Fatal(err)
+	}
+	if restoredNode.Status.Condition != apiObject.Unknown {
+		t.Errorf("node condition should be Unknown but got %s", restoredNode.Status.Condition)
+	}
+}
+
+func TestBackupExcludesSecrets(t *testing.T) {
+	src := newFakeStore()
+	src.Put(serverconfig.EtcdPodPath+"default/pod1", []byte(`{"metadata":{"name":"pod1"}}`))
+	src.Put(serverconfig.EtcdTokenPath+"node-secret-token", []byte(`{"user":"node:node1"}`))
+	src.Put(serverconfig.EtcdJobCredentialPath+"default/job1", []byte(`{"token":"job-secret-token"}`))
+
+	buf := &bytes.Buffer{}
+	if _, err := Backup(src, buf); err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"node-secret-token", "job-secret-token", serverconfig.EtcdTokenPath, serverconfig.EtcdJobCredentialPath} {
+		if bytes.Contains(plain, []byte(secret)) {
+			t.Errorf("archive should not contain %s", secret)
+		}
+	}
+
+	archive, err := ReadArchive(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 恢复的时候保留原来的token和job凭证
+	dst := newFakeStore()
+	dst.Put(serverconfig.EtcdTokenPath+"live-token", []byte(`{}`))
+	dst.Put(serverconfig.EtcdJobCredentialPath+"default/job2", []byte(`{}`))
+	dst.Put(serverconfig.EtcdPodPath+"default/stale", []byte(`{}`))
+	if err := Restore(dst, archive); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dst.data[serverconfig.EtcdTokenPath+"live-token"]; !ok {
+		t.Errorf("tokens should be kept on restore")
+	}
+	if _, ok := dst.data[serverconfig.EtcdJobCredentialPath+"default/job2"]; !ok {
+		t.Errorf("job credentials should be kept on restore")
+	}
+	if _, ok := dst.data[serverconfig.EtcdPodPath+"default/stale"]; ok {
+		t.Errorf("objects not in the backup should be removed")
+	}
+	if _, ok := dst.data[serverconfig.EtcdPodPath+"default/pod1"]; !ok {
+		t.Errorf("pod is not restored")
+	}
+}
+
+func TestReadArchiveVersion(t *testing.T) {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	json.NewEncoder(gw).Encode(&Archive{Version: ArchiveVersion + 1})
+	gw.Close()
+
+	if _, err := ReadArchive(buf); err == nil {
+		t.Errorf("unknown version should fail")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		name := archiveName(now.Add(time.Duration(i) * time.Hour))
+		if err := os.WriteFile(filepath.Join(dir, name), []byte{}, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "other.txt"), []byte{}, 0600)
+
+	removed, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("expected 3 removed but got %d", len(removed))
+	}
+	for i, file := range removed {
+		if filepath.Base(file) != archiveName(now.Add(time.Duration(i)*time.Hour)) {
+			t.Errorf("the oldest backups should be removed first, got %s", file)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("expected 2 backups and other.txt left but got %d files", len(entries))
+	}
+}
+
+func TestBackupToDir(t *testing.T) {
+	store := newFakeStore()
+	store.Put(serverconfig.EtcdPodPath+"default/pod1", []byte(`{}`))
+
+	dir := t.TempDir()
+	file, err := BackupToDir(store, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := ReadArchiveFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.Entries) != 1 {
+		t.Errorf("expected 1 entry but got %d", len(archive.Entries))
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary file should be removed")
+	}
+}
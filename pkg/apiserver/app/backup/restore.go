@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"strconv"
+	"strings"
+)
+
+// 用备份文件重建etcd中的集群状态，恢复之前需要先停止APIServer
+// RegistryPrefix下面原来的数据除了token和job的凭证会被全部删除
+// 已经分配的ClusterIP根据恢复的Service重新计算，Node的状态设置为Unknown，等kubelet重新上报
+func Restore(store Store, archive *Archive) error {
+	if err := clearRegistry(store); err != nil {
+		return err
+	}
+
+	services := make([]string, 0)
+	nodes := 0
+	for _, entry := range archive.Entries {
+		value := entry.Value
+		switch {
+		case entry.Key == serverconfig.EtcdIPPath:
+			// 最后根据Service重新生成
+			continue
+		case strings.HasPrefix(entry.Key, serverconfig.EtcdServicePath):
+			services = append(services, value)
+		case strings.HasPrefix(entry.Key, serverconfig.EtcdNodePath):
+			value = staleNode(value)
+			nodes++
+		}
+
+		if err := store.Put(entry.Key, []byte(value)); err != nil {
+			return fmt.Errorf("restore %s failed: %v", entry.Key, err)
+		}
+	}
+
+	allocatedIP := allocatedClusterIPs(services)
+	allocatedIPJson, err := json.Marshal(allocatedIP)
+	if err != nil {
+		return err
+	}
+	if err := store.Put(serverconfig.EtcdIPPath, allocatedIPJson); err != nil {
+		return err
+	}
+
+	k8log.InfoLog("Backup", fmt.Sprintf("restore %d objects from revision %d, %d cluster ips, %d nodes are waiting for kubelet",
+		len(archive.Entries), archive.Revision, len(allocatedIP), nodes))
+	return nil
+}
+
+// 按照/registry/下面的第一级前缀删除原来的数据，备份里面没有的凭证保留下来，已经签发的token恢复之后还可以使用
+func clearRegistry(store Store) error {
+	res, _, err := store.PrefixGetWithRevision(RegistryPrefix)
+	if err != nil {
+		return err
+	}
+
+	cleared := make(map[string]bool)
+	for _, kv := range res {
+		prefix := kv.Key
+		if i := strings.Index(strings.TrimPrefix(kv.Key, RegistryPrefix), "/"); i >= 0 {
+			prefix = kv.Key[:len(RegistryPrefix)+i+1]
+		}
+		if cleared[prefix] || secret(prefix) {
+			continue
+		}
+		if err := store.PrefixDel(prefix); err != nil {
+			return err
+		}
+		cleared[prefix] = true
+	}
+	return nil
+}
+
+func secret(key string) bool {
+	for _, prefix := range secretPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// 把Node的状态设置为Unknown，调度器不会往上面调度Pod，kubelet重新上报之后恢复
+func staleNode(value string) string {
+	node := apiObject.NodeStore{}
+	if err := json.Unmarshal([]byte(value), &node); err != nil {
+		return value
+	}
+	node.Status.Condition = apiObject.Unknown
+	res, err := json.Marshal(node)
+	if err != nil {
+		return value
+	}
+	return string(res)
+}
+
+// 和helper.AllocClusterIP使用一样的格式，key是IP的后两位num2*256+num3
+func allocatedClusterIPs(services []string) map[int]bool {
+	allocatedIP := make(map[int]bool)
+	prefix := strconv.Itoa(config.SERVICE_IP_PREFIX[0]) + "." + strconv.Itoa(config.SERVICE_IP_PREFIX[1]) + "."
+	for _, value := range services {
+		service := apiObject.ServiceStore{}
+		if err := json.Unmarshal([]byte(value), &service); err != nil {
+			continue
+		}
+		if !strings.HasPrefix(service.Spec.ClusterIP, prefix) {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(service.Spec.ClusterIP, prefix), ".")
+		if len(parts) != 2 {
+			continue
+		}
+		num2, err2 := strconv.Atoi(parts[0])
+		num3, err3 := strconv.Atoi(parts[1])
+		if err2 != nil || err3 != nil || num2 < 0 || num2 > 255 || num3 < 0 || num3 > 255 {
+			continue
+		}
+		allocatedIP[num2*256+num3] = true
+	}
+	return allocatedIP
+}
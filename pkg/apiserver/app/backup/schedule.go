@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"fmt"
+	"miniK8s/pkg/k8log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// 每隔interval在dir下面做一次备份，只保留最新的keep个备份，stopCh关闭的时候退出
+func RunPeriodic(store Store, dir string, interval time.Duration, keep int, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := BackupToDir(store, dir); err != nil {
+			k8log.ErrorLog("Backup", "periodic backup failed: "+err.Error())
+		} else if removed, err := Prune(dir, keep); err != nil {
+			k8log.ErrorLog("Backup", "prune backups failed: "+err.Error())
+		} else if len(removed) != 0 {
+			k8log.InfoLog("Backup", fmt.Sprintf("removed %d old backups", len(removed)))
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// 删除dir下面旧的备份文件，只保留最新的keep个，返回被删除的文件
+// keep小于等于0的时候不删除
+func Prune(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	archives := make([]string, 0)
+	for _, entry := range entries {
+		if !entry.IsDir() && isArchiveName(entry.Name()) {
+			archives = append(archives, entry.Name())
+		}
+	}
+	if len(archives) <= keep {
+		return nil, nil
+	}
+
+	// 名字里面的时间是固定长度的，按照名字排序就是按照时间排序
+	sort.Strings(archives)
+	removed := make([]string, 0)
+	for _, name := range archives[:len(archives)-keep] {
+		file := filepath.Join(dir, name)
+		if err := os.Remove(file); err != nil {
+			return removed, err
+		}
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
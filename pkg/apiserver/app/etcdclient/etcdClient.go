@@ -1,19 +1,43 @@
 package etcdclient
 
 import (
+	"fmt"
 	"miniK8s/pkg/apiserver/serverconfig"
 	"miniK8s/pkg/etcd"
+	"miniK8s/pkg/etcd/memory"
 	"miniK8s/pkg/k8log"
 )
 
-var EtcdStore *etcd.Store = nil
+var EtcdStore etcd.Storage = nil
 
 func init() {
 	etcdConfig := serverconfig.DefaultEtcdConfig()
-	etcdStore, err := etcd.NewEtcdStore(etcdConfig.EtcdEndpoints, etcdConfig.EtcdTimeout)
+	etcdStore, err := NewStorage(etcdConfig)
 	if err != nil {
 		k8log.FatalLog("APIServer", "init etcd client failed, err is "+err.Error())
 	}
-	k8log.InfoLog("APIServer", "init etcd client connect success")
-	EtcdStore = etcdStore
+	k8log.InfoLog("APIServer", "init "+etcdConfig.Backend+" storage success")
+	// 记录每次存储操作的耗时，通过/metrics输出
+	EtcdStore = etcd.NewInstrumentedStorage(etcdStore)
+}
+
+// 根据配置创建存储
+func NewStorage(etcdConfig *serverconfig.EtcdConfig) (etcd.Storage, error) {
+	switch etcdConfig.Backend {
+	case serverconfig.StorageBackendEtcd:
+		return etcd.NewEtcdStore(etcdConfig.EtcdEndpoints, etcdConfig.EtcdTimeout)
+	case serverconfig.StorageBackendMemory:
+		return memory.NewStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %s", etcdConfig.Backend)
+	}
+}
+
+// readyz检查读取的key，不存在也没有关系，能正常返回就说明存储可用
+const healthzKey = "/healthz"
+
+// Healthy 检查存储是否可用
+func Healthy() error {
+	_, err := EtcdStore.Get(healthzKey)
+	return err
 }
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/admission"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+
+	"github.com/goccy/go-json"
+)
+
+// 所有通过通用handler创建、更新、删除的对象都要经过这个准入控制链
+// 顺序是：填充默认值 -> 修改型webhook -> 检查容器重名 -> 检查namespace -> 校验型webhook
+var admissionChain = newAdmissionChain()
+
+func newAdmissionChain() *admission.Chain {
+	chain := admission.NewChain()
+	chain.AddMutator(admission.NewDefaultValues())
+	chain.AddMutator(admission.NewMutatingWebhook(listMutatingWebhookConfigurations))
+	chain.AddValidator(admission.NewDuplicateContainerNames())
+	chain.AddValidator(admission.NewNamespaceLifecycle(checkNamespaceActive))
+	chain.AddValidator(admission.NewValidatingWebhook(listValidatingWebhookConfigurations))
+	return chain
+}
+
+// "/apis/v1/mutatingwebhookconfigurations"
+// "/apis/v1/mutatingwebhookconfigurations/:name"
+var mutatingWebhookResource = &Resource{
+	Kind:       apiObject.MutatingWebhookConfigurationKind,
+	EtcdPath:   serverconfig.EtcdMutatingWebhookPath,
+	Namespaced: false,
+
+	// 只能更新webhooks
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldStore.(*apiObject.MutatingWebhookConfiguration).Webhooks = newStore.(*apiObject.MutatingWebhookConfiguration).Webhooks
+		return nil
+	},
+}
+
+// "/apis/v1/validatingwebhookconfigurations"
+// "/apis/v1/validatingwebhookconfigurations/:name"
+var validatingWebhookResource = &Resource{
+	Kind:       apiObject.ValidatingWebhookConfigurationKind,
+	EtcdPath:   serverconfig.EtcdValidatingWebhookPath,
+	Namespaced: false,
+
+	// 只能更新webhooks
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldStore.(*apiObject.ValidatingWebhookConfiguration).Webhooks = newStore.(*apiObject.ValidatingWebhookConfiguration).Webhooks
+		return nil
+	},
+}
+
+func init() {
+	Register(mutatingWebhookResource)
+	Register(validatingWebhookResource)
+}
+
+// 从etcd中读取所有的MutatingWebhookConfiguration
+func listMutatingWebhookConfigurations() ([]apiObject.MutatingWebhookConfiguration, error) {
+	res, err := etcdclient.EtcdStore.PrefixGet(serverconfig.EtcdMutatingWebhookPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]apiObject.MutatingWebhookConfiguration, 0, len(res))
+	for _, v := range res {
+		config := apiObject.MutatingWebhookConfiguration{}
+		if err := json.Unmarshal([]byte(v.Value), &config); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// 从etcd中读取所有的ValidatingWebhookConfiguration
+func listValidatingWebhookConfigurations() ([]apiObject.ValidatingWebhookConfiguration, error) {
+	res, err := etcdclient.EtcdStore.PrefixGet(serverconfig.EtcdValidatingWebhookPath)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]apiObject.ValidatingWebhookConfiguration, 0, len(res))
+	for _, v := range res {
+		config := apiObject.ValidatingWebhookConfiguration{}
+		if err := json.Unmarshal([]byte(v.Value), &config); err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
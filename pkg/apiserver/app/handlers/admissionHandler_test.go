@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/util/jsonpatch"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// Node和Namespace的写操作和其他资源一样经过校验型的webhook
+func TestValidatingWebhookRejectsNode(t *testing.T) {
+	useMemoryStore(t, 1000)
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	InstallResources(r)
+
+	// 拒绝所有带有reject标签的对象
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		review := apiObject.AdmissionReview{}
+		json.NewDecoder(req.Body).Decode(&review)
+		meta := struct {
+			Metadata apiObject.Metadata `json:"metadata"`
+		}{}
+		json.Unmarshal(review.Request.Object, &meta)
+		_, reject := meta.Metadata.Labels["reject"]
+		review.Response = &apiObject.AdmissionResponse{
+			UID:     review.Request.UID,
+			Allowed: !reject,
+			Message: "label reject is not allowed",
+		}
+		review.Request = nil
+		json.NewEncoder(w).Encode(review)
+	}))
+	defer server.Close()
+
+	webhookConfig := apiObject.ValidatingWebhookConfiguration{
+		Webhooks: []apiObject.Webhook{{
+			Name: "no-reject",
+			URL:  server.URL,
+			Rules: []apiObject.WebhookRule{{
+				Operations: []string{apiObject.AdmissionMatchAll},
+				Kinds:      []string{apiObject.NodeKind, apiObject.NamespaceKind},
+			}},
+		}},
+	}
+	webhookConfig.Kind = apiObject.ValidatingWebhookConfigurationKind
+	webhookConfig.Metadata.Name = "no-reject"
+	if w := serveJSON(r, http.MethodPost, config.ValidatingWebhookConfigurationsURL, webhookConfig); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	node := apiObject.Node{}
+	node.Kind = apiObject.NodeKind
+	node.NodeMetadata.Name = "node1"
+	node.IP = "192.168.1.10"
+	if w := serveJSON(r, http.MethodPost, config.NodesURL, node); w.Code != http.StatusCreated {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	node.NodeMetadata.Name = "node2"
+	node.NodeMetadata.Labels = map[string]string{"reject": "true"}
+	if w := serveJSON(r, http.MethodPost, config.NodesURL, node); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+
+	// 给已有的Node打上标签同样会被拒绝
+	req := httptest.NewRequest(http.MethodPatch, config.NodesURL+"/node1", bytes.NewReader([]byte(`{"metadata":{"labels":{"reject":"true"}}}`)))
+	req.Header.Set("Content-Type", jsonpatch.MergePatchType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+
+	namespace := apiObject.Namespace{}
+	namespace.Kind = apiObject.NamespaceKind
+	namespace.Metadata.Name = "test"
+	namespace.Metadata.Labels = map[string]string{"reject": "true"}
+	if w := serveJSON(r, http.MethodPost, config.NamespacesURL, namespace); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusForbidden, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/audit"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+func init() {
+	registerRoute(config.AuditEventsURL, apiObject.AuditEventKind, true)
+}
+
+// GET 按照时间范围和对象查询审计日志
+// "/api/v1/auditevents?since=2023-06-01T00:00:00Z&kind=ReplicaSet&namespace=default&name=rs1"
+func GetAuditEvents(logger *audit.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k8log.InfoLog("APIServer", "GetAuditEvents")
+
+		filter := &audit.Filter{
+			Kind:      c.Query(config.URL_QUERY_KIND),
+			Namespace: c.Query(config.URL_PARAM_NAMESPACE),
+			Name:      c.Query(config.URL_PARAM_NAME),
+			User:      c.Query(config.URL_QUERY_USER),
+		}
+
+		var err error
+		if filter.Since, err = parseQueryTime(c, config.URL_QUERY_SINCE); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "GetAuditEvents: invalid since, " + err.Error(),
+			})
+			return
+		}
+		if filter.Until, err = parseQueryTime(c, config.URL_QUERY_UNTIL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "GetAuditEvents: invalid until, " + err.Error(),
+			})
+			return
+		}
+
+		events, err := logger.Query(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "GetAuditEvents: " + err.Error(),
+			})
+			return
+		}
+
+		targets := make([]string, 0, len(events))
+		for _, event := range events {
+			eventJson, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			targets = append(targets, string(eventJson))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data": stringutil.StringSliceToJsonArray(targets),
+		})
+	}
+}
+
+// 查询参数里面的时间是RFC3339格式，没有的时候返回零值
+func parseQueryTime(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiObject/validation"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// CRD的增删查都由通用的handler完成，CRD不支持更新，需要修改的时候先删除再创建
+// "/apis/v1/customresourcedefinitions"
+// "/apis/v1/customresourcedefinitions/:name"
+var crdResource = &Resource{
+	Kind:       apiObject.CRDKind,
+	EtcdPath:   serverconfig.EtcdCRDPath,
+	Namespaced: false,
+
+	PrepareForCreate: func(store interface{}) error {
+		return validateCRD(store.(*apiObject.CustomResourceDefinition))
+	},
+	// 删除CRD的时候，把它定义的所有自定义对象一起删除
+	AfterDelete: func(store interface{}) {
+		crd := store.(*apiObject.CustomResourceDefinition)
+		if err := etcdclient.EtcdStore.PrefixDel(customResourceEtcdPath(crd)); err != nil {
+			k8log.ErrorLog("APIServer", "DeleteCustomResourceDefinition: delete custom objects failed "+err.Error())
+		}
+	},
+}
+
+func init() {
+	Register(crdResource)
+}
+
+// 检查CRD是否和已有的资源冲突，字段的合法性由validation.Validate检查
+func validateCRD(crd *apiObject.CustomResourceDefinition) error {
+	spec := crd.Spec
+
+	// 不能和内置的资源重名
+	for kind := range apiObject.KindToStructType {
+		if strings.EqualFold(kind, spec.Names.Kind) || strings.EqualFold(kind, crd.SingularName()) {
+			return newStatusError(http.StatusConflict, "kind %s is a built-in kind", spec.Names.Kind)
+		}
+	}
+
+	// kubectl通过kind找到CRD，所以kind不能和其他的CRD重复
+	crds, err := listCRDs()
+	if err != nil {
+		return newStatusError(http.StatusInternalServerError, "list custom resource definitions failed %s", err.Error())
+	}
+	for _, other := range crds {
+		if other.Spec.Names.Kind == spec.Names.Kind || other.SingularName() == crd.SingularName() {
+			return newStatusError(http.StatusConflict, "kind %s is already defined by %s", spec.Names.Kind, other.Metadata.Name)
+		}
+	}
+	return nil
+}
+
+// 获取所有的CRD
+func listCRDs() ([]apiObject.CustomResourceDefinition, error) {
+	res, err := etcdclient.EtcdStore.PrefixGet(serverconfig.EtcdCRDPath)
+	if err != nil {
+		return nil, err
+	}
+
+	crds := make([]apiObject.CustomResourceDefinition, 0, len(res))
+	for _, v := range res {
+		crd := apiObject.CustomResourceDefinition{}
+		if err := json.Unmarshal([]byte(v.Value), &crd); err != nil {
+			return nil, err
+		}
+		crds = append(crds, crd)
+	}
+	return crds, nil
+}
+
+// CRD定义的自定义对象在etcd中的前缀
+// 完整路径是 /registry/custom/<group>/<plural>/<namespace>/<name>
+func customResourceEtcdPath(crd *apiObject.CustomResourceDefinition) string {
+	return serverconfig.EtcdCustomResourcePath + crd.Spec.Group + "/" + crd.Spec.Names.Plural + "/"
+}
+
+// 所有CRD定义的自定义对象在etcd中的前缀，删除namespace的时候需要检查
+func customResourceEtcdPaths() ([]string, error) {
+	crds, err := listCRDs()
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(crds))
+	for i := range crds {
+		paths = append(paths, customResourceEtcdPath(&crds[i]))
+	}
+	return paths, nil
+}
+
+// 根据CRD生成自定义对象的Resource，自定义对象的增删改查和内置的资源共用通用的handler
+func newCustomResource(crd *apiObject.CustomResourceDefinition) *Resource {
+	return &Resource{
+		Kind:       crd.Spec.Names.Kind,
+		EtcdPath:   customResourceEtcdPath(crd),
+		Namespaced: true,
+		StoreType:  reflect.TypeOf(apiObject.CustomObject{}),
+
+		PrepareForCreate: func(store interface{}) error {
+			obj := store.(*apiObject.CustomObject)
+			if obj.Kind == "" {
+				obj.Kind = crd.Spec.Names.Kind
+			}
+			if obj.APIVersion == "" {
+				obj.APIVersion = crd.APIVersion()
+			}
+			if obj.Kind != crd.Spec.Names.Kind {
+				return newStatusError(http.StatusBadRequest, "kind should be %s", crd.Spec.Names.Kind)
+			}
+			if obj.APIVersion != crd.APIVersion() {
+				return newStatusError(http.StatusBadRequest, "apiVersion should be %s", crd.APIVersion())
+			}
+			return validateCustomObject(crd, obj)
+		},
+		// 可以更新spec、labels和annotations
+		PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+			oldObj := oldStore.(*apiObject.CustomObject)
+			newObj := newStore.(*apiObject.CustomObject)
+			if err := validateCustomObject(crd, newObj); err != nil {
+				return err
+			}
+			oldObj.Spec = newObj.Spec
+			if len(newObj.Metadata.Labels) != 0 {
+				oldObj.Metadata.Labels = newObj.Metadata.Labels
+			}
+			if len(newObj.Metadata.Annotations) != 0 {
+				oldObj.Metadata.Annotations = newObj.Metadata.Annotations
+			}
+			return nil
+		},
+	}
+}
+
+// 用CRD里面的schema校验自定义对象的spec，不合法的时候返回422
+func validateCustomObject(crd *apiObject.CustomResourceDefinition, obj *apiObject.CustomObject) error {
+	if errs := validation.ValidateCustomObject(crd, obj); len(errs) != 0 {
+		return validation.NewInvalidError(crd.Spec.Names.Kind, obj.Metadata.Name, errs)
+	}
+	return nil
+}
+
+// 根据url里面的group、version和plural找到CRD，生成对应的Resource
+// 没有找到的时候已经返回了错误
+func customResourceFromRequest(c *gin.Context) (*Resource, bool) {
+	group := c.Param(config.URL_PARAM_GROUP)
+	version := c.Param(config.URL_PARAM_VERSION)
+	plural := c.Param(config.URL_PARAM_PLURAL)
+
+	res, err := etcdclient.EtcdStore.Get(serverconfig.EtcdCRDPath + plural + "." + group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "get custom resource definition failed " + err.Error(),
+		})
+		return nil, false
+	}
+
+	if len(res) != 1 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "the server could not find the requested resource " + plural + "." + group,
+		})
+		return nil, false
+	}
+
+	crd := &apiObject.CustomResourceDefinition{}
+	if err := json.Unmarshal([]byte(res[0].Value), crd); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "parse custom resource definition failed " + err.Error(),
+		})
+		return nil, false
+	}
+
+	if crd.Spec.Version != version {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "the server could not find the requested resource " + plural + "." + group + "/" + version,
+		})
+		return nil, false
+	}
+
+	return newCustomResource(crd), true
+}
+
+// GET 获取某个namespace下面的所有自定义对象，支持watch
+// "/apis/:group/:version/namespaces/:namespace/:plural"
+func GetCustomObjects(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		ListOrWatch(r.List, r.EtcdPath, true)(c)
+	}
+}
+
+// GET 获取单个自定义对象
+// "/apis/:group/:version/namespaces/:namespace/:plural/:name"
+func GetCustomObject(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		r.Get(c)
+	}
+}
+
+// POST 创建自定义对象
+// "/apis/:group/:version/namespaces/:namespace/:plural"
+func AddCustomObject(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		r.Create(c)
+	}
+}
+
+// PUT 更新自定义对象
+// "/apis/:group/:version/namespaces/:namespace/:plural/:name"
+func UpdateCustomObject(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		r.Update(c)
+	}
+}
+
+// PATCH 用merge patch或者json patch更新自定义对象
+// "/apis/:group/:version/namespaces/:namespace/:plural/:name"
+func PatchCustomObject(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		r.Patch(c)
+	}
+}
+
+// DELETE 删除自定义对象
+// "/apis/:group/:version/namespaces/:namespace/:plural/:name"
+func DeleteCustomObject(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		r.Delete(c)
+	}
+}
+
+// PUT 更新自定义对象的ownerReferences和finalizers
+// "/apis/:group/:version/namespaces/:namespace/:plural/:name/metadata"
+func UpdateCustomObjectMetadata(c *gin.Context) {
+	if r, ok := customResourceFromRequest(c); ok {
+		r.UpdateMetadata(c)
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+)
+
+// Event的增删改查都由通用的handler完成，写入etcd的时候带上过期时间
+// "/api/v1/namespaces/:namespace/events"
+// "/api/v1/namespaces/:namespace/events/:name"
+// "/api/v1/events"
+var eventResource = &Resource{
+	Kind:       apiObject.EventKind,
+	EtcdPath:   serverconfig.EtcdEventPath,
+	Namespaced: true,
+	GlobalURL:  config.GlobalEventsURL,
+	TTL:        serverconfig.EventTTL,
+
+	// 重复发生的事件只更新次数、最后一次发生的时间和信息
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldEvent := oldStore.(*apiObject.Event)
+		newEvent := newStore.(*apiObject.Event)
+		oldEvent.Count = newEvent.Count
+		oldEvent.LastTimestamp = newEvent.LastTimestamp
+		oldEvent.Message = newEvent.Message
+		return nil
+	},
+}
+
+func init() {
+	Register(eventResource)
+}
@@ -0,0 +1,847 @@
+package handlers
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiObject/validation"
+	"miniK8s/pkg/apiserver/app/admission"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/jsonpatch"
+	"miniK8s/util/stringutil"
+	"miniK8s/util/uuid"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// 所有注册的资源共用的handler，具体的资源通过Resource里面的钩子定制
+// 返回给客户端的数据格式和之前每种资源单独的handler保持一致
+
+// PATCH和更新状态的时候写入冲突最多重试的次数
+const maxPatchRetries = 5
+
+// 从url中解析namespace和name
+func (r *Resource) parseParams(c *gin.Context) (string, string, bool) {
+	namespace := c.Param(config.URL_PARAM_NAMESPACE)
+	nameParam := r.NameParam
+	if nameParam == "" {
+		nameParam = config.URL_PARAM_NAME
+	}
+	name := c.Param(nameParam)
+
+	if r.Namespaced && namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	// 集群级别的对象没有namespace，Namespace自己的名字也在:namespace参数里面
+	if !r.Namespaced {
+		namespace = ""
+	}
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is empty",
+		})
+		k8log.ErrorLog("APIServer", r.Kind+": name is empty")
+		return "", "", false
+	}
+	return namespace, name, true
+}
+
+// 从etcd中读取一个对象，读取失败或者不存在的时候已经返回了错误
+func (r *Resource) getFromEtcd(c *gin.Context, action string, key string) (interface{}, int64, bool) {
+	res, err := etcdclient.EtcdStore.Get(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return nil, 0, false
+	}
+
+	if len(res) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": action + ": not found",
+		})
+		return nil, 0, false
+	}
+
+	if len(res) != 1 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": more than one result",
+		})
+		return nil, 0, false
+	}
+
+	store := r.newStore()
+	if err := json.Unmarshal([]byte(res[0].Value), store); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return nil, 0, false
+	}
+
+	return store, res[0].ResourceVersion, true
+}
+
+// 以CAS的方式写回etcd，失败的时候已经返回了错误
+func (r *Resource) casToEtcd(c *gin.Context, action string, key string, resourceVersion int64, store interface{}) bool {
+	storeJson, err := json.Marshal(store)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return false
+	}
+
+	ok, err := etcdclient.EtcdStore.CompareAndSwapWithTTL(key, resourceVersion, storeJson, r.ttl(store))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return false
+	}
+
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": action + ": " + r.Kind + " has been modified, please get the latest version and try again",
+		})
+		return false
+	}
+	return true
+}
+
+// GET 获取单个对象
+// 比如 "/api/v1/namespaces/:namespace/pods/:name"
+func (r *Resource) Get(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Get" + r.Kind
+	k8log.InfoLog("APIServer", fmt.Sprintf("%s: namespace=%s, name=%s", action, namespace, name))
+
+	res, err := etcdclient.EtcdStore.Get(r.etcdKey(namespace, name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	if len(res) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": action + ": not found",
+		})
+		return
+	}
+
+	if len(res) != 1 {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": more than one result",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": injectResourceVersion(res[0].Value, res[0].ResourceVersion),
+	})
+}
+
+// GET 获取某个namespace下面的所有对象
+// 比如 "/api/v1/namespaces/:namespace/pods"
+func (r *Resource) List(c *gin.Context) {
+	prefix := r.EtcdPath
+	if r.Namespaced {
+		namespace := c.Param(config.URL_PARAM_NAMESPACE)
+		if namespace == "" {
+			namespace = config.DefaultNamespace
+		}
+		prefix = r.EtcdPath + namespace + "/"
+	}
+	r.listPrefix(c, "List"+r.Kind, prefix)
+}
+
+// GET 获取所有namespace下面的对象
+// 比如 "/api/v1/pods"
+func (r *Resource) ListGlobal(c *gin.Context) {
+	r.listPrefix(c, "ListGlobal"+r.Kind, r.EtcdPath)
+}
+
+func (r *Resource) listPrefix(c *gin.Context, action string, prefix string) {
+	k8log.InfoLog("APIServer", action+": prefix="+prefix)
+
+	// 根据limit、continue、labelSelector和fieldSelector读取
+	res, nextContinue, listVersion, err := listFromEtcd(c, prefix)
+	if err != nil {
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		c.JSON(errorStatusCode(err), gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	targets := make([]string, 0)
+	for _, obj := range res {
+		targets = append(targets, injectResourceVersion(obj.Value, obj.ResourceVersion))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":            stringutil.StringSliceToJsonArray(targets),
+		"continue":        nextContinue,
+		"resourceVersion": strconv.FormatInt(listVersion, 10),
+	})
+}
+
+// POST 创建一个对象
+// 比如 "/api/v1/namespaces/:namespace/pods"
+func (r *Resource) Create(c *gin.Context) {
+	action := "Add" + r.Kind
+	k8log.InfoLog("APIServer", action)
+
+	// 从请求中解析对象
+	obj := r.newObject()
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return
+	}
+
+	// 转换为存储的对象
+	store := r.newStore()
+	if err := convertObject(obj, store); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	meta := objectMetadata(store)
+	if meta.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": name is empty",
+		})
+		k8log.ErrorLog("APIServer", action+": name is empty")
+		return
+	}
+
+	if r.Namespaced {
+		if meta.Namespace == "" {
+			meta.Namespace = config.DefaultNamespace
+		}
+	} else {
+		meta.Namespace = ""
+	}
+
+	// 准入控制：填充默认值、调用修改型的webhook
+	attr := &admission.Attributes{
+		Kind:      r.Kind,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		Operation: apiObject.AdmissionOperationCreate,
+		Object:    store,
+	}
+	if err := admissionChain.Admit(attr); err != nil {
+		r.rejectRequest(c, action, err)
+		return
+	}
+	// Metadata里面namespace的默认值是default，集群级别的对象需要清空
+	if !r.Namespaced {
+		meta.Namespace = ""
+	}
+
+	// 检查对象的合法性，不合法的时候返回422和所有不合法的字段
+	if err := r.validate(store, meta.Name); err != nil {
+		r.rejectRequest(c, action, err)
+		return
+	}
+
+	// 检查是否已经存在
+	key := r.etcdKey(meta.Namespace, meta.Name)
+	res, err := etcdclient.EtcdStore.Get(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return
+	}
+
+	if len(res) != 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": action + ": already exists",
+		})
+		k8log.ErrorLog("APIServer", action+": "+meta.Name+" already exists")
+		return
+	}
+
+	// 设置UUID，哪怕用户自己设置了UUID，也会被覆盖
+	meta.UUID = uuid.NewUUID()
+	// deletionTimestamp只能由删除操作设置
+	meta.DeletionTimestamp = nil
+	// resourceVersion由etcd决定，不会持久化
+	meta.ResourceVersion = ""
+
+	// 准入控制：检查容器重名、namespace是否可用、调用校验型的webhook
+	// 必须在PrepareForCreate之前，被拒绝的对象不会分配ClusterIP这样的资源
+	if err := admissionChain.Validate(attr); err != nil {
+		r.rejectRequest(c, action, err)
+		return
+	}
+
+	if r.PrepareForCreate != nil {
+		if err := r.PrepareForCreate(store); err != nil {
+			r.rejectRequest(c, action, err)
+			return
+		}
+	}
+
+	storeJson, err := json.Marshal(store)
+	if err != nil {
+		r.abortCreate(store)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	// 版本为0表示key不存在，保证并发创建的时候只有一个能成功
+	ok, err := etcdclient.EtcdStore.CompareAndSwapWithTTL(key, 0, storeJson, r.ttl(store))
+	if err != nil {
+		r.abortCreate(store)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return
+	}
+
+	if !ok {
+		r.abortCreate(store)
+		c.JSON(http.StatusConflict, gin.H{
+			"error": action + ": already exists",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": action + ": success",
+	})
+
+	if r.AfterCreate != nil {
+		r.AfterCreate(store)
+	}
+}
+
+// PrepareForCreate之后创建失败，释放已经分配的资源
+func (r *Resource) abortCreate(store interface{}) {
+	if r.AbortCreate != nil {
+		r.AbortCreate(store)
+	}
+}
+
+// PUT 更新一个对象，具体更新哪些字段由PrepareForUpdate决定
+// 比如 "/api/v1/namespaces/:namespace/pods/:name"
+func (r *Resource) Update(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Update" + r.Kind
+	k8log.InfoLog("APIServer", fmt.Sprintf("%s: namespace=%s, name=%s", action, namespace, name))
+
+	key := r.etcdKey(namespace, name)
+	oldStore, resourceVersion, ok := r.getFromEtcd(c, action, key)
+	if !ok {
+		return
+	}
+
+	// 解析请求体里面的对象
+	newStore := r.newStore()
+	if err := c.ShouldBindJSON(newStore); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	// 检查请求中的resourceVersion是否过期
+	if !resourceVersionMatch(objectMetadata(newStore).ResourceVersion, resourceVersion) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": action + ": " + r.Kind + " has been modified, please get the latest version and try again",
+		})
+		return
+	}
+
+	if !r.mergeUpdate(c, action, namespace, name, oldStore, newStore, false) {
+		return
+	}
+
+	if !r.casToEtcd(c, action, key, resourceVersion, oldStore) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": action + ": success",
+	})
+}
+
+// 把请求中的对象合并到oldStore上面，经过准入控制和合法性检查，失败的时候已经返回了错误
+// PUT和PATCH共用，合并之后的结果在oldStore里面
+// patched为true的时候newStore是patch之后完整的对象，labels和annotations直接以它为准，这样patch可以删除所有的label
+func (r *Resource) mergeUpdate(c *gin.Context, action string, namespace string, name string, oldStore interface{}, newStore interface{}, patched bool) bool {
+	// 合并之前的对象，交给准入控制作为OldObject
+	original := r.newStore()
+	reflectCopy(original, oldStore)
+
+	// ownerReferences、finalizers和deletionTimestamp只能通过metadata子资源和删除操作修改
+	oldMeta := *objectMetadata(oldStore)
+
+	if err := r.PrepareForUpdate(oldStore, newStore); err != nil {
+		r.rejectRequest(c, action, err)
+		return false
+	}
+
+	meta := objectMetadata(oldStore)
+	if patched {
+		meta.Labels = objectMetadata(newStore).Labels
+		meta.Annotations = objectMetadata(newStore).Annotations
+	}
+	meta.OwnerReferences = oldMeta.OwnerReferences
+	meta.Finalizers = oldMeta.Finalizers
+	meta.DeletionTimestamp = oldMeta.DeletionTimestamp
+
+	// 准入控制处理的是合并之后的对象，避免默认值覆盖掉请求中没有填写的字段
+	attr := &admission.Attributes{
+		Kind:      r.Kind,
+		Namespace: namespace,
+		Name:      name,
+		Operation: apiObject.AdmissionOperationUpdate,
+		Object:    oldStore,
+		OldObject: original,
+	}
+	if err := admissionChain.Admit(attr); err != nil {
+		r.rejectRequest(c, action, err)
+		return false
+	}
+	if !r.Namespaced {
+		objectMetadata(oldStore).Namespace = ""
+	}
+	if err := r.validate(oldStore, name); err != nil {
+		r.rejectRequest(c, action, err)
+		return false
+	}
+	if err := admissionChain.Validate(attr); err != nil {
+		r.rejectRequest(c, action, err)
+		return false
+	}
+	return true
+}
+
+// PATCH 用merge patch或者json patch更新一个对象，Content-Type决定patch的类型
+// 比如 "/api/v1/namespaces/:namespace/replicasets/:name"，body是 {"spec": {"replicas": 3}}
+// patch应用在etcd中最新的对象上面，之后和PUT一样经过PrepareForUpdate、准入控制和合法性检查
+// patch里面没有带resourceVersion的时候，写入冲突会重新读取对象再应用一次
+func (r *Resource) Patch(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Patch" + r.Kind
+	k8log.InfoLog("APIServer", fmt.Sprintf("%s: namespace=%s, name=%s", action, namespace, name))
+
+	patchType := c.ContentType()
+	if patchType != jsonpatch.MergePatchType && patchType != jsonpatch.JSONPatchType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error": action + ": " + jsonpatch.ErrUnsupportedPatchType.Error(),
+		})
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	key := r.etcdKey(namespace, name)
+	for i := 0; i < maxPatchRetries; i++ {
+		oldStore, resourceVersion, ok := r.getFromEtcd(c, action, key)
+		if !ok {
+			return
+		}
+
+		oldJson, err := json.Marshal(oldStore)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			return
+		}
+
+		patchedJson, err := jsonpatch.Apply(patchType, oldJson, patch)
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			return
+		}
+
+		newStore := r.newStore()
+		if err := json.Unmarshal(patchedJson, newStore); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			return
+		}
+
+		// etcd中的对象不带resourceVersion，patch里面指定了才检查
+		requestVersion := objectMetadata(newStore).ResourceVersion
+		if !resourceVersionMatch(requestVersion, resourceVersion) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": action + ": " + r.Kind + " has been modified, please get the latest version and try again",
+			})
+			return
+		}
+
+		if !r.mergeUpdate(c, action, namespace, name, oldStore, newStore, true) {
+			return
+		}
+
+		storeJson, err := json.Marshal(oldStore)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			return
+		}
+
+		ok, err = etcdclient.EtcdStore.CompareAndSwapWithTTL(key, resourceVersion, storeJson, r.ttl(oldStore))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			k8log.ErrorLog("APIServer", action+": "+err.Error())
+			return
+		}
+
+		if ok {
+			c.JSON(http.StatusOK, gin.H{
+				"message": action + ": success",
+			})
+			return
+		}
+
+		if requestVersion != "" {
+			break
+		}
+		k8log.DebugLog("APIServer", action+": conflict, retry")
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error": action + ": " + r.Kind + " has been modified, please get the latest version and try again",
+	})
+}
+
+// DELETE 删除一个对象
+// 比如 "/api/v1/namespaces/:namespace/pods/:name?propagationPolicy=Foreground"
+// 对象没有finalizers并且是后台删除的时候直接删除，依赖它的对象由GC在后台删除，返回204
+// 否则只设置deletionTimestamp，等finalizers都被移除之后再真正删除，返回202
+// GracefulDelete的资源总是返回202，由controller清理完之后真正删除
+func (r *Resource) Delete(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Delete" + r.Kind
+	key := r.etcdKey(namespace, name)
+	k8log.InfoLog("APIServer", action+": key="+key)
+
+	policy := c.DefaultQuery(config.URL_QUERY_PROPAGATION_POLICY, apiObject.DeletePropagationBackground)
+	switch policy {
+	case apiObject.DeletePropagationBackground, apiObject.DeletePropagationForeground, apiObject.DeletePropagationOrphan:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": unknown propagationPolicy " + policy,
+		})
+		return
+	}
+
+	// 先读出来，删除之后的钩子需要用到
+	store, resourceVersion, ok := r.getFromEtcd(c, action, key)
+	if !ok {
+		return
+	}
+
+	// 删除只经过校验型的准入控制
+	attr := &admission.Attributes{
+		Kind:      r.Kind,
+		Namespace: namespace,
+		Name:      name,
+		Operation: apiObject.AdmissionOperationDelete,
+		OldObject: store,
+	}
+	if err := admissionChain.Validate(attr); err != nil {
+		r.rejectRequest(c, action, err)
+		return
+	}
+
+	meta := objectMetadata(store)
+	if meta.IsBeingDeleted() {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": action + ": " + r.Kind + " is being deleted",
+		})
+		return
+	}
+
+	if r.PrepareForDelete != nil {
+		if err := r.PrepareForDelete(store); err != nil {
+			r.rejectRequest(c, action, err)
+			return
+		}
+	}
+
+	if policy == apiObject.DeletePropagationBackground && len(meta.Finalizers) == 0 && !r.GracefulDelete {
+		if !r.deleteFromEtcd(c, action, key, resourceVersion) {
+			return
+		}
+		c.JSON(http.StatusNoContent, gin.H{
+			"message": action + ": success",
+		})
+		if r.AfterDelete != nil {
+			r.AfterDelete(store)
+		}
+		return
+	}
+
+	now := time.Now()
+	meta.DeletionTimestamp = &now
+	switch policy {
+	case apiObject.DeletePropagationForeground:
+		meta.AddFinalizer(apiObject.FinalizerForegroundDeletion)
+	case apiObject.DeletePropagationOrphan:
+		meta.AddFinalizer(apiObject.FinalizerOrphan)
+	}
+	meta.ResourceVersion = ""
+
+	if !r.casToEtcd(c, action, key, resourceVersion, store) {
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": action + ": " + r.Kind + " is being deleted",
+	})
+}
+
+// PUT 更新对象的ownerReferences和finalizers
+// 比如 "/api/v1/namespaces/:namespace/pods/:name/metadata"
+// 正在删除的对象只能移除finalizers，finalizers为空的时候对象会被真正删除，返回204
+func (r *Resource) UpdateMetadata(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Update" + r.Kind + "Metadata"
+	k8log.InfoLog("APIServer", fmt.Sprintf("%s: namespace=%s, name=%s", action, namespace, name))
+
+	key := r.etcdKey(namespace, name)
+	store, resourceVersion, ok := r.getFromEtcd(c, action, key)
+	if !ok {
+		return
+	}
+
+	update := &apiObject.MetadataUpdate{}
+	if err := c.ShouldBindJSON(update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	if !resourceVersionMatch(update.ResourceVersion, resourceVersion) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": action + ": " + r.Kind + " has been modified, please get the latest version and try again",
+		})
+		return
+	}
+
+	meta := objectMetadata(store)
+	if errs := validation.ValidateMetadataUpdate(meta, update, "metadata"); len(errs) != 0 {
+		r.rejectRequest(c, action, validation.NewInvalidError(r.Kind, name, errs))
+		return
+	}
+
+	meta.OwnerReferences = update.OwnerReferences
+	meta.Finalizers = update.Finalizers
+	meta.ResourceVersion = ""
+
+	// 最后一个finalizer被移除，真正删除对象，GracefulDelete的资源由自己的接口删除
+	if meta.IsBeingDeleted() && len(meta.Finalizers) == 0 && !r.GracefulDelete {
+		if !r.deleteFromEtcd(c, action, key, resourceVersion) {
+			return
+		}
+		c.JSON(http.StatusNoContent, gin.H{
+			"message": action + ": " + r.Kind + " deleted",
+		})
+		if r.AfterDelete != nil {
+			r.AfterDelete(store)
+		}
+		return
+	}
+
+	if !r.casToEtcd(c, action, key, resourceVersion, store) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": action + ": success",
+	})
+}
+
+// 以CAS的方式从etcd中删除，失败的时候已经返回了错误
+func (r *Resource) deleteFromEtcd(c *gin.Context, action string, key string, resourceVersion int64) bool {
+	ok, err := etcdclient.EtcdStore.CompareAndDelete(key, resourceVersion)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", action+": "+err.Error())
+		return false
+	}
+
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": action + ": " + r.Kind + " has been modified, please try again",
+		})
+		return false
+	}
+	return true
+}
+
+// 对象不合法或者准入控制拒绝了请求
+func (r *Resource) rejectRequest(c *gin.Context, action string, err error) {
+	c.JSON(errorStatusCode(err), errorBody(action, err))
+	k8log.ErrorLog("APIServer", action+": "+err.Error())
+}
+
+// 检查对象的合法性，不合法的时候返回validation.InvalidError
+func (r *Resource) validate(store interface{}, name string) error {
+	if errs := validation.Validate(store); len(errs) != 0 {
+		return validation.NewInvalidError(r.Kind, name, errs)
+	}
+	return nil
+}
+
+// GET 获取对象的状态
+// 比如 "/api/v1/namespaces/:namespace/pods/:name/status"
+func (r *Resource) GetStatus(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Get" + r.Kind + "Status"
+	k8log.InfoLog("APIServer", fmt.Sprintf("%s: namespace=%s, name=%s", action, namespace, name))
+
+	store, _, ok := r.getFromEtcd(c, action, r.etcdKey(namespace, name))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": objectStatus(store),
+	})
+}
+
+// PUT/POST 更新对象的状态
+// 和更新对象分开，避免对象被删除之后，还在运行的组件回传状态的时候又把对象创建出来
+// 比如 "/api/v1/namespaces/:namespace/pods/:name/status"
+func (r *Resource) UpdateStatus(c *gin.Context) {
+	namespace, name, ok := r.parseParams(c)
+	if !ok {
+		return
+	}
+
+	action := "Update" + r.Kind + "Status"
+	k8log.InfoLog("APIServer", fmt.Sprintf("%s: namespace=%s, name=%s", action, namespace, name))
+
+	key := r.etcdKey(namespace, name)
+	store, resourceVersion, ok := r.getFromEtcd(c, action, key)
+	if !ok {
+		return
+	}
+
+	// 解析请求体里面的状态，类型和Status字段一样
+	status := reflectNew(objectStatus(store))
+	if err := c.ShouldBindJSON(status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": action + ": " + err.Error(),
+		})
+		return
+	}
+
+	// 状态里面没有resourceVersion，写入冲突的时候在最新的对象上面重新合并状态
+	// 多个组件同时回传状态(比如多个Pod的状态变化触发ReplicaSet更新)的时候不需要客户端重试
+	for i := 0; i < maxPatchRetries; i++ {
+		if i > 0 {
+			store, resourceVersion, ok = r.getFromEtcd(c, action, key)
+			if !ok {
+				return
+			}
+		}
+
+		if r.PrepareForStatusUpdate != nil {
+			if err := r.PrepareForStatusUpdate(store, status); err != nil {
+				c.JSON(errorStatusCode(err), gin.H{
+					"error": action + ": " + err.Error(),
+				})
+				return
+			}
+		} else {
+			reflectCopy(objectStatus(store), status)
+		}
+
+		storeJson, err := json.Marshal(store)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			return
+		}
+
+		ok, err = etcdclient.EtcdStore.CompareAndSwapWithTTL(key, resourceVersion, storeJson, r.ttl(store))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": action + ": " + err.Error(),
+			})
+			k8log.ErrorLog("APIServer", action+": "+err.Error())
+			return
+		}
+
+		if ok {
+			c.JSON(http.StatusOK, gin.H{
+				"message": action + ": success",
+			})
+			return
+		}
+		k8log.DebugLog("APIServer", action+": conflict, retry")
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error": action + ": " + r.Kind + " has been modified, please get the latest version and try again",
+	})
+}
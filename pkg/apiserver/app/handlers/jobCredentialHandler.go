@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/pki"
+	"miniK8s/util/uuid"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// job-server只能读取自己的Job和JobFile，更新Job的状态和JobFile(上传运行的结果)
+func jobServerRules(jobName string) []apiObject.PolicyRule {
+	return []apiObject.PolicyRule{
+		{
+			Verbs:         []string{apiObject.VerbGet},
+			Kinds:         []string{apiObject.JobKind, apiObject.JobKind + "/" + apiObject.JobFileSubresource},
+			ResourceNames: []string{jobName},
+		},
+		{
+			Verbs: []string{apiObject.VerbUpdate},
+			Kinds: []string{
+				apiObject.JobKind + "/" + apiObject.StatusSubresource,
+				apiObject.JobKind + "/" + apiObject.JobFileSubresource,
+			},
+			ResourceNames: []string{jobName},
+		},
+	}
+}
+
+func jobCredentialKey(namespace string, name string) string {
+	return serverconfig.EtcdJobCredentialPath + namespace + "/" + name
+}
+
+// POST 签发Job的job-server使用的凭证，已经签发过的时候返回原来的凭证
+// kubelet创建带有jobCredential volume的Pod的时候调用，只有cluster-admin有这个权限
+// "/apis/v1/namespaces/:namespace/jobs/:name/credential"
+func IssueJobCredential(c *gin.Context) {
+	namespace := c.Param(config.URL_PARAM_NAMESPACE)
+	name := c.Param(config.URL_PARAM_NAME)
+	k8log.InfoLog("APIServer", "IssueJobCredential: namespace="+namespace+", name="+name)
+
+	job := &apiObject.JobStore{}
+	found, err := getObjectFromEtcd(jobResource.etcdKey(namespace, name), job)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "IssueJobCredential: " + err.Error(),
+		})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "IssueJobCredential: job " + namespace + "/" + name + " not found",
+		})
+		return
+	}
+
+	credential, created, err := getOrCreateJobCredential(job)
+	if err != nil {
+		k8log.ErrorLog("APIServer", "IssueJobCredential: "+err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "IssueJobCredential: " + err.Error(),
+		})
+		return
+	}
+
+	credentialJson, err := json.Marshal(credential)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "IssueJobCredential: " + err.Error(),
+		})
+		return
+	}
+
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	c.JSON(code, gin.H{
+		"data": string(credentialJson),
+	})
+}
+
+// 读取已经签发的凭证，没有的时候签发一个新的，第二个返回值表示是否新签发
+func getOrCreateJobCredential(job *apiObject.JobStore) (*apiObject.JobCredential, bool, error) {
+	namespace, name := job.Metadata.Namespace, job.Metadata.Name
+	key := jobCredentialKey(namespace, name)
+	credential := &apiObject.JobCredential{}
+	found, err := getObjectFromEtcd(key, credential)
+	if err != nil {
+		return nil, false, err
+	}
+	if found {
+		return credential, false, nil
+	}
+
+	user := apiObject.JobCredentialUser(namespace, name, job.Metadata.UUID)
+	if err := issueJobClientCert(credential, user); err != nil {
+		return nil, false, err
+	}
+	// 下面任何一步失败都要撤销已经写入的Role、RoleBinding和token，不能留下没有对应凭证的权限
+	if err := putJobServerRBAC(namespace, name, user); err != nil {
+		rollbackJobCredential(namespace, name, user, "")
+		return nil, false, err
+	}
+
+	record, err := CreateToken(apiObject.TokenTypeUser, user)
+	if err != nil {
+		rollbackJobCredential(namespace, name, user, "")
+		return nil, false, err
+	}
+	credential.Token = record.Token
+
+	credentialJson, err := json.Marshal(credential)
+	if err != nil {
+		rollbackJobCredential(namespace, name, user, record.Token)
+		return nil, false, err
+	}
+
+	// 两个kubelet同时申请的时候只有一个能写入，另一个使用已经写入的凭证
+	created, err := etcdclient.EtcdStore.CompareAndSwap(key, 0, credentialJson)
+	if err != nil || !created {
+		rollbackJobCredential(namespace, name, user, record.Token)
+		if err != nil {
+			return nil, false, err
+		}
+		found, err := getObjectFromEtcd(key, credential)
+		if err != nil {
+			return nil, false, err
+		}
+		if !found {
+			return nil, false, fmt.Errorf("credential of job %s/%s was deleted concurrently", namespace, name)
+		}
+		return credential, false, nil
+	}
+
+	k8log.InfoLog("APIServer", "credential issued for job "+namespace+"/"+name)
+	return credential, true, nil
+}
+
+// 签发凭证失败的时候，删除这次签发写入的token、Role和RoleBinding
+// 同一个Job的凭证已经被别人签发(比如两个kubelet同时申请)的时候，Role和RoleBinding属于那个凭证，只删除token
+// token是这次签发的凭证已经写入了etcd(比如写入成功但是返回了超时)的时候不需要撤销
+func rollbackJobCredential(namespace string, name string, user string, token string) {
+	existing := &apiObject.JobCredential{}
+	found, err := getObjectFromEtcd(jobCredentialKey(namespace, name), existing)
+	if err == nil && found && token != "" && existing.Token == token {
+		return
+	}
+
+	if token != "" {
+		if err := DelToken(token); err != nil {
+			k8log.ErrorLog("APIServer", "delete token of job "+namespace+"/"+name+" failed, "+err.Error())
+		}
+	}
+	if err != nil {
+		k8log.ErrorLog("APIServer", "get credential of job "+namespace+"/"+name+" failed, keep its role, "+err.Error())
+		return
+	}
+	if found {
+		return
+	}
+	for _, k := range []string{roleBindingResource.etcdKey(namespace, user), roleResource.etcdKey(namespace, user)} {
+		if err := etcdclient.EtcdStore.Del(k); err != nil {
+			k8log.ErrorLog("APIServer", "delete "+k+" failed, "+err.Error())
+		}
+	}
+}
+
+// 给job-server的用户创建Role和RoleBinding，已经存在的时候覆盖
+func putJobServerRBAC(namespace string, name string, user string) error {
+	role := &apiObject.Role{Rules: jobServerRules(name)}
+	role.APIVersion = serverconfig.APIVersion
+	role.Kind = apiObject.RoleKind
+	role.Metadata.Name = user
+	role.Metadata.Namespace = namespace
+	role.Metadata.UUID = uuid.NewUUID()
+
+	binding := &apiObject.RoleBinding{
+		Subjects: []apiObject.Subject{{Kind: apiObject.SubjectKindUser, Name: user}},
+		RoleRef:  apiObject.RoleRef{Kind: apiObject.RoleKind, Name: user},
+	}
+	binding.APIVersion = serverconfig.APIVersion
+	binding.Kind = apiObject.RoleBindingKind
+	binding.Metadata.Name = user
+	binding.Metadata.Namespace = namespace
+	binding.Metadata.UUID = uuid.NewUUID()
+
+	objects := []struct {
+		key string
+		obj interface{}
+	}{
+		{roleResource.etcdKey(namespace, user), role},
+		{roleBindingResource.etcdKey(namespace, user), binding},
+	}
+	for _, object := range objects {
+		objJson, err := json.Marshal(object.obj)
+		if err != nil {
+			return err
+		}
+		if err := etcdclient.EtcdStore.Put(object.key, objJson); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 开启TLS的时候用集群CA给job-server签发客户端证书，master上没有CA的私钥的时候只有token
+func issueJobClientCert(credential *apiObject.JobCredential, user string) error {
+	caCert, err := os.ReadFile(config.CACertFile)
+	if err != nil {
+		return nil
+	}
+	credential.CAData = string(caCert)
+
+	caKey, err := os.ReadFile(config.CAKeyFile)
+	if err != nil {
+		k8log.WarnLog("APIServer", "CA key "+config.CAKeyFile+" not found, job "+user+" gets no client certificate")
+		return nil
+	}
+	cert, key, err := pki.IssueCert(caCert, caKey, &pki.CertRequest{CommonName: user, Usage: pki.UsageClient})
+	if err != nil {
+		return err
+	}
+	credential.ClientCertData = string(cert)
+	credential.ClientKeyData = string(key)
+	return nil
+}
+
+// Job删除之后吊销它的凭证，删除token、Role和RoleBinding
+// 客户端证书不能吊销，但是对应的Role已经删除，证书没有任何权限
+func deleteJobCredential(job *apiObject.JobStore) {
+	namespace, name := job.Metadata.Namespace, job.Metadata.Name
+	key := jobCredentialKey(namespace, name)
+	credential := &apiObject.JobCredential{}
+	found, err := getObjectFromEtcd(key, credential)
+	if err != nil {
+		k8log.ErrorLog("APIServer", "get credential of job "+namespace+"/"+name+" failed, "+err.Error())
+		return
+	}
+	if !found {
+		return
+	}
+
+	if credential.Token != "" {
+		if err := DelToken(credential.Token); err != nil {
+			k8log.ErrorLog("APIServer", "delete token of job "+namespace+"/"+name+" failed, "+err.Error())
+		}
+	}
+	user := apiObject.JobCredentialUser(namespace, name, job.Metadata.UUID)
+	for _, k := range []string{roleBindingResource.etcdKey(namespace, user), roleResource.etcdKey(namespace, user), key} {
+		if err := etcdclient.EtcdStore.Del(k); err != nil {
+			k8log.ErrorLog("APIServer", "delete "+k+" failed, "+err.Error())
+		}
+	}
+	k8log.InfoLog("APIServer", "credential of job "+namespace+"/"+name+" revoked")
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"miniK8s/pkg/apiObject"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/etcd/memory"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+)
+
+// 写入Job凭证的时候出错的存储，winner不为空的时候模拟别人先写入了凭证
+type credentialConflictStore struct {
+	*memory.Store
+	winner *apiObject.JobCredential
+}
+
+func (s *credentialConflictStore) CompareAndSwap(key string, resourceVersion int64, val []byte) (bool, error) {
+	if !strings.HasPrefix(key, serverconfig.EtcdJobCredentialPath) {
+		return s.Store.CompareAndSwap(key, resourceVersion, val)
+	}
+	if s.winner == nil {
+		return false, errors.New("etcd is unavailable")
+	}
+	winnerJson, _ := json.Marshal(s.winner)
+	s.Store.Put(key, winnerJson)
+	return false, nil
+}
+
+func useCredentialConflictStore(t *testing.T, winner *apiObject.JobCredential) *credentialConflictStore {
+	store := &credentialConflictStore{Store: memory.NewStore(), winner: winner}
+	old := etcdclient.EtcdStore
+	etcdclient.EtcdStore = store
+	t.Cleanup(func() { etcdclient.EtcdStore = old })
+	return store
+}
+
+func newCredentialTestJob() *apiObject.JobStore {
+	job := &apiObject.JobStore{}
+	job.Metadata.Namespace = "default"
+	job.Metadata.Name = "job1"
+	job.Metadata.UUID = "uuid1"
+	return job
+}
+
+func countKeys(t *testing.T, store *credentialConflictStore, prefix string) int {
+	res, err := store.PrefixGet(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(res)
+}
+
+func TestJobCredentialRollback(t *testing.T) {
+	store := useCredentialConflictStore(t, nil)
+
+	if _, _, err := getOrCreateJobCredential(newCredentialTestJob()); err == nil {
+		t.Fatal("expected an error when the credential can not be written")
+	}
+	for _, prefix := range []string{serverconfig.EtcdTokenPath, serverconfig.EtcdRolePath, serverconfig.EtcdRoleBindingPath} {
+		if n := countKeys(t, store, prefix); n != 0 {
+			t.Errorf("expected no keys under %s after rollback but got %d", prefix, n)
+		}
+	}
+}
+
+func TestJobCredentialConcurrentIssue(t *testing.T) {
+	winner := &apiObject.JobCredential{Token: "winner"}
+	store := useCredentialConflictStore(t, winner)
+
+	credential, created, err := getOrCreateJobCredential(newCredentialTestJob())
+	if err != nil || created || credential.Token != winner.Token {
+		t.Fatalf("expected the credential written first but got %+v, %v, %v", credential, created, err)
+	}
+	// 这次签发的token被删除，Role和RoleBinding属于先写入的凭证
+	if n := countKeys(t, store, serverconfig.EtcdTokenPath); n != 0 {
+		t.Errorf("expected the losing token to be deleted but got %d tokens", n)
+	}
+	if countKeys(t, store, serverconfig.EtcdRolePath) != 1 || countKeys(t, store, serverconfig.EtcdRoleBindingPath) != 1 {
+		t.Error("expected the role and role binding of the issued credential to be kept")
+	}
+}
@@ -13,168 +13,48 @@ import (
 	"miniK8s/util/stringutil"
 	"miniK8s/util/uuid"
 	"net/http"
+	"reflect"
 	"regexp"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// 获取单个的Job
-// @Summary 获取单个的Job
+// Job的增删查都由通用的handler完成，Job不支持更新，JobFile的handler在下面
+// "/apis/v1/namespaces/:namespace/jobs"
 // "/apis/v1/namespaces/:namespace/jobs/:name"
-func GetJob(c *gin.Context) {
-
-	// 解析里面的参数
-	namespace := c.Param(config.URL_PARAM_NAMESPACE)
-	name := c.Param(config.URL_PARAM_NAME)
-
-	if namespace == "" || name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "namespace or name is empty",
-		})
-		return
-	}
-
-	logStr := "GetJob: namespace=" + namespace + ", name=" + name
-	k8log.InfoLog("APIServer", logStr)
-
-	// 完整路径：/registry/jobs/<namespace>/<job-name>
-	key := fmt.Sprintf(serverconfig.EtcdJobPath+"%s/%s", namespace, name)
-
-	// 从etcd中获取数据
-	res, err := etcdclient.EtcdStore.Get(key)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		return
-	}
-
-	if len(res) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "get job err, not find job",
-		})
-		return
-	}
-
-	// 处理Res，如果有多个返回的，报错
-	if len(res) != 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get job err, find more than one job",
-		})
-		return
-	}
-
-	targetJob := res[0].Value
-	c.JSON(http.StatusOK, gin.H{
-		"data": targetJob,
-	})
+// "/apis/v1/namespaces/:namespace/jobs/:name/status"
+var jobResource = &Resource{
+	Kind:       apiObject.JobKind,
+	EtcdPath:   serverconfig.EtcdJobPath,
+	Namespaced: true,
+	StoreType:  reflect.TypeOf(apiObject.JobStore{}),
+	StatusURL:  config.JobSpecStatusURL,
+
+	PrepareForCreate: func(store interface{}) error {
+		addJobFileSuffix(store.(*apiObject.JobStore))
+		return nil
+	},
+	PrepareForStatusUpdate: func(oldStore interface{}, status interface{}) error {
+		selectiveUpdateJobStatus(oldStore.(*apiObject.JobStore), status.(*apiObject.JobStatus))
+		return nil
+	},
+	// 吊销job-server使用的凭证
+	AfterDelete: func(store interface{}) {
+		deleteJobCredential(store.(*apiObject.JobStore))
+	},
 }
 
-// "/apis/v1/namespaces/:namespace/jobs"
-func GetJobs(c *gin.Context) {
-	// TODO
-	namespace := c.Param(config.URL_PARAM_NAMESPACE)
-
-	if namespace == "" {
-		// c.JSON(http.StatusBadRequest, gin.H{
-		// 	"error": "namespace is empty",
-		// })
-		// return
-		namespace = config.DefaultNamespace
-	}
-
-	// 完整路径：/registry/jobs/<namespace>
-	// 从etcd中获取数据
-	logStr := "GetJobs: namespace=" + namespace
-	k8log.InfoLog("APIServer", logStr)
-
-	key := fmt.Sprintf(serverconfig.EtcdJobPath+"%s", namespace)
-	res, err := etcdclient.EtcdStore.PrefixGet(key)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get jobs failed " + err.Error(),
-		})
-		return
-	}
-
-	// 处理Res，如果有多个返回的，报错
-	targetJobs := make([]string, 0)
-
-	for _, pod := range res {
-		targetJobs = append(targetJobs, pod.Value)
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"data": stringutil.StringSliceToJsonArray(targetJobs),
-	})
+func init() {
+	Register(jobResource)
 }
 
-// 创建Job
-// "/apis/v1/namespaces/:namespace/jobs"
-func AddJob(c *gin.Context) {
-	// log
-	k8log.InfoLog("APIServer", "AddJob")
-
-	// 从body中获取Job的信息
-	var job apiObject.Job
-
-	if err := c.ShouldBind(&job); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "parse job err, " + err.Error(),
-		})
-
-		k8log.ErrorLog("APIServer", "parse job err, "+err.Error())
-		return
-	}
-
-	// 检查Job的合法性
-	newJobName := job.Metadata.Name
-	if newJobName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "job name is empty",
-		})
+// 测试里面直接使用的handler
+var AddJob = jobResource.Create
 
-		k8log.ErrorLog("APIServer", "job name is empty")
-		return
-	}
-
-	// 检查Job的namespace是否存在
-	if job.GetObjectNamespace() == "" {
-		job.Metadata.Namespace = config.DefaultNamespace
-	}
-
-	// 检查Job是否存在
-	key := fmt.Sprintf(serverconfig.EtcdJobPath+"%s/%s", job.GetObjectNamespace(), newJobName)
-
-	// 从etcd中获取数据
-	res, err := etcdclient.EtcdStore.Get(key)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get job err, " + err.Error(),
-		})
-		return
-	}
-
-	if len(res) != 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "job already exist",
-		})
-		k8log.ErrorLog("APIServer", "job already exist")
-		return
-	}
-
-	// 给Job设置UUID，用于后续的操作
-	// 哪怕用户自己设置了UUID，也会被覆盖
-	job.Metadata.UUID = uuid.NewUUID()
-
-	// 将Job转化为JobStore
-	jobStore := job.ToJobStore()
+// 在文件名的文件类型前加入随机字符串，防止文件名重复
+func addJobFileSuffix(jobStore *apiObject.JobStore) {
 	randomSuffix := "-" + stringutil.GenerateRandomStr(6)
-	// 在文件名的文件类型前加入随机字符串，防止文件名重复
 	re := regexp.MustCompile(`(.*)(\.[^.]+)`)
 	match := re.FindStringSubmatch(jobStore.Spec.OutputFile)
 
@@ -191,239 +71,6 @@ func AddJob(c *gin.Context) {
 	} else {
 		fmt.Println("无法解析文件名")
 	}
-
-	// 将JobStore转化为json
-	jobStoreJson, err := json.Marshal(jobStore)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "marshal job err, " + err.Error(),
-		})
-		return
-	}
-
-	// 将JobStore存入etcd
-	key = fmt.Sprintf(serverconfig.EtcdJobPath+"%s/%s", job.GetObjectNamespace(), newJobName)
-
-	// 将JobStore存入etcd
-	err = etcdclient.EtcdStore.Put(key, jobStoreJson)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "put job err, " + err.Error(),
-		})
-		return
-	}
-
-	// 返回
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "create job success",
-	})
-
-	k8log.InfoLog("APIServer", "create job success")
-
-	// 后面可能要发消息给controller，
-	/*
-		// 发送消息给controller，然后controller会去etcd中获取job的信息
-		// 之后会根据job的信息创建pod，然后执行任务
-	*/
-}
-
-// "/apis/v1/namespaces/:namespace/jobs/:name"
-func DeleteJob(c *gin.Context) {
-	// log
-	k8log.InfoLog("APIServer", "DeleteJob")
-
-	// 获取namespace和jobName
-	namespace := c.Param(config.URL_PARAM_NAMESPACE)
-	name := c.Param(config.URL_PARAM_NAME)
-
-	// 检查参数是否为空
-	if namespace == "" || name == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "namespace or name is empty",
-		})
-		return
-	}
-
-	logStr := "DeleteJob: namespace=" + namespace + ", name=" + name
-	k8log.InfoLog("APIServer", logStr)
-
-	key := fmt.Sprintf(serverconfig.EtcdJobPath+"%s/%s", namespace, name)
-	// 删除Job
-	err := etcdclient.EtcdStore.Del(key)
-
-	if err != nil {
-		k8log.DebugLog("APIServer", "delete job err, "+err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "delete job err, " + err.Error(),
-		})
-		return
-	}
-
-	// 返回
-	c.JSON(http.StatusNoContent, gin.H{
-		"message": "delete job success",
-	})
-
-	k8log.InfoLog("APIServer", "delete job success")
-
-	// 后面要不要垃圾回收？
-	/*
-		【TODO】
-	*/
-}
-
-// 获取Job的状态
-// "/apis/v1/namespaces/:namespace/jobs/:name/status"
-func GetJobStatus(c *gin.Context) {
-	jobNamespace := c.Param(config.URL_PARAM_NAMESPACE)
-	jobName := c.Param(config.URL_PARAM_NAME)
-
-	// 检查参数是否为空
-	if jobNamespace == "" || jobName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "namespace or name is empty",
-		})
-		return
-	}
-
-	// 从etcd中获取Job
-	logStr := "GetJobStatus: namespace=" + jobNamespace + ", name=" + jobName
-	k8log.InfoLog("APIServer", logStr)
-
-	key := fmt.Sprintf(serverconfig.EtcdJobPath+"%s/%s", jobNamespace, jobName)
-
-	res, err := etcdclient.EtcdStore.Get(key)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get job err, " + err.Error(),
-		})
-		return
-	}
-
-	if len(res) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "job not found",
-		})
-		return
-	}
-
-	if len(res) != 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get job err, job is not unique",
-		})
-		return
-	}
-
-	// 获取res[0]的JobStore
-	jobStore := &apiObject.JobStore{}
-	err = json.Unmarshal([]byte(res[0].Value), jobStore)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unmarshal job err, " + err.Error(),
-		})
-		return
-	}
-
-	// 返回jobStore的Status
-	c.JSON(http.StatusOK, gin.H{
-		"data": jobStore.Status,
-	})
-
-}
-
-// 更新Job的状态
-func UpdateJobStatus(c *gin.Context) {
-	jobName := c.Param(config.URL_PARAM_NAME)
-	jobNamespace := c.Param(config.URL_PARAM_NAMESPACE)
-
-	// 检查参数是否为空
-	if jobNamespace == "" || jobName == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "namespace or name is empty",
-		})
-		return
-	}
-
-	logStr := "UpdateJobStatus: namespace=" + jobNamespace + ", name=" + jobName
-	k8log.InfoLog("APIServer", logStr)
-
-	// 从etcd中获取Job
-	key := fmt.Sprintf(serverconfig.EtcdJobPath+"%s/%s", jobNamespace, jobName)
-
-	res, err := etcdclient.EtcdStore.Get(key)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get job err, " + err.Error(),
-		})
-		return
-	}
-
-	if len(res) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "job not found",
-		})
-		return
-	}
-
-	if len(res) != 1 {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "get job err, job is not unique",
-		})
-		return
-	}
-
-	// 获取res[0]的JobStore
-	jobStore := &apiObject.JobStore{}
-	err = json.Unmarshal([]byte(res[0].Value), jobStore)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "unmarshal job err, " + err.Error(),
-		})
-		return
-	}
-
-	// 获取status
-	jobStatus := &apiObject.JobStatus{}
-
-	err = c.ShouldBind(jobStatus)
-
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "bind job status err, " + err.Error(),
-		})
-		return
-	}
-
-	// 更新jobStore的Status
-	selectiveUpdateJobStatus(jobStore, jobStatus)
-
-	// 将jobStore转化为json
-	jobStoreJson, err := json.Marshal(jobStore)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "marshal job err, " + err.Error(),
-		})
-		return
-	}
-
-	// 更新etcd中的job
-	err = etcdclient.EtcdStore.Put(key, jobStoreJson)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "update job err, " + err.Error(),
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "update job status success",
-	})
 }
 
 func selectiveUpdateJobStatus(oldJob *apiObject.JobStore, newJob *apiObject.JobStatus) {
@@ -506,7 +153,7 @@ func GetJobFile(c *gin.Context) {
 	}
 
 	// 获取res[0]的JobFile
-	targetFile := res[0].Value
+	targetFile := injectResourceVersion(res[0].Value, res[0].ResourceVersion)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": targetFile,
@@ -551,6 +198,15 @@ func AddJobFile(c *gin.Context) {
 		jobFile.Basic.Metadata.Namespace = config.DefaultNamespace
 	}
 
+	// 检查namespace是否存在并且没有在删除中
+	if code, err := checkNamespaceActive(jobFile.GetJobNamespace()); err != nil {
+		c.JSON(code, gin.H{
+			"error": "AddJobFile: " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", "AddJobFile: "+err.Error())
+		return
+	}
+
 	// 检查Jobfile是否存在
 	key := fmt.Sprintf(serverconfig.EtcdJobFilePath+"%s/%s", jobFile.GetJobNamespace(), newJobName)
 
@@ -672,6 +328,14 @@ func UpdateJobFile(c *gin.Context) {
 		return
 	}
 
+	// 检查请求中的resourceVersion是否过期
+	if !resourceVersionMatch(reqJobFile.Metadata.ResourceVersion, res[0].ResourceVersion) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "job file has been modified, please get the latest version and try again",
+		})
+		return
+	}
+
 	// 选择性的只更新UserUploadFile、OutputFile、ErrorFile
 	selectiveUpdateJobFile(oldJobFile, reqJobFile)
 
@@ -687,7 +351,7 @@ func UpdateJobFile(c *gin.Context) {
 
 	key = fmt.Sprintf(serverconfig.EtcdJobFilePath+"%s/%s", jobNamespace, jobName)
 
-	err = etcdclient.EtcdStore.Put(key, jobFileJson)
+	ok, err := etcdclient.EtcdStore.CompareAndSwap(key, res[0].ResourceVersion, jobFileJson)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -696,6 +360,13 @@ func UpdateJobFile(c *gin.Context) {
 		return
 	}
 
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "job file has been modified, please get the latest version and try again",
+		})
+		return
+	}
+
 	// 返回成功
 	c.JSON(http.StatusOK, gin.H{
 		"message": "update job file success",
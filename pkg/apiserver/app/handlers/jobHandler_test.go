@@ -33,6 +33,10 @@ func TestMain(m *testing.M) {
 			panic(res)
 		}
 	}
+	// 创建对象之前namespace必须存在
+	if err := InitDefaultNamespace(); err != nil {
+		panic(err)
+	}
 	m.Run()
 }
 
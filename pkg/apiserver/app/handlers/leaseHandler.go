@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"time"
+)
+
+// Lease的增删改查都由通用的handler完成，写入etcd的时候带上spec.leaseDurationSeconds作为过期时间
+// 持有者每次续约都会重新计算过期时间，持有者退出之后对象自动删除，其他副本就可以创建新的Lease
+// 并发的续约和接管通过resourceVersion保证只有一个成功
+// "/api/v1/namespaces/:namespace/leases"
+// "/api/v1/namespaces/:namespace/leases/:name"
+var leaseResource = &Resource{
+	Kind:       apiObject.LeaseKind,
+	EtcdPath:   serverconfig.EtcdLeasePath,
+	Namespaced: true,
+
+	TTLFunc: func(store interface{}) time.Duration {
+		lease := store.(*apiObject.Lease)
+		return time.Duration(lease.Spec.LeaseDurationSeconds) * time.Second
+	},
+
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldStore.(*apiObject.Lease).Spec = newStore.(*apiObject.Lease).Spec
+		return nil
+	},
+}
+
+func init() {
+	Register(leaseResource)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/pkg/metrics"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func init() {
+	registerRoute(config.MetricsURL, apiObject.MetricsKind, false)
+	metrics.Registry.MustRegister(newObjectCountCollector())
+}
+
+var metricsHandler = metrics.Handler()
+
+// GET 以Prometheus的文本格式输出APIServer的指标
+// "/metrics"
+func GetMetrics(c *gin.Context) {
+	metricsHandler.ServeHTTP(c.Writer, c.Request)
+}
+
+// 每次抓取指标的时候统计etcd中每种对象的个数
+type objectCountCollector struct {
+	desc *prometheus.Desc
+}
+
+func newObjectCountCollector() *objectCountCollector {
+	return &objectCountCollector{
+		desc: prometheus.NewDesc("apiserver_storage_objects", "Number of stored objects at the time of last check, partitioned by kind.", []string{"kind"}, nil),
+	}
+}
+
+func (o *objectCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- o.desc
+}
+
+func (o *objectCountCollector) Collect(ch chan<- prometheus.Metric) {
+	// 通用的资源以及apiserver.go里面手动注册的资源
+	paths := map[string]string{
+		apiObject.NodeKind:      serverconfig.EtcdNodePath,
+		apiObject.NamespaceKind: serverconfig.EtcdNamespacePath,
+	}
+	for _, kind := range resourceKinds {
+		paths[kind] = resourceRegistry[kind].EtcdPath
+	}
+
+	for kind, path := range paths {
+		res, err := etcdclient.EtcdStore.PrefixGet(path)
+		if err != nil {
+			k8log.ErrorLog("APIServer", "count "+kind+" failed, "+err.Error())
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(o.desc, prometheus.GaugeValue, float64(len(res)), kind)
+	}
+
+	// 自定义对象的路径是/registry/custom/<group>/<plural>/<namespace>/<name>，按照<plural>.<group>统计
+	res, err := etcdclient.EtcdStore.PrefixGet(serverconfig.EtcdCustomResourcePath)
+	if err != nil {
+		k8log.ErrorLog("APIServer", "count custom objects failed, "+err.Error())
+		return
+	}
+	counts := make(map[string]int)
+	for _, obj := range res {
+		parts := strings.Split(strings.TrimPrefix(obj.Key, serverconfig.EtcdCustomResourcePath), "/")
+		if len(parts) >= 2 {
+			counts[parts[1]+"."+parts[0]]++
+		}
+	}
+	for kind, count := range counts {
+		ch <- prometheus.MustNewConstMetric(o.desc, prometheus.GaugeValue, float64(count), kind)
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/uuid"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// Namespace下面所有资源在etcd中的前缀，删除Namespace之前这些前缀下面必须都为空
+// 完整路径是 <prefix><namespace>/<name>
+var namespacedEtcdPaths = []string{
+	serverconfig.EtcdPodPath,
+	serverconfig.EtcdServicePath,
+	serverconfig.EtcdJobPath,
+	serverconfig.EtcdReplicaSetPath,
+	serverconfig.EtcdDnsPath,
+	serverconfig.EtcdHpaPath,
+	serverconfig.EtcdFunctionPath,
+	serverconfig.EtcdWorkflowPath,
+	serverconfig.EtcdRolePath,
+	serverconfig.EtcdRoleBindingPath,
+	serverconfig.EtcdEventPath,
+	serverconfig.EtcdLeasePath,
+}
+
+// Namespace的创建、查询和删除都由通用的handler完成
+// "/api/v1/namespaces"
+// "/api/v1/namespaces/:namespace"
+// "/api/v1/namespaces/:namespace/status"
+// 删除的时候只会把Namespace标记为Terminating，真正的删除由NamespaceController完成
+// NamespaceController会先删除Namespace下面所有的资源，然后调用finalize删除Namespace本身
+var namespaceResource = &Resource{
+	Kind:           apiObject.NamespaceKind,
+	EtcdPath:       serverconfig.EtcdNamespacePath,
+	Namespaced:     false,
+	StoreType:      reflect.TypeOf(apiObject.NamespaceStore{}),
+	StatusURL:      config.NamespaceSpecStatusURL,
+	NameParam:      config.URL_PARAM_NAMESPACE,
+	GracefulDelete: true,
+
+	PrepareForCreate: func(store interface{}) error {
+		namespaceStore := store.(*apiObject.NamespaceStore)
+		namespaceStore.Status.Phase = apiObject.NamespaceActive
+		namespaceStore.Status.UpdateTime = time.Now()
+		return nil
+	},
+	// Namespace的状态只能通过删除操作改变
+	PrepareForStatusUpdate: func(oldStore interface{}, status interface{}) error {
+		return newStatusError(http.StatusMethodNotAllowed, "namespace status can only be changed by deleting the namespace")
+	},
+	PrepareForDelete: func(store interface{}) error {
+		namespaceStore := store.(*apiObject.NamespaceStore)
+		// default是系统的namespace，不允许删除
+		if namespaceStore.Metadata.Name == config.DefaultNamespace {
+			return newStatusError(http.StatusForbidden, "namespace %s can not be deleted", config.DefaultNamespace)
+		}
+		namespaceStore.Status.Phase = apiObject.NamespaceTerminating
+		namespaceStore.Status.UpdateTime = time.Now()
+		return nil
+	},
+}
+
+func init() {
+	Register(namespaceResource)
+}
+
+// PUT 真正删除一个Namespace
+// "/api/v1/namespaces/:namespace/finalize"
+// 只有处于Terminating并且下面已经没有任何资源的Namespace才能被删除
+func FinalizeNamespace(c *gin.Context) {
+	k8log.InfoLog("APIServer", "FinalizeNamespace")
+
+	name := c.Param(config.URL_PARAM_NAMESPACE)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name is empty",
+		})
+		return
+	}
+
+	key := serverconfig.EtcdNamespacePath + name
+	res, err := etcdclient.EtcdStore.Get(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "FinalizeNamespace: " + err.Error(),
+		})
+		return
+	}
+	if len(res) != 1 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "FinalizeNamespace: not found",
+		})
+		return
+	}
+
+	namespaceStore := &apiObject.NamespaceStore{}
+	err = json.Unmarshal([]byte(res[0].Value), namespaceStore)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "FinalizeNamespace: " + err.Error(),
+		})
+		return
+	}
+
+	if namespaceStore.Status.Phase != apiObject.NamespaceTerminating {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "FinalizeNamespace: namespace is not terminating",
+		})
+		return
+	}
+
+	// 自定义对象的前缀由CRD决定，需要一起检查
+	customPaths, err := customResourceEtcdPaths()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "FinalizeNamespace: " + err.Error(),
+		})
+		return
+	}
+
+	// 检查namespace下面是否还有资源
+	remains := make([]string, 0)
+	for _, prefix := range append(customPaths, namespacedEtcdPaths...) {
+		objs, err := etcdclient.EtcdStore.PrefixGet(prefix + name + "/")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "FinalizeNamespace: " + err.Error(),
+			})
+			return
+		}
+		for _, obj := range objs {
+			remains = append(remains, obj.Key)
+		}
+	}
+
+	if len(remains) != 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("FinalizeNamespace: %d objects remain in namespace, %s", len(remains), strings.Join(remains, ", ")),
+		})
+		return
+	}
+
+	// JobFile没有单独的删除接口，跟着namespace一起删除
+	err = etcdclient.EtcdStore.PrefixDel(serverconfig.EtcdJobFilePath + name + "/")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "FinalizeNamespace: " + err.Error(),
+		})
+		return
+	}
+
+	err = etcdclient.EtcdStore.Del(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "FinalizeNamespace: " + err.Error(),
+		})
+		k8log.ErrorLog("APIServer", err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "FinalizeNamespace: success",
+	})
+}
+
+// 检查namespace是否存在并且没有在删除中，创建namespace下面的资源之前调用
+// 返回的int是检查不通过的时候应该返回给客户端的状态码
+func checkNamespaceActive(namespace string) (int, error) {
+	key := serverconfig.EtcdNamespacePath + namespace
+	res, err := etcdclient.EtcdStore.Get(key)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if len(res) == 0 {
+		return http.StatusNotFound, errors.New("namespace " + namespace + " not found")
+	}
+
+	namespaceStore := &apiObject.NamespaceStore{}
+	if err := json.Unmarshal([]byte(res[0].Value), namespaceStore); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if namespaceStore.Status.Phase == apiObject.NamespaceTerminating {
+		return http.StatusForbidden, errors.New("namespace " + namespace + " is terminating")
+	}
+	return http.StatusOK, nil
+}
+
+// 确保default namespace存在，APIServer启动的时候调用
+func InitDefaultNamespace() error {
+	key := serverconfig.EtcdNamespacePath + config.DefaultNamespace
+	res, err := etcdclient.EtcdStore.Get(key)
+	if err != nil {
+		return err
+	}
+	if len(res) != 0 {
+		return nil
+	}
+
+	namespace := apiObject.Namespace{}
+	namespace.APIVersion = "v1"
+	namespace.Kind = apiObject.NamespaceKind
+	namespace.Metadata.Name = config.DefaultNamespace
+	namespace.Metadata.UUID = uuid.NewUUID()
+
+	namespaceStore := namespace.ToNamespaceStore()
+	namespaceStore.Status.Phase = apiObject.NamespaceActive
+	namespaceStore.Status.UpdateTime = time.Now()
+
+	namespaceJson, err := json.Marshal(namespaceStore)
+	if err != nil {
+		return err
+	}
+
+	// 多个APIServer同时启动的时候只需要一个创建成功
+	_, err = etcdclient.EtcdStore.CompareAndSwap(key, 0, namespaceJson)
+	return err
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"encoding/json"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNamespaceDelete(t *testing.T) {
+	store := useMemoryStore(t, 1000)
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	InstallResources(r)
+	r.PUT(config.NamespaceFinalizeURL, FinalizeNamespace)
+
+	for _, name := range []string{config.DefaultNamespace, "test"} {
+		namespace := apiObject.Namespace{}
+		namespace.Kind = apiObject.NamespaceKind
+		namespace.Metadata.Name = name
+		if w := serveJSON(r, http.MethodPost, config.NamespacesURL, namespace); w.Code != http.StatusCreated {
+			t.Fatalf("expected status %v but got %v: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	}
+
+	// default不能删除
+	if w := serveJSON(r, http.MethodDelete, config.NamespacesURL+"/"+config.DefaultNamespace, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %v but got %v", http.StatusForbidden, w.Code)
+	}
+
+	// 删除只标记为Terminating，metadata子资源不能绕过finalize删除
+	for i := 0; i < 2; i++ {
+		if w := serveJSON(r, http.MethodDelete, config.NamespacesURL+"/test", nil); w.Code != http.StatusAccepted {
+			t.Fatalf("expected status %v but got %v", http.StatusAccepted, w.Code)
+		}
+	}
+	if w := serveJSON(r, http.MethodPut, config.NamespacesURL+"/test/metadata", apiObject.MetadataUpdate{}); w.Code != http.StatusOK {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	res, err := store.Get(serverconfig.EtcdNamespacePath + "test")
+	if err != nil || len(res) != 1 {
+		t.Fatalf("expected namespace test to remain but got %v, %v", res, err)
+	}
+	namespaceStore := apiObject.NamespaceStore{}
+	if err := json.Unmarshal([]byte(res[0].Value), &namespaceStore); err != nil {
+		t.Fatal(err)
+	}
+	if namespaceStore.Status.Phase != apiObject.NamespaceTerminating {
+		t.Fatalf("expected phase %s but got %s", apiObject.NamespaceTerminating, namespaceStore.Status.Phase)
+	}
+
+	// 状态只能通过删除改变
+	status := apiObject.NamespaceStatus{Phase: apiObject.NamespaceActive}
+	if w := serveJSON(r, http.MethodPut, config.NamespacesURL+"/test/status", status); w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %v but got %v", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	if w := serveJSON(r, http.MethodPut, config.NamespacesURL+"/test/finalize", nil); w.Code != http.StatusOK {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if res, _ := store.Get(serverconfig.EtcdNamespacePath + "test"); len(res) != 0 {
+		t.Fatal("expected namespace test to be finalized")
+	}
+}
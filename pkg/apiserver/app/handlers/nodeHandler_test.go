@@ -156,3 +156,64 @@ func TestDeleteNode(t *testing.T) {
 	}
 
 }
+
+// 发送一个json请求，返回响应
+func serveJSON(r *gin.Engine, method string, uri string, body interface{}) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		jsonBytes, _ := json.Marshal(body)
+		reader = bytes.NewReader(jsonBytes)
+	}
+	req := httptest.NewRequest(method, uri, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestNodeResource(t *testing.T) {
+	useMemoryStore(t, 1000)
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	InstallResources(r)
+
+	node := apiObject.Node{}
+	node.Kind = apiObject.NodeKind
+	node.NodeMetadata.Name = "node1"
+	node.IP = "192.168.1.10"
+
+	// 重复注册同一个Node返回409
+	for _, code := range []int{http.StatusCreated, http.StatusConflict} {
+		if w := serveJSON(r, http.MethodPost, config.NodesURL, node); w.Code != code {
+			t.Fatalf("expected status %v but got %v: %s", code, w.Code, w.Body.String())
+		}
+	}
+
+	// 状态只更新请求中不为空的字段
+	statusURL := config.NodesURL + "/node1/status"
+	status := apiObject.NodeStatus{Condition: apiObject.Ready, CpuPercent: 0.5}
+	if w := serveJSON(r, http.MethodPut, statusURL, status); w.Code != http.StatusOK {
+		t.Fatalf("expected status %v but got %v: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	w := serveJSON(r, http.MethodGet, config.NodesURL+"/node1", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %v but got %v", http.StatusOK, w.Code)
+	}
+	var res struct {
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	stored := apiObject.NodeStore{}
+	if err := json.Unmarshal([]byte(res.Data), &stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status.Condition != apiObject.Ready || stored.Status.CpuPercent != 0.5 || stored.Status.Ip != node.IP {
+		t.Fatalf("unexpected status %+v", stored.Status)
+	}
+	if stored.NodeMetadata.Namespace != "" || stored.NodeMetadata.UUID == "" {
+		t.Fatalf("unexpected metadata %+v", stored.NodeMetadata)
+	}
+}
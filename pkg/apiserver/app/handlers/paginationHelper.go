@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/etcd"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// continue token里面记录的内容，对客户端来说是不透明的
+// ResourceVersion是第一页读取时etcd的revision，后面的页都读取同一个快照
+// StartKey是下一页开始的key
+type continueToken struct {
+	ResourceVersion int64  `json:"rv"`
+	StartKey        string `json:"start"`
+}
+
+// 分页参数
+type listOptions struct {
+	limit int64
+	token continueToken
+}
+
+// 解析请求中的limit和continue
+func parseListOptions(c *gin.Context, prefix string) (listOptions, error) {
+	opts := listOptions{}
+
+	if limitStr := c.Query(config.URL_QUERY_LIMIT); limitStr != "" {
+		limit, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil || limit < 0 {
+			return opts, errors.New("invalid limit " + limitStr)
+		}
+		opts.limit = limit
+	}
+
+	if token := c.Query(config.URL_QUERY_CONTINUE); token != "" {
+		if opts.limit == 0 {
+			return opts, errors.New("continue must be used with limit")
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		if err != nil {
+			return opts, errors.New("invalid continue token")
+		}
+		if err := json.Unmarshal(raw, &opts.token); err != nil {
+			return opts, errors.New("invalid continue token")
+		}
+		// 不允许通过continue读取其他前缀下面的key
+		if prefix != "" && !strings.HasPrefix(opts.token.StartKey, prefix) {
+			return opts, errors.New("continue token does not match this list")
+		}
+	}
+
+	return opts, nil
+}
+
+// 根据请求中的limit、continue和selector读取prefix下面的对象
+// 没有带limit的时候，一次性读取prefix下面所有的对象
+// 返回的第二个值是下一页的continue token，为空表示已经是最后一页
+// 返回的第三个值是读取时etcd的revision，客户端可以从这个版本开始watch
+// 返回的错误是statusError，参数不合法的时候是400，continue token过期的时候是410
+func listFromEtcd(c *gin.Context, prefix string) ([]etcd.ListRes, string, int64, error) {
+	return listFromEtcdWithFilter(c, prefix, nil)
+}
+
+// 和listFromEtcd一样，filter不为nil的时候只返回selector和filter都匹配的对象
+// 先过滤再计入limit，每一页尽量返回limit个匹配的对象
+func listFromEtcdWithFilter(c *gin.Context, prefix string, filter func(value string) bool) ([]etcd.ListRes, string, int64, error) {
+	opts, err := parseListOptions(c, prefix)
+	if err != nil {
+		return nil, "", 0, newStatusError(http.StatusBadRequest, "%s", err.Error())
+	}
+	selectorMatch, err := selectorMatcherFromRequest(c)
+	if err != nil {
+		return nil, "", 0, newStatusError(http.StatusBadRequest, "invalid selector: %s", err.Error())
+	}
+	match := selectorMatch
+	if filter != nil {
+		match = func(value string) bool {
+			return selectorMatch(value) && filter(value)
+		}
+	}
+
+	if opts.limit == 0 {
+		res, revision, err := etcdclient.EtcdStore.PrefixGetWithRevision(prefix)
+		if err != nil {
+			return nil, "", 0, storageStatusError(err)
+		}
+		return filterListRes(res, match), "", revision, nil
+	}
+
+	ret := make([]etcd.ListRes, 0, opts.limit)
+	startKey, revision := opts.token.StartKey, opts.token.ResourceVersion
+	for {
+		// 后面的页都读取第一页的revision
+		res, more, rev, err := etcdclient.EtcdStore.PrefixGetPage(prefix, startKey, revision, opts.limit)
+		if err != nil {
+			return nil, "", 0, storageStatusError(err)
+		}
+		revision = rev
+
+		for i, v := range res {
+			if !match(v.Value) {
+				continue
+			}
+			ret = append(ret, v)
+			if int64(len(ret)) < opts.limit {
+				continue
+			}
+			// 已经读满一页，这一批后面还有对象或者etcd中还有数据的时候返回continue
+			if i == len(res)-1 && !more {
+				return ret, "", revision, nil
+			}
+			token, err := encodeContinueToken(revision, v.Key)
+			if err != nil {
+				return nil, "", 0, newStatusError(http.StatusInternalServerError, "%s", err.Error())
+			}
+			return ret, token, revision, nil
+		}
+
+		if !more || len(res) == 0 {
+			return ret, "", revision, nil
+		}
+		startKey = res[len(res)-1].Key + "\x00"
+	}
+}
+
+// 下一页从lastKey的下一个key开始
+func encodeContinueToken(revision int64, lastKey string) (string, error) {
+	next := continueToken{
+		ResourceVersion: revision,
+		StartKey:        lastKey + "\x00",
+	}
+	raw, err := json.Marshal(next)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// continue token里面的revision已经被压缩的时候返回410，客户端需要不带continue重新list
+func storageStatusError(err error) error {
+	if errors.Is(err, etcd.ErrCompacted) {
+		return newStatusError(http.StatusGone, "continue token has expired, please list again without continue: %s", err.Error())
+	}
+	return newStatusError(http.StatusInternalServerError, "%s", err.Error())
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/etcd/memory"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newListContext(query url.Values) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/list?"+query.Encode(), nil)
+	return c
+}
+
+// 使用内存存储，测试结束之后恢复
+func useMemoryStore(t *testing.T, historyLimit int64) *memory.Store {
+	store := memory.NewStoreWithHistoryLimit(historyLimit)
+	old := etcdclient.EtcdStore
+	etcdclient.EtcdStore = store
+	t.Cleanup(func() { etcdclient.EtcdStore = old })
+	return store
+}
+
+func TestListFromEtcdSelectorBeforeLimit(t *testing.T) {
+	store := useMemoryStore(t, 1000)
+	// a、c、e的label是app=a，b、d、f是app=b
+	for i, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		app := "a"
+		if i%2 == 1 {
+			app = "b"
+		}
+		obj, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "labels": map[string]string{"app": app}},
+		})
+		if err := store.Put("/list/"+name, obj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := url.Values{}
+	query.Set("limit", "2")
+	query.Set("labelSelector", "app=a")
+	res, token, _, err := listFromEtcd(newListContext(query), "/list/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Key != "/list/a" || res[1].Key != "/list/c" {
+		t.Fatalf("expected a and c on the first page but got %v", res)
+	}
+	if token == "" {
+		t.Fatal("expected a continue token")
+	}
+
+	query.Set("continue", token)
+	res, token, _, err = listFromEtcd(newListContext(query), "/list/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Key != "/list/e" || token != "" {
+		t.Fatalf("expected only e on the last page but got %v, continue %q", res, token)
+	}
+
+	// 和自定义的过滤条件一起使用，过滤掉c之后第一页是a和e
+	query.Del("continue")
+	res, token, _, err = listFromEtcdWithFilter(newListContext(query), "/list/", func(value string) bool {
+		return !strings.Contains(value, `"name":"c"`)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Key != "/list/a" || res[1].Key != "/list/e" {
+		t.Fatalf("expected a and e with the extra filter but got %v", res)
+	}
+
+	query.Set("labelSelector", "app in (a")
+	if _, _, _, err := listFromEtcd(newListContext(query), "/list/"); errorStatusCode(err) != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid selector but got %v", err)
+	}
+}
+
+func TestListFromEtcdCompacted(t *testing.T) {
+	store := useMemoryStore(t, 4)
+	for i := 0; i < 3; i++ {
+		if err := store.Put("/list/"+strconv.Itoa(i), []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := url.Values{}
+	query.Set("limit", "1")
+	_, token, _, err := listFromEtcd(newListContext(query), "/list/")
+	if err != nil || token == "" {
+		t.Fatalf("expected a continue token but got %q, %v", token, err)
+	}
+
+	// 继续写入，continue token里面的revision被压缩
+	for i := 0; i < 10; i++ {
+		if err := store.Put("/other", []byte("{}")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query.Set("continue", token)
+	if _, _, _, err := listFromEtcd(newListContext(query), "/list/"); errorStatusCode(err) != http.StatusGone {
+		t.Fatalf("expected 410 for a compacted continue token but got %v", err)
+	}
+}
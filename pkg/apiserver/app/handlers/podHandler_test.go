@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/admission"
 	"miniK8s/pkg/config"
 	"miniK8s/pkg/k8log"
 	"miniK8s/util/stringutil"
@@ -190,3 +191,36 @@ func TestDeletePod(t *testing.T) {
 	}
 
 }
+
+func TestValidatePodSpecUpdate(t *testing.T) {
+	oldSpec := apiObject.PodSpec{
+		Containers: []apiObject.Container{{Name: "c", Image: "nginx"}},
+	}
+	if err := admission.SetDefaults(&oldSpec); err != nil {
+		t.Fatal(err)
+	}
+	oldSpec.NodeName = "node1"
+
+	// 和调度器一样只修改nodeName，或者请求中不带spec，都是允许的
+	bind := oldSpec
+	bind.NodeName = "node2"
+	if err := validatePodSpecUpdate(oldSpec, bind); err != nil {
+		t.Fatalf("expected nodeName change to be allowed but got %v", err)
+	}
+	if err := validatePodSpecUpdate(oldSpec, apiObject.PodSpec{}); err != nil {
+		t.Fatalf("expected empty spec to be allowed but got %v", err)
+	}
+	// 没有写默认值的spec和存储的spec一样
+	if err := validatePodSpecUpdate(oldSpec, apiObject.PodSpec{
+		Containers: []apiObject.Container{{Name: "c", Image: "nginx"}},
+	}); err != nil {
+		t.Fatalf("expected unchanged spec without defaults to be allowed but got %v", err)
+	}
+
+	changed := apiObject.PodSpec{
+		Containers: []apiObject.Container{{Name: "c", Image: "nginx:1.25"}},
+	}
+	if err := validatePodSpecUpdate(oldSpec, changed); errorStatusCode(err) != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for an image change but got %v", err)
+	}
+}
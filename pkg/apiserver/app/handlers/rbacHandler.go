@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/app/auth"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/uuid"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// "/apis/v1/namespaces/:namespace/roles"
+// "/apis/v1/namespaces/:namespace/roles/:name"
+var roleResource = &Resource{
+	Kind:       apiObject.RoleKind,
+	EtcdPath:   serverconfig.EtcdRolePath,
+	Namespaced: true,
+
+	// 只能更新rules
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldStore.(*apiObject.Role).Rules = newStore.(*apiObject.Role).Rules
+		return nil
+	},
+}
+
+// "/apis/v1/clusterroles"
+// "/apis/v1/clusterroles/:name"
+var clusterRoleResource = &Resource{
+	Kind:       apiObject.ClusterRoleKind,
+	EtcdPath:   serverconfig.EtcdClusterRolePath,
+	Namespaced: false,
+
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldStore.(*apiObject.ClusterRole).Rules = newStore.(*apiObject.ClusterRole).Rules
+		return nil
+	},
+}
+
+// "/apis/v1/namespaces/:namespace/rolebindings"
+// "/apis/v1/namespaces/:namespace/rolebindings/:name"
+var roleBindingResource = &Resource{
+	Kind:       apiObject.RoleBindingKind,
+	EtcdPath:   serverconfig.EtcdRoleBindingPath,
+	Namespaced: true,
+
+	// roleRef不能修改，需要修改的时候先删除再创建
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldBinding := oldStore.(*apiObject.RoleBinding)
+		newBinding := newStore.(*apiObject.RoleBinding)
+		if newBinding.RoleRef != oldBinding.RoleRef {
+			return newStatusError(http.StatusUnprocessableEntity, "roleRef can not be changed")
+		}
+		oldBinding.Subjects = newBinding.Subjects
+		return nil
+	},
+}
+
+// "/apis/v1/clusterrolebindings"
+// "/apis/v1/clusterrolebindings/:name"
+var clusterRoleBindingResource = &Resource{
+	Kind:       apiObject.ClusterRoleBindingKind,
+	EtcdPath:   serverconfig.EtcdClusterRoleBindingPath,
+	Namespaced: false,
+
+	PrepareForUpdate: func(oldStore interface{}, newStore interface{}) error {
+		oldBinding := oldStore.(*apiObject.ClusterRoleBinding)
+		newBinding := newStore.(*apiObject.ClusterRoleBinding)
+		if newBinding.RoleRef != oldBinding.RoleRef {
+			return newStatusError(http.StatusUnprocessableEntity, "roleRef can not be changed")
+		}
+		oldBinding.Subjects = newBinding.Subjects
+		return nil
+	},
+}
+
+func init() {
+	Register(roleResource)
+	Register(clusterRoleResource)
+	Register(roleBindingResource)
+	Register(clusterRoleBindingResource)
+
+	// 下面是apiserver.go里面手动注册的路由，通用资源的路由由InstallResources登记
+	registerRoute(config.NodeAllPodsURL, apiObject.PodKind, true)
+
+	// JobFile是Job的一部分，使用Job的权限，对应Job/file子资源
+	registerSubresourceRoute(config.JobFileURL, apiObject.JobKind, apiObject.JobFileSubresource, true)
+	registerSubresourceRoute(config.JobFileSpecURL, apiObject.JobKind, apiObject.JobFileSubresource, false)
+	// 签发job-server使用的凭证，对应Job/credential子资源的create
+	registerSubresourceRoute(config.JobSpecCredentialURL, apiObject.JobKind, apiObject.JobCredentialSubresource, true)
+
+	registerSubresourceRoute(config.NamespaceFinalizeURL, apiObject.NamespaceKind, apiObject.FinalizeSubresource, false)
+
+	// 自定义对象的kind要根据CRD确定
+	registerRoute(config.CustomResourcesURL, "", true)
+	registerRoute(config.CustomResourceSpecURL, "", false)
+	registerSubresourceRoute(config.CustomResourceSpecMetadataURL, "", apiObject.MetadataSubresource, false)
+
+	registerRoute(config.TokensURL, apiObject.TokenKind, true)
+	registerRoute(config.TokenSpecURL, apiObject.TokenKind, false)
+}
+
+// 路由 -> 路由操作的对象
+type routeInfo struct {
+	kind string
+	// 路由操作的子资源，比如status，操作对象本身的时候为空
+	subresource string
+	// 为true的时候是某一类对象的列表，GET对应list，否则对应get
+	collection bool
+}
+
+var routeInfos = make(map[string]routeInfo)
+
+// 登记路由对应的kind，没有登记的路由会被权限控制拒绝
+func registerRoute(path string, kind string, collection bool) {
+	routeInfos[path] = routeInfo{kind: kind, collection: collection}
+}
+
+// 登记子资源的路由，Role里面可以用Kind/subresource单独授权
+func registerSubresourceRoute(path string, kind string, subresource string, collection bool) {
+	routeInfos[path] = routeInfo{kind: kind, subresource: subresource, collection: collection}
+}
+
+// 根据路由和请求方法解析出请求的操作，给权限控制的中间件使用
+func ResolveRequestInfo(c *gin.Context) (*auth.RequestInfo, bool) {
+	route, ok := routeInfos[c.FullPath()]
+	if !ok {
+		return nil, false
+	}
+
+	info := &auth.RequestInfo{
+		Kind:        route.kind,
+		Namespace:   c.Param(config.URL_PARAM_NAMESPACE),
+		Name:        c.Param(config.URL_PARAM_NAME),
+		Subresource: route.subresource,
+	}
+	if info.Kind == "" {
+		info.Kind = customResourceKind(c)
+	}
+	// Namespace的URL里面:namespace是Namespace自己的名字，Namespace本身是集群级别的资源
+	if info.Kind == apiObject.NamespaceKind {
+		info.Name = info.Namespace
+		info.Namespace = ""
+	}
+	// 列表的URL里面的:name是别的对象，比如节点上的所有Pod
+	if route.collection {
+		info.Name = ""
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet:
+		if !route.collection {
+			info.Verb = apiObject.VerbGet
+		} else if c.Query(config.URL_QUERY_WATCH) == "true" {
+			info.Verb = apiObject.VerbWatch
+		} else {
+			info.Verb = apiObject.VerbList
+		}
+	case http.MethodPost:
+		// kubelet用POST更新Pod的状态
+		if route.collection {
+			info.Verb = apiObject.VerbCreate
+		} else {
+			info.Verb = apiObject.VerbUpdate
+		}
+	case http.MethodPut:
+		info.Verb = apiObject.VerbUpdate
+	case http.MethodPatch:
+		info.Verb = apiObject.VerbPatch
+	case http.MethodDelete:
+		info.Verb = apiObject.VerbDelete
+	default:
+		return nil, false
+	}
+
+	return info, true
+}
+
+// 根据url里面的group和plural找到CRD定义的kind，找不到的时候返回<plural>.<group>
+// CRD不存在的时候后面的handler会返回404
+func customResourceKind(c *gin.Context) string {
+	name := c.Param(config.URL_PARAM_PLURAL) + "." + c.Param(config.URL_PARAM_GROUP)
+	res, err := etcdclient.EtcdStore.Get(serverconfig.EtcdCRDPath + name)
+	if err != nil || len(res) != 1 {
+		return name
+	}
+	crd := &apiObject.CustomResourceDefinition{}
+	if err := json.Unmarshal([]byte(res[0].Value), crd); err != nil {
+		return name
+	}
+	return crd.Spec.Names.Kind
+}
+
+// 从etcd中读取权限控制对象
+type etcdRBACLister struct{}
+
+func NewRBACAuthorizer() *auth.RBACAuthorizer {
+	return auth.NewRBACAuthorizer(&etcdRBACLister{})
+}
+
+func (l *etcdRBACLister) ListClusterRoleBindings() ([]apiObject.ClusterRoleBinding, error) {
+	res, err := etcdclient.EtcdStore.PrefixGet(serverconfig.EtcdClusterRoleBindingPath)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]apiObject.ClusterRoleBinding, 0, len(res))
+	for _, v := range res {
+		binding := apiObject.ClusterRoleBinding{}
+		if err := json.Unmarshal([]byte(v.Value), &binding); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+func (l *etcdRBACLister) ListRoleBindings(namespace string) ([]apiObject.RoleBinding, error) {
+	res, err := etcdclient.EtcdStore.PrefixGet(serverconfig.EtcdRoleBindingPath + namespace + "/")
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]apiObject.RoleBinding, 0, len(res))
+	for _, v := range res {
+		binding := apiObject.RoleBinding{}
+		if err := json.Unmarshal([]byte(v.Value), &binding); err != nil {
+			return nil, err
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+func (l *etcdRBACLister) GetClusterRole(name string) (*apiObject.ClusterRole, error) {
+	role := &apiObject.ClusterRole{}
+	found, err := getObjectFromEtcd(serverconfig.EtcdClusterRolePath+name, role)
+	if err != nil || !found {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (l *etcdRBACLister) GetRole(namespace string, name string) (*apiObject.Role, error) {
+	role := &apiObject.Role{}
+	found, err := getObjectFromEtcd(serverconfig.EtcdRolePath+namespace+"/"+name, role)
+	if err != nil || !found {
+		return nil, err
+	}
+	return role, nil
+}
+
+// 读取etcd中的一个对象，不存在的时候返回false
+func getObjectFromEtcd(key string, obj interface{}) (bool, error) {
+	res, err := etcdclient.EtcdStore.Get(key)
+	if err != nil {
+		return false, err
+	}
+	if len(res) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal([]byte(res[0].Value), obj)
+}
+
+// 确保cluster-admin存在，管理员和所有节点组件都拥有所有的权限
+// APIServer启动的时候调用，已经存在的时候不会覆盖
+func InitBootstrapRBAC() error {
+	clusterAdmin := &apiObject.ClusterRole{
+		Rules: []apiObject.PolicyRule{
+			{Verbs: []string{apiObject.VerbAll}, Kinds: []string{apiObject.VerbAll}},
+		},
+	}
+	clusterAdmin.APIVersion = serverconfig.APIVersion
+	clusterAdmin.Kind = apiObject.ClusterRoleKind
+	clusterAdmin.Metadata.Name = apiObject.ClusterAdminName
+	clusterAdmin.Metadata.UUID = uuid.NewUUID()
+
+	binding := &apiObject.ClusterRoleBinding{
+		Subjects: []apiObject.Subject{
+			{Kind: apiObject.SubjectKindUser, Name: config.AdminTokenUser},
+			{Kind: apiObject.SubjectKindGroup, Name: apiObject.GroupNodes},
+		},
+		RoleRef: apiObject.RoleRef{Kind: apiObject.ClusterRoleKind, Name: apiObject.ClusterAdminName},
+	}
+	binding.APIVersion = serverconfig.APIVersion
+	binding.Kind = apiObject.ClusterRoleBindingKind
+	binding.Metadata.Name = apiObject.ClusterAdminName
+	binding.Metadata.UUID = uuid.NewUUID()
+
+	objects := map[string]interface{}{
+		serverconfig.EtcdClusterRolePath + apiObject.ClusterAdminName:        clusterAdmin,
+		serverconfig.EtcdClusterRoleBindingPath + apiObject.ClusterAdminName: binding,
+	}
+	for key, obj := range objects {
+		objJson, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		// 版本为0表示key不存在，已经存在的时候不覆盖
+		created, err := etcdclient.EtcdStore.CompareAndSwap(key, 0, objJson)
+		if err != nil {
+			return err
+		}
+		if created {
+			k8log.InfoLog("APIServer", "bootstrap "+key+" created")
+		}
+	}
+	return nil
+}
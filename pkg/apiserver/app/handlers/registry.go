@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiObject/validation"
+	"miniK8s/pkg/apiserver/app/admission"
+	"miniK8s/pkg/config"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
+)
+
+// 一种资源在APIServer中的注册信息
+// 通用的增删改查都由genericHandler.go完成，每种资源只需要提供自己特有的钩子
+// 请求中的对象类型来自apiObject.KindToStructType，路由来自config.ApiResourceMap和config.ApiSpecResourceMap
+type Resource struct {
+	// 资源的种类，比如apiObject.PodKind
+	Kind string
+	// etcd中存储的路径前缀，来自serverconfig
+	EtcdPath string
+	// 是否属于某个namespace
+	// 为true的时候etcd中的路径是<EtcdPath><namespace>/<name>，否则是<EtcdPath><name>
+	Namespaced bool
+	// 存储到etcd中的对象类型，比如PodStore，为nil的时候和请求中的对象类型一样
+	StoreType reflect.Type
+
+	// 获取全局资源的URL，为空的时候不注册
+	GlobalURL string
+	// 获取和更新资源状态的URL，为空的时候不注册，StoreType里面必须有Status字段
+	StatusURL string
+	// URL里面对象名字的参数，为空的时候是config.URL_PARAM_NAME
+	// Namespace的URL用的是:namespace，见config.NamespaceSpecURL
+	NameParam string
+	// 为true的时候删除只设置deletionTimestamp，由controller清理完之后通过自己的接口真正删除
+	// 比如Namespace要等下面所有的资源都删除之后，才能通过finalize接口删除
+	GracefulDelete bool
+	// 对象在etcd中的过期时间，为0的时候不过期，每次创建和更新都会重新计算
+	TTL time.Duration
+	// 根据对象计算过期时间，比如Lease的过期时间由spec里面的leaseDurationSeconds决定，为nil的时候使用TTL
+	TTLFunc func(store interface{}) time.Duration
+
+	// 下面是每种资源自己的钩子，参数都是StoreType的指针，都可以为nil
+
+	// 创建之前调用，用来填充默认值、检查合法性，返回错误的时候不会创建
+	// 在准入控制之后调用，被准入控制拒绝的对象不会走到这里
+	PrepareForCreate func(store interface{}) error
+	// PrepareForCreate成功之后没有写入etcd的时候调用(比如同名对象已经存在)，释放PrepareForCreate分配的资源
+	AbortCreate func(store interface{})
+	// 创建成功之后调用，比如写入索引、发送消息通知其他组件
+	AfterCreate func(store interface{})
+	// 更新的时候调用，把请求中的对象选择性地合并到etcd中已有的对象上面
+	// PATCH的时候newStore是patch应用到已有对象之后的结果
+	// 为nil的时候不支持更新
+	PrepareForUpdate func(oldStore interface{}, newStore interface{}) error
+	// 更新状态的时候调用，把请求中的状态合并到已有的对象上面
+	// 为nil的时候直接用请求中的状态替换原来的状态
+	PrepareForStatusUpdate func(oldStore interface{}, status interface{}) error
+	// 删除之前调用，返回错误的时候不会删除，可以修改对象，GracefulDelete的时候修改会写回etcd
+	PrepareForDelete func(store interface{}) error
+	// 删除成功之后调用，参数是删除之前的对象
+	AfterDelete func(store interface{})
+}
+
+// kind -> 注册的资源
+var resourceRegistry = make(map[string]*Resource)
+
+// 按照注册的顺序记录kind，注册路由的时候保持顺序
+var resourceKinds = make([]string, 0)
+
+// 注册一种资源，同一种资源只能注册一次
+func Register(r *Resource) {
+	if _, ok := resourceRegistry[r.Kind]; ok {
+		panic("resource " + r.Kind + " already registered")
+	}
+	if _, ok := apiObject.KindToStructType[r.Kind]; !ok {
+		panic("resource " + r.Kind + " is not in apiObject.KindToStructType")
+	}
+	if r.StoreType == nil {
+		r.StoreType = apiObject.KindToStructType[r.Kind]
+	}
+	// 通用的handler需要通过Metadata拿到name和namespace
+	if objectMetadata(reflect.New(r.StoreType).Interface()) == nil {
+		panic("resource " + r.Kind + " has no metadata")
+	}
+	if r.StatusURL != "" && objectStatus(reflect.New(r.StoreType).Interface()) == nil {
+		panic("resource " + r.Kind + " has no status")
+	}
+
+	resourceRegistry[r.Kind] = r
+	resourceKinds = append(resourceKinds, r.Kind)
+}
+
+// 获取注册的资源，没有注册的时候返回nil
+func GetResource(kind string) *Resource {
+	return resourceRegistry[kind]
+}
+
+// 把所有注册的资源的路由注册到router上面
+func InstallResources(router *gin.Engine) {
+	for _, kind := range resourceKinds {
+		r := resourceRegistry[kind]
+
+		listURL := config.ApiResourceMap[kind]
+		specURL := config.ApiSpecResourceMap[kind]
+
+		// list类型的GET请求用ListOrWatch包装，支持watch
+		router.GET(listURL, ListOrWatch(r.List, r.EtcdPath, r.Namespaced))
+		router.POST(listURL, r.Create)
+		router.GET(specURL, r.Get)
+		router.DELETE(specURL, r.Delete)
+		if r.PrepareForUpdate != nil {
+			router.PUT(specURL, r.Update)
+			router.PATCH(specURL, r.Patch)
+		}
+		router.PUT(specURL+config.MetadataSubresource, r.UpdateMetadata)
+		registerRoute(listURL, kind, true)
+		registerRoute(specURL, kind, false)
+		registerSubresourceRoute(specURL+config.MetadataSubresource, kind, apiObject.MetadataSubresource, false)
+
+		if r.GlobalURL != "" {
+			router.GET(r.GlobalURL, ListOrWatch(r.ListGlobal, r.EtcdPath, false))
+			registerRoute(r.GlobalURL, kind, true)
+		}
+
+		if r.StatusURL != "" {
+			router.GET(r.StatusURL, r.GetStatus)
+			// kubelet用POST更新Pod的状态，其他组件用PUT，两种都支持
+			router.PUT(r.StatusURL, r.UpdateStatus)
+			router.POST(r.StatusURL, r.UpdateStatus)
+			registerSubresourceRoute(r.StatusURL, kind, apiObject.StatusSubresource, false)
+		}
+	}
+}
+
+// 对象写入etcd时的过期时间
+func (r *Resource) ttl(store interface{}) time.Duration {
+	if r.TTLFunc != nil {
+		return r.TTLFunc(store)
+	}
+	return r.TTL
+}
+
+// 创建一个空的存储对象
+func (r *Resource) newStore() interface{} {
+	return reflect.New(r.StoreType).Interface()
+}
+
+// 创建一个空的请求对象
+func (r *Resource) newObject() interface{} {
+	if t, ok := apiObject.KindToStructType[r.Kind]; ok {
+		return reflect.New(t).Interface()
+	}
+	// 自定义资源不在KindToStructType里面，请求和存储用同一种类型
+	return r.newStore()
+}
+
+// 资源在etcd中的key
+func (r *Resource) etcdKey(namespace string, name string) string {
+	if r.Namespaced {
+		return r.EtcdPath + namespace + "/" + name
+	}
+	return r.EtcdPath + name
+}
+
+// 通过反射拿到对象的Metadata，对象是结构体的指针
+// Node的元数据字段叫NodeMetadata，类型和其他对象一样
+// 对象里面没有apiObject.Metadata类型的Metadata或者NodeMetadata字段的时候返回nil
+func objectMetadata(obj interface{}) *apiObject.Metadata {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	for _, name := range []string{"Metadata", "NodeMetadata"} {
+		field := v.Elem().FieldByName(name)
+		if field.IsValid() && field.Type() == reflect.TypeOf(apiObject.Metadata{}) {
+			return field.Addr().Interface().(*apiObject.Metadata)
+		}
+	}
+	return nil
+}
+
+// 通过反射拿到对象的Status字段的指针，没有的时候返回nil
+func objectStatus(obj interface{}) interface{} {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	field := v.Elem().FieldByName("Status")
+	if !field.IsValid() || field.Kind() != reflect.Struct {
+		return nil
+	}
+	return field.Addr().Interface()
+}
+
+// 创建一个和ptr指向的对象同类型的空对象，返回它的指针
+func reflectNew(ptr interface{}) interface{} {
+	return reflect.New(reflect.TypeOf(ptr).Elem()).Interface()
+}
+
+// 把src指向的对象复制到dst指向的对象，两者类型必须一样
+func reflectCopy(dst interface{}, src interface{}) {
+	reflect.ValueOf(dst).Elem().Set(reflect.ValueOf(src).Elem())
+}
+
+// 把请求中的对象转换为存储的对象，比如Pod转换为PodStore
+// 存储对象的json是请求对象的超集，所以直接通过json转换
+func convertObject(src interface{}, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// 钩子返回这个错误的时候，用里面的状态码返回给客户端，准入控制返回的admission.Error也一样
+// 对象不合法的时候返回422，其他的错误一律返回400
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func newStatusError(code int, format string, args ...interface{}) error {
+	return &statusError{
+		code: code,
+		msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// 根据钩子返回的错误得到状态码
+func errorStatusCode(err error) int {
+	if se, ok := err.(*statusError); ok {
+		return se.code
+	}
+	if ae, ok := err.(*admission.Error); ok {
+		return ae.Code
+	}
+	if _, ok := err.(*validation.InvalidError); ok {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// 返回给客户端的错误信息，对象不合法的时候用details带上所有不合法的字段
+// 比如 {"error": "...", "details": [{"field": "spec.replicas", "reason": "..."}]}
+func errorBody(action string, err error) gin.H {
+	body := gin.H{
+		"error": action + ": " + err.Error(),
+	}
+	if ie, ok := err.(*validation.InvalidError); ok {
+		body["details"] = ie.Errors
+	}
+	return body
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// resourceVersion直接使用etcd中key的ModRevision，不会持久化到value里面
+// 读取对象的时候注入到metadata.resourceVersion，更新对象的时候用来做乐观并发控制
+
+// 把resourceVersion注入到对象json的metadata.resourceVersion中
+// 如果value不是一个带metadata的对象，就原样返回
+func injectResourceVersion(value string, resourceVersion int64) string {
+	obj := make(map[string]interface{})
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	// 避免数字被转换成float64之后丢失精度
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return value
+	}
+
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return value
+	}
+	metadata["resourceVersion"] = strconv.FormatInt(resourceVersion, 10)
+
+	res, err := json.Marshal(obj)
+	if err != nil {
+		return value
+	}
+	return string(res)
+}
+
+// 检查请求中带的resourceVersion和etcd中的是否一致
+// 请求中没有带resourceVersion的时候，视为不做检查
+func resourceVersionMatch(reqVersion string, curVersion int64) bool {
+	if reqVersion == "" {
+		return true
+	}
+	return reqVersion == strconv.FormatInt(curVersion, 10)
+}
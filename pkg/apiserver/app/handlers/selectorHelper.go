@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/etcd"
+	"miniK8s/util/selector"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 根据请求中的labelSelector和fieldSelector生成一个匹配函数，参数是对象的json
+// 请求中没有带selector的时候，匹配所有的对象
+func selectorMatcherFromRequest(c *gin.Context) (func(value string) bool, error) {
+	labelSel, err := selector.ParseLabelSelector(c.Query(config.URL_QUERY_LABEL_SELECTOR))
+	if err != nil {
+		return nil, err
+	}
+	fieldSel, err := selector.ParseFieldSelector(c.Query(config.URL_QUERY_FIELD_SELECTOR))
+	if err != nil {
+		return nil, err
+	}
+
+	if labelSel.Empty() && fieldSel.Empty() {
+		return func(value string) bool { return true }, nil
+	}
+
+	return func(value string) bool {
+		obj := make(map[string]interface{})
+		decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&obj); err != nil {
+			return false
+		}
+
+		// 取出metadata.labels
+		labels := make(map[string]string)
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			if rawLabels, ok := metadata["labels"].(map[string]interface{}); ok {
+				for k, v := range rawLabels {
+					if str, ok := v.(string); ok {
+						labels[k] = str
+					}
+				}
+			}
+		}
+
+		return labelSel.MatchLabels(labels) && fieldSel.MatchFields(obj)
+	}, nil
+}
+
+// 只保留match返回true的对象
+func filterListRes(res []etcd.ListRes, match func(value string) bool) []etcd.ListRes {
+	filtered := make([]etcd.ListRes, 0, len(res))
+	for _, v := range res {
+		if match(v.Value) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
@@ -1,39 +1,72 @@
 package handlers
 
 import (
+	"crypto/rand"
 	"errors"
-	"math/rand"
-	"miniK8s/pkg/apiserver/serverconfig"
+	"math/big"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiObject/validation"
+	"miniK8s/pkg/apiserver/app/auth"
 	"miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
 	"miniK8s/pkg/k8log"
+	"net/http"
+	"os"
+	"strings"
 	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goccy/go-json"
 )
 
 // 生成一个长度为64的随机字母和数字的组合的token
+// token就是访问APIServer的凭证，所以使用crypto/rand生成
 func GenerateToken() string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	source := rand.NewSource(time.Now().UnixNano())
-	var seededRand *rand.Rand = rand.New(source)
+	max := big.NewInt(int64(len(charset)))
 	b := make([]byte, 64)
 	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = charset[n.Int64()]
 	}
 	return string(b)
 }
 
-// 返回一个新的令牌，并在屏幕上显示它
-func AddNewToken() (string, error) {
-	// 1. 生成一个长度为64的随机字母和数字的组合的token
-	newToken := GenerateToken()
-	// 记录日志
-	logStr := "new token created: " + newToken
-	k8log.InfoLog("APIServer", logStr)
+// 生成一个新的token，并把token记录存储到etcd中
+func CreateToken(tokenType string, user string) (*apiObject.Token, error) {
+	record := &apiObject.Token{
+		Token:             GenerateToken(),
+		Type:              tokenType,
+		User:              user,
+		CreationTimestamp: time.Now(),
+	}
 
-	// 2. 将token存储到etcd中
-	key := serverconfig.EtcdTokenPath + newToken
-	etcdclient.EtcdStore.Put(key, []byte(newToken))
-	// 3. 返回token
-	return newToken, nil
+	recordJson, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	key := serverconfig.EtcdTokenPath + record.Token
+	if err := etcdclient.EtcdStore.Put(key, recordJson); err != nil {
+		return nil, err
+	}
+
+	// 日志里面不记录token本身
+	k8log.InfoLog("APIServer", "new "+tokenType+" token created for "+user)
+	return record, nil
+}
+
+// 返回一个新的管理员用户的令牌
+func AddNewToken() (string, error) {
+	record, err := CreateToken(apiObject.TokenTypeUser, config.AdminTokenUser)
+	if err != nil {
+		return "", err
+	}
+	return record.Token, nil
 }
 
 func DelToken(token string) error {
@@ -41,7 +74,7 @@ func DelToken(token string) error {
 	key := serverconfig.EtcdTokenPath + token
 	err := etcdclient.EtcdStore.Del(key)
 	// 记录日志
-	logStr := "token deleted: " + token
+	logStr := "token deleted: " + maskToken(token)
 	k8log.WarnLog("APIServer", logStr)
 	return err
 }
@@ -54,24 +87,180 @@ func DelAllToken() error {
 	return err
 }
 
-func VerifyToken(token string) (bool, error) {
-	// 1. 从etcd中获取token
+// 从etcd中读取token记录，token不存在的时候返回nil, nil
+// 认证中间件每个请求都会调用，所以这里不打日志
+func GetToken(token string) (*apiObject.Token, error) {
+	if token == "" || strings.Contains(token, "/") {
+		return nil, nil
+	}
+
 	key := serverconfig.EtcdTokenPath + token
 	res, err := etcdclient.EtcdStore.Get(key)
 	if err != nil {
-		return false, err
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, nil
 	}
-	// 2. 如果token存在，则返回true，否则返回false
-	if len(res) == 1 {
-		logStr := "token verified successful: " + token
+	if len(res) > 1 {
+		return nil, errors.New("token duplicate error")
+	}
+
+	record := &apiObject.Token{}
+	if err := json.Unmarshal([]byte(res[0].Value), record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func VerifyToken(token string) (bool, error) {
+	record, err := GetToken(token)
+	if err != nil {
+		logStr := "token verified failed[" + err.Error() + "]: " + maskToken(token)
 		k8log.InfoLog("APIServer", logStr)
-		return true, nil
-	} else if len(res) > 1 {
-		logStr := "token verified failed[duplicate token error]: " + token
+		return false, err
+	}
+	if record == nil {
+		logStr := "token verified failed: " + maskToken(token)
 		k8log.InfoLog("APIServer", logStr)
-		return false, errors.New("token duplicate error")
+		return false, nil
 	}
-	logStr := "token verified failed: " + token
+	logStr := "token verified successful: " + maskToken(token)
 	k8log.InfoLog("APIServer", logStr)
-	return false, nil
+	return true, nil
+}
+
+// 日志里面只显示token的前几位
+func maskToken(token string) string {
+	if len(token) <= 6 {
+		return "******"
+	}
+	return token[:6] + "******"
+}
+
+// APIServer启动的时候调用，保证管理员和节点组件都有可用的token
+// token写到config.TokenDir下面，kubectl和节点组件默认从这里读取
+func InitBootstrapTokens() error {
+	bootstrapTokens := []struct {
+		file      string
+		tokenType string
+		user      string
+	}{
+		{config.AdminTokenFile, apiObject.TokenTypeUser, config.AdminTokenUser},
+		{config.NodeTokenFile, apiObject.TokenTypeNode, config.NodeTokenUser},
+	}
+
+	for _, bootstrap := range bootstrapTokens {
+		// 文件里面的token还有效的时候直接使用
+		if content, err := os.ReadFile(bootstrap.file); err == nil {
+			record, err := GetToken(strings.TrimSpace(string(content)))
+			if err != nil {
+				return err
+			}
+			if record != nil && record.Type == bootstrap.tokenType {
+				continue
+			}
+		}
+
+		record, err := CreateToken(bootstrap.tokenType, bootstrap.user)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(config.TokenDir, 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(bootstrap.file, []byte(record.Token+"\n"), 0600); err != nil {
+			return err
+		}
+		k8log.InfoLog("APIServer", "bootstrap "+bootstrap.tokenType+" token written to "+bootstrap.file)
+	}
+
+	return nil
+}
+
+// POST 创建一个token，body里面指定token的种类和用户名
+// "/api/v1/tokens"
+func AddToken(c *gin.Context) {
+	k8log.InfoLog("APIServer", "AddToken")
+
+	// 节点组件的token不能用来创建新的token
+	if user, ok := auth.UserFromContext(c); ok && user.IsNode() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "AddToken: node token can not manage tokens",
+		})
+		return
+	}
+
+	var req apiObject.TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "AddToken: " + err.Error(),
+		})
+		return
+	}
+
+	if errs := validation.ValidateTokenRequest(&req); len(errs) != 0 {
+		err := validation.NewInvalidError(apiObject.TokenKind, req.User, errs)
+		c.JSON(http.StatusUnprocessableEntity, errorBody("AddToken", err))
+		return
+	}
+
+	record, err := CreateToken(req.Type, req.User)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "AddToken: " + err.Error(),
+		})
+		return
+	}
+
+	recordJson, err := json.Marshal(record)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "AddToken: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": string(recordJson),
+	})
+}
+
+// DELETE 删除一个token，删除之后使用这个token的请求都会返回401
+// "/api/v1/tokens/:name"
+func DeleteToken(c *gin.Context) {
+	k8log.InfoLog("APIServer", "DeleteToken")
+
+	if user, ok := auth.UserFromContext(c); ok && user.IsNode() {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "DeleteToken: node token can not manage tokens",
+		})
+		return
+	}
+
+	token := c.Param(config.URL_PARAM_NAME)
+	record, err := GetToken(token)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "DeleteToken: " + err.Error(),
+		})
+		return
+	}
+	if record == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "DeleteToken: not found",
+		})
+		return
+	}
+
+	if err := DelToken(token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "DeleteToken: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, gin.H{
+		"message": "DeleteToken: success",
+	})
 }
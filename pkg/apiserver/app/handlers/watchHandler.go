@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"miniK8s/pkg/apiObject"
+	etcdclient "miniK8s/pkg/apiserver/app/etcdclient"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/etcd"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 包装list类型的handler，请求中带有watch=true的时候，转为监听etcdPrefix下面对象的变化
+// 不是watch的时候，会先检查limit和continue这些分页参数
+// namespaced为true的时候，监听的是url中namespace下面的对象
+func ListOrWatch(listHandler gin.HandlerFunc, etcdPrefix string, namespaced bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		prefix := etcdPrefix
+		if namespaced {
+			namespace := c.Param(config.URL_PARAM_NAMESPACE)
+			if namespace == "" {
+				namespace = config.DefaultNamespace
+			}
+			prefix = path.Join(etcdPrefix, namespace) + "/"
+		}
+
+		if c.Query(config.URL_QUERY_WATCH) != "true" {
+			// 提前检查分页的参数，参数不对的时候直接返回400
+			if _, err := parseListOptions(c, prefix); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			listHandler(c)
+			return
+		}
+
+		watchPrefix(c, prefix)
+	}
+}
+
+// 以chunked的方式持续推送prefix下面对象的变化，一行一个WatchEvent
+// 没有带resourceVersion的时候，先把当前所有的对象作为ADDED推送一遍，再推送之后的变化
+// 带了resourceVersion的时候，只推送这个版本之后的变化
+func watchPrefix(c *gin.Context, prefix string) {
+	k8log.InfoLog("APIServer", "watchPrefix: prefix = "+prefix)
+
+	// watch同样支持labelSelector和fieldSelector，只推送满足条件的对象
+	match, err := selectorMatcherFromRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid selector: " + err.Error(),
+		})
+		return
+	}
+
+	initEvents := make([]apiObject.WatchEvent, 0)
+	var startRevision int64
+
+	reqVersion := c.Query(config.URL_QUERY_RESOURCE_VERSION)
+	if reqVersion == "" || reqVersion == "0" {
+		res, revision, err := etcdclient.EtcdStore.PrefixGetWithRevision(prefix)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "watch failed " + err.Error(),
+			})
+			return
+		}
+		for _, v := range res {
+			if !match(v.Value) {
+				continue
+			}
+			initEvents = append(initEvents, apiObject.WatchEvent{
+				Type:   apiObject.WatchEventAdded,
+				Object: json.RawMessage(injectResourceVersion(v.Value, v.ResourceVersion)),
+			})
+		}
+		startRevision = revision + 1
+	} else {
+		revision, err := strconv.ParseInt(reqVersion, 10, 64)
+		if err != nil || revision < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "invalid resourceVersion " + reqVersion,
+			})
+			return
+		}
+		startRevision = revision + 1
+	}
+
+	cancel, watchChan := etcdclient.EtcdStore.PrefixWatchFromRevision(prefix, startRevision)
+	defer cancel()
+
+	c.Header("Content-Type", "application/json")
+	c.Header("Transfer-Encoding", "chunked")
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for _, event := range initEvents {
+		if err := encoder.Encode(event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	// 客户端断开连接或者etcd的watch结束的时候，退出
+	// etcd的watch失败的时候先推送一个ERROR事件，客户端据此决定是否需要重新list
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case res, ok := <-watchChan:
+			if !ok {
+				return false
+			}
+			if res.ResType == etcd.ERROR {
+				k8log.WarnLog("APIServer", "watchPrefix: watch "+prefix+" failed, "+res.Err.Error())
+				encoder.Encode(watchErrorEvent(res.Err))
+				return false
+			}
+			event, ok := selectedWatchEvent(res, match)
+			if !ok {
+				return true
+			}
+			if err := encoder.Encode(event); err != nil {
+				return false
+			}
+			return true
+		}
+	})
+}
+
+// 把etcd的事件转换成满足selector的客户端看到的watch事件，客户端不关心这个事件的时候返回false
+// 修改之后不再满足selector的对象，对客户端来说是被删除了，推送修改之前的对象
+// 修改之后才满足selector的对象，对客户端来说是新增的
+func selectedWatchEvent(res etcd.WatchRes, match func(value string) bool) (apiObject.WatchEvent, bool) {
+	event := apiObject.WatchEvent{}
+	value := res.Value
+
+	switch {
+	case res.ResType == etcd.DELETE:
+		if value == "" || !match(value) {
+			return event, false
+		}
+		event.Type = apiObject.WatchEventDeleted
+	case res.IsCreate:
+		if !match(value) {
+			return event, false
+		}
+		event.Type = apiObject.WatchEventAdded
+	default:
+		matched := match(value)
+		// 拿不到修改之前的值的时候，当作满足条件的情况没有变化
+		prevMatched := matched
+		if res.PrevValue != "" {
+			prevMatched = match(res.PrevValue)
+		}
+		switch {
+		case matched && prevMatched:
+			event.Type = apiObject.WatchEventModified
+		case matched:
+			event.Type = apiObject.WatchEventAdded
+		case prevMatched:
+			event.Type = apiObject.WatchEventDeleted
+			value = res.PrevValue
+		default:
+			return event, false
+		}
+	}
+
+	event.Object = json.RawMessage(injectResourceVersion(value, res.ResourceVersion))
+	return event, true
+}
+
+// watch失败的时候推送给客户端的ERROR事件，revision被压缩的时候是410
+func watchErrorEvent(err error) apiObject.WatchEvent {
+	status := apiObject.WatchStatus{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
+	}
+	if errors.Is(err, etcd.ErrCompacted) {
+		status.Code = http.StatusGone
+		status.Reason = apiObject.WatchReasonExpired
+		status.Message = "resourceVersion is too old, please list again: " + err.Error()
+	}
+	object, _ := json.Marshal(status)
+	return apiObject.WatchEvent{
+		Type:   apiObject.WatchEventError,
+		Object: object,
+	}
+}
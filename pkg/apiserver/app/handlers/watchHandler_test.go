@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/etcd"
+	"strings"
+	"testing"
+)
+
+func TestSelectedWatchEvent(t *testing.T) {
+	match := func(value string) bool {
+		return strings.Contains(value, `"app":"a"`)
+	}
+	a := `{"metadata":{"name":"x","labels":{"app":"a"}}}`
+	b := `{"metadata":{"name":"x","labels":{"app":"b"}}}`
+
+	tests := []struct {
+		name string
+		res  etcd.WatchRes
+		// 为空的时候不推送
+		expectedType string
+		// 推送的对象的label
+		expectedApp string
+	}{
+		{"create matched", etcd.WatchRes{ResType: etcd.PUT, IsCreate: true, Value: a}, apiObject.WatchEventAdded, "a"},
+		{"create not matched", etcd.WatchRes{ResType: etcd.PUT, IsCreate: true, Value: b}, "", ""},
+		{"still matched", etcd.WatchRes{ResType: etcd.PUT, IsModify: true, Value: a, PrevValue: a}, apiObject.WatchEventModified, "a"},
+		{"starts matching", etcd.WatchRes{ResType: etcd.PUT, IsModify: true, Value: a, PrevValue: b}, apiObject.WatchEventAdded, "a"},
+		{"stops matching", etcd.WatchRes{ResType: etcd.PUT, IsModify: true, Value: b, PrevValue: a}, apiObject.WatchEventDeleted, "a"},
+		{"never matched", etcd.WatchRes{ResType: etcd.PUT, IsModify: true, Value: b, PrevValue: b}, "", ""},
+		{"no previous value", etcd.WatchRes{ResType: etcd.PUT, IsModify: true, Value: a}, apiObject.WatchEventModified, "a"},
+		{"delete matched", etcd.WatchRes{ResType: etcd.DELETE, Value: a, PrevValue: a}, apiObject.WatchEventDeleted, "a"},
+		{"delete not matched", etcd.WatchRes{ResType: etcd.DELETE, Value: b, PrevValue: b}, "", ""},
+	}
+
+	for _, test := range tests {
+		event, ok := selectedWatchEvent(test.res, match)
+		if ok != (test.expectedType != "") {
+			t.Errorf("%s: expected sent %v but got %v", test.name, test.expectedType != "", ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if event.Type != test.expectedType || !strings.Contains(string(event.Object), `"app":"`+test.expectedApp+`"`) {
+			t.Errorf("%s: unexpected event %s %s", test.name, event.Type, string(event.Object))
+		}
+	}
+}
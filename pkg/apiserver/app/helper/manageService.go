@@ -65,6 +65,41 @@ func AllocClusterIP() (string, error) {
 	return "", fmt.Errorf("IP is out of range")
 }
 
+// 创建Service失败的时候释放已经分配的IP
+func ReleaseClusterIP(clusterIP string) error {
+	ipArr := strings.Split(clusterIP, ".")
+	if len(ipArr) != 4 {
+		return fmt.Errorf("IP is invalid")
+	}
+	num2, err2 := strconv.Atoi(ipArr[2])
+	num3, err3 := strconv.Atoi(ipArr[3])
+	if err2 != nil || err3 != nil {
+		return fmt.Errorf("IP is invalid")
+	}
+
+	curMaxIP, err := etcdclient.EtcdStore.Get(serverconfig.EtcdIPPath)
+	if err != nil {
+		return err
+	}
+	if len(curMaxIP) == 0 {
+		return nil
+	}
+	allocatedIP := make(map[int]bool)
+	if err := json.Unmarshal([]byte(curMaxIP[0].Value), &allocatedIP); err != nil {
+		return err
+	}
+	if !allocatedIP[num2*256+num3] {
+		return nil
+	}
+
+	delete(allocatedIP, num2*256+num3)
+	allocatedIPJson, err := json.Marshal(allocatedIP)
+	if err != nil {
+		return err
+	}
+	return etcdclient.EtcdStore.Put(serverconfig.EtcdIPPath, allocatedIPJson)
+}
+
 func JudgeServiceIPAddress(cluserIP string) error {
 	// 从etcd中获取IPMap
 	curMaxIP, err := etcdclient.EtcdStore.Get(serverconfig.EtcdIPPath)
@@ -1,13 +1,24 @@
 package apiserver
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
+	"miniK8s/pkg/apiserver/app/audit"
+	"miniK8s/pkg/apiserver/app/auth"
+	"miniK8s/pkg/apiserver/app/etcdclient"
 	"miniK8s/pkg/apiserver/app/handlers"
 	serverConfig "miniK8s/pkg/apiserver/serverconfig"
 	config "miniK8s/pkg/config"
+	"miniK8s/pkg/healthz"
 	"miniK8s/pkg/k8log"
 	"miniK8s/pkg/listwatcher"
+	"miniK8s/pkg/metrics"
+	"net/http"
+	"os"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,11 +27,21 @@ type ApiServer interface {
 }
 
 type apiServer struct {
-	router   *gin.Engine
-	listenIP string
-	port     int
-	ifDebug  bool
-	lw       *listwatcher.Listwatcher
+	router     *gin.Engine
+	listenIP   string
+	port       int
+	ifDebug    bool
+	enableAuth bool
+	lw         *listwatcher.Listwatcher
+
+	tlsCertFile       string
+	tlsKeyFile        string
+	clientCAFile      string
+	requireClientCert bool
+	allowInsecureHTTP bool
+
+	// 为nil的时候不记录审计日志
+	auditLogger *audit.Logger
 }
 
 func New(c *serverConfig.ServerConfig) ApiServer {
@@ -30,13 +51,42 @@ func New(c *serverConfig.ServerConfig) ApiServer {
 		k8log.FatalLog("apiserver", fmt.Sprintf("创建ListWatcher失败:%s", err.Error()))
 	}
 
+	auditLogger, err := newAuditLogger(c)
+	if err != nil {
+		k8log.FatalLog("apiserver", fmt.Sprintf("创建审计日志失败:%s", err.Error()))
+	}
+
 	return &apiServer{
-		router:   gin.Default(),
-		port:     c.Port,
-		listenIP: c.ListenIP,
-		ifDebug:  c.IfDebug,
-		lw:       lw,
+		router:     gin.Default(),
+		port:       c.Port,
+		listenIP:   c.ListenIP,
+		ifDebug:    c.IfDebug,
+		enableAuth: c.EnableAuth,
+		lw:         lw,
+
+		tlsCertFile:       c.TLSCertFile,
+		tlsKeyFile:        c.TLSKeyFile,
+		clientCAFile:      c.ClientCAFile,
+		requireClientCert: c.RequireClientCert,
+		allowInsecureHTTP: c.AllowInsecureHTTP,
+
+		auditLogger: auditLogger,
+	}
+}
+
+func newAuditLogger(c *serverConfig.ServerConfig) (*audit.Logger, error) {
+	if c.AuditLogFile == "" {
+		return nil, nil
+	}
+	policy, err := audit.LoadPolicy(c.AuditPolicyFile)
+	if err != nil {
+		return nil, err
 	}
+	writer, err := audit.NewRotatingWriter(c.AuditLogFile, c.AuditLogMaxSize, c.AuditLogMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return audit.NewLogger(writer, policy), nil
 }
 
 type ResponseData struct {
@@ -53,58 +103,118 @@ func (s *apiServer) Run() {
 		k8log.InfoLog("APIServer", "Debug mode is off, release mode is on")
 	}
 
+	// 确保default namespace存在，没有指定namespace的对象都会放在default下面
+	if err := handlers.InitDefaultNamespace(); err != nil {
+		k8log.ErrorLog("APIServer", "init default namespace failed, "+err.Error())
+	}
+
+	// 确保管理员和节点组件的token存在，写到config.TokenDir下面
+	// 并且确保cluster-admin存在，管理员和节点组件拥有所有的权限
+	if s.enableAuth {
+		if err := handlers.InitBootstrapTokens(); err != nil {
+			k8log.ErrorLog("APIServer", "init bootstrap tokens failed, "+err.Error())
+		}
+		if err := handlers.InitBootstrapRBAC(); err != nil {
+			k8log.ErrorLog("APIServer", "init bootstrap rbac failed, "+err.Error())
+		}
+	} else {
+		k8log.WarnLog("APIServer", "Authentication is off, anyone can access the api server")
+	}
+
 	s.bind()
 	runAddr := s.listenIP + ":" + fmt.Sprint(s.port)
-	k8log.InfoLog("APIServer", "Listening on "+runAddr)
-	s.router.Run("0.0.0.0:" + fmt.Sprint(s.port))
+
+	// 没有服务端证书的时候只有显式允许才使用http，证书由cmd/ca init生成
+	if _, err := os.Stat(s.tlsCertFile); err != nil {
+		if !s.allowInsecureHTTP || s.requireClientCert {
+			k8log.FatalLog("APIServer", "TLS certificate "+s.tlsCertFile+" not found, run cmd/ca init first or start with -insecure-http")
+		}
+		k8log.WarnLog("APIServer", "TLS certificate "+s.tlsCertFile+" not found, serving plain http because insecure http is allowed")
+		k8log.InfoLog("APIServer", "Listening on "+runAddr)
+		s.router.Run("0.0.0.0:" + fmt.Sprint(s.port))
+		return
+	}
+	if _, err := os.Stat(s.tlsKeyFile); err != nil {
+		k8log.FatalLog("APIServer", "TLS key "+s.tlsKeyFile+" not found, "+err.Error())
+	}
+
+	tlsConfig, err := s.buildTLSConfig()
+	if err != nil {
+		k8log.FatalLog("APIServer", "load tls config failed, "+err.Error())
+	}
+	server := &http.Server{
+		Addr:      "0.0.0.0:" + fmt.Sprint(s.port),
+		Handler:   s.router,
+		TLSConfig: tlsConfig,
+	}
+	k8log.InfoLog("APIServer", "Listening on "+runAddr+" with TLS")
+	if err := server.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile); err != nil {
+		k8log.FatalLog("APIServer", "serve tls failed, "+err.Error())
+	}
+}
+
+// 服务端的TLS配置，配置了客户端CA的时候验证客户端证书，CA读不到的时候返回错误
+// requireClientCert为false的时候，没有客户端证书的请求还可以使用token认证
+func (s *apiServer) buildTLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if s.clientCAFile == "" {
+		if s.requireClientCert {
+			return nil, errors.New("client certificates are required but no client CA is configured")
+		}
+		return tlsConfig, nil
+	}
+	caData, err := os.ReadFile(s.clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, errors.New("no valid certificate found in " + s.clientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+
+	if s.requireClientCert {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
 }
 
 func (s *apiServer) bind() {
 
+	// 指标的中间件最先注册，被拒绝的请求也会被统计
+	s.router.Use(metrics.Middleware())
+
+	// 健康检查在审计和认证的中间件之前注册，探针不需要带token
+	healthz.InstallHandlers(s.router, "apiserver", nil, []healthz.HealthChecker{
+		healthz.NamedCheck("etcd", etcdclient.Healthy),
+	})
+
+	// 审计日志的中间件在认证之前注册，被拒绝的请求也会记录下来
+	if s.auditLogger != nil {
+		s.router.Use(audit.Audit(handlers.ResolveRequestInfo, s.auditLogger))
+	}
+
+	// 认证和权限控制的中间件必须在所有路由之前注册，所有请求都要带上Authorization: Bearer <token>
+	// 新增的路由需要在handlers里面登记对应的kind，否则会被权限控制拒绝
+	if s.enableAuth {
+		s.router.Use(auth.Authenticate(handlers.GetToken))
+		s.router.Use(auth.Authorize(handlers.ResolveRequestInfo, handlers.NewRBACAuthorizer()))
+	}
+
 	// Rest风格的api
+	// 所有list类型的GET请求都用ListOrWatch包装，带上?watch=true&resourceVersion=N可以持续监听变化
 	// 在Kubernetes API中，节点（Node）的标识符是其名称，因此在API URI中，
-	// 节点的名称用于区分不同的节点。例如，获取名为node-1的节点的状态，可以使用以下URI：
-	s.router.GET(config.NodesURL, handlers.GetNodes)
-	s.router.GET(config.NodeSpecURL, handlers.GetNode)
-	s.router.POST(config.NodesURL, handlers.AddNode)
-	s.router.PUT(config.NodeSpecURL, handlers.UpdateNode)
-	s.router.DELETE(config.NodeSpecURL, handlers.DeleteNode)
-
-	// 对于节点的状态
-	s.router.GET(config.NodeSpecStatusURL, handlers.GetNodeStatus)
-	s.router.PUT(config.NodeSpecStatusURL, handlers.UpdateNodeStatus)
+	// 节点的名称用于区分不同的节点。Node的增删改查和status由下面的InstallResources生成
 
 	// 节点的Pod
 	s.router.GET(config.NodeAllPodsURL, handlers.GetNodePods)
 
-	// Pod相关的api
-	s.router.GET(config.GlobalPodsURL, handlers.GetGlobalPods) // 所有pod
-	s.router.GET(config.PodsURL, handlers.GetPods)             // 所有pod
-	s.router.GET(config.PodSpecURL, handlers.GetPod)           // 单个pod
-	s.router.POST(config.PodsURL, handlers.AddPod)             // 创建pod
-	s.router.PUT(config.PodSpecURL, handlers.UpdatePod)        // 更新Pod
-	s.router.DELETE(config.PodSpecURL, handlers.DeletePod)     // 删除Pod
-
-	// PodStatus相关的api
-	s.router.GET(config.PodSpecStatusURL, handlers.GetPodStatus)     // 获取PodStatus
-	s.router.POST(config.PodSpecStatusURL, handlers.UpdatePodStatus) // 更新PodStatus
-
-	// Service相关的api
-	s.router.POST(config.ServiceURL, handlers.AddService)          // 创建service
-	s.router.GET(config.ServiceURL, handlers.GetServices)          // 获取所有service
-	s.router.GET(config.ServiceSpecURL, handlers.GetService)       // 获取单个service
-	s.router.PUT(config.ServiceSpecURL, handlers.UpdateService)    // 更新service
-	s.router.DELETE(config.ServiceSpecURL, handlers.DeleteService) // 删除service
-
-	// Job相关的api
-	s.router.GET(config.JobsURL, handlers.GetJobs)         // 获取所有job
-	s.router.GET(config.JobSpecURL, handlers.GetJob)       // 获取单个job
-	s.router.POST(config.JobsURL, handlers.AddJob)         // 创建job
-	s.router.DELETE(config.JobSpecURL, handlers.DeleteJob) // 删除job
-
-	// JobStatus相关的api
-	s.router.GET(config.JobSpecStatusURL, handlers.GetJobStatus)    // 获取jobStatus
-	s.router.PUT(config.JobSpecStatusURL, handlers.UpdateJobStatus) // 更新jobStatus
+	// Pod、Service、Job、ReplicaSet、Dns、HPA、Function、WorkFlow、Node、Namespace的api
+	// 由handlers包里面注册的资源统一生成，包括list/watch、增删改查以及status
+	handlers.InstallResources(s.router)
 
 	// JobFile相关的api
 	s.router.GET(config.JobFileSpecURL, handlers.GetJobFile) // 获取jobFile
@@ -112,52 +222,31 @@ func (s *apiServer) bind() {
 
 	s.router.PUT(config.JobFileSpecURL, handlers.UpdateJobFile) // 更新jobFile
 
-	// replicaSet相关的api
-	s.router.GET(config.GlobalReplicaSetsURL, handlers.GetReplicaSets)   // 获取所有replicaSet
-	s.router.GET(config.ReplicaSetsURL, handlers.GetReplicaSets)         // 获取名字空间下面的所有replicaSet
-	s.router.GET(config.ReplicaSetSpecURL, handlers.GetReplicaSet)       // 获取单个replicaSet
-	s.router.POST(config.ReplicaSetsURL, handlers.AddReplicaSet)         // 创建replicaSet
-	s.router.DELETE(config.ReplicaSetSpecURL, handlers.DeleteReplicaSet) // 删除replicaSet
-	s.router.PUT(config.ReplicaSetSpecURL, handlers.UpdateReplicaSet)    // 更新replicaSet
-
-	//
-	s.router.GET(config.ReplicaSetSpecStatusURL, handlers.GetReplicaSetStatus)    // 获取replicaSetStatus
-	s.router.PUT(config.ReplicaSetSpecStatusURL, handlers.UpdateReplicaSetStatus) // 更新replicaSetStatus
-
-	// Dns相关的api
-	s.router.GET(config.DnsURL, handlers.GetDnsList)       // 获取所有dns
-	s.router.GET(config.DnsSpecURL, handlers.GetDns)       // 获取单个dns
-	s.router.POST(config.DnsURL, handlers.AddDns)          // 创建dns
-	s.router.DELETE(config.DnsSpecURL, handlers.DeleteDns) // 删除dns
-
-	// HPA相关的api
-	s.router.GET(config.HPAURL, handlers.GetHPAs)             // 获取所有hpa
-	s.router.GET(config.HPASpecURL, handlers.GetHPA)          // 获取单个hpa
-	s.router.POST(config.HPAURL, handlers.AddHPA)             // 创建hpa
-	s.router.PUT(config.HPASpecURL, handlers.UpdateHPA)       // 更新hpa
-	s.router.DELETE(config.HPASpecURL, handlers.DeleteHPA)    // 删除hpa
-	s.router.GET(config.GlobalHPAURL, handlers.GetGlobalHPAs) // 获取所有hpa
-
-	// Function相关的api
-	s.router.GET(config.FunctionURL, handlers.GetFunctions)       // 获取所有function
-	s.router.GET(config.FunctionSpecURL, handlers.GetFunction)    // 获取单个function
-	s.router.POST(config.FunctionURL, handlers.AddFunction)       // 创建function
-	s.router.PUT(config.FunctionSpecURL, handlers.UpdateFunction) // 更新function
-	s.router.DELETE(config.FunctionSpecURL, handlers.DeleteFunction)
-	s.router.GET(config.GlobalFunctionsURL, handlers.GetGlobalFunctions) // 获取所有function
-
-	s.router.PUT(config.HPASpecStatusURL, handlers.UpdateHPAStatus) // 更新hpaStatus
-
-	// WorkFlow相关的api
-	s.router.GET(config.GlobalWorkflowsURL, handlers.GetGlobalWorkFlows) // 获取所有WorkFlow
-	s.router.GET(config.WorkflowURL, handlers.GetWorkFlows)              // 获取所有WorkFlow
-	s.router.GET(config.WorkflowSpecURL, handlers.GetWorkFlow)           // 获取单个WorkFlow
-	s.router.POST(config.WorkflowURL, handlers.AddWorkFlow)              // 创建WorkFlow
-	s.router.PUT(config.WorkflowSpecURL, handlers.UpdateWorkFlow)        // 更新WorkFlow
-	s.router.DELETE(config.WorkflowSpecURL, handlers.DeleteWorkFlow)     // 删除WorkFlow
-
-	// WorkFlowStatus相关的api
-	s.router.GET(config.WorkflowSpecStatusURL, handlers.GetWorkFlowStatus)    // 获取WorkFlowStatus
-	s.router.PUT(config.WorkflowSpecStatusURL, handlers.UpdateWorkFlowStatus) // 更新WorkFlowStatus
+	// 签发job-server使用的凭证，kubelet创建Job的Pod的时候调用
+	s.router.POST(config.JobSpecCredentialURL, handlers.IssueJobCredential)
+
+	// Namespace真正的删除，增删查和status由InstallResources生成，删除只会标记为Terminating
+	s.router.PUT(config.NamespaceFinalizeURL, handlers.FinalizeNamespace) // namespace下面的资源清空之后真正删除namespace
+
+	// 自定义对象相关的api，CRD本身的api由InstallResources统一生成
+	s.router.GET(config.CustomResourcesURL, handlers.GetCustomObjects)                      // 获取所有自定义对象
+	s.router.GET(config.CustomResourceSpecURL, handlers.GetCustomObject)                    // 获取单个自定义对象
+	s.router.POST(config.CustomResourcesURL, handlers.AddCustomObject)                      // 创建自定义对象
+	s.router.PUT(config.CustomResourceSpecURL, handlers.UpdateCustomObject)                 // 更新自定义对象
+	s.router.PATCH(config.CustomResourceSpecURL, handlers.PatchCustomObject)                // patch自定义对象
+	s.router.DELETE(config.CustomResourceSpecURL, handlers.DeleteCustomObject)              // 删除自定义对象
+	s.router.PUT(config.CustomResourceSpecMetadataURL, handlers.UpdateCustomObjectMetadata) // 更新自定义对象的ownerReferences和finalizers
+
+	// Token相关的api，节点组件的token不能创建和删除token
+	s.router.POST(config.TokensURL, handlers.AddToken)         // 创建token
+	s.router.DELETE(config.TokenSpecURL, handlers.DeleteToken) // 删除token
+
+	// 审计日志的查询api
+	if s.auditLogger != nil {
+		s.router.GET(config.AuditEventsURL, handlers.GetAuditEvents(s.auditLogger))
+	}
+
+	// Prometheus格式的指标，Prometheus抓取的时候需要带上有Metrics权限的token
+	s.router.GET(config.MetricsURL, handlers.GetMetrics)
 
 }
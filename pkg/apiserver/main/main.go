@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	apiserver "miniK8s/pkg/apiserver/app/server"
 	"miniK8s/pkg/apiserver/serverconfig"
 )
@@ -9,5 +10,9 @@ func main() {
 	// apiServer := apiserver.New(config.DefaultServerConfig())
 	// apiServer.Run()
 
-	apiserver.New(serverconfig.DefaultServerConfig()).Run()
+	c := serverconfig.DefaultServerConfig()
+	flag.BoolVar(&c.AllowInsecureHTTP, "insecure-http", false, "serve plain http when the TLS certificate is missing, only for development")
+	flag.Parse()
+
+	apiserver.New(c).Run()
 }
@@ -2,7 +2,11 @@
 
 package serverconfig
 
-import "time"
+import (
+	"os"
+	"strings"
+	"time"
+)
 
 const (
 	//用来记录分配的最大的ServiceIP
@@ -30,6 +34,9 @@ const (
 	// 完整路径：/registry/jobfile/<namespace>/<job-name>
 	EtcdJobFilePath = "/registry/jobfile/"
 
+	// 完整路径：/registry/jobcredentials/<namespace>/<job-name>
+	EtcdJobCredentialPath = "/registry/jobcredentials/"
+
 	// 完整路径：/registry/replicasets/<namespace>/<replicaset-name>
 	EtcdReplicaSetPath = "/registry/replicasets/"
 
@@ -44,15 +51,63 @@ const (
 
 	// 完整路径：/registry/workflows/<namespace>/<workflow-name>
 	EtcdWorkflowPath = "/registry/workflows/"
+
+	// 完整路径：/registry/namespaces/<namespace-name>
+	EtcdNamespacePath = "/registry/namespaces/"
+
+	// 完整路径：/registry/customresourcedefinitions/<plural>.<group>
+	EtcdCRDPath = "/registry/customresourcedefinitions/"
+
+	// 完整路径：/registry/custom/<group>/<plural>/<namespace>/<name>
+	EtcdCustomResourcePath = "/registry/custom/"
+
+	// 完整路径：/registry/mutatingwebhookconfigurations/<name>
+	EtcdMutatingWebhookPath = "/registry/mutatingwebhookconfigurations/"
+
+	// 完整路径：/registry/validatingwebhookconfigurations/<name>
+	EtcdValidatingWebhookPath = "/registry/validatingwebhookconfigurations/"
+
+	// 完整路径：/registry/roles/<namespace>/<role-name>
+	EtcdRolePath = "/registry/roles/"
+
+	// 完整路径：/registry/rolebindings/<namespace>/<rolebinding-name>
+	EtcdRoleBindingPath = "/registry/rolebindings/"
+
+	// 完整路径：/registry/clusterroles/<clusterrole-name>
+	EtcdClusterRolePath = "/registry/clusterroles/"
+
+	// 完整路径：/registry/clusterrolebindings/<clusterrolebinding-name>
+	EtcdClusterRoleBindingPath = "/registry/clusterrolebindings/"
+
+	// 完整路径：/registry/events/<namespace>/<event-name>
+	EtcdEventPath = "/registry/events/"
+
+	// 完整路径：/registry/leases/<namespace>/<lease-name>
+	EtcdLeasePath = "/registry/leases/"
+)
+
+// APIServer使用的存储，memory不依赖外部的etcd，重启之后数据会丢失，只用于开发和测试
+const (
+	StorageBackendEtcd   = "etcd"
+	StorageBackendMemory = "memory"
+
+	// 通过环境变量选择存储，没有设置的时候使用etcd
+	StorageBackendEnvName = "MINIK8S_STORAGE_BACKEND"
 )
 
 type EtcdConfig struct {
+	Backend       string
 	EtcdEndpoints []string
 	EtcdTimeout   time.Duration
 }
 
 func DefaultEtcdConfig() *EtcdConfig {
+	backend := strings.TrimSpace(os.Getenv(StorageBackendEnvName))
+	if backend == "" {
+		backend = StorageBackendEtcd
+	}
 	return &EtcdConfig{
+		Backend:       backend,
 		EtcdEndpoints: []string{"localhost:2379"},
 		EtcdTimeout:   5 * time.Second,
 	}
@@ -1,22 +1,64 @@
 package serverconfig
 
-import "miniK8s/pkg/config"
+import (
+	"miniK8s/pkg/config"
+	"time"
+)
 
 const (
 	ResourceName  = "ResourceName"
 	APIVersion   = "v1"
 )
 
+// 审计日志默认的位置
+const DefaultAuditLogFile = "/var/log/minik8s/audit.log"
+
+// etcd备份文件默认的目录
+const DefaultBackupDir = "/var/lib/minik8s/backup/"
+
+// 事件在etcd中保存的时间，同一个事件重复发生的时候重新计算
+const EventTTL = time.Hour
+
 type ServerConfig struct {
 	IfDebug  bool
 	Port     int
 	ListenIP string
+	// 为true的时候所有请求都必须带上Authorization: Bearer <token>
+	EnableAuth bool
+	// 服务端证书和私钥，文件不存在的时候APIServer拒绝启动
+	TLSCertFile string
+	TLSKeyFile  string
+	// 用来验证客户端证书的CA
+	ClientCAFile string
+	// 为true的时候所有请求都必须带上ClientCAFile签发的客户端证书
+	RequireClientCert bool
+	// 为true并且RequireClientCert为false的时候，没有服务端证书也使用http启动，只用于开发和测试
+	AllowInsecureHTTP bool
+	// 审计日志，为空的时候不记录
+	AuditLogFile string
+	// 审计策略，文件不存在的时候所有修改类的请求都按照Metadata级别记录
+	AuditPolicyFile string
+	// 单个审计日志文件的最大字节数，以及保留的旧文件个数
+	AuditLogMaxSize    int64
+	AuditLogMaxBackups int
 }
 
 func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
-		IfDebug:  false,
-		ListenIP: "0.0.0.0",
-		Port:     config.API_Server_Port,
+		IfDebug:    false,
+		ListenIP:   "0.0.0.0",
+		Port:       config.API_Server_Port,
+		EnableAuth: true,
+
+		TLSCertFile:       config.APIServerCertFile,
+		TLSKeyFile:        config.APIServerKeyFile,
+		ClientCAFile:      config.CACertFile,
+		RequireClientCert: false,
+		AllowInsecureHTTP: false,
+
+		AuditLogFile:       DefaultAuditLogFile,
+		AuditPolicyFile:    config.TokenDir + "audit-policy.yaml",
+		AuditLogMaxSize:    100 * 1024 * 1024,
+		AuditLogMaxBackups: 5,
 	}
 }
@@ -0,0 +1,314 @@
+package cache
+
+import (
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 按照namespace建立的索引的名字
+const NamespaceIndex = "namespace"
+
+// 计算对象在缓存里面的key
+type KeyFunc func(obj interface{}) (string, error)
+
+// 计算对象在某个索引里面的值，一个对象可以对应多个值
+type IndexFunc func(obj interface{}) ([]string, error)
+
+// 索引的名字 -> 计算索引的函数
+type Indexers map[string]IndexFunc
+
+// Indexer 是线程安全的本地缓存，对象按照 namespace/name 保存，同时维护额外的索引
+// 缓存里面的对象是共享的，读取之后不要修改，需要修改的时候先复制一份
+type Indexer interface {
+	Add(obj interface{}) error
+	Update(obj interface{}) error
+	Delete(obj interface{}) error
+	// 用list替换缓存里面所有的对象
+	Replace(list []interface{}) error
+
+	Get(obj interface{}) (interface{}, bool, error)
+	GetByKey(key string) (interface{}, bool, error)
+	// 按照key排序返回
+	List() []interface{}
+	ListKeys() []string
+	// 返回索引indexName的值为indexedValue的所有对象
+	ByIndex(indexName string, indexedValue string) ([]interface{}, error)
+}
+
+// 对象的元数据，不同的对象元数据的字段不一样，比如Node是NodeMetadata
+type objectMeta struct {
+	Namespace       string
+	Name            string
+	ResourceVersion string
+	Labels          map[string]string
+}
+
+// 通过反射读取对象的Metadata或者NodeMetadata字段
+func metaOf(obj interface{}) (*objectMeta, error) {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, errors.New("object is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("object is not a struct")
+	}
+
+	if field := v.FieldByName("Metadata"); field.IsValid() && field.Type() == reflect.TypeOf(apiObject.Metadata{}) {
+		meta := field.Interface().(apiObject.Metadata)
+		return &objectMeta{
+			Namespace:       meta.Namespace,
+			Name:            meta.Name,
+			ResourceVersion: meta.ResourceVersion,
+			Labels:          meta.Labels,
+		}, nil
+	}
+	if field := v.FieldByName("NodeMetadata"); field.IsValid() && field.Type() == reflect.TypeOf(apiObject.NodeMetadata{}) {
+		meta := field.Interface().(apiObject.NodeMetadata)
+		return &objectMeta{
+			Name:            meta.Name,
+			ResourceVersion: meta.ResourceVersion,
+			Labels:          meta.Labels,
+		}, nil
+	}
+	return nil, errors.New("object has no metadata")
+}
+
+// 默认的key，属于namespace的对象是 namespace/name，否则是 name
+func MetaNamespaceKeyFunc(obj interface{}) (string, error) {
+	meta, err := metaOf(obj)
+	if err != nil {
+		return "", err
+	}
+	if meta.Namespace == "" {
+		return meta.Name, nil
+	}
+	return meta.Namespace + "/" + meta.Name, nil
+}
+
+// 把key拆成namespace和name
+func SplitMetaNamespaceKey(key string) (string, string) {
+	parts := strings.SplitN(key, "/", 2)
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+// 按照namespace建立索引
+func MetaNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	meta, err := metaOf(obj)
+	if err != nil {
+		return nil, err
+	}
+	return []string{meta.Namespace}, nil
+}
+
+// 按照某个label的值建立索引，没有这个label的对象不进入索引
+func LabelIndexFunc(label string) IndexFunc {
+	return func(obj interface{}) ([]string, error) {
+		meta, err := metaOf(obj)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := meta.Labels[label]
+		if !ok {
+			return nil, nil
+		}
+		return []string{value}, nil
+	}
+}
+
+// 对象的resourceVersion，读取失败的时候为空
+func ResourceVersion(obj interface{}) string {
+	meta, err := metaOf(obj)
+	if err != nil {
+		return ""
+	}
+	return meta.ResourceVersion
+}
+
+type threadSafeIndexer struct {
+	lock     sync.RWMutex
+	keyFunc  KeyFunc
+	indexers Indexers
+
+	items map[string]interface{}
+	// 索引的名字 -> 索引的值 -> key的集合
+	indices map[string]map[string]map[string]struct{}
+}
+
+// 创建一个Indexer，indexers可以为nil
+func NewIndexer(keyFunc KeyFunc, indexers Indexers) Indexer {
+	if indexers == nil {
+		indexers = Indexers{}
+	}
+	indices := make(map[string]map[string]map[string]struct{})
+	for name := range indexers {
+		indices[name] = make(map[string]map[string]struct{})
+	}
+	return &threadSafeIndexer{
+		keyFunc:  keyFunc,
+		indexers: indexers,
+		items:    make(map[string]interface{}),
+		indices:  indices,
+	}
+}
+
+func (s *threadSafeIndexer) Add(obj interface{}) error {
+	return s.Update(obj)
+}
+
+func (s *threadSafeIndexer) Update(obj interface{}) error {
+	key, err := s.keyFunc(obj)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.deleteFromIndices(key)
+	s.items[key] = obj
+	return s.addToIndices(key, obj)
+}
+
+func (s *threadSafeIndexer) Delete(obj interface{}) error {
+	key, err := s.keyFunc(obj)
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.deleteFromIndices(key)
+	delete(s.items, key)
+	return nil
+}
+
+func (s *threadSafeIndexer) Replace(list []interface{}) error {
+	items := make(map[string]interface{}, len(list))
+	for _, obj := range list {
+		key, err := s.keyFunc(obj)
+		if err != nil {
+			return err
+		}
+		items[key] = obj
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items = make(map[string]interface{}, len(items))
+	for name := range s.indices {
+		s.indices[name] = make(map[string]map[string]struct{})
+	}
+	for key, obj := range items {
+		s.items[key] = obj
+		if err := s.addToIndices(key, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *threadSafeIndexer) Get(obj interface{}) (interface{}, bool, error) {
+	key, err := s.keyFunc(obj)
+	if err != nil {
+		return nil, false, err
+	}
+	return s.GetByKey(key)
+}
+
+func (s *threadSafeIndexer) GetByKey(key string) (interface{}, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	obj, ok := s.items[key]
+	return obj, ok, nil
+}
+
+func (s *threadSafeIndexer) List() []interface{} {
+	keys := s.ListKeys()
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	list := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		if obj, ok := s.items[key]; ok {
+			list = append(list, obj)
+		}
+	}
+	return list
+}
+
+func (s *threadSafeIndexer) ListKeys() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys := make([]string, 0, len(s.items))
+	for key := range s.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *threadSafeIndexer) ByIndex(indexName string, indexedValue string) ([]interface{}, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	index, ok := s.indices[indexName]
+	if !ok {
+		return nil, errors.New("index " + indexName + " does not exist")
+	}
+
+	keys := make([]string, 0, len(index[indexedValue]))
+	for key := range index[indexedValue] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	list := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, s.items[key])
+	}
+	return list, nil
+}
+
+// 调用的时候需要持有写锁
+func (s *threadSafeIndexer) addToIndices(key string, obj interface{}) error {
+	for name, indexFunc := range s.indexers {
+		values, err := indexFunc(obj)
+		if err != nil {
+			return err
+		}
+		index := s.indices[name]
+		for _, value := range values {
+			if index[value] == nil {
+				index[value] = make(map[string]struct{})
+			}
+			index[value][key] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// 调用的时候需要持有写锁
+func (s *threadSafeIndexer) deleteFromIndices(key string) {
+	obj, ok := s.items[key]
+	if !ok {
+		return
+	}
+	for name, indexFunc := range s.indexers {
+		values, err := indexFunc(obj)
+		if err != nil {
+			continue
+		}
+		index := s.indices[name]
+		for _, value := range values {
+			delete(index[value], key)
+			if len(index[value]) == 0 {
+				delete(index, value)
+			}
+		}
+	}
+}
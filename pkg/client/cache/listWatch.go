@@ -0,0 +1,25 @@
+package cache
+
+import "miniK8s/pkg/client"
+
+// ListerWatcher 是informer读取对象的方式
+type ListerWatcher interface {
+	// 返回所有对象的指针和读取时的resourceVersion
+	List() ([]interface{}, string, error)
+	// 从resourceVersion开始监听变化
+	Watch(resourceVersion string) (client.Watcher, error)
+}
+
+// 用两个函数实现ListerWatcher，typed informer用它包装typed client
+type ListWatch struct {
+	ListFunc  func() ([]interface{}, string, error)
+	WatchFunc func(resourceVersion string) (client.Watcher, error)
+}
+
+func (lw *ListWatch) List() ([]interface{}, string, error) {
+	return lw.ListFunc()
+}
+
+func (lw *ListWatch) Watch(resourceVersion string) (client.Watcher, error) {
+	return lw.WatchFunc(resourceVersion)
+}
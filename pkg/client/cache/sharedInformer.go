@@ -0,0 +1,312 @@
+package cache
+
+import (
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// list或者watch失败之后，等待这么久再重新list
+var DefaultRetryPeriod = time.Second
+
+// ResourceEventHandler 接收缓存里面对象的变化
+// 所有的回调都在informer的协程里面依次调用，不能阻塞，一般只是把key放到队列里面
+type ResourceEventHandler interface {
+	OnAdd(obj interface{})
+	// 定期resync的时候oldObj和newObj是同一个对象，resourceVersion相同
+	OnUpdate(oldObj interface{}, newObj interface{})
+	// obj是删除之前最后的状态
+	OnDelete(obj interface{})
+}
+
+// 用函数实现ResourceEventHandler，不关心的事件可以为nil
+type ResourceEventHandlerFuncs struct {
+	AddFunc    func(obj interface{})
+	UpdateFunc func(oldObj interface{}, newObj interface{})
+	DeleteFunc func(obj interface{})
+}
+
+func (f ResourceEventHandlerFuncs) OnAdd(obj interface{}) {
+	if f.AddFunc != nil {
+		f.AddFunc(obj)
+	}
+}
+
+func (f ResourceEventHandlerFuncs) OnUpdate(oldObj interface{}, newObj interface{}) {
+	if f.UpdateFunc != nil {
+		f.UpdateFunc(oldObj, newObj)
+	}
+}
+
+func (f ResourceEventHandlerFuncs) OnDelete(obj interface{}) {
+	if f.DeleteFunc != nil {
+		f.DeleteFunc(obj)
+	}
+}
+
+// SharedIndexInformer 先list再从list的resourceVersion开始watch，把对象保存在本地的Indexer里面
+// 同一种资源的多个controller共用一个informer，只和APIServer保持一个watch连接
+// watch断开(比如etcd的revision被压缩)之后重新list，和缓存比较之后补发漏掉的事件
+type SharedIndexInformer interface {
+	// 在回调里面调用会死锁
+	// informer已经同步过的时候，缓存里面已有的对象会先作为OnAdd通知给新的handler
+	AddEventHandler(handler ResourceEventHandler)
+	GetIndexer() Indexer
+	// 第一次list成功之后返回true
+	HasSynced() bool
+	// 函数会阻塞直到stopCh关闭，只能调用一次
+	Run(stopCh <-chan struct{})
+}
+
+type sharedIndexInformer struct {
+	lw      ListerWatcher
+	indexer Indexer
+	// 大于0的时候，每隔resyncPeriod把缓存里面所有的对象作为OnUpdate通知一遍
+	resyncPeriod time.Duration
+	retryPeriod  time.Duration
+
+	// 分发事件的时候也持有这个锁，保证新加入的handler不会漏掉事件
+	lock     sync.Mutex
+	handlers []ResourceEventHandler
+	started  bool
+	synced   bool
+}
+
+// 默认带有namespace的索引，indexers里面可以添加额外的索引
+func NewSharedIndexInformer(lw ListerWatcher, resyncPeriod time.Duration, indexers Indexers) SharedIndexInformer {
+	allIndexers := Indexers{NamespaceIndex: MetaNamespaceIndexFunc}
+	for name, indexFunc := range indexers {
+		allIndexers[name] = indexFunc
+	}
+	return &sharedIndexInformer{
+		lw:           lw,
+		indexer:      NewIndexer(MetaNamespaceKeyFunc, allIndexers),
+		resyncPeriod: resyncPeriod,
+		retryPeriod:  DefaultRetryPeriod,
+	}
+}
+
+func (s *sharedIndexInformer) AddEventHandler(handler ResourceEventHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.handlers = append(s.handlers, handler)
+	if !s.synced {
+		return
+	}
+	for _, obj := range s.indexer.List() {
+		handler.OnAdd(obj)
+	}
+}
+
+func (s *sharedIndexInformer) GetIndexer() Indexer {
+	return s.indexer
+}
+
+func (s *sharedIndexInformer) HasSynced() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.synced
+}
+
+func (s *sharedIndexInformer) Run(stopCh <-chan struct{}) {
+	s.lock.Lock()
+	if s.started {
+		s.lock.Unlock()
+		k8log.WarnLog("informer", "informer has already been started")
+		return
+	}
+	s.started = true
+	s.lock.Unlock()
+
+	var resyncCh <-chan time.Time
+	if s.resyncPeriod > 0 {
+		ticker := time.NewTicker(s.resyncPeriod)
+		defer ticker.Stop()
+		resyncCh = ticker.C
+	}
+
+	for {
+		resourceVersion, err := s.listAndReplace()
+		if err == nil {
+			err = s.watch(resourceVersion, resyncCh, stopCh)
+		}
+
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+
+		// watch被服务器关闭的时候马上重新list，出错的时候等一会儿再重试
+		if err != nil {
+			k8log.ErrorLog("informer", "list and watch failed: "+err.Error())
+			select {
+			case <-stopCh:
+				return
+			case <-time.After(s.retryPeriod):
+			}
+		}
+	}
+}
+
+// 读取所有对象替换缓存，和原来的缓存比较，通知新增、修改和删除的对象
+func (s *sharedIndexInformer) listAndReplace() (string, error) {
+	list, resourceVersion, err := s.lw.List()
+	if err != nil {
+		return "", err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old := make(map[string]interface{})
+	for _, key := range s.indexer.ListKeys() {
+		if obj, ok, _ := s.indexer.GetByKey(key); ok {
+			old[key] = obj
+		}
+	}
+	if err := s.indexer.Replace(list); err != nil {
+		return "", err
+	}
+	s.synced = true
+
+	for _, obj := range list {
+		key, err := MetaNamespaceKeyFunc(obj)
+		if err != nil {
+			continue
+		}
+		oldObj, ok := old[key]
+		delete(old, key)
+		if !ok {
+			s.distributeAdd(obj)
+		} else if ResourceVersion(oldObj) != ResourceVersion(obj) {
+			s.distributeUpdate(oldObj, obj)
+		}
+	}
+	// 剩下的是watch断开期间被删除的对象
+	for _, obj := range old {
+		s.distributeDelete(obj)
+	}
+	return resourceVersion, nil
+}
+
+// 处理watch推送的事件，直到watch结束或者stopCh关闭
+func (s *sharedIndexInformer) watch(resourceVersion string, resyncCh <-chan time.Time, stopCh <-chan struct{}) error {
+	watcher, err := s.lw.Watch(resourceVersion)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return nil
+		case <-resyncCh:
+			s.resync()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				k8log.DebugLog("informer", "watch closed, relist")
+				return nil
+			}
+			if event.Type == apiObject.WatchEventError {
+				return watchError(event)
+			}
+			s.handleEvent(event)
+		}
+	}
+}
+
+// resourceVersion过期(410)的时候马上重新list，其他的错误等一会儿再重试
+func watchError(event client.Event) error {
+	status, ok := event.Object.(*apiObject.WatchStatus)
+	if !ok {
+		return errors.New("watch failed with an unknown error")
+	}
+	if status.Code == http.StatusGone {
+		k8log.DebugLog("informer", "resourceVersion expired, relist: "+status.Message)
+		return nil
+	}
+	return &client.StatusError{Code: status.Code, Message: "watch failed: " + status.Message}
+}
+
+func (s *sharedIndexInformer) handleEvent(event client.Event) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	oldObj, exists, err := s.indexer.Get(event.Object)
+	if err != nil {
+		k8log.ErrorLog("informer", "handle watch event failed: "+err.Error())
+		return
+	}
+
+	switch event.Type {
+	case apiObject.WatchEventAdded, apiObject.WatchEventModified:
+		if err := s.indexer.Update(event.Object); err != nil {
+			k8log.ErrorLog("informer", "update cache failed: "+err.Error())
+			return
+		}
+		if exists {
+			s.distributeUpdate(oldObj, event.Object)
+		} else {
+			s.distributeAdd(event.Object)
+		}
+	case apiObject.WatchEventDeleted:
+		s.indexer.Delete(event.Object)
+		s.distributeDelete(event.Object)
+	}
+}
+
+func (s *sharedIndexInformer) resync() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, obj := range s.indexer.List() {
+		s.distributeUpdate(obj, obj)
+	}
+}
+
+// 下面的函数调用的时候需要持有锁
+
+func (s *sharedIndexInformer) distributeAdd(obj interface{}) {
+	for _, handler := range s.handlers {
+		handler.OnAdd(obj)
+	}
+}
+
+func (s *sharedIndexInformer) distributeUpdate(oldObj interface{}, newObj interface{}) {
+	for _, handler := range s.handlers {
+		handler.OnUpdate(oldObj, newObj)
+	}
+}
+
+func (s *sharedIndexInformer) distributeDelete(obj interface{}) {
+	for _, handler := range s.handlers {
+		handler.OnDelete(obj)
+	}
+}
+
+// 等待所有的informer同步完成，stopCh关闭的时候返回false
+func WaitForCacheSync(stopCh <-chan struct{}, cacheSyncs ...func() bool) bool {
+	for {
+		synced := true
+		for _, hasSynced := range cacheSyncs {
+			if !hasSynced() {
+				synced = false
+				break
+			}
+		}
+		if synced {
+			return true
+		}
+
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeWatcher struct {
+	result   chan client.Event
+	stopOnce sync.Once
+}
+
+func newFakeWatcher() *fakeWatcher {
+	return &fakeWatcher{result: make(chan client.Event)}
+}
+
+func (w *fakeWatcher) ResultChan() <-chan client.Event {
+	return w.result
+}
+
+func (w *fakeWatcher) Stop() {
+}
+
+// 模拟服务器关闭watch连接
+func (w *fakeWatcher) close() {
+	w.stopOnce.Do(func() { close(w.result) })
+}
+
+// 每次List返回items里面的对象，每次Watch创建一个新的fakeWatcher
+type fakeListerWatcher struct {
+	lock     sync.Mutex
+	items    []interface{}
+	version  string
+	watchers chan *fakeWatcher
+	// 每次Watch的时候传入的resourceVersion
+	watchVersions []string
+}
+
+func (lw *fakeListerWatcher) List() ([]interface{}, string, error) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	return append([]interface{}{}, lw.items...), lw.version, nil
+}
+
+func (lw *fakeListerWatcher) Watch(resourceVersion string) (client.Watcher, error) {
+	lw.lock.Lock()
+	lw.watchVersions = append(lw.watchVersions, resourceVersion)
+	lw.lock.Unlock()
+	w := newFakeWatcher()
+	lw.watchers <- w
+	return w, nil
+}
+
+func (lw *fakeListerWatcher) setItems(version string, items ...interface{}) {
+	lw.lock.Lock()
+	defer lw.lock.Unlock()
+	lw.version = version
+	lw.items = items
+}
+
+func newPod(namespace string, name string, version string, labels map[string]string) *apiObject.PodStore {
+	pod := &apiObject.PodStore{}
+	pod.Metadata.Namespace = namespace
+	pod.Metadata.Name = name
+	pod.Metadata.ResourceVersion = version
+	pod.Metadata.Labels = labels
+	return pod
+}
+
+// 记录收到的事件，格式是 类型:key@resourceVersion
+type recordingHandler struct {
+	events chan string
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{events: make(chan string, 100)}
+}
+
+func describe(obj interface{}) string {
+	key, _ := MetaNamespaceKeyFunc(obj)
+	return key + "@" + ResourceVersion(obj)
+}
+
+func (h *recordingHandler) OnAdd(obj interface{}) {
+	h.events <- "add:" + describe(obj)
+}
+
+func (h *recordingHandler) OnUpdate(oldObj interface{}, newObj interface{}) {
+	h.events <- "update:" + describe(newObj)
+}
+
+func (h *recordingHandler) OnDelete(obj interface{}) {
+	h.events <- "delete:" + describe(obj)
+}
+
+func (h *recordingHandler) expect(t *testing.T, expected ...string) {
+	t.Helper()
+	for _, e := range expected {
+		select {
+		case got := <-h.events:
+			if got != e {
+				t.Fatalf("expected event %s but got %s", e, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected event %s but got nothing", e)
+		}
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	indexer := NewIndexer(MetaNamespaceKeyFunc, Indexers{
+		NamespaceIndex: MetaNamespaceIndexFunc,
+		"app":          LabelIndexFunc("app"),
+	})
+	indexer.Add(newPod("default", "a", "1", map[string]string{"app": "nginx"}))
+	indexer.Add(newPod("default", "b", "2", map[string]string{"app": "redis"}))
+	indexer.Add(newPod("test", "c", "3", map[string]string{"app": "nginx"}))
+
+	if objs, _ := indexer.ByIndex(NamespaceIndex, "default"); len(objs) != 2 {
+		t.Errorf("expected 2 pods in default but got %d", len(objs))
+	}
+	if objs, _ := indexer.ByIndex("app", "nginx"); len(objs) != 2 || describe(objs[0]) != "default/a@1" || describe(objs[1]) != "test/c@3" {
+		t.Errorf("unexpected pods for app=nginx: %v", objs)
+	}
+
+	// 更新之后旧的索引被删除
+	indexer.Update(newPod("default", "a", "4", map[string]string{"app": "redis"}))
+	if objs, _ := indexer.ByIndex("app", "nginx"); len(objs) != 1 {
+		t.Errorf("expected 1 pod for app=nginx after update but got %d", len(objs))
+	}
+	if objs, _ := indexer.ByIndex("app", "redis"); len(objs) != 2 {
+		t.Errorf("expected 2 pods for app=redis after update but got %d", len(objs))
+	}
+
+	indexer.Delete(newPod("test", "c", "", nil))
+	if keys := indexer.ListKeys(); len(keys) != 2 || keys[0] != "default/a" || keys[1] != "default/b" {
+		t.Errorf("unexpected keys after delete: %v", keys)
+	}
+	if _, err := indexer.ByIndex("unknown", ""); err == nil {
+		t.Errorf("expected an error for an unknown index")
+	}
+}
+
+func TestInformerListAndWatch(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *fakeWatcher, 10)}
+	lw.setItems("10", newPod("default", "a", "5", nil), newPod("default", "b", "6", nil))
+
+	informer := NewSharedIndexInformer(lw, 0, nil)
+	handler := newRecordingHandler()
+	informer.AddEventHandler(handler)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+
+	if !WaitForCacheSync(stopCh, informer.HasSynced) {
+		t.Fatalf("expected the informer to sync")
+	}
+	handler.expect(t, "add:default/a@5", "add:default/b@6")
+
+	// 从list的resourceVersion开始watch
+	w := <-lw.watchers
+	w.result <- client.Event{Type: apiObject.WatchEventAdded, Object: newPod("default", "c", "11", nil)}
+	w.result <- client.Event{Type: apiObject.WatchEventModified, Object: newPod("default", "a", "12", nil)}
+	w.result <- client.Event{Type: apiObject.WatchEventDeleted, Object: newPod("default", "b", "13", nil)}
+	handler.expect(t, "add:default/c@11", "update:default/a@12", "delete:default/b@13")
+
+	if keys := informer.GetIndexer().ListKeys(); len(keys) != 2 || keys[0] != "default/a" || keys[1] != "default/c" {
+		t.Errorf("unexpected keys in cache: %v", keys)
+	}
+
+	// watch断开期间c被删除、a被修改、d被创建，重新list之后补发事件，没有变化的对象不通知
+	lw.setItems("20", newPod("default", "a", "15", nil), newPod("default", "d", "16", nil))
+	w.close()
+	w = <-lw.watchers
+
+	events := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case e := <-handler.events:
+			events[e] = true
+		case <-time.After(time.Second):
+			t.Fatalf("expected events after relist, got %v", events)
+		}
+	}
+	for _, e := range []string{"update:default/a@15", "add:default/d@16", "delete:default/c@11"} {
+		if !events[e] {
+			t.Errorf("expected event %s after relist, got %v", e, events)
+		}
+	}
+
+	lw.lock.Lock()
+	versions := append([]string{}, lw.watchVersions...)
+	lw.lock.Unlock()
+	if len(versions) != 2 || versions[0] != "10" || versions[1] != "20" {
+		t.Errorf("expected to watch from 10 and 20 but got %v", versions)
+	}
+
+	// 同步之后加入的handler会先收到已有的对象
+	late := newRecordingHandler()
+	informer.AddEventHandler(late)
+	late.expect(t, "add:default/a@15", "add:default/d@16")
+	w.close()
+}
+
+func TestInformerResync(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *fakeWatcher, 10)}
+	lw.setItems("1", newPod("default", "a", "1", nil))
+
+	informer := NewSharedIndexInformer(lw, 50*time.Millisecond, nil)
+	handler := newRecordingHandler()
+	informer.AddEventHandler(handler)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+
+	// resync的时候把缓存里面的对象作为update再通知一遍
+	handler.expect(t, "add:default/a@1", "update:default/a@1", "update:default/a@1")
+}
+
+func TestInformerWatchExpired(t *testing.T) {
+	lw := &fakeListerWatcher{watchers: make(chan *fakeWatcher, 10)}
+	lw.setItems("1", newPod("default", "a", "1", nil))
+
+	informer := NewSharedIndexInformer(lw, 0, nil)
+	handler := newRecordingHandler()
+	informer.AddEventHandler(handler)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go informer.Run(stopCh)
+	handler.expect(t, "add:default/a@1")
+
+	// resourceVersion过期之后不等待重试，马上重新list
+	lw.setItems("30", newPod("default", "a", "25", nil))
+	w := <-lw.watchers
+	w.result <- client.Event{Type: apiObject.WatchEventError, Object: &apiObject.WatchStatus{Code: 410, Reason: apiObject.WatchReasonExpired}}
+	handler.expect(t, "update:default/a@25")
+
+	select {
+	case w = <-lw.watchers:
+	case <-time.After(DefaultRetryPeriod / 2):
+		t.Fatalf("expected to watch again right after the relist")
+	}
+	lw.lock.Lock()
+	versions := append([]string{}, lw.watchVersions...)
+	lw.lock.Unlock()
+	if len(versions) != 2 || versions[1] != "30" {
+		t.Errorf("expected to watch from 30 after relist but got %v", versions)
+	}
+	w.close()
+}
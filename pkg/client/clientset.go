@@ -0,0 +1,78 @@
+package client
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
+	"reflect"
+)
+
+// Clientset 包含每种资源的typed client，所有组件通过它访问APIServer，不再自己拼URL
+// 请求复用netrequest里面的http.Client，TLS和token的配置和其他请求一样
+type Clientset struct {
+	prefix string
+}
+
+// 使用配置里面的APIServer地址
+func NewClientset() *Clientset {
+	return NewClientsetForURL(config.GetAPIServerURLPrefix())
+}
+
+// 指定APIServer的地址，比如 http://127.0.0.1:8090，发往这个地址的请求会带上token
+func NewClientsetForURL(prefix string) *Clientset {
+	netrequest.AddAPIServerURLPrefix(prefix)
+	return &Clientset{prefix: prefix}
+}
+
+func (cs *Clientset) Pods() PodInterface {
+	return &pods{cs.resource(apiObject.PodKind, apiObject.PodStore{}, true,
+		config.PodsURL, config.PodSpecURL, config.PodSpecStatusURL, config.GlobalPodsURL)}
+}
+
+func (cs *Clientset) Services() ServiceInterface {
+	return &services{cs.resource(apiObject.ServiceKind, apiObject.ServiceStore{}, true,
+		config.ServiceURL, config.ServiceSpecURL, config.ServiceSpecStatusURL, "")}
+}
+
+func (cs *Clientset) ReplicaSets() ReplicaSetInterface {
+	return &replicaSets{cs.resource(apiObject.ReplicaSetKind, apiObject.ReplicaSetStore{}, true,
+		config.ReplicaSetsURL, config.ReplicaSetSpecURL, config.ReplicaSetSpecStatusURL, config.GlobalReplicaSetsURL)}
+}
+
+func (cs *Clientset) HPAs() HPAInterface {
+	return &hpas{cs.resource(apiObject.HpaKind, apiObject.HPAStore{}, true,
+		config.HPAURL, config.HPASpecURL, config.HPASpecStatusURL, config.GlobalHPAURL)}
+}
+
+func (cs *Clientset) Jobs() JobInterface {
+	return &jobs{cs.resource(apiObject.JobKind, apiObject.JobStore{}, true,
+		config.JobsURL, config.JobSpecURL, config.JobSpecStatusURL, "")}
+}
+
+func (cs *Clientset) Functions() FunctionInterface {
+	return &functions{cs.resource(apiObject.FunctionKind, apiObject.Function{}, true,
+		config.FunctionURL, config.FunctionSpecURL, "", config.GlobalFunctionsURL)}
+}
+
+func (cs *Clientset) Workflows() WorkflowInterface {
+	return &workflows{cs.resource(apiObject.WorkflowKind, apiObject.WorkflowStore{}, true,
+		config.WorkflowURL, config.WorkflowSpecURL, config.WorkflowSpecStatusURL, config.GlobalWorkflowsURL)}
+}
+
+func (cs *Clientset) Nodes() NodeInterface {
+	return &nodes{cs.resource(apiObject.NodeKind, apiObject.NodeStore{}, false,
+		config.NodesURL, config.NodeSpecURL, config.NodeSpecStatusURL, "")}
+}
+
+func (cs *Clientset) resource(kind string, obj interface{}, namespaced bool, listURL string, specURL string, statusURL string, globalURL string) *resourceClient {
+	return &resourceClient{
+		prefix:     cs.prefix,
+		kind:       kind,
+		namespaced: namespaced,
+		listURL:    listURL,
+		specURL:    specURL,
+		statusURL:  statusURL,
+		globalURL:  globalURL,
+		objType:    reflect.TypeOf(obj),
+	}
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIServer返回了非预期的状态码
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s, code: %d", e.Message, e.Code)
+}
+
+func newStatusError(code int, action string, kind string) error {
+	return &StatusError{
+		Code:    code,
+		Message: action + " " + kind + " failed",
+	}
+}
+
+// 对象不存在
+func IsNotFound(err error) bool {
+	return statusCode(err) == http.StatusNotFound
+}
+
+// 对象已经被别人修改过，需要重新读取之后再更新
+func IsConflict(err error) bool {
+	return statusCode(err) == http.StatusConflict
+}
+
+func statusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return 0
+}
@@ -0,0 +1,59 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// FunctionInterface 读写Function，namespace为空的时候使用default
+type FunctionInterface interface {
+	Get(namespace string, name string) (*apiObject.Function, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的Function
+	List(namespace string, opts ListOptions) ([]apiObject.Function, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.Function, string, error)
+	Create(function *apiObject.Function) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(function *apiObject.Function) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type functions struct {
+	client *resourceClient
+}
+
+func (c *functions) Get(namespace string, name string) (*apiObject.Function, error) {
+	function := &apiObject.Function{}
+	if err := c.client.get(namespace, name, function); err != nil {
+		return nil, err
+	}
+	return function, nil
+}
+
+func (c *functions) List(namespace string, opts ListOptions) ([]apiObject.Function, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *functions) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.Function, string, error) {
+	list := make([]apiObject.Function, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *functions) Create(function *apiObject.Function) error {
+	return c.client.create(function.Metadata.Namespace, function)
+}
+
+func (c *functions) Update(function *apiObject.Function) error {
+	return c.client.update(function.Metadata.Namespace, function.Metadata.Name, function)
+}
+
+func (c *functions) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *functions) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
@@ -0,0 +1,64 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// HPAInterface 读写HPA，namespace为空的时候使用default
+type HPAInterface interface {
+	Get(namespace string, name string) (*apiObject.HPAStore, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的HPA
+	List(namespace string, opts ListOptions) ([]apiObject.HPAStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.HPAStore, string, error)
+	Create(hpa *apiObject.HPA) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(hpa *apiObject.HPAStore) error
+	UpdateStatus(namespace string, name string, status *apiObject.HPAStatus) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type hpas struct {
+	client *resourceClient
+}
+
+func (c *hpas) Get(namespace string, name string) (*apiObject.HPAStore, error) {
+	hpa := &apiObject.HPAStore{}
+	if err := c.client.get(namespace, name, hpa); err != nil {
+		return nil, err
+	}
+	return hpa, nil
+}
+
+func (c *hpas) List(namespace string, opts ListOptions) ([]apiObject.HPAStore, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *hpas) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.HPAStore, string, error) {
+	list := make([]apiObject.HPAStore, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *hpas) Create(hpa *apiObject.HPA) error {
+	return c.client.create(hpa.Metadata.Namespace, hpa)
+}
+
+func (c *hpas) Update(hpa *apiObject.HPAStore) error {
+	return c.client.update(hpa.Metadata.Namespace, hpa.Metadata.Name, hpa)
+}
+
+func (c *hpas) UpdateStatus(namespace string, name string, status *apiObject.HPAStatus) error {
+	return c.client.updateStatus(namespace, name, status)
+}
+
+func (c *hpas) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *hpas) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
@@ -0,0 +1,114 @@
+package informers
+
+import (
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+	"miniK8s/pkg/config"
+	"sync"
+	"time"
+)
+
+// SharedInformerFactory 按照资源的种类创建informer，同一种资源只创建一个，所有使用者共享缓存和watch连接
+// informer监听的是所有namespace，所以只支持有全局URL的资源
+type SharedInformerFactory interface {
+	Pods() PodInformer
+	ReplicaSets() ReplicaSetInformer
+	HPAs() HPAInformer
+	Functions() FunctionInformer
+	Workflows() WorkflowInformer
+	Nodes() NodeInformer
+
+	// 启动所有已经创建的informer，可以多次调用，只会启动新创建的informer
+	Start(stopCh <-chan struct{})
+	// 等待所有已经启动的informer同步完成
+	WaitForCacheSync(stopCh <-chan struct{}) bool
+}
+
+type sharedInformerFactory struct {
+	clientset    *client.Clientset
+	resyncPeriod time.Duration
+	// 所有informer的List和Watch都带上这些selector
+	listOptions client.ListOptions
+
+	lock      sync.Mutex
+	informers map[string]cache.SharedIndexInformer
+	started   map[string]bool
+}
+
+// resyncPeriod为0的时候不定期resync
+func NewSharedInformerFactory(clientset *client.Clientset, resyncPeriod time.Duration) SharedInformerFactory {
+	return NewFilteredSharedInformerFactory(clientset, resyncPeriod, client.ListOptions{})
+}
+
+// 只缓存满足selector的对象，比如serverless只关心带有function label的Pod
+func NewFilteredSharedInformerFactory(clientset *client.Clientset, resyncPeriod time.Duration, opts client.ListOptions) SharedInformerFactory {
+	return &sharedInformerFactory{
+		clientset:    clientset,
+		resyncPeriod: resyncPeriod,
+		listOptions:  opts,
+		informers:    make(map[string]cache.SharedIndexInformer),
+		started:      make(map[string]bool),
+	}
+}
+
+// 同一种资源只创建一次informer
+func (f *sharedInformerFactory) informerFor(kind string, newListWatch func(opts client.ListOptions) cache.ListerWatcher) cache.SharedIndexInformer {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if informer, ok := f.informers[kind]; ok {
+		return informer
+	}
+	informer := cache.NewSharedIndexInformer(newListWatch(f.listOptions), f.resyncPeriod, nil)
+	f.informers[kind] = informer
+	return informer
+}
+
+func (f *sharedInformerFactory) Start(stopCh <-chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for kind, informer := range f.informers {
+		if !f.started[kind] {
+			go informer.Run(stopCh)
+			f.started[kind] = true
+		}
+	}
+}
+
+func (f *sharedInformerFactory) WaitForCacheSync(stopCh <-chan struct{}) bool {
+	f.lock.Lock()
+	syncs := make([]func() bool, 0)
+	for kind, informer := range f.informers {
+		if f.started[kind] {
+			syncs = append(syncs, informer.HasSynced)
+		}
+	}
+	f.lock.Unlock()
+	return cache.WaitForCacheSync(stopCh, syncs...)
+}
+
+// 把typed client的List结果转换成对象的指针
+func toObjects(length int, item func(i int) interface{}) []interface{} {
+	objs := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		objs = append(objs, item(i))
+	}
+	return objs
+}
+
+// 从缓存里面读取某个namespace下面的对象，namespace为NamespaceAll的时候读取所有的对象
+func listByNamespace(indexer cache.Indexer, namespace string) []interface{} {
+	if namespace == client.NamespaceAll {
+		return indexer.List()
+	}
+	objs, _ := indexer.ByIndex(cache.NamespaceIndex, namespace)
+	return objs
+}
+
+// 从缓存里面读取一个对象，namespace为空的时候使用default
+func getByName(indexer cache.Indexer, namespace string, name string) (interface{}, bool) {
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	obj, ok, _ := indexer.GetByKey(namespace + "/" + name)
+	return obj, ok
+}
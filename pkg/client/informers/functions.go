@@ -0,0 +1,75 @@
+package informers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+)
+
+// FunctionInformer 提供Function的informer和从缓存读取的lister
+type FunctionInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() FunctionLister
+}
+
+// FunctionLister 从informer的缓存里面读取Function，不访问APIServer
+// 返回的是缓存里面对象的浅拷贝，不要修改里面的map和切片
+type FunctionLister interface {
+	// namespace为client.NamespaceAll的时候返回所有namespace下面的Function
+	List(namespace string) []apiObject.Function
+	Get(namespace string, name string) (*apiObject.Function, bool)
+}
+
+type functionInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (f *sharedInformerFactory) Functions() FunctionInformer {
+	return &functionInformer{factory: f}
+}
+
+func (i *functionInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apiObject.FunctionKind, func(opts client.ListOptions) cache.ListerWatcher {
+		functionClient := i.factory.clientset.Functions()
+		return &cache.ListWatch{
+			ListFunc: func() ([]interface{}, string, error) {
+				list, resourceVersion, err := functionClient.ListWithResourceVersion(client.NamespaceAll, opts)
+				if err != nil {
+					return nil, "", err
+				}
+				return toObjects(len(list), func(index int) interface{} { return &list[index] }), resourceVersion, nil
+			},
+			WatchFunc: func(resourceVersion string) (client.Watcher, error) {
+				watchOpts := opts
+				watchOpts.ResourceVersion = resourceVersion
+				return functionClient.Watch(client.NamespaceAll, watchOpts)
+			},
+		}
+	})
+}
+
+func (i *functionInformer) Lister() FunctionLister {
+	return &functionLister{indexer: i.Informer().GetIndexer()}
+}
+
+type functionLister struct {
+	indexer cache.Indexer
+}
+
+func (l *functionLister) List(namespace string) []apiObject.Function {
+	objs := listByNamespace(l.indexer, namespace)
+	list := make([]apiObject.Function, 0, len(objs))
+	for _, obj := range objs {
+		list = append(list, *obj.(*apiObject.Function))
+	}
+	return list
+}
+
+func (l *functionLister) Get(namespace string, name string) (*apiObject.Function, bool) {
+	obj, ok := getByName(l.indexer, namespace, name)
+	if !ok {
+		return nil, false
+	}
+	function := *obj.(*apiObject.Function)
+	return &function, true
+}
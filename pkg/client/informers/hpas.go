@@ -0,0 +1,75 @@
+package informers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+)
+
+// HPAInformer 提供HPA的informer和从缓存读取的lister
+type HPAInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() HPALister
+}
+
+// HPALister 从informer的缓存里面读取HPA，不访问APIServer
+// 返回的是缓存里面对象的浅拷贝，不要修改里面的map和切片
+type HPALister interface {
+	// namespace为client.NamespaceAll的时候返回所有namespace下面的HPA
+	List(namespace string) []apiObject.HPAStore
+	Get(namespace string, name string) (*apiObject.HPAStore, bool)
+}
+
+type hpaInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (f *sharedInformerFactory) HPAs() HPAInformer {
+	return &hpaInformer{factory: f}
+}
+
+func (i *hpaInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apiObject.HpaKind, func(opts client.ListOptions) cache.ListerWatcher {
+		hpaClient := i.factory.clientset.HPAs()
+		return &cache.ListWatch{
+			ListFunc: func() ([]interface{}, string, error) {
+				list, resourceVersion, err := hpaClient.ListWithResourceVersion(client.NamespaceAll, opts)
+				if err != nil {
+					return nil, "", err
+				}
+				return toObjects(len(list), func(index int) interface{} { return &list[index] }), resourceVersion, nil
+			},
+			WatchFunc: func(resourceVersion string) (client.Watcher, error) {
+				watchOpts := opts
+				watchOpts.ResourceVersion = resourceVersion
+				return hpaClient.Watch(client.NamespaceAll, watchOpts)
+			},
+		}
+	})
+}
+
+func (i *hpaInformer) Lister() HPALister {
+	return &hpaLister{indexer: i.Informer().GetIndexer()}
+}
+
+type hpaLister struct {
+	indexer cache.Indexer
+}
+
+func (l *hpaLister) List(namespace string) []apiObject.HPAStore {
+	objs := listByNamespace(l.indexer, namespace)
+	list := make([]apiObject.HPAStore, 0, len(objs))
+	for _, obj := range objs {
+		list = append(list, *obj.(*apiObject.HPAStore))
+	}
+	return list
+}
+
+func (l *hpaLister) Get(namespace string, name string) (*apiObject.HPAStore, bool) {
+	obj, ok := getByName(l.indexer, namespace, name)
+	if !ok {
+		return nil, false
+	}
+	hpa := *obj.(*apiObject.HPAStore)
+	return &hpa, true
+}
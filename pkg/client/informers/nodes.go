@@ -0,0 +1,74 @@
+package informers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+)
+
+// NodeInformer 提供Node的informer和从缓存读取的lister
+type NodeInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() NodeLister
+}
+
+// NodeLister 从informer的缓存里面读取Node，不访问APIServer
+// 返回的是缓存里面对象的浅拷贝，不要修改里面的map和切片
+type NodeLister interface {
+	List() []apiObject.NodeStore
+	Get(name string) (*apiObject.NodeStore, bool)
+}
+
+type nodeInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (f *sharedInformerFactory) Nodes() NodeInformer {
+	return &nodeInformer{factory: f}
+}
+
+func (i *nodeInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apiObject.NodeKind, func(opts client.ListOptions) cache.ListerWatcher {
+		nodeClient := i.factory.clientset.Nodes()
+		return &cache.ListWatch{
+			ListFunc: func() ([]interface{}, string, error) {
+				list, resourceVersion, err := nodeClient.ListWithResourceVersion(opts)
+				if err != nil {
+					return nil, "", err
+				}
+				return toObjects(len(list), func(index int) interface{} { return &list[index] }), resourceVersion, nil
+			},
+			WatchFunc: func(resourceVersion string) (client.Watcher, error) {
+				watchOpts := opts
+				watchOpts.ResourceVersion = resourceVersion
+				return nodeClient.Watch(watchOpts)
+			},
+		}
+	})
+}
+
+func (i *nodeInformer) Lister() NodeLister {
+	return &nodeLister{indexer: i.Informer().GetIndexer()}
+}
+
+type nodeLister struct {
+	indexer cache.Indexer
+}
+
+func (l *nodeLister) List() []apiObject.NodeStore {
+	objs := l.indexer.List()
+	list := make([]apiObject.NodeStore, 0, len(objs))
+	for _, obj := range objs {
+		list = append(list, *obj.(*apiObject.NodeStore))
+	}
+	return list
+}
+
+func (l *nodeLister) Get(name string) (*apiObject.NodeStore, bool) {
+	obj, ok, _ := l.indexer.GetByKey(name)
+	if !ok {
+		return nil, false
+	}
+	node := *obj.(*apiObject.NodeStore)
+	return &node, true
+}
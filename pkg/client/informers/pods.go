@@ -0,0 +1,75 @@
+package informers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+)
+
+// PodInformer 提供Pod的informer和从缓存读取的lister
+type PodInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() PodLister
+}
+
+// PodLister 从informer的缓存里面读取Pod，不访问APIServer
+// 返回的是缓存里面对象的浅拷贝，不要修改里面的map和切片
+type PodLister interface {
+	// namespace为client.NamespaceAll的时候返回所有namespace下面的Pod
+	List(namespace string) []apiObject.PodStore
+	Get(namespace string, name string) (*apiObject.PodStore, bool)
+}
+
+type podInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (f *sharedInformerFactory) Pods() PodInformer {
+	return &podInformer{factory: f}
+}
+
+func (i *podInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apiObject.PodKind, func(opts client.ListOptions) cache.ListerWatcher {
+		podClient := i.factory.clientset.Pods()
+		return &cache.ListWatch{
+			ListFunc: func() ([]interface{}, string, error) {
+				list, resourceVersion, err := podClient.ListWithResourceVersion(client.NamespaceAll, opts)
+				if err != nil {
+					return nil, "", err
+				}
+				return toObjects(len(list), func(index int) interface{} { return &list[index] }), resourceVersion, nil
+			},
+			WatchFunc: func(resourceVersion string) (client.Watcher, error) {
+				watchOpts := opts
+				watchOpts.ResourceVersion = resourceVersion
+				return podClient.Watch(client.NamespaceAll, watchOpts)
+			},
+		}
+	})
+}
+
+func (i *podInformer) Lister() PodLister {
+	return &podLister{indexer: i.Informer().GetIndexer()}
+}
+
+type podLister struct {
+	indexer cache.Indexer
+}
+
+func (l *podLister) List(namespace string) []apiObject.PodStore {
+	objs := listByNamespace(l.indexer, namespace)
+	list := make([]apiObject.PodStore, 0, len(objs))
+	for _, obj := range objs {
+		list = append(list, *obj.(*apiObject.PodStore))
+	}
+	return list
+}
+
+func (l *podLister) Get(namespace string, name string) (*apiObject.PodStore, bool) {
+	obj, ok := getByName(l.indexer, namespace, name)
+	if !ok {
+		return nil, false
+	}
+	pod := *obj.(*apiObject.PodStore)
+	return &pod, true
+}
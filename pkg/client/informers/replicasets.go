@@ -0,0 +1,75 @@
+package informers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+)
+
+// ReplicaSetInformer 提供ReplicaSet的informer和从缓存读取的lister
+type ReplicaSetInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() ReplicaSetLister
+}
+
+// ReplicaSetLister 从informer的缓存里面读取ReplicaSet，不访问APIServer
+// 返回的是缓存里面对象的浅拷贝，不要修改里面的map和切片
+type ReplicaSetLister interface {
+	// namespace为client.NamespaceAll的时候返回所有namespace下面的ReplicaSet
+	List(namespace string) []apiObject.ReplicaSetStore
+	Get(namespace string, name string) (*apiObject.ReplicaSetStore, bool)
+}
+
+type replicaSetInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (f *sharedInformerFactory) ReplicaSets() ReplicaSetInformer {
+	return &replicaSetInformer{factory: f}
+}
+
+func (i *replicaSetInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apiObject.ReplicaSetKind, func(opts client.ListOptions) cache.ListerWatcher {
+		replicaSetClient := i.factory.clientset.ReplicaSets()
+		return &cache.ListWatch{
+			ListFunc: func() ([]interface{}, string, error) {
+				list, resourceVersion, err := replicaSetClient.ListWithResourceVersion(client.NamespaceAll, opts)
+				if err != nil {
+					return nil, "", err
+				}
+				return toObjects(len(list), func(index int) interface{} { return &list[index] }), resourceVersion, nil
+			},
+			WatchFunc: func(resourceVersion string) (client.Watcher, error) {
+				watchOpts := opts
+				watchOpts.ResourceVersion = resourceVersion
+				return replicaSetClient.Watch(client.NamespaceAll, watchOpts)
+			},
+		}
+	})
+}
+
+func (i *replicaSetInformer) Lister() ReplicaSetLister {
+	return &replicaSetLister{indexer: i.Informer().GetIndexer()}
+}
+
+type replicaSetLister struct {
+	indexer cache.Indexer
+}
+
+func (l *replicaSetLister) List(namespace string) []apiObject.ReplicaSetStore {
+	objs := listByNamespace(l.indexer, namespace)
+	list := make([]apiObject.ReplicaSetStore, 0, len(objs))
+	for _, obj := range objs {
+		list = append(list, *obj.(*apiObject.ReplicaSetStore))
+	}
+	return list
+}
+
+func (l *replicaSetLister) Get(namespace string, name string) (*apiObject.ReplicaSetStore, bool) {
+	obj, ok := getByName(l.indexer, namespace, name)
+	if !ok {
+		return nil, false
+	}
+	replicaSet := *obj.(*apiObject.ReplicaSetStore)
+	return &replicaSet, true
+}
@@ -0,0 +1,75 @@
+package informers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+)
+
+// WorkflowInformer 提供Workflow的informer和从缓存读取的lister
+type WorkflowInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() WorkflowLister
+}
+
+// WorkflowLister 从informer的缓存里面读取Workflow，不访问APIServer
+// 返回的是缓存里面对象的浅拷贝，不要修改里面的map和切片
+type WorkflowLister interface {
+	// namespace为client.NamespaceAll的时候返回所有namespace下面的Workflow
+	List(namespace string) []apiObject.WorkflowStore
+	Get(namespace string, name string) (*apiObject.WorkflowStore, bool)
+}
+
+type workflowInformer struct {
+	factory *sharedInformerFactory
+}
+
+func (f *sharedInformerFactory) Workflows() WorkflowInformer {
+	return &workflowInformer{factory: f}
+}
+
+func (i *workflowInformer) Informer() cache.SharedIndexInformer {
+	return i.factory.informerFor(apiObject.WorkflowKind, func(opts client.ListOptions) cache.ListerWatcher {
+		workflowClient := i.factory.clientset.Workflows()
+		return &cache.ListWatch{
+			ListFunc: func() ([]interface{}, string, error) {
+				list, resourceVersion, err := workflowClient.ListWithResourceVersion(client.NamespaceAll, opts)
+				if err != nil {
+					return nil, "", err
+				}
+				return toObjects(len(list), func(index int) interface{} { return &list[index] }), resourceVersion, nil
+			},
+			WatchFunc: func(resourceVersion string) (client.Watcher, error) {
+				watchOpts := opts
+				watchOpts.ResourceVersion = resourceVersion
+				return workflowClient.Watch(client.NamespaceAll, watchOpts)
+			},
+		}
+	})
+}
+
+func (i *workflowInformer) Lister() WorkflowLister {
+	return &workflowLister{indexer: i.Informer().GetIndexer()}
+}
+
+type workflowLister struct {
+	indexer cache.Indexer
+}
+
+func (l *workflowLister) List(namespace string) []apiObject.WorkflowStore {
+	objs := listByNamespace(l.indexer, namespace)
+	list := make([]apiObject.WorkflowStore, 0, len(objs))
+	for _, obj := range objs {
+		list = append(list, *obj.(*apiObject.WorkflowStore))
+	}
+	return list
+}
+
+func (l *workflowLister) Get(namespace string, name string) (*apiObject.WorkflowStore, bool) {
+	obj, ok := getByName(l.indexer, namespace, name)
+	if !ok {
+		return nil, false
+	}
+	workflow := *obj.(*apiObject.WorkflowStore)
+	return &workflow, true
+}
@@ -0,0 +1,64 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// JobInterface 读写Job，namespace为空的时候使用default
+type JobInterface interface {
+	Get(namespace string, name string) (*apiObject.JobStore, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的Job
+	List(namespace string, opts ListOptions) ([]apiObject.JobStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.JobStore, string, error)
+	Create(job *apiObject.Job) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(job *apiObject.JobStore) error
+	UpdateStatus(namespace string, name string, status *apiObject.JobStatus) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type jobs struct {
+	client *resourceClient
+}
+
+func (c *jobs) Get(namespace string, name string) (*apiObject.JobStore, error) {
+	job := &apiObject.JobStore{}
+	if err := c.client.get(namespace, name, job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (c *jobs) List(namespace string, opts ListOptions) ([]apiObject.JobStore, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *jobs) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.JobStore, string, error) {
+	list := make([]apiObject.JobStore, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *jobs) Create(job *apiObject.Job) error {
+	return c.client.create(job.Metadata.Namespace, job)
+}
+
+func (c *jobs) Update(job *apiObject.JobStore) error {
+	return c.client.update(job.Metadata.Namespace, job.Metadata.Name, job)
+}
+
+func (c *jobs) UpdateStatus(namespace string, name string, status *apiObject.JobStatus) error {
+	return c.client.updateStatus(namespace, name, status)
+}
+
+func (c *jobs) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *jobs) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
@@ -0,0 +1,47 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// NodeInterface 读取Node，Node由kubelet注册，这里只提供读取和更新状态
+type NodeInterface interface {
+	Get(name string) (*apiObject.NodeStore, error)
+	List(opts ListOptions) ([]apiObject.NodeStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(opts ListOptions) ([]apiObject.NodeStore, string, error)
+	UpdateStatus(name string, status *apiObject.NodeStatus) error
+	Watch(opts ListOptions) (Watcher, error)
+}
+
+type nodes struct {
+	client *resourceClient
+}
+
+func (c *nodes) Get(name string) (*apiObject.NodeStore, error) {
+	node := &apiObject.NodeStore{}
+	if err := c.client.get("", name, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+func (c *nodes) List(opts ListOptions) ([]apiObject.NodeStore, error) {
+	list, _, err := c.ListWithResourceVersion(opts)
+	return list, err
+}
+
+func (c *nodes) ListWithResourceVersion(opts ListOptions) ([]apiObject.NodeStore, string, error) {
+	list := make([]apiObject.NodeStore, 0)
+	resourceVersion, err := c.client.list("", opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *nodes) UpdateStatus(name string, status *apiObject.NodeStatus) error {
+	return c.client.updateStatus("", name, status)
+}
+
+func (c *nodes) Watch(opts ListOptions) (Watcher, error) {
+	return c.client.watch("", opts)
+}
@@ -0,0 +1,64 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// PodInterface 读写Pod，namespace为空的时候使用default
+type PodInterface interface {
+	Get(namespace string, name string) (*apiObject.PodStore, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的Pod
+	List(namespace string, opts ListOptions) ([]apiObject.PodStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.PodStore, string, error)
+	Create(pod *apiObject.Pod) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(pod *apiObject.PodStore) error
+	UpdateStatus(namespace string, name string, status *apiObject.PodStatus) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type pods struct {
+	client *resourceClient
+}
+
+func (c *pods) Get(namespace string, name string) (*apiObject.PodStore, error) {
+	pod := &apiObject.PodStore{}
+	if err := c.client.get(namespace, name, pod); err != nil {
+		return nil, err
+	}
+	return pod, nil
+}
+
+func (c *pods) List(namespace string, opts ListOptions) ([]apiObject.PodStore, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *pods) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.PodStore, string, error) {
+	list := make([]apiObject.PodStore, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *pods) Create(pod *apiObject.Pod) error {
+	return c.client.create(pod.Metadata.Namespace, pod)
+}
+
+func (c *pods) Update(pod *apiObject.PodStore) error {
+	return c.client.update(pod.Metadata.Namespace, pod.Metadata.Name, pod)
+}
+
+func (c *pods) UpdateStatus(namespace string, name string, status *apiObject.PodStatus) error {
+	return c.client.updateStatus(namespace, name, status)
+}
+
+func (c *pods) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *pods) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
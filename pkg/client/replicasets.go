@@ -0,0 +1,64 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// ReplicaSetInterface 读写ReplicaSet，namespace为空的时候使用default
+type ReplicaSetInterface interface {
+	Get(namespace string, name string) (*apiObject.ReplicaSetStore, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的ReplicaSet
+	List(namespace string, opts ListOptions) ([]apiObject.ReplicaSetStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.ReplicaSetStore, string, error)
+	Create(replicaSet *apiObject.ReplicaSet) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(replicaSet *apiObject.ReplicaSetStore) error
+	UpdateStatus(namespace string, name string, status *apiObject.ReplicaSetStatus) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type replicaSets struct {
+	client *resourceClient
+}
+
+func (c *replicaSets) Get(namespace string, name string) (*apiObject.ReplicaSetStore, error) {
+	replicaSet := &apiObject.ReplicaSetStore{}
+	if err := c.client.get(namespace, name, replicaSet); err != nil {
+		return nil, err
+	}
+	return replicaSet, nil
+}
+
+func (c *replicaSets) List(namespace string, opts ListOptions) ([]apiObject.ReplicaSetStore, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *replicaSets) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.ReplicaSetStore, string, error) {
+	list := make([]apiObject.ReplicaSetStore, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *replicaSets) Create(replicaSet *apiObject.ReplicaSet) error {
+	return c.client.create(replicaSet.Metadata.Namespace, replicaSet)
+}
+
+func (c *replicaSets) Update(replicaSet *apiObject.ReplicaSetStore) error {
+	return c.client.update(replicaSet.Metadata.Namespace, replicaSet.Metadata.Name, replicaSet)
+}
+
+func (c *replicaSets) UpdateStatus(namespace string, name string, status *apiObject.ReplicaSetStatus) error {
+	return c.client.updateStatus(namespace, name, status)
+}
+
+func (c *replicaSets) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *replicaSets) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
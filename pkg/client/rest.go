@@ -0,0 +1,196 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// 表示所有的namespace，List和Watch的时候使用全局的URL
+const NamespaceAll = ""
+
+// List和Watch的参数
+type ListOptions struct {
+	// 和kubectl一样的selector，比如 app=nginx,tier!=db
+	LabelSelector string
+	FieldSelector string
+	// Watch的时候只推送这个版本之后的变化，为空的时候先推送所有的对象
+	ResourceVersion string
+}
+
+// 某一种资源的增删改查，URL都来自config，typed client在它上面包装成具体的类型
+type resourceClient struct {
+	// APIServer的地址，比如 http://127.0.0.1:8090
+	prefix string
+	kind   string
+
+	// 为false的时候URL里面没有namespace
+	namespaced bool
+	listURL    string
+	specURL    string
+	// 为空的时候不支持对应的操作
+	statusURL string
+	globalURL string
+
+	// 读取的时候反序列化的类型，比如PodStore
+	objType reflect.Type
+}
+
+// 把URL里面的namespace和name替换成具体的值
+func (c *resourceClient) url(template string, namespace string, name string) string {
+	uri := c.prefix + template
+	if c.namespaced {
+		if namespace == "" {
+			namespace = config.DefaultNamespace
+		}
+		uri = stringutil.Replace(uri, config.URL_PARAM_NAMESPACE_PART, namespace)
+	}
+	if name != "" {
+		uri = stringutil.Replace(uri, config.URL_PARAM_NAME_PART, name)
+	}
+	return uri
+}
+
+// list和watch的URL，namespace为NamespaceAll的时候使用全局的URL
+func (c *resourceClient) collectionURL(namespace string, opts ListOptions, watch bool) (string, error) {
+	uri := ""
+	switch {
+	case !c.namespaced:
+		uri = c.prefix + c.listURL
+	case namespace != NamespaceAll:
+		uri = c.url(c.listURL, namespace, "")
+	case c.globalURL != "":
+		uri = c.prefix + c.globalURL
+	default:
+		return "", errors.New(c.kind + " can not be listed across all namespaces")
+	}
+
+	query := url.Values{}
+	if opts.LabelSelector != "" {
+		query.Set(config.URL_QUERY_LABEL_SELECTOR, opts.LabelSelector)
+	}
+	if opts.FieldSelector != "" {
+		query.Set(config.URL_QUERY_FIELD_SELECTOR, opts.FieldSelector)
+	}
+	if watch {
+		query.Set(config.URL_QUERY_WATCH, "true")
+		if opts.ResourceVersion != "" {
+			query.Set(config.URL_QUERY_RESOURCE_VERSION, opts.ResourceVersion)
+		}
+	}
+	if len(query) > 0 {
+		uri += "?" + query.Encode()
+	}
+	return uri, nil
+}
+
+// 读取一个对象到target中
+func (c *resourceClient) get(namespace string, name string, target interface{}) error {
+	code, err := netrequest.GetRequestByTarget(c.url(c.specURL, namespace, name), target, "data")
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return newStatusError(code, "get", c.kind)
+	}
+	return nil
+}
+
+// 读取所有满足条件的对象到target指向的切片中，返回读取时的resourceVersion，可以用来接着watch
+func (c *resourceClient) list(namespace string, opts ListOptions, target interface{}) (string, error) {
+	uri, err := c.collectionURL(namespace, opts, false)
+	if err != nil {
+		return "", err
+	}
+
+	code, res, err := netrequest.GetRequest(uri)
+	if err != nil {
+		return "", err
+	}
+	if code != http.StatusOK {
+		return "", newStatusError(code, "list", c.kind)
+	}
+
+	data, ok := res["data"]
+	if !ok || data == nil {
+		return "", errors.New("list " + c.kind + " failed, resp[data] is nil")
+	}
+	if err := json.Unmarshal([]byte(fmt.Sprint(data)), target); err != nil {
+		return "", err
+	}
+
+	resourceVersion, _ := res[config.URL_QUERY_RESOURCE_VERSION].(string)
+	return resourceVersion, nil
+}
+
+func (c *resourceClient) create(namespace string, obj interface{}) error {
+	code, _, err := netrequest.PostRequestByTarget(c.url(c.listURL, namespace, ""), obj)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusCreated {
+		return newStatusError(code, "create", c.kind)
+	}
+	return nil
+}
+
+// obj里面带上resourceVersion的时候，对象被别人修改过会返回409
+func (c *resourceClient) update(namespace string, name string, obj interface{}) error {
+	code, _, err := netrequest.PutRequestByTarget(c.url(c.specURL, namespace, name), obj)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return newStatusError(code, "update", c.kind)
+	}
+	return nil
+}
+
+func (c *resourceClient) updateStatus(namespace string, name string, status interface{}) error {
+	if c.statusURL == "" {
+		return errors.New(c.kind + " has no status")
+	}
+	code, _, err := netrequest.PutRequestByTarget(c.url(c.statusURL, namespace, name), status)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return newStatusError(code, "update status of", c.kind)
+	}
+	return nil
+}
+
+// 对象带有finalizers的时候只是标记为正在删除，同样算作成功
+func (c *resourceClient) delete(namespace string, name string) error {
+	code, err := netrequest.DelRequest(c.url(c.specURL, namespace, name))
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK && code != http.StatusAccepted && code != http.StatusNoContent {
+		return newStatusError(code, "delete", c.kind)
+	}
+	return nil
+}
+
+// 开始监听对象的变化，推送的对象是objType的指针
+func (c *resourceClient) watch(namespace string, opts ListOptions) (Watcher, error) {
+	uri, err := c.collectionURL(namespace, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := netrequest.GetStream(uri)
+	if err != nil {
+		if response != nil {
+			return nil, newStatusError(response.StatusCode, "watch", c.kind)
+		}
+		return nil, err
+	}
+	return newStreamWatcher(response.Body, c.objType), nil
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"encoding/json"
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListAndWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/pods" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("labelSelector") != "app=nginx" {
+			t.Errorf("expected labelSelector app=nginx but got %q", r.URL.Query().Get("labelSelector"))
+		}
+
+		if r.URL.Query().Get("watch") != "true" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"data":            `[{"metadata":{"name":"a","namespace":"default","resourceVersion":"3"}}]`,
+				"continue":        "",
+				"resourceVersion": "7",
+			})
+			return
+		}
+
+		if r.URL.Query().Get("resourceVersion") != "7" {
+			t.Errorf("expected to watch from 7 but got %q", r.URL.Query().Get("resourceVersion"))
+		}
+		encoder := json.NewEncoder(w)
+		encoder.Encode(apiObject.WatchEvent{Type: apiObject.WatchEventAdded, Object: json.RawMessage(`{"metadata":{"name":"b","resourceVersion":"8"}}`)})
+		encoder.Encode(apiObject.WatchEvent{Type: apiObject.WatchEventDeleted, Object: json.RawMessage(`{"metadata":{"name":"a","resourceVersion":"9"}}`)})
+		encoder.Encode(apiObject.WatchEvent{Type: apiObject.WatchEventError, Object: json.RawMessage(`{"code":410,"reason":"Expired","message":"too old"}`)})
+	}))
+	defer server.Close()
+
+	pods := NewClientsetForURL(server.URL).Pods()
+	list, resourceVersion, err := pods.ListWithResourceVersion(NamespaceAll, ListOptions{LabelSelector: "app=nginx"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resourceVersion != "7" || len(list) != 1 || list[0].Metadata.Name != "a" {
+		t.Fatalf("unexpected list result %q %v", resourceVersion, list)
+	}
+
+	watcher, err := pods.Watch(NamespaceAll, ListOptions{LabelSelector: "app=nginx", ResourceVersion: resourceVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Stop()
+
+	expected := []string{apiObject.WatchEventAdded + "/b/8", apiObject.WatchEventDeleted + "/a/9"}
+	for _, e := range expected {
+		select {
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				t.Fatalf("expected event %s but the watch is closed", e)
+			}
+			pod := event.Object.(*apiObject.PodStore)
+			if got := event.Type + "/" + pod.Metadata.Name + "/" + pod.Metadata.ResourceVersion; got != e {
+				t.Errorf("expected event %s but got %s", e, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected event %s", e)
+		}
+	}
+
+	// ERROR事件的Object是WatchStatus
+	select {
+	case event := <-watcher.ResultChan():
+		status, ok := event.Object.(*apiObject.WatchStatus)
+		if event.Type != apiObject.WatchEventError || !ok || status.Code != http.StatusGone {
+			t.Errorf("expected an ERROR event with 410 but got %s %+v", event.Type, event.Object)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected an ERROR event")
+	}
+
+	// 服务器结束响应之后channel被关闭
+	select {
+	case _, ok := <-watcher.ResultChan():
+		if ok {
+			t.Errorf("expected the watch to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the watch to be closed")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": "not found"})
+	}))
+	defer server.Close()
+
+	clientset := NewClientsetForURL(server.URL)
+	if _, err := clientset.ReplicaSets().Get("default", "rs"); !IsNotFound(err) {
+		t.Errorf("expected a not found error but got %v", err)
+	}
+	if _, err := clientset.Services().List(NamespaceAll, ListOptions{}); err == nil {
+		t.Errorf("expected services not to be listed across all namespaces")
+	}
+	if _, err := clientset.Nodes().Watch(ListOptions{}); !IsNotFound(err) {
+		t.Errorf("expected a not found error for watch but got %v", err)
+	}
+}
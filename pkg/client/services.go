@@ -0,0 +1,64 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// ServiceInterface 读写Service，namespace为空的时候使用default
+type ServiceInterface interface {
+	Get(namespace string, name string) (*apiObject.ServiceStore, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的Service
+	List(namespace string, opts ListOptions) ([]apiObject.ServiceStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.ServiceStore, string, error)
+	Create(service *apiObject.Service) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(service *apiObject.ServiceStore) error
+	UpdateStatus(namespace string, name string, status *apiObject.ServiceStatus) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type services struct {
+	client *resourceClient
+}
+
+func (c *services) Get(namespace string, name string) (*apiObject.ServiceStore, error) {
+	service := &apiObject.ServiceStore{}
+	if err := c.client.get(namespace, name, service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+func (c *services) List(namespace string, opts ListOptions) ([]apiObject.ServiceStore, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *services) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.ServiceStore, string, error) {
+	list := make([]apiObject.ServiceStore, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *services) Create(service *apiObject.Service) error {
+	return c.client.create(service.Metadata.Namespace, service)
+}
+
+func (c *services) Update(service *apiObject.ServiceStore) error {
+	return c.client.update(service.Metadata.Namespace, service.Metadata.Name, service)
+}
+
+func (c *services) UpdateStatus(namespace string, name string, status *apiObject.ServiceStatus) error {
+	return c.client.updateStatus(namespace, name, status)
+}
+
+func (c *services) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *services) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/k8log"
+	"reflect"
+	"sync"
+)
+
+// watch推送的一个变化，Type是apiObject.WatchEventAdded等
+// Object是对象的指针，比如*apiObject.PodStore，metadata里面带有resourceVersion
+// Type是apiObject.WatchEventError的时候Object是*apiObject.WatchStatus，之后channel会被关闭
+type Event struct {
+	Type   string
+	Object interface{}
+}
+
+type Watcher interface {
+	// 连接断开或者调用Stop之后，channel会被关闭
+	ResultChan() <-chan Event
+	Stop()
+}
+
+// 从APIServer返回的chunked响应里面逐行解析WatchEvent
+type streamWatcher struct {
+	body    io.ReadCloser
+	objType reflect.Type
+	result  chan Event
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func newStreamWatcher(body io.ReadCloser, objType reflect.Type) Watcher {
+	w := &streamWatcher{
+		body:    body,
+		objType: objType,
+		result:  make(chan Event),
+		stopCh:  make(chan struct{}),
+	}
+	go w.receive()
+	return w
+}
+
+func (w *streamWatcher) ResultChan() <-chan Event {
+	return w.result
+}
+
+// 关闭连接，receive读取失败之后退出
+func (w *streamWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopCh)
+		w.body.Close()
+	})
+}
+
+func (w *streamWatcher) receive() {
+	defer close(w.result)
+	defer w.Stop()
+
+	decoder := json.NewDecoder(w.body)
+	for {
+		event := apiObject.WatchEvent{}
+		if err := decoder.Decode(&event); err != nil {
+			select {
+			case <-w.stopCh:
+			default:
+				if err != io.EOF {
+					k8log.DebugLog("client", "watch stream closed: "+err.Error())
+				}
+			}
+			return
+		}
+
+		var obj interface{}
+		if event.Type == apiObject.WatchEventError {
+			obj = &apiObject.WatchStatus{}
+		} else {
+			obj = reflect.New(w.objType).Interface()
+		}
+		if err := json.Unmarshal(event.Object, obj); err != nil {
+			k8log.ErrorLog("client", "decode watch event failed: "+err.Error())
+			continue
+		}
+
+		select {
+		case w.result <- Event{Type: event.Type, Object: obj}:
+		case <-w.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import "miniK8s/pkg/apiObject"
+
+// WorkflowInterface 读写Workflow，namespace为空的时候使用default
+type WorkflowInterface interface {
+	Get(namespace string, name string) (*apiObject.WorkflowStore, error)
+	// namespace为NamespaceAll的时候返回所有namespace下面的Workflow
+	List(namespace string, opts ListOptions) ([]apiObject.WorkflowStore, error)
+	// 和List一样，额外返回读取时的resourceVersion，informer从这个版本开始watch
+	ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.WorkflowStore, string, error)
+	Create(workflow *apiObject.Workflow) error
+	// 带上metadata.resourceVersion的时候，对象被别人修改过会返回409
+	Update(workflow *apiObject.WorkflowStore) error
+	UpdateStatus(namespace string, name string, status *apiObject.WorkflowStatus) error
+	Delete(namespace string, name string) error
+	Watch(namespace string, opts ListOptions) (Watcher, error)
+}
+
+type workflows struct {
+	client *resourceClient
+}
+
+func (c *workflows) Get(namespace string, name string) (*apiObject.WorkflowStore, error) {
+	workflow := &apiObject.WorkflowStore{}
+	if err := c.client.get(namespace, name, workflow); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+func (c *workflows) List(namespace string, opts ListOptions) ([]apiObject.WorkflowStore, error) {
+	list, _, err := c.ListWithResourceVersion(namespace, opts)
+	return list, err
+}
+
+func (c *workflows) ListWithResourceVersion(namespace string, opts ListOptions) ([]apiObject.WorkflowStore, string, error) {
+	list := make([]apiObject.WorkflowStore, 0)
+	resourceVersion, err := c.client.list(namespace, opts, &list)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, resourceVersion, nil
+}
+
+func (c *workflows) Create(workflow *apiObject.Workflow) error {
+	return c.client.create(workflow.Metadata.Namespace, workflow)
+}
+
+func (c *workflows) Update(workflow *apiObject.WorkflowStore) error {
+	return c.client.update(workflow.Metadata.Namespace, workflow.Metadata.Name, workflow)
+}
+
+func (c *workflows) UpdateStatus(namespace string, name string, status *apiObject.WorkflowStatus) error {
+	return c.client.updateStatus(namespace, name, status)
+}
+
+func (c *workflows) Delete(namespace string, name string) error {
+	return c.client.delete(namespace, name)
+}
+
+func (c *workflows) Watch(namespace string, opts ListOptions) (Watcher, error) {
+	return c.client.watch(namespace, opts)
+}
@@ -0,0 +1,24 @@
+package config
+
+// APIServer的所有接口都需要在请求头里面带上Authorization: Bearer <token>
+const (
+	// 设置了这个环境变量的时候优先使用环境变量里面的token
+	TokenEnvName = "MINIK8S_TOKEN"
+	// APIServer第一次启动的时候生成的token会写到下面的目录里面
+	TokenDir = "/etc/minik8s/"
+	// 管理员用户的token，kubectl默认使用
+	AdminTokenFile = TokenDir + "admin.token"
+	// 节点组件(kubelet、kube-proxy等)的token，worker节点需要从master拷贝这个文件
+	NodeTokenFile = TokenDir + "node.token"
+	// job-server的token，kubelet把Job的凭证挂载到容器的TokenDir下面
+	JobTokenFile = TokenDir + "job.token"
+
+	// 内置的用户名
+	AdminTokenUser = "admin"
+	NodeTokenUser  = "system:node"
+)
+
+// 优先读取环境变量，否则读取tokenFile，都没有的时候返回空字符串
+func LoadToken(tokenFile string) string {
+	return LoadEnvOrFile(TokenEnvName, tokenFile)
+}
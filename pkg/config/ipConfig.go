@@ -9,9 +9,18 @@ const (
 	API_Server_Port       = 8090
 	Serveless_Server_Port = 28080
 	API_Server_Scheme     = "http://"
+	API_Server_TLS_Scheme = "https://"
 	clusterMode           = true // 是否是集群模式
 )
 
+// 没有HTTP服务的组件单独监听一个端口提供/healthz、/livez和/readyz
+const (
+	Kubelet_Healthz_Port            = 10248
+	Scheduler_Healthz_Port          = 10251
+	Controller_Manager_Healthz_Port = 10252
+	Kube_Proxy_Healthz_Port         = 10256
+)
+
 var SERVICE_IP_PREFIX = [2]int{192, 168}
 
 func GetMasterIP() string {
@@ -24,9 +33,17 @@ func GetMasterIP() string {
 	}
 }
 
+// 开启TLS的时候使用https，见TLSEnabled
+func GetAPIServerScheme() string {
+	if TLSEnabled() {
+		return API_Server_TLS_Scheme
+	}
+	return API_Server_Scheme
+}
+
 // 如果时localhost模式，返回的是 "http://127.0.0.1:8090"
 func GetAPIServerURLPrefix() string {
-	return API_Server_Scheme + GetMasterIP() + ":" + strconv.Itoa(API_Server_Port)
+	return GetAPIServerScheme() + GetMasterIP() + ":" + strconv.Itoa(API_Server_Port)
 }
 
 func GetServelessServerURLPrefix() string {
@@ -0,0 +1,8 @@
+package config
+
+// 调度器的配置，整个集群使用同一份，放在master上面
+const (
+	// 设置了这个环境变量的时候优先使用环境变量里面的yaml
+	SchedulerConfigEnvName = "MINIK8S_SCHEDULER_CONFIG"
+	SchedulerConfigFile    = TokenDir + "scheduler.yaml"
+)
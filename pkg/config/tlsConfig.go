@@ -0,0 +1,81 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+// APIServer的TLS配置，证书由cmd/ca生成，都是PEM格式
+const (
+	PKIDir = TokenDir + "pki/"
+	// 集群CA的证书和私钥，私钥只放在master上面
+	CACertFile = PKIDir + "ca.crt"
+	CAKeyFile  = PKIDir + "ca.key"
+	// APIServer的服务端证书
+	APIServerCertFile = PKIDir + "apiserver.crt"
+	APIServerKeyFile  = PKIDir + "apiserver.key"
+	// 节点组件和kubectl使用的客户端证书，节点加入集群的时候由cmd/ca签发
+	ClientCertFile = PKIDir + "client.crt"
+	ClientKeyFile  = PKIDir + "client.key"
+
+	// 容器里面没有上面的文件，可以通过环境变量直接传入PEM的内容
+	CADataEnvName         = "MINIK8S_CA_DATA"
+	ClientCertDataEnvName = "MINIK8S_CLIENT_CERT_DATA"
+	ClientKeyDataEnvName  = "MINIK8S_CLIENT_KEY_DATA"
+)
+
+var (
+	tlsEnabled     bool
+	tlsEnabledOnce sync.Once
+)
+
+// 优先读取环境变量，否则读取文件，都没有的时候返回空字符串
+func LoadEnvOrFile(envName string, file string) string {
+	if value := strings.TrimSpace(os.Getenv(envName)); value != "" {
+		return value
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// 能拿到集群CA的时候，组件使用https访问APIServer，否则还是使用http
+func TLSEnabled() bool {
+	tlsEnabledOnce.Do(func() {
+		tlsEnabled = LoadEnvOrFile(CADataEnvName, CACertFile) != ""
+	})
+	return tlsEnabled
+}
+
+// 组件访问APIServer使用的TLS配置，没有开启TLS的时候返回nil
+// 有客户端证书的时候一并带上，APIServer要求客户端证书的时候需要
+func LoadClientTLSConfig() (*tls.Config, error) {
+	caData := LoadEnvOrFile(CADataEnvName, CACertFile)
+	if caData == "" {
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caData)) {
+		return nil, errors.New("no valid certificate found in CA bundle")
+	}
+	tlsConfig := &tls.Config{RootCAs: pool}
+
+	certData := LoadEnvOrFile(ClientCertDataEnvName, ClientCertFile)
+	keyData := LoadEnvOrFile(ClientKeyDataEnvName, ClientKeyFile)
+	if certData != "" && keyData != "" {
+		cert, err := tls.X509KeyPair([]byte(certData), []byte(keyData))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
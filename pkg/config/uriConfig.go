@@ -57,6 +57,8 @@ const (
 	JobSpecURL = "/apis/v1/namespaces/:namespace/jobs/:name"
 	// 获取Job的某个状态的URL
 	JobSpecStatusURL = "/apis/v1/namespaces/:namespace/jobs/:name/status"
+	// 签发Job的job-server使用的凭证的URL
+	JobSpecCredentialURL = "/apis/v1/namespaces/:namespace/jobs/:name/credential"
 	// Job的文件的URL
 	JobFileURL = "/apis/v1/namespaces/:namespace/jobfiles"
 	// 某个特定Job的文件的URL
@@ -104,16 +106,113 @@ const (
 	WorkflowSpecURL = "/apis/v1/namespaces/:namespace/workflows/:name"
 	// Workflow的Status的URL
 	WorkflowSpecStatusURL = "/apis/v1/namespaces/:namespace/workflows/:name/status"
+
+	// Namespace相关的URL
+	// Namespace的参数和其他资源保持一致，都用:namespace，否则gin的路由会冲突
+	// 所有Namespace的URL
+	NamespacesURL = "/api/v1/namespaces"
+	// 某个特定Namespace的URL
+	NamespaceSpecURL = "/api/v1/namespaces/:namespace"
+	// 获取Namespace的状态的URL
+	NamespaceSpecStatusURL = "/api/v1/namespaces/:namespace/status"
+	// Namespace下面的资源都删除之后，由controller调用这个URL真正删除Namespace
+	NamespaceFinalizeURL = "/api/v1/namespaces/:namespace/finalize"
+
+	// CustomResourceDefinition相关的URL
+	// 所有CRD的URL
+	CRDsURL = "/apis/v1/customresourcedefinitions"
+	// 某个特定CRD的URL，名字是<plural>.<group>
+	CRDSpecURL = "/apis/v1/customresourcedefinitions/:name"
+	// 某个CRD定义的所有自定义对象的URL(Namespace级别)
+	CustomResourcesURL = "/apis/:group/:version/namespaces/:namespace/:plural"
+	// 某个特定自定义对象的URL
+	CustomResourceSpecURL = "/apis/:group/:version/namespaces/:namespace/:plural/:name"
+	// 更新自定义对象的ownerReferences和finalizers
+	CustomResourceSpecMetadataURL = CustomResourceSpecURL + MetadataSubresource
+
+	// 准入控制webhook相关的URL，都是集群级别的资源
+	MutatingWebhookConfigurationsURL      = "/apis/v1/mutatingwebhookconfigurations"
+	MutatingWebhookConfigurationSpecURL   = "/apis/v1/mutatingwebhookconfigurations/:name"
+	ValidatingWebhookConfigurationsURL    = "/apis/v1/validatingwebhookconfigurations"
+	ValidatingWebhookConfigurationSpecURL = "/apis/v1/validatingwebhookconfigurations/:name"
+
+	// 权限控制相关的URL，Role和RoleBinding属于namespace，ClusterRole和ClusterRoleBinding是集群级别的资源
+	RolesURL                  = "/apis/v1/namespaces/:namespace/roles"
+	RoleSpecURL               = "/apis/v1/namespaces/:namespace/roles/:name"
+	RoleBindingsURL           = "/apis/v1/namespaces/:namespace/rolebindings"
+	RoleBindingSpecURL        = "/apis/v1/namespaces/:namespace/rolebindings/:name"
+	ClusterRolesURL           = "/apis/v1/clusterroles"
+	ClusterRoleSpecURL        = "/apis/v1/clusterroles/:name"
+	ClusterRoleBindingsURL    = "/apis/v1/clusterrolebindings"
+	ClusterRoleBindingSpecURL = "/apis/v1/clusterrolebindings/:name"
+
+	// Event相关的URL
+	GlobalEventsURL = "/api/v1/events"
+	EventsURL       = "/api/v1/namespaces/:namespace/events"
+	EventSpecURL    = "/api/v1/namespaces/:namespace/events/:name"
+
+	// Lease相关的URL，选主的时候使用
+	LeasesURL    = "/api/v1/namespaces/:namespace/leases"
+	LeaseSpecURL = "/api/v1/namespaces/:namespace/leases/:name"
+
+	// Token相关的URL，创建的时候返回新的token，删除的时候:name就是token本身
+	TokensURL    = "/api/v1/tokens"
+	TokenSpecURL = "/api/v1/tokens/:name"
+
+	// 审计日志的查询接口，只读
+	AuditEventsURL = "/api/v1/auditevents"
+
+	// Prometheus格式的指标
+	MetricsURL = "/metrics"
+
+	// 健康检查，所有组件都提供，不需要认证
+	HealthzURL = "/healthz"
+	LivezURL   = "/livez"
+	ReadyzURL  = "/readyz"
 )
 
 const (
 	// 请把所有【参数】相关的放在下面，这部分是不带冒号的
 	URL_PARAM_NAME      = "name"
 	URL_PARAM_NAMESPACE = "namespace"
+	URL_PARAM_GROUP     = "group"
+	URL_PARAM_VERSION   = "version"
+	URL_PARAM_PLURAL    = "plural"
 
 	// 请把所有【参数】相关的放在下面，【PART】是指URI里面带冒号的部分
 	URL_PARAM_NAME_PART      = ":name"
 	URL_PARAM_NAMESPACE_PART = ":namespace"
+	URL_PARAM_GROUP_PART     = ":group"
+	URL_PARAM_VERSION_PART   = ":version"
+	URL_PARAM_PLURAL_PART    = ":plural"
+)
+
+const (
+	// 请把所有【查询参数】相关的放在下面，也就是URL里面?后面的部分
+	// list类型的URL带上watch=true的时候，会持续推送对象的变化
+	URL_QUERY_WATCH = "watch"
+	// 配合watch使用，只推送这个版本之后的变化
+	URL_QUERY_RESOURCE_VERSION = "resourceVersion"
+	// list类型的URL按照label和字段过滤，比如labelSelector=app=web,env in (a,b)
+	URL_QUERY_LABEL_SELECTOR = "labelSelector"
+	URL_QUERY_FIELD_SELECTOR = "fieldSelector"
+	// list类型的URL分页读取，limit是每页最多的个数，continue是上一页返回的token
+	URL_QUERY_LIMIT    = "limit"
+	URL_QUERY_CONTINUE = "continue"
+	// 审计日志按照时间范围和对象过滤，时间是RFC3339格式
+	URL_QUERY_SINCE = "since"
+	URL_QUERY_UNTIL = "until"
+	URL_QUERY_KIND  = "kind"
+	URL_QUERY_USER  = "user"
+	// 删除对象时的级联策略，Background、Foreground或者Orphan，见apiObject.DeletePropagationBackground
+	URL_QUERY_PROPAGATION_POLICY = "propagationPolicy"
+)
+
+// 通用资源的子资源，拼在某个对象的URL后面
+// 比如 "/api/v1/namespaces/:namespace/pods/:name/metadata"
+const (
+	// 更新对象的ownerReferences和finalizers
+	MetadataSubresource = "/metadata"
 )
 
 // kind->返回所有资源的URL(给定namespace)
@@ -126,6 +225,20 @@ var ApiResourceMap = map[string]string{
 	apiObject.ReplicaSetKind: ReplicaSetsURL,
 	apiObject.HpaKind:        HPAURL,
 	apiObject.FunctionKind:   FunctionURL,
+	apiObject.WorkflowKind:   WorkflowURL,
+	apiObject.NamespaceKind:  NamespacesURL,
+	apiObject.CRDKind:        CRDsURL,
+
+	apiObject.MutatingWebhookConfigurationKind:   MutatingWebhookConfigurationsURL,
+	apiObject.ValidatingWebhookConfigurationKind: ValidatingWebhookConfigurationsURL,
+
+	apiObject.RoleKind:               RolesURL,
+	apiObject.ClusterRoleKind:        ClusterRolesURL,
+	apiObject.RoleBindingKind:        RoleBindingsURL,
+	apiObject.ClusterRoleBindingKind: ClusterRoleBindingsURL,
+
+	apiObject.EventKind: EventsURL,
+	apiObject.LeaseKind: LeasesURL,
 }
 
 // kind->返回特定资源的URL(给定namespace)
@@ -138,4 +251,18 @@ var ApiSpecResourceMap = map[string]string{
 	apiObject.ReplicaSetKind: ReplicaSetSpecURL,
 	apiObject.HpaKind:        HPASpecURL,
 	apiObject.FunctionKind:   FunctionSpecURL,
+	apiObject.WorkflowKind:   WorkflowSpecURL,
+	apiObject.NamespaceKind:  NamespaceSpecURL,
+	apiObject.CRDKind:        CRDSpecURL,
+
+	apiObject.MutatingWebhookConfigurationKind:   MutatingWebhookConfigurationSpecURL,
+	apiObject.ValidatingWebhookConfigurationKind: ValidatingWebhookConfigurationSpecURL,
+
+	apiObject.RoleKind:               RoleSpecURL,
+	apiObject.ClusterRoleKind:        ClusterRoleSpecURL,
+	apiObject.RoleBindingKind:        RoleBindingSpecURL,
+	apiObject.ClusterRoleBindingKind: ClusterRoleBindingSpecURL,
+
+	apiObject.EventKind: EventSpecURL,
+	apiObject.LeaseKind: LeaseSpecURL,
 }
@@ -8,6 +8,7 @@ import (
 	"miniK8s/pkg/k8log"
 	"miniK8s/pkg/listwatcher"
 	"miniK8s/pkg/message"
+	"miniK8s/pkg/workqueue"
 	"miniK8s/util/file"
 	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/nginx"
@@ -23,8 +24,13 @@ var NginxPodYamlPath = os.Getenv("MINIK8S_PATH") + "util/nginx/yaml/dns-nginx-po
 var NginxServiceYamlPath = os.Getenv("MINIK8S_PATH") + "util/nginx/yaml/dns-nginx-service.yaml"
 var NginxDnsYamlPath = os.Getenv("MINIK8S_PATH") + "util/nginx/yaml/dns-nginx-dns.yaml"
 
+// hostList的修改需要按照消息的顺序进行，所以只用一个worker
+const dnsControllerWorkers = 1
+
 type DnsController interface {
 	Run()
+	// Healthy 检查监听消息使用的RabbitMQ链接是否可用
+	Healthy() error
 }
 
 type dnsController struct {
@@ -32,6 +38,9 @@ type dnsController struct {
 	hostList     []string // 通过dns创建的host列表，这些host将被解析为nginx的service ip
 	nginxSvcName string   // nginx service的名称
 	nginxSvcIp   string   // nginx service的ip
+
+	// 从RabbitMQ收到的dns消息，处理失败之后退避重试
+	queue workqueue.RateLimitingInterface
 }
 
 func NewDnsController() (DnsController, error) {
@@ -45,22 +54,24 @@ func NewDnsController() (DnsController, error) {
 	return &dnsController{
 		lw:       newlw,
 		hostList: make([]string, 0),
+		queue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 	}, nil
 }
 
-func (dc *dnsController) DnsCreateHandler(parsedMsg *message.Message) {
+// 消息格式错误的时候重试没有意义，只记录日志
+func (dc *dnsController) DnsCreateHandler(parsedMsg *message.Message) error {
 	dnsUpdate := &entity.DnsUpdate{}
 	err := json.Unmarshal([]byte(parsedMsg.Content), dnsUpdate)
 	if err != nil {
 		k8log.ErrorLog("Dns-Controller", "failed to unmarshal")
-		return
+		return nil
 	}
 
 	dnsStore := dnsUpdate.DnsTarget
 
 	if dnsStore.Spec.Host == "" {
 		k8log.ErrorLog("Dns-Controller", "host is empty")
-		return
+		return nil
 	}
 
 	if dnsStore.Metadata.Namespace == "" {
@@ -73,7 +84,10 @@ func (dc *dnsController) DnsCreateHandler(parsedMsg *message.Message) {
 	// 添加/etc/hosts
 	k8log.DebugLog("Dns-Controller", "DnsCreateHandler: newhostEntry is "+dc.nginxSvcIp+" "+dnsStore.Spec.Host)
 	newHostEntry := dc.nginxSvcIp + " " + dnsStore.Spec.Host
-	dc.hostList = append(dc.hostList, newHostEntry)
+	// 重试的时候host已经加入过了
+	if !stringutil.ContainsString(dc.hostList, newHostEntry) {
+		dc.hostList = append(dc.hostList, newHostEntry)
+	}
 
 	// 创建hostUpdate消息
 	hostUpdate := &entity.HostUpdate{
@@ -85,22 +99,22 @@ func (dc *dnsController) DnsCreateHandler(parsedMsg *message.Message) {
 
 	// TODO: 通知所有的节点进行hosts的修改
 	k8log.DebugLog("Dns-Controller", "DnsCreateHandler: publish hostUpdate")
-	message.PubelishUpdateHost(hostUpdate)
+	return message.PubelishUpdateHost(hostUpdate)
 }
 
-func (dc *dnsController) DnsDeleteHandler(parsedMsg *message.Message) {
+func (dc *dnsController) DnsDeleteHandler(parsedMsg *message.Message) error {
 	dnsUpdate := &entity.DnsUpdate{}
 	err := json.Unmarshal([]byte(parsedMsg.Content), dnsUpdate)
 	if err != nil {
 		k8log.ErrorLog("Dns-Controller", "failed to unmarshal")
-		return
+		return nil
 	}
 
 	dnsStore := dnsUpdate.DnsTarget
 
 	if dnsStore.Spec.Host == "" {
 		k8log.ErrorLog("Dns-Controller", "host is empty")
-		return
+		return nil
 	}
 
 	if dnsStore.Metadata.Namespace == "" {
@@ -111,7 +125,7 @@ func (dc *dnsController) DnsDeleteHandler(parsedMsg *message.Message) {
 	err = dc.DeleteNginxConf(dnsStore.ToDns())
 	if err != nil {
 		k8log.ErrorLog("Dns-Controller", "DnsDeleteHandler: failed to delete nginx conf")
-		return
+		return err
 	}
 
 	// 删除/etc/hosts
@@ -133,26 +147,37 @@ func (dc *dnsController) DnsDeleteHandler(parsedMsg *message.Message) {
 
 	// TODO: 通知所有的节点进行hosts的修改
 	k8log.DebugLog("Dns-Controller", "DnsCreateHandler: publish hostUpdate")
-	message.PubelishUpdateHost(hostUpdate)
+	return message.PubelishUpdateHost(hostUpdate)
 }
 
+// 收到的消息放入队列，由worker处理，相同的消息在队列里面只保留一份
 func (dc *dnsController) DnsUpdatehandler(msg amqp.Delivery) {
 	k8log.WarnLog("Dns-Controller", "收到消息"+string(msg.Body))
 
 	parsedMsg, err := message.ParseJsonMessageFromBytes(msg.Body)
 	if err != nil {
 		k8log.ErrorLog("Dns-Controller", "消息格式错误,无法转换为Message")
+		return
 	}
 
+	dc.queue.Add(*parsedMsg)
+}
+
+// 处理一条dns消息，删除和创建都可以重复执行
+func (dc *dnsController) syncDns(item interface{}) error {
+	parsedMsg := item.(message.Message)
 	switch parsedMsg.Type {
 	case message.CREATE:
-		dc.DnsCreateHandler(parsedMsg)
+		return dc.DnsCreateHandler(&parsedMsg)
 	case message.DELETE:
-		dc.DnsDeleteHandler(parsedMsg)
+		return dc.DnsDeleteHandler(&parsedMsg)
 	case message.UPDATE:
-		dc.DnsDeleteHandler(parsedMsg)
-		dc.DnsCreateHandler(parsedMsg)
+		if err := dc.DnsDeleteHandler(&parsedMsg); err != nil {
+			return err
+		}
+		return dc.DnsCreateHandler(&parsedMsg)
 	}
+	return nil
 }
 
 func (dc *dnsController) DeleteNginxConf(dns *apiObject.Dns) error {
@@ -254,12 +279,17 @@ func (dc *dnsController) CreateNginxDns() {
 	k8log.InfoLog("Dns-Controller", "HandleServiceUpdate: success to create nginx dns")
 }
 
+func (dc *dnsController) Healthy() error {
+	return dc.lw.Healthy()
+}
+
 func (dc *dnsController) Run() {
 	// 创建nginx service
 	dc.CreateNginxService()
 	// 更新nginxService的ip
 	dc.UpdateNginxSvcIP()
 
-	// 监听dns的更新
+	// 监听dns的更新，消息由worker处理
+	go workqueue.Run("Dns-Controller", dc.queue, dnsControllerWorkers, workqueue.DefaultMaxRetries, dc.syncDns, nil)
 	dc.lw.WatchQueue_Block(message.DnsUpdateQueue, dc.DnsUpdatehandler, make(chan struct{}))
 }
@@ -0,0 +1,87 @@
+package allcontollers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"sync"
+	"time"
+)
+
+// 超过这个时间还没有观察到，就不再等待，比如pod在创建之后马上被删除了
+const podExpectationsTimeout = time.Minute
+
+const (
+	podExpectationAdd    = "add"
+	podExpectationDelete = "delete"
+)
+
+// 记录controller发出了创建或者删除、但是informer的缓存里面还没有出现的pod
+// 缓存落后于API Server的时候，controller看到的pod数量不准，需要等期望都被观察到之后再调整
+type podExpectations struct {
+	lock sync.Mutex
+	// owner的 namespace/name -> 期望
+	items map[string]*podExpectation
+}
+
+type podExpectation struct {
+	// 操作类型 -> pod的 namespace/name 的集合
+	pending   map[string]map[string]struct{}
+	timestamp time.Time
+}
+
+func newPodExpectations() *podExpectations {
+	return &podExpectations{items: make(map[string]*podExpectation)}
+}
+
+// 发出请求之前记录期望
+func (e *podExpectations) expect(action string, ownerKey string, namespace string, name string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	item, ok := e.items[ownerKey]
+	if !ok {
+		item = &podExpectation{pending: make(map[string]map[string]struct{})}
+		e.items[ownerKey] = item
+	}
+	if item.pending[action] == nil {
+		item.pending[action] = make(map[string]struct{})
+	}
+	item.pending[action][namespace+"/"+name] = struct{}{}
+	item.timestamp = time.Now()
+}
+
+// 请求失败的时候撤销期望
+func (e *podExpectations) lower(action string, ownerKey string, namespace string, name string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if item, ok := e.items[ownerKey]; ok {
+		delete(item.pending[action], namespace+"/"+name)
+	}
+}
+
+// informer推送了pod的变化，对应的期望已经满足
+func (e *podExpectations) observe(action string, pod *apiObject.PodStore) {
+	podKey := pod.Metadata.Namespace + "/" + pod.Metadata.Name
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, item := range e.items {
+		delete(item.pending[action], podKey)
+	}
+}
+
+// 所有的期望都已经满足或者已经超时
+func (e *podExpectations) satisfied(ownerKey string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	item, ok := e.items[ownerKey]
+	if !ok {
+		return true
+	}
+	pending := 0
+	for _, pods := range item.pending {
+		pending += len(pods)
+	}
+	if pending == 0 || time.Since(item.timestamp) > podExpectationsTimeout {
+		delete(e.items, ownerKey)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,326 @@
+package allcontollers
+
+import (
+	"errors"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/executor"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	GCControllerUpdateDelay     = time.Second * 5
+	GCControllerUpdateFrequency = []time.Duration{5 * time.Second}
+	GCControllerUpdateLoop      = true
+)
+
+// GC根据ownerReferences删除所有者已经不存在的对象
+// 后台删除：所有者直接被删除，GC发现所有者不存在之后删除依赖它的对象
+// 前台删除：所有者带着foregroundDeletion的finalizer，GC先删除依赖它的对象，再移除finalizer
+// 孤儿删除：所有者带着orphan的finalizer，GC去掉依赖对象指向它的ownerReferences，再移除finalizer
+// Service的endpoints保存在Service自己的status里面，随着Service一起删除，不需要GC处理
+type GCController interface {
+	Run()
+}
+
+type gcController struct {
+}
+
+func NewGCController() (GCController, error) {
+	return &gcController{}, nil
+}
+
+// GC管理的对象，记录kind，因为列表接口返回的对象里面kind不一定是填好的
+type gcObject struct {
+	Kind string
+	apiObject.Basic
+}
+
+// 依赖对象需要去掉的ownerReferences
+type gcOrphan struct {
+	Object          gcObject
+	OwnerReferences []apiObject.OwnerReference
+}
+
+// 依赖对象需要删除，Propagation是删除它时使用的级联策略
+type gcDelete struct {
+	Object      gcObject
+	Propagation string
+}
+
+// 所有者需要移除的finalizer
+type gcFinalize struct {
+	Object    gcObject
+	Finalizer string
+}
+
+// 一轮GC需要执行的操作
+type gcPlan struct {
+	Deletes   []gcDelete
+	Orphans   []gcOrphan
+	Finalizes []gcFinalize
+}
+
+// 根据对象之间的所有关系计算这一轮需要执行的操作，kinds是列出来的所有对象的kind
+// kind不在kinds里面的所有者GC看不到，总是当作存在处理
+func planGarbageCollection(objects []gcObject, kinds map[string]bool) *gcPlan {
+	plan := &gcPlan{}
+
+	owners := make(map[string]*gcObject, len(objects))
+	for i := range objects {
+		owners[objects[i].Metadata.UUID] = &objects[i]
+	}
+
+	// 所有者的uuid->还有没有依赖它的对象
+	blocked := make(map[string]bool)
+	for _, obj := range objects {
+		meta := &obj.Metadata
+		if len(meta.OwnerReferences) == 0 {
+			continue
+		}
+
+		refs := make([]apiObject.OwnerReference, 0, len(meta.OwnerReferences))
+		alive := false
+		foreground := false
+		for _, ref := range meta.OwnerReferences {
+			owner, ok := owners[ref.UUID]
+			if !ok {
+				refs = append(refs, ref)
+				if !kinds[ref.Kind] {
+					alive = true
+				}
+				continue
+			}
+
+			if owner.Metadata.IsBeingDeleted() && owner.Metadata.HasFinalizer(apiObject.FinalizerOrphan) {
+				// 孤儿删除，去掉这个ownerReference
+				blocked[ref.UUID] = true
+				continue
+			}
+
+			refs = append(refs, ref)
+			if owner.Metadata.IsBeingDeleted() && owner.Metadata.HasFinalizer(apiObject.FinalizerForegroundDeletion) {
+				foreground = true
+				if ref.BlockOwnerDeletion {
+					blocked[ref.UUID] = true
+				}
+				continue
+			}
+			alive = true
+		}
+
+		if len(refs) != len(meta.OwnerReferences) {
+			plan.Orphans = append(plan.Orphans, gcOrphan{Object: obj, OwnerReferences: refs})
+			continue
+		}
+
+		// 还有所有者存在，或者已经在删除了
+		if alive || meta.IsBeingDeleted() {
+			continue
+		}
+
+		propagation := apiObject.DeletePropagationBackground
+		if foreground {
+			// 前台删除一层一层传递下去
+			propagation = apiObject.DeletePropagationForeground
+		}
+		plan.Deletes = append(plan.Deletes, gcDelete{Object: obj, Propagation: propagation})
+	}
+
+	// 依赖对象都处理完之后，移除所有者的finalizer
+	for _, obj := range objects {
+		meta := &obj.Metadata
+		if !meta.IsBeingDeleted() || blocked[meta.UUID] {
+			continue
+		}
+		for _, finalizer := range []string{apiObject.FinalizerForegroundDeletion, apiObject.FinalizerOrphan} {
+			if meta.HasFinalizer(finalizer) {
+				plan.Finalizes = append(plan.Finalizes, gcFinalize{Object: obj, Finalizer: finalizer})
+				break
+			}
+		}
+	}
+
+	return plan
+}
+
+func (gc *gcController) GetAllNamespacesFromAPIServer() ([]apiObject.NamespaceStore, error) {
+	url := config.GetAPIServerURLPrefix() + config.NamespacesURL
+
+	allNamespaces := make([]apiObject.NamespaceStore, 0)
+
+	code, err := netrequest.GetRequestByTarget(url, &allNamespaces, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, errors.New("get all namespaces from apiserver failed")
+	}
+
+	return allNamespaces, nil
+}
+
+// 列出所有namespace下面GC管理的对象，有一类对象获取失败的时候返回错误，避免误删
+func (gc *gcController) GetAllObjects() ([]gcObject, error) {
+	namespaces, err := gc.GetAllNamespacesFromAPIServer()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]gcObject, 0)
+	for _, namespace := range namespaces {
+		for _, kind := range namespaceDeleteOrder {
+			url := config.GetAPIServerURLPrefix() + namespaceResourceURLs[kind]
+			url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace.Metadata.Name)
+
+			list := make([]apiObject.Basic, 0)
+			code, err := netrequest.GetRequestByTarget(url, &list, "data")
+			if err != nil {
+				return nil, err
+			}
+			if code != http.StatusOK {
+				return nil, errors.New("get " + kind + " from apiserver failed, code " + strconv.Itoa(code))
+			}
+
+			for _, obj := range list {
+				objects = append(objects, gcObject{Kind: kind, Basic: obj})
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+func (gc *gcController) objectURL(obj *gcObject, subresource string) string {
+	url := config.GetAPIServerURLPrefix() + namespaceResourceSpecURLs[obj.Kind] + subresource
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, obj.Metadata.Namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, obj.Metadata.Name)
+	return url
+}
+
+// 所有者可能是在列出对象之后才创建的，删除之前再向api server确认一次
+func (gc *gcController) OwnerExists(namespace string, ref *apiObject.OwnerReference) (bool, error) {
+	owner := &gcObject{Kind: ref.Kind}
+	owner.Metadata.Namespace = namespace
+	owner.Metadata.Name = ref.Name
+
+	current := &apiObject.Basic{}
+	code, err := netrequest.GetRequestByTarget(gc.objectURL(owner, ""), current, "data")
+	if err != nil {
+		return false, err
+	}
+	if code == http.StatusNotFound {
+		return false, nil
+	}
+	if code != http.StatusOK {
+		return false, errors.New("get " + ref.Kind + " " + ref.Name + " failed, code " + strconv.Itoa(code))
+	}
+
+	// 同名的对象被删除之后又重新创建了
+	return current.Metadata.UUID == ref.UUID, nil
+}
+
+func (gc *gcController) DeleteObject(obj *gcObject, propagation string) error {
+	url := gc.objectURL(obj, "") + "?" + config.URL_QUERY_PROPAGATION_POLICY + "=" + propagation
+
+	code, err := netrequest.DelRequest(url)
+	if err != nil {
+		return err
+	}
+
+	// 已经被其他人删除了，也算删除成功
+	if code != http.StatusNoContent && code != http.StatusAccepted && code != http.StatusNotFound {
+		return errors.New("delete " + obj.Kind + " " + obj.Metadata.Name + " failed, code " + strconv.Itoa(code))
+	}
+
+	return nil
+}
+
+// 通过metadata子资源更新ownerReferences和finalizers，带上resourceVersion，对象被修改过的时候下一轮再处理
+func (gc *gcController) UpdateObjectMetadata(obj *gcObject, refs []apiObject.OwnerReference, finalizers []string) error {
+	update := &apiObject.MetadataUpdate{
+		ResourceVersion: obj.Metadata.ResourceVersion,
+		OwnerReferences: refs,
+		Finalizers:      finalizers,
+	}
+
+	code, _, err := netrequest.PutRequestByTarget(gc.objectURL(obj, config.MetadataSubresource), update)
+	if err != nil {
+		return err
+	}
+
+	// 移除最后一个finalizer的时候对象被删除，返回204
+	if code != http.StatusOK && code != http.StatusNoContent && code != http.StatusNotFound {
+		return errors.New("update metadata of " + obj.Kind + " " + obj.Metadata.Name + " failed, code " + strconv.Itoa(code))
+	}
+
+	return nil
+}
+
+func (gc *gcController) Routine() {
+	objects, err := gc.GetAllObjects()
+	if err != nil {
+		k8log.ErrorLog("GCController", "Routine: "+err.Error())
+		return
+	}
+
+	kinds := make(map[string]bool, len(namespaceDeleteOrder))
+	for _, kind := range namespaceDeleteOrder {
+		kinds[kind] = true
+	}
+	plan := planGarbageCollection(objects, kinds)
+
+	for _, orphan := range plan.Orphans {
+		obj := &orphan.Object
+		k8log.DebugLog("GCController", fmt.Sprintf("Routine: orphan %s %s/%s", obj.Kind, obj.Metadata.Namespace, obj.Metadata.Name))
+		if err := gc.UpdateObjectMetadata(obj, orphan.OwnerReferences, obj.Metadata.Finalizers); err != nil {
+			k8log.ErrorLog("GCController", "Routine: "+err.Error())
+		}
+	}
+
+	for _, del := range plan.Deletes {
+		obj := &del.Object
+		if del.Propagation == apiObject.DeletePropagationBackground && gc.anyOwnerExists(obj) {
+			continue
+		}
+		k8log.InfoLog("GCController", fmt.Sprintf("Routine: delete %s %s/%s", obj.Kind, obj.Metadata.Namespace, obj.Metadata.Name))
+		if err := gc.DeleteObject(obj, del.Propagation); err != nil {
+			k8log.ErrorLog("GCController", "Routine: "+err.Error())
+		}
+	}
+
+	for _, finalize := range plan.Finalizes {
+		obj := &finalize.Object
+		k8log.DebugLog("GCController", fmt.Sprintf("Routine: remove finalizer %s from %s %s/%s", finalize.Finalizer, obj.Kind, obj.Metadata.Namespace, obj.Metadata.Name))
+		if err := gc.UpdateObjectMetadata(obj, obj.Metadata.OwnerReferences, obj.Metadata.WithoutFinalizer(finalize.Finalizer)); err != nil {
+			k8log.ErrorLog("GCController", "Routine: "+err.Error())
+		}
+	}
+}
+
+// 确认失败的时候也当作所有者存在，下一轮再处理
+func (gc *gcController) anyOwnerExists(obj *gcObject) bool {
+	for i := range obj.Metadata.OwnerReferences {
+		exists, err := gc.OwnerExists(obj.Metadata.Namespace, &obj.Metadata.OwnerReferences[i])
+		if err != nil {
+			k8log.ErrorLog("GCController", "Routine: "+err.Error())
+			return true
+		}
+		if exists {
+			return true
+		}
+	}
+	return false
+}
+
+func (gc *gcController) Run() {
+	// 定期执行
+	executor.Period(GCControllerUpdateDelay, GCControllerUpdateFrequency, gc.Routine, GCControllerUpdateLoop)
+}
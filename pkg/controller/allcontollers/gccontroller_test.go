@@ -0,0 +1,114 @@
+package allcontollers
+
+import (
+	"miniK8s/pkg/apiObject"
+	"testing"
+	"time"
+)
+
+func newGCObject(kind string, name string, uuid string, owners ...*gcObject) gcObject {
+	obj := gcObject{Kind: kind}
+	obj.Metadata.Name = name
+	obj.Metadata.Namespace = "default"
+	obj.Metadata.UUID = uuid
+	for _, owner := range owners {
+		obj.Metadata.OwnerReferences = append(obj.Metadata.OwnerReferences, apiObject.NewOwnerReference(owner.Kind, &owner.Metadata))
+	}
+	return obj
+}
+
+// 开始删除对象，带上级联删除对应的finalizer
+func markDeleting(obj *gcObject, finalizer string) {
+	now := time.Now()
+	obj.Metadata.DeletionTimestamp = &now
+	if finalizer != "" {
+		obj.Metadata.AddFinalizer(finalizer)
+	}
+}
+
+var gcTestKinds = map[string]bool{
+	apiObject.ReplicaSetKind: true,
+	apiObject.PodKind:        true,
+	apiObject.FunctionKind:   true,
+}
+
+func TestPlanGarbageCollectionBackground(t *testing.T) {
+	rs := newGCObject(apiObject.ReplicaSetKind, "rs", "rs-uuid")
+	// rs已经被删除了，pod1成了孤儿
+	pod1 := newGCObject(apiObject.PodKind, "pod1", "pod1-uuid", &rs)
+	// 所有者不在GC管理的范围之内，当作存在
+	pod2 := newGCObject(apiObject.PodKind, "pod2", "pod2-uuid", &gcObject{Kind: "Unknown", Basic: apiObject.Basic{Metadata: apiObject.Metadata{Name: "x", UUID: "x-uuid"}}})
+	// 还有一个所有者存在
+	fn := newGCObject(apiObject.FunctionKind, "fn", "fn-uuid")
+	pod3 := newGCObject(apiObject.PodKind, "pod3", "pod3-uuid", &rs, &fn)
+
+	plan := planGarbageCollection([]gcObject{pod1, pod2, fn, pod3}, gcTestKinds)
+	if len(plan.Deletes) != 1 || plan.Deletes[0].Object.Metadata.Name != "pod1" || plan.Deletes[0].Propagation != apiObject.DeletePropagationBackground {
+		t.Errorf("expected to delete pod1 in background, got %+v", plan.Deletes)
+	}
+	if len(plan.Orphans) != 0 || len(plan.Finalizes) != 0 {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+}
+
+func TestPlanGarbageCollectionForeground(t *testing.T) {
+	fn := newGCObject(apiObject.FunctionKind, "fn", "fn-uuid")
+	rs := newGCObject(apiObject.ReplicaSetKind, "rs", "rs-uuid", &fn)
+	pod := newGCObject(apiObject.PodKind, "pod", "pod-uuid", &rs)
+	markDeleting(&fn, apiObject.FinalizerForegroundDeletion)
+
+	// 第一轮：rs被前台删除，fn等待rs
+	plan := planGarbageCollection([]gcObject{fn, rs, pod}, gcTestKinds)
+	if len(plan.Deletes) != 1 || plan.Deletes[0].Object.Metadata.Name != "rs" || plan.Deletes[0].Propagation != apiObject.DeletePropagationForeground {
+		t.Fatalf("expected to delete rs in foreground, got %+v", plan.Deletes)
+	}
+	if len(plan.Finalizes) != 0 {
+		t.Errorf("fn should wait for its dependents, got %+v", plan.Finalizes)
+	}
+
+	// 第二轮：rs正在删除，删除pod，rs和fn都在等待
+	markDeleting(&rs, apiObject.FinalizerForegroundDeletion)
+	plan = planGarbageCollection([]gcObject{fn, rs, pod}, gcTestKinds)
+	if len(plan.Deletes) != 1 || plan.Deletes[0].Object.Metadata.Name != "pod" {
+		t.Fatalf("expected to delete pod, got %+v", plan.Deletes)
+	}
+	if len(plan.Finalizes) != 0 {
+		t.Errorf("owners should wait for their dependents, got %+v", plan.Finalizes)
+	}
+
+	// 第三轮：pod已经删除，移除rs的finalizer
+	plan = planGarbageCollection([]gcObject{fn, rs}, gcTestKinds)
+	if len(plan.Finalizes) != 1 || plan.Finalizes[0].Object.Metadata.Name != "rs" || plan.Finalizes[0].Finalizer != apiObject.FinalizerForegroundDeletion {
+		t.Fatalf("expected to finalize rs, got %+v", plan.Finalizes)
+	}
+
+	// 第四轮：rs已经删除，移除fn的finalizer
+	plan = planGarbageCollection([]gcObject{fn}, gcTestKinds)
+	if len(plan.Finalizes) != 1 || plan.Finalizes[0].Object.Metadata.Name != "fn" {
+		t.Fatalf("expected to finalize fn, got %+v", plan.Finalizes)
+	}
+}
+
+func TestPlanGarbageCollectionOrphan(t *testing.T) {
+	rs := newGCObject(apiObject.ReplicaSetKind, "rs", "rs-uuid")
+	fn := newGCObject(apiObject.FunctionKind, "fn", "fn-uuid")
+	pod := newGCObject(apiObject.PodKind, "pod", "pod-uuid", &rs, &fn)
+	markDeleting(&rs, apiObject.FinalizerOrphan)
+
+	plan := planGarbageCollection([]gcObject{rs, fn, pod}, gcTestKinds)
+	if len(plan.Deletes) != 0 {
+		t.Errorf("orphaned pod should not be deleted, got %+v", plan.Deletes)
+	}
+	if len(plan.Orphans) != 1 || len(plan.Orphans[0].OwnerReferences) != 1 || plan.Orphans[0].OwnerReferences[0].UUID != "fn-uuid" {
+		t.Fatalf("expected to remove rs from the owners of pod, got %+v", plan.Orphans)
+	}
+	if len(plan.Finalizes) != 0 {
+		t.Errorf("rs should wait until pod is orphaned, got %+v", plan.Finalizes)
+	}
+
+	pod.Metadata.OwnerReferences = plan.Orphans[0].OwnerReferences
+	plan = planGarbageCollection([]gcObject{rs, fn, pod}, gcTestKinds)
+	if len(plan.Finalizes) != 1 || plan.Finalizes[0].Finalizer != apiObject.FinalizerOrphan {
+		t.Errorf("expected to finalize rs, got %+v", plan.Finalizes)
+	}
+}
@@ -1,25 +1,29 @@
 package allcontollers
 
 import (
-	"errors"
 	"fmt"
 	"math"
 	"miniK8s/pkg/apiObject"
-	"miniK8s/pkg/config"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+	"miniK8s/pkg/client/informers"
+	"miniK8s/pkg/event"
 	"miniK8s/pkg/k8log"
 	minik8stypes "miniK8s/pkg/minik8sTypes"
+	"miniK8s/pkg/workqueue"
 	"miniK8s/util/executor"
-	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
-	"net/http"
 	"strconv"
 	"time"
 )
 
 var (
-	HpaControllerUpdateDelay     = time.Second * 0
+	// 新创建的hpa由informer的事件马上处理，周期性的检查从一个周期之后开始
+	HpaControllerUpdateDelay     = time.Second * 15
 	HpaControllerUpdateFrequency = []time.Duration{15 * time.Second}
 	HpaControllerUpdateLoop      = true
+	// 并行处理hpa的worker数量
+	HpaControllerWorkers = 2
 )
 
 type HpaController interface {
@@ -27,46 +31,40 @@ type HpaController interface {
 }
 
 type hpaController struct {
-}
-
-func NewHpaController() (HpaController, error) {
-	return &hpaController{}, nil
-}
+	// 记录扩容、缩容的事件
+	recorder  event.Recorder
+	clientset *client.Clientset
 
-func (hc *hpaController) GetAllHpaFromAPIServer() ([]apiObject.HPAStore, error) {
-	url := config.GetAPIServerURLPrefix() + config.GlobalHPAURL
+	// 从informer的缓存读取pod和hpa，周期性检查的时候不再访问API Server
+	podLister   informers.PodLister
+	hpaLister   informers.HPALister
+	cacheSynced []func() bool
 
-	allHPA := make([]apiObject.HPAStore, 0)
-
-	code, err := netrequest.GetRequestByTarget(url, &allHPA, "data")
+	// 需要检查的hpa的 namespace/name，周期性检查的时候同一个hpa不会被重复处理
+	queue workqueue.RateLimitingInterface
+}
 
-	if err != nil {
-		return nil, err
+func NewHpaController(clientset *client.Clientset, factory informers.SharedInformerFactory) (HpaController, error) {
+	hc := &hpaController{
+		recorder:    event.NewRecorder(event.ComponentHPAController),
+		clientset:   clientset,
+		podLister:   factory.Pods().Lister(),
+		hpaLister:   factory.HPAs().Lister(),
+		cacheSynced: []func() bool{factory.Pods().Informer().HasSynced, factory.HPAs().Informer().HasSynced},
+		queue:       workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 	}
 
-	if code != http.StatusOK {
-		return nil, errors.New("get all hpa from apiserver failed")
-	}
+	// hpa的状态每个周期都会更新，所以只处理新建的hpa，其余的变化留给周期性的检查
+	factory.HPAs().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: hc.enqueue,
+	})
 
-	return allHPA, nil
+	return hc, nil
 }
 
 // 将更新过的hpa存入到etcd中
 func (hc *hpaController) UpdateHpaStatus(hpa apiObject.HPAStore) error {
-	url := config.GetAPIServerURLPrefix() + config.HPASpecStatusURL
-	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, hpa.Metadata.Namespace)
-	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, hpa.Metadata.Name)
-
-	code, _, err := netrequest.PutRequestByTarget(url, &(hpa.Status))
-
-	if err != nil {
-		return err
-	}
-	if code != http.StatusOK {
-		return errors.New("update hpa status failed, code expected 200, but got " + strconv.Itoa(code))
-	}
-
-	return nil
+	return hc.clientset.HPAs().UpdateStatus(hpa.Metadata.Namespace, hpa.Metadata.Name, &hpa.Status)
 }
 
 func (hc *hpaController) AddOneHpaPod(hpa apiObject.HPAStore, podTemplate apiObject.Pod) error {
@@ -74,6 +72,12 @@ func (hc *hpaController) AddOneHpaPod(hpa apiObject.HPAStore, podTemplate apiObj
 	// 根据podTemplate，创建新的pod
 	newPod := podTemplate
 	newPod.Metadata.Name = podTemplate.GetObjectName() + "-" + stringutil.GenerateRandomStr(5)
+	// podTemplate来自informer的缓存，labels和containers复制一份再修改
+	newPod.Metadata.Labels = make(map[string]string)
+	for key, value := range podTemplate.Metadata.Labels {
+		newPod.Metadata.Labels[key] = value
+	}
+	newPod.Spec.Containers = append([]apiObject.Container{}, podTemplate.Spec.Containers...)
 
 	// 修改container的name
 	for index := range podTemplate.Spec.Containers {
@@ -84,42 +88,37 @@ func (hc *hpaController) AddOneHpaPod(hpa apiObject.HPAStore, podTemplate apiObj
 	newPod.Metadata.Labels[minik8stypes.Pod_HPA_Name] = hpa.Metadata.Name
 	newPod.Metadata.Labels[minik8stypes.Pod_HPA_Namespace] = hpa.Metadata.Namespace
 	newPod.Metadata.Labels[minik8stypes.Pod_HPA_UUID] = hpa.Metadata.UUID
-
-	// 通过api server创建pod
-	url := config.GetAPIServerURLPrefix() + config.PodsURL
-	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, hpa.Spec.Workload.Metadata.Namespace)
-	code, _, err := netrequest.PostRequestByTarget(url, &newPod)
+	// podTemplate可能是其他对象创建的pod，所有者换成hpa，hpa删除之后GC会删除这些pod
+	newPod.Metadata.OwnerReferences = []apiObject.OwnerReference{apiObject.NewOwnerReference(apiObject.HpaKind, &hpa.Metadata)}
+	newPod.Metadata.Finalizers = nil
+	newPod.Metadata.DeletionTimestamp = nil
+
+	// 通过api server创建pod，和workload在同一个namespace
+	if namespace := hpa.Spec.Workload.Metadata.Namespace; namespace != "" {
+		newPod.Metadata.Namespace = namespace
+	}
+	err := hc.clientset.Pods().Create(&newPod)
 	if err != nil {
 		k8log.ErrorLog("hpaController", "AddHpaPods "+err.Error())
 		return err
 	}
-	if code != http.StatusCreated {
-		k8log.ErrorLog("hpaController", "AddHpaPods code not 201")
-		return err
-	}
 	return nil
 }
 
 func (hc *hpaController) ReduceOneHpaPod(pod apiObject.PodStore) error {
 	k8log.DebugLog("HpaController", fmt.Sprintf("ReduceOneHpaPod: pod=%s", pod.Metadata.Name))
 	// 通过api server删除pod
-	url := config.GetAPIServerURLPrefix() + config.PodSpecURL
-	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, pod.Metadata.Namespace)
-	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, pod.Metadata.Name)
-
-	code, err := netrequest.DelRequest(url)
+	err := hc.clientset.Pods().Delete(pod.Metadata.Namespace, pod.Metadata.Name)
 	if err != nil {
 		k8log.ErrorLog("hpaController", "ReducePods "+err.Error())
 		return err
 	}
-	if code != http.StatusNoContent {
-		k8log.ErrorLog("hpaController", "ReducePods code not 204")
-		return err
-	}
 	return nil
 }
 
-func (hc *hpaController) HandleHPAUpdate(hpa apiObject.HPAStore, pods []apiObject.PodStore) {
+// 返回扩缩容失败的错误，由队列退避之后重试
+// 更新status失败的时候只记录日志，下一个周期会重新计算，避免重试的时候缓存还没有看到新的pod而重复扩容
+func (hc *hpaController) HandleHPAUpdate(hpa apiObject.HPAStore, pods []apiObject.PodStore) error {
 	// 1. 根据hpa的selector，找到所有满足要求的pod
 	meetRequirementPods := make([]apiObject.PodStore, 0)
 	for _, pod := range pods {
@@ -128,6 +127,11 @@ func (hc *hpaController) HandleHPAUpdate(hpa apiObject.HPAStore, pods []apiObjec
 		}
 	}
 	hpa.Status.CurrentReplicas = len(meetRequirementPods)
+	// 新的pod需要以已有的pod为模板创建
+	if len(meetRequirementPods) == 0 {
+		k8log.DebugLog("hpaController", "hpa can't find any pods matching the selector")
+		return nil
+	}
 
 	// 2. 判断hpa的pod是否在规定区间之内, 如果不在，需要扩容或者缩容
 	if hpa.Status.CurrentReplicas < hpa.Spec.MinReplicas {
@@ -135,14 +139,16 @@ func (hc *hpaController) HandleHPAUpdate(hpa apiObject.HPAStore, pods []apiObjec
 		if err != nil {
 			k8log.ErrorLog("hpaController", "HandleHPAUpdate "+err.Error())
 		}
-		return
+		hc.recordRescale(hpa, hpa.Status.CurrentReplicas+1, "below minReplicas", err)
+		return err
 	}
 	if hpa.Status.CurrentReplicas > hpa.Spec.MaxReplicas {
 		err := hc.ReduceOneHpaPod(meetRequirementPods[0])
 		if err != nil {
 			k8log.ErrorLog("hpaController", "HandleHPAUpdate "+err.Error())
 		}
-		return
+		hc.recordRescale(hpa, hpa.Status.CurrentReplicas-1, "above maxReplicas", err)
+		return err
 	}
 
 	// 3. 计算hpa匹配的pod的平均cpu和memory使用率
@@ -151,11 +157,14 @@ func (hc *hpaController) HandleHPAUpdate(hpa apiObject.HPAStore, pods []apiObjec
 
 	// 4. 根据hpa的spec和计算出来的平均使用率，得到期望的replica个数
 	expectedReplicas := hc.CalculateExpectedReplicas(hpa, averageCPUUsage, averageMemoryUsage)
+	var rescaleErr error
 	if expectedReplicas > hpa.Status.CurrentReplicas {
-		hc.AddOneHpaPod(hpa, *meetRequirementPods[0].ToPod())
+		rescaleErr = hc.AddOneHpaPod(hpa, *meetRequirementPods[0].ToPod())
+		hc.recordRescale(hpa, hpa.Status.CurrentReplicas+1, "resource utilization above target", rescaleErr)
 	}
 	if expectedReplicas < hpa.Status.CurrentReplicas {
-		hc.ReduceOneHpaPod(meetRequirementPods[0])
+		rescaleErr = hc.ReduceOneHpaPod(meetRequirementPods[0])
+		hc.recordRescale(hpa, hpa.Status.CurrentReplicas-1, "resource utilization below target", rescaleErr)
 	}
 
 	// 5. 更新hpa的status, replica的数量不会马上更新，因为需要时间创建或者删除pod
@@ -167,53 +176,55 @@ func (hc *hpaController) HandleHPAUpdate(hpa apiObject.HPAStore, pods []apiObjec
 		k8log.ErrorLog("hpaController", "HandleHPAUpdate "+err.Error())
 	}
 
+	return rescaleErr
 }
 
-func (hc *hpaController) Routine() {
-	pods, err := GetAllPodFromAPIServer()
+// 记录一次扩容或者缩容的结果
+func (hc *hpaController) recordRescale(hpa apiObject.HPAStore, newSize int, reason string, err error) {
+	ref := apiObject.NewObjectReference(apiObject.HpaKind, &hpa.Metadata)
 	if err != nil {
+		hc.recorder.Eventf(ref, apiObject.EventTypeWarning, "FailedRescale", "New size: %d; reason: %s; error: %s", newSize, reason, err.Error())
 		return
 	}
-	if len(pods) == 0 {
-		k8log.DebugLog("hpaController", "hpa can't find any pods")
-		return
-	}
+	hc.recorder.Eventf(ref, apiObject.EventTypeNormal, "SuccessfulRescale", "New size: %d; reason: %s", newSize, reason)
+}
 
-	hpas, err := hc.GetAllHpaFromAPIServer()
+func (hc *hpaController) enqueue(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
 	if err != nil {
+		k8log.ErrorLog("hpaController", "enqueue hpa failed: "+err.Error())
 		return
 	}
+	hc.queue.Add(key)
+}
 
-	// 构造一个namespace/name的map，映射的value是hpa的uuid
-	hpaMap := make(map[string]string, 0)
+func (hc *hpaController) Routine() {
+	// 把所有的hpa加入队列，还在队列里面或者正在处理的hpa不会重复加入
+	for _, hpa := range hc.hpaLister.List(client.NamespaceAll) {
+		hc.queue.Add(hpa.Metadata.Namespace + "/" + hpa.Metadata.Name)
+	}
+}
 
-	for _, hpa := range hpas {
-		key := hpa.Metadata.Namespace + "/" + hpa.Metadata.Name
-		hpaMap[key] = hpa.Metadata.UUID
+// 用缓存里面的pod检查一个hpa
+func (hc *hpaController) syncHpa(item interface{}) error {
+	namespace, name := cache.SplitMetaNamespaceKey(item.(string))
+	hpa, ok := hc.hpaLister.Get(namespace, name)
+	// 已经被删除的hpa，pod由GC删除
+	if !ok {
+		return nil
 	}
 
-	// 遍历所有的hpa，执行update操作
-	for _, hpa := range hpas {
-		go hc.HandleHPAUpdate(hpa, pods)
+	// 正在删除的hpa不再扩缩容，pod由GC删除
+	if hpa.Metadata.IsBeingDeleted() {
+		return nil
 	}
 
-	// 对于已经删除的hpa，如果发现其对应的pod还存在，那么就删除这些pod
-	for _, pod := range pods {
-		if pod.Metadata.Labels[minik8stypes.Pod_HPA_UUID] != "" {
-			if pod.Metadata.Labels[minik8stypes.Pod_HPA_Namespace] == "" {
-				continue
-			}
-			if pod.Metadata.Labels[minik8stypes.Pod_HPA_Name] == "" {
-				continue
-			}
-
-			key := pod.Metadata.Labels[minik8stypes.Pod_HPA_Namespace] + "/" + pod.Metadata.Labels[minik8stypes.Pod_HPA_Name]
-			if _, ok := hpaMap[key]; !ok {
-				// 说明这个pod对应的replicasets已经被删除了，那么就删除这个pod
-				hc.ReduceOneHpaPod(pod)
-			}
-		}
+	pods := hc.podLister.List(client.NamespaceAll)
+	if len(pods) == 0 {
+		k8log.DebugLog("hpaController", "hpa can't find any pods")
+		return nil
 	}
+	return hc.HandleHPAUpdate(*hpa, pods)
 }
 
 func (hc *hpaController) CalculateAverageCPUUsage(pods []apiObject.PodStore) float64 {
@@ -254,6 +265,12 @@ func (hc *hpaController) CalculateExpectedReplicas(hpa apiObject.HPAStore, cpuUs
 }
 
 func (hc *hpaController) Run() {
-	// 定期执行
-	executor.Period(HpaControllerUpdateDelay, HpaControllerUpdateFrequency, hc.Routine, HpaControllerUpdateLoop)
+	// controller一直运行到进程退出，所以不需要stopCh
+	if !cache.WaitForCacheSync(nil, hc.cacheSynced...) {
+		return
+	}
+
+	// 定期把所有的hpa加入队列，由多个worker处理
+	go executor.Period(HpaControllerUpdateDelay, HpaControllerUpdateFrequency, hc.Routine, HpaControllerUpdateLoop)
+	workqueue.Run("hpaController", hc.queue, HpaControllerWorkers, workqueue.DefaultMaxRetries, hc.syncHpa, nil)
 }
@@ -1,13 +1,15 @@
 package allcontollers
 
 import (
-	"encoding/json"
+	"fmt"
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/apiserver/serverconfig"
 	"miniK8s/pkg/config"
+	"miniK8s/pkg/event"
 	"miniK8s/pkg/k8log"
 	"miniK8s/pkg/listwatcher"
 	"miniK8s/pkg/message"
+	"miniK8s/pkg/workqueue"
 	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
 	"net/http"
@@ -17,14 +19,27 @@ import (
 
 const (
 	GPU_Server_Image = "musicminion/minik8s-gpu:latest"
+	// 挂载Job凭证的volume的名字
+	jobCredentialVolume = "job-credential"
+)
+
+var (
+	// 并行创建job pod的worker数量
+	JobControllerWorkers = 2
 )
 
 type JobController interface {
 	Run()
+	// Healthy 检查监听消息使用的RabbitMQ链接是否可用
+	Healthy() error
 }
 
 type jobController struct {
 	lw *listwatcher.Listwatcher
+	// 记录创建job pod的事件
+	recorder event.Recorder
+	// 需要创建pod的job的URI，创建失败之后退避重试
+	queue workqueue.RateLimitingInterface
 }
 
 func NewJobController() (JobController, error) {
@@ -36,34 +51,34 @@ func NewJobController() (JobController, error) {
 	}
 
 	return &jobController{
-		lw: newlw,
+		lw:       newlw,
+		recorder: event.NewRecorder(event.ComponentJobController),
+		queue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 	}, nil
 }
 
-func (jc *jobController) JobCreateHandler(parsedMsg *message.Message) {
-	jobMeta := &apiObject.Basic{}
-	err := json.Unmarshal([]byte(parsedMsg.Content), jobMeta)
-
-	if err != nil {
-		k8log.ErrorLog("Job-Controller", "HandleServiceUpdate: failed to unmarshal")
-		return
-	}
-
+// 为job创建运行job-server的pod，返回错误的时候由队列退避之后重试
+func (jc *jobController) JobCreateHandler(resourceURI string) error {
 	// 主动请求job的信息
-	targetURI := parsedMsg.ResourceURI
-	targetURI = config.GetAPIServerURLPrefix() + targetURI
+	targetURI := config.GetAPIServerURLPrefix() + resourceURI
 
 	job := &apiObject.JobStore{}
 	code, err := netrequest.GetRequestByTarget(targetURI, job, "data")
 
 	if err != nil {
 		k8log.ErrorLog("Job-Controller", "HandleJobCreate: failed to get job"+err.Error())
-		return
+		return err
+	}
+
+	// 重试之前job已经被删除了
+	if code == http.StatusNotFound {
+		k8log.WarnLog("Job-Controller", "HandleJobCreate: job "+resourceURI+" not found")
+		return nil
 	}
 
 	if code != http.StatusOK {
 		k8log.ErrorLog("Job-Controller", "HandleJobCreate: failed to get job [Not 200]")
-		return
+		return fmt.Errorf("get job %s failed, code %d", resourceURI, code)
 	}
 
 	// 不需要主动检查jobFile
@@ -86,6 +101,8 @@ func (jc *jobController) JobCreateHandler(parsedMsg *message.Message) {
 			Metadata: apiObject.Metadata{
 				Name:      job.Metadata.Name,
 				Namespace: job.Metadata.Namespace,
+				// job删除之后，GC会删除这个pod
+				OwnerReferences: []apiObject.OwnerReference{apiObject.NewOwnerReference(apiObject.JobKind, &job.Metadata)},
 			},
 		},
 		Spec: apiObject.PodSpec{
@@ -95,6 +112,16 @@ func (jc *jobController) JobCreateHandler(parsedMsg *message.Message) {
 					Name:    "gpu-server" + job.Metadata.UUID,
 					Image:   GPU_Server_Image,
 					Command: containerCmd,
+					// job-server访问APIServer使用Job自己的token和客户端证书，由kubelet写成文件挂载进来，只能访问这个Job
+					VolumeMounts: []apiObject.VolumeMount{
+						{Name: jobCredentialVolume, MountPath: config.TokenDir, ReadOnly: true},
+					},
+				},
+			},
+			Volumes: []apiObject.Volume{
+				{
+					Name:          jobCredentialVolume,
+					JobCredential: apiObject.JobCredentialVolumeSource{JobName: job.Metadata.Name},
 				},
 			},
 		},
@@ -105,17 +132,28 @@ func (jc *jobController) JobCreateHandler(parsedMsg *message.Message) {
 
 	code, _, err = netrequest.PostRequestByTarget(podURI, pod)
 
+	jobRef := apiObject.NewObjectReference(apiObject.JobKind, &job.Metadata)
 	if err != nil {
 		k8log.ErrorLog("Job-Controller", "HandleServiceUpdate: failed to create pod"+err.Error())
-		return
+		jc.recorder.Eventf(jobRef, apiObject.EventTypeWarning, "FailedCreate", "Error creating pod %s: %s", pod.Metadata.Name, err.Error())
+		return err
+	}
+
+	// 上一次请求已经创建了pod，只是没有收到响应
+	if code == http.StatusConflict {
+		k8log.InfoLog("Job-Controller", "HandleServiceUpdate: pod already exists")
+		return nil
 	}
 
 	if code != http.StatusCreated {
 		k8log.ErrorLog("Job-Controller", "HandleServiceUpdate: failed to create pod [Not 201]")
-		return
+		jc.recorder.Eventf(jobRef, apiObject.EventTypeWarning, "FailedCreate", "Error creating pod %s, code %d", pod.Metadata.Name, code)
+		return fmt.Errorf("create pod %s failed, code %d", pod.Metadata.Name, code)
 	}
+	jc.recorder.Eventf(jobRef, apiObject.EventTypeNormal, "SuccessfulCreate", "Created pod: %s", pod.Metadata.Name)
 
 	k8log.InfoLog("Job-Controller", "HandleServiceUpdate: success to create pod")
+	return nil
 }
 
 func (jc *jobController) JobDeleteHandler(msg *message.Message) {
@@ -128,18 +166,28 @@ func (jc *jobController) MsgHandler(msg amqp.Delivery) {
 	parsedMsg, err := message.ParseJsonMessageFromBytes(msg.Body)
 	if err != nil {
 		k8log.ErrorLog("Job-Controller", "消息格式错误,无法转换为Message")
+		return
 	}
 
 	switch parsedMsg.Type {
-	// 同样的也是创建一个Pod
+	// 同样的也是创建一个Pod，放入队列由worker处理，同一个job在队列里面只有一份
 	case message.UPDATE:
-		jc.JobCreateHandler(parsedMsg)
+		jc.queue.Add(parsedMsg.ResourceURI)
 	// 这里的删除对应的是删除pod
 	case message.DELETE:
 		jc.JobDeleteHandler(parsedMsg)
 	}
 }
 
+func (jc *jobController) Healthy() error {
+	return jc.lw.Healthy()
+}
+
+func (jc *jobController) syncJob(item interface{}) error {
+	return jc.JobCreateHandler(item.(string))
+}
+
 func (jc *jobController) Run() {
+	go workqueue.Run("Job-Controller", jc.queue, JobControllerWorkers, workqueue.DefaultMaxRetries, jc.syncJob, nil)
 	jc.lw.WatchQueue_Block(message.JobUpdateQueue, jc.MsgHandler, make(chan struct{}))
 }
@@ -0,0 +1,278 @@
+package allcontollers
+
+import (
+	"errors"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"miniK8s/util/executor"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	NamespaceControllerUpdateDelay     = time.Second * 0
+	NamespaceControllerUpdateFrequency = []time.Duration{5 * time.Second}
+	NamespaceControllerUpdateLoop      = true
+)
+
+// 删除namespace的时候，按照这个顺序删除namespace下面的资源
+// 先删除会创建Pod的上层资源，避免删除Pod之后又被重新创建出来，最后再删除Pod
+var namespaceDeleteOrder = []string{
+	apiObject.HpaKind,
+	apiObject.ReplicaSetKind,
+	apiObject.FunctionKind,
+	apiObject.WorkflowKind,
+	apiObject.JobKind,
+	apiObject.ServiceKind,
+	apiObject.DnsKind,
+	apiObject.PodKind,
+	// 删除上面的资源的过程中还会产生事件，放在后面删除
+	apiObject.LeaseKind,
+	apiObject.EventKind,
+	// 权限最后删除，保证删除的过程中用户仍然可以操作namespace里面的资源
+	apiObject.RoleBindingKind,
+	apiObject.RoleKind,
+}
+
+// kind->namespace下面所有这类资源的URL
+var namespaceResourceURLs = map[string]string{
+	apiObject.HpaKind:        config.HPAURL,
+	apiObject.ReplicaSetKind: config.ReplicaSetsURL,
+	apiObject.FunctionKind:   config.FunctionURL,
+	apiObject.WorkflowKind:   config.WorkflowURL,
+	apiObject.JobKind:        config.JobsURL,
+	apiObject.ServiceKind:    config.ServiceURL,
+	apiObject.DnsKind:        config.DnsURL,
+	apiObject.PodKind:        config.PodsURL,
+	apiObject.LeaseKind:      config.LeasesURL,
+	apiObject.EventKind:      config.EventsURL,
+
+	apiObject.RoleBindingKind: config.RoleBindingsURL,
+	apiObject.RoleKind:        config.RolesURL,
+}
+
+// kind->namespace下面某个资源的URL
+var namespaceResourceSpecURLs = map[string]string{
+	apiObject.HpaKind:        config.HPASpecURL,
+	apiObject.ReplicaSetKind: config.ReplicaSetSpecURL,
+	apiObject.FunctionKind:   config.FunctionSpecURL,
+	apiObject.WorkflowKind:   config.WorkflowSpecURL,
+	apiObject.JobKind:        config.JobSpecURL,
+	apiObject.ServiceKind:    config.ServiceSpecURL,
+	apiObject.DnsKind:        config.DnsSpecURL,
+	apiObject.PodKind:        config.PodSpecURL,
+	apiObject.LeaseKind:      config.LeaseSpecURL,
+	apiObject.EventKind:      config.EventSpecURL,
+
+	apiObject.RoleBindingKind: config.RoleBindingSpecURL,
+	apiObject.RoleKind:        config.RoleSpecURL,
+}
+
+type NamespaceController interface {
+	Run()
+}
+
+type namespaceController struct {
+}
+
+func NewNamespaceController() (NamespaceController, error) {
+	return &namespaceController{}, nil
+}
+
+func (nc *namespaceController) GetAllNamespacesFromAPIServer() ([]apiObject.NamespaceStore, error) {
+	url := config.GetAPIServerURLPrefix() + config.NamespacesURL
+
+	allNamespaces := make([]apiObject.NamespaceStore, 0)
+
+	code, err := netrequest.GetRequestByTarget(url, &allNamespaces, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, errors.New("get all namespaces from apiserver failed")
+	}
+
+	return allNamespaces, nil
+}
+
+// 获取namespace下面某一类资源的所有对象，只需要用到metadata
+func (nc *namespaceController) GetNamespacedObjects(kind string, namespace string) ([]apiObject.Basic, error) {
+	url := config.GetAPIServerURLPrefix() + namespaceResourceURLs[kind]
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
+
+	objects := make([]apiObject.Basic, 0)
+
+	code, err := netrequest.GetRequestByTarget(url, &objects, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, errors.New("get " + kind + " from apiserver failed, code " + strconv.Itoa(code))
+	}
+
+	return objects, nil
+}
+
+// 通过api server删除一个对象，这样删除时候的其他操作(比如释放Service的IP)也会被执行
+func (nc *namespaceController) DeleteNamespacedObject(kind string, namespace string, name string) error {
+	url := config.GetAPIServerURLPrefix() + namespaceResourceSpecURLs[kind]
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, name)
+
+	code, err := netrequest.DelRequest(url)
+	if err != nil {
+		return err
+	}
+
+	// 已经被其他人删除了，也算删除成功；202表示对象还有finalizers，下一轮再检查
+	if code != http.StatusNoContent && code != http.StatusAccepted && code != http.StatusNotFound {
+		return errors.New("delete " + kind + " " + name + " failed, code " + strconv.Itoa(code))
+	}
+
+	return nil
+}
+
+// 获取所有的CRD，namespace下面的自定义对象也需要删除
+func (nc *namespaceController) GetAllCRDsFromAPIServer() ([]apiObject.CustomResourceDefinition, error) {
+	url := config.GetAPIServerURLPrefix() + config.CRDsURL
+
+	crds := make([]apiObject.CustomResourceDefinition, 0)
+
+	code, err := netrequest.GetRequestByTarget(url, &crds, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, errors.New("get all crds from apiserver failed, code " + strconv.Itoa(code))
+	}
+
+	return crds, nil
+}
+
+// 删除namespace下面某个CRD定义的所有自定义对象，已经没有对象的时候返回true
+func (nc *namespaceController) DeleteCustomObjects(crd *apiObject.CustomResourceDefinition, namespace string) (bool, error) {
+	url := config.GetAPIServerURLPrefix() + config.CustomResourcesURL
+	url = stringutil.Replace(url, config.URL_PARAM_GROUP_PART, crd.Spec.Group)
+	url = stringutil.Replace(url, config.URL_PARAM_VERSION_PART, crd.Spec.Version)
+	url = stringutil.Replace(url, config.URL_PARAM_PLURAL_PART, crd.Spec.Names.Plural)
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
+
+	objects := make([]apiObject.Basic, 0)
+	code, err := netrequest.GetRequestByTarget(url, &objects, "data")
+	if err != nil {
+		return false, err
+	}
+	if code != http.StatusOK {
+		return false, errors.New("get " + crd.Spec.Names.Kind + " from apiserver failed, code " + strconv.Itoa(code))
+	}
+
+	for _, obj := range objects {
+		k8log.DebugLog("NamespaceController", fmt.Sprintf("DeleteCustomObjects: delete %s %s/%s", crd.Spec.Names.Kind, namespace, obj.Metadata.Name))
+		code, err := netrequest.DelRequest(url + "/" + obj.Metadata.Name)
+		if err != nil {
+			k8log.ErrorLog("NamespaceController", "DeleteCustomObjects: "+err.Error())
+			continue
+		}
+		// 已经被其他人删除了，也算删除成功
+		if code != http.StatusNoContent && code != http.StatusAccepted && code != http.StatusNotFound {
+			k8log.ErrorLog("NamespaceController", "DeleteCustomObjects: delete "+obj.Metadata.Name+" failed, code "+strconv.Itoa(code))
+		}
+	}
+	return len(objects) == 0, nil
+}
+
+// 下面的资源都删除之后，通知api server真正删除namespace
+func (nc *namespaceController) FinalizeNamespace(namespace string) error {
+	url := config.GetAPIServerURLPrefix() + config.NamespaceFinalizeURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
+
+	code, _, err := netrequest.PutRequestByTarget(url, nil)
+	if err != nil {
+		return err
+	}
+
+	if code != http.StatusOK {
+		return errors.New("finalize namespace " + namespace + " failed, code " + strconv.Itoa(code))
+	}
+
+	return nil
+}
+
+// 删除namespace下面的所有资源，全部删除完毕之后返回true
+func (nc *namespaceController) DeleteNamespaceContent(namespace string) bool {
+	empty := true
+	for _, kind := range namespaceDeleteOrder {
+		objects, err := nc.GetNamespacedObjects(kind, namespace)
+		if err != nil {
+			k8log.ErrorLog("NamespaceController", "DeleteNamespaceContent: "+err.Error())
+			empty = false
+			continue
+		}
+
+		for _, obj := range objects {
+			empty = false
+			k8log.DebugLog("NamespaceController", fmt.Sprintf("DeleteNamespaceContent: delete %s %s/%s", kind, namespace, obj.Metadata.Name))
+			if err := nc.DeleteNamespacedObject(kind, namespace, obj.Metadata.Name); err != nil {
+				k8log.ErrorLog("NamespaceController", "DeleteNamespaceContent: "+err.Error())
+			}
+		}
+	}
+
+	// 自定义对象
+	crds, err := nc.GetAllCRDsFromAPIServer()
+	if err != nil {
+		k8log.ErrorLog("NamespaceController", "DeleteNamespaceContent: "+err.Error())
+		return false
+	}
+	for i := range crds {
+		deleted, err := nc.DeleteCustomObjects(&crds[i], namespace)
+		if err != nil {
+			k8log.ErrorLog("NamespaceController", "DeleteNamespaceContent: "+err.Error())
+		}
+		if !deleted {
+			empty = false
+		}
+	}
+	return empty
+}
+
+func (nc *namespaceController) Routine() {
+	namespaces, err := nc.GetAllNamespacesFromAPIServer()
+	if err != nil {
+		k8log.ErrorLog("NamespaceController", "Routine: "+err.Error())
+		return
+	}
+
+	for _, namespace := range namespaces {
+		if namespace.Status.Phase != apiObject.NamespaceTerminating {
+			continue
+		}
+
+		name := namespace.Metadata.Name
+		k8log.InfoLog("NamespaceController", "Routine: namespace "+name+" is terminating")
+
+		// 这一轮还有资源没有删除干净，下一轮再检查
+		if !nc.DeleteNamespaceContent(name) {
+			continue
+		}
+
+		if err := nc.FinalizeNamespace(name); err != nil {
+			k8log.ErrorLog("NamespaceController", "Routine: "+err.Error())
+			continue
+		}
+		k8log.InfoLog("NamespaceController", "Routine: namespace "+name+" deleted")
+	}
+}
+
+func (nc *namespaceController) Run() {
+	// 定期执行
+	executor.Period(NamespaceControllerUpdateDelay, NamespaceControllerUpdateFrequency, nc.Routine, NamespaceControllerUpdateLoop)
+}
@@ -4,106 +4,149 @@ import (
 	"errors"
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/apiserver/serverconfig"
-	"miniK8s/pkg/config"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+	"miniK8s/pkg/client/informers"
+	"miniK8s/pkg/event"
 	"miniK8s/pkg/k8log"
 	minik8stypes "miniK8s/pkg/minik8sTypes"
-	"miniK8s/util/executor"
-	netrequest "miniK8s/util/netRequest"
+	"miniK8s/pkg/workqueue"
 	"miniK8s/util/stringutil"
-	"net/http"
 	"time"
 )
 
+var (
+	// 并行处理replicaset的worker数量
+	ReplicaControllerWorkers = 2
+)
+
 type ReplicaController interface {
 	Run()
 }
 
 type replicaController struct {
+	// 记录创建、删除pod的事件
+	recorder  event.Recorder
+	clientset *client.Clientset
+
+	// 从informer的缓存读取pod和replicaset，不再轮询API Server
+	podLister        informers.PodLister
+	replicaSetLister informers.ReplicaSetLister
+	cacheSynced      []func() bool
+
+	// 需要同步的replicaset的 namespace/name，失败之后退避重试
+	queue workqueue.RateLimitingInterface
+	// 已经发出但是还没有在缓存里面观察到的创建和删除
+	expectations *podExpectations
 }
 
-func NewReplicaController() (ReplicaController, error) {
-	return &replicaController{}, nil
-}
-
-func (rc *replicaController) GetAllReplicasetsFromAPIServer() ([]apiObject.ReplicaSetStore, error) {
-	url := config.GetAPIServerURLPrefix() + config.GlobalReplicaSetsURL
-
-	allReplicaSets := make([]apiObject.ReplicaSetStore, 0)
-
-	code, err := netrequest.GetRequestByTarget(url, &allReplicaSets, "data")
-
-	if err != nil {
-		return nil, err
-	}
-
-	if code != http.StatusOK {
-		return nil, errors.New("get all replicasets from apiserver failed")
+func NewReplicaController(clientset *client.Clientset, factory informers.SharedInformerFactory) (ReplicaController, error) {
+	rc := &replicaController{
+		recorder:         event.NewRecorder(event.ComponentReplicaSetController),
+		clientset:        clientset,
+		podLister:        factory.Pods().Lister(),
+		replicaSetLister: factory.ReplicaSets().Lister(),
+		cacheSynced:      []func() bool{factory.Pods().Informer().HasSynced, factory.ReplicaSets().Informer().HasSynced},
+		queue:            workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		expectations:     newPodExpectations(),
 	}
 
-	return allReplicaSets, nil
+	// informer定期resync的时候也会触发，保证漏掉的变化最终会被处理
+	factory.ReplicaSets().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    rc.enqueueReplicaSet,
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) { rc.enqueueReplicaSet(newObj) },
+		DeleteFunc: rc.enqueueReplicaSet,
+	})
+	factory.Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			pod := obj.(*apiObject.PodStore)
+			rc.expectations.observe(podExpectationAdd, pod)
+			rc.enqueuePodOwners(pod)
+		},
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) {
+			// 带有finalizers的pod删除的时候只是设置了deletionTimestamp
+			pod := newObj.(*apiObject.PodStore)
+			if pod.Metadata.IsBeingDeleted() {
+				rc.expectations.observe(podExpectationDelete, pod)
+			}
+			// label变化之后，原来和现在匹配的replicaset都需要同步
+			rc.enqueuePodOwners(oldObj.(*apiObject.PodStore))
+			rc.enqueuePodOwners(pod)
+		},
+		DeleteFunc: func(obj interface{}) {
+			pod := obj.(*apiObject.PodStore)
+			rc.expectations.observe(podExpectationDelete, pod)
+			rc.enqueuePodOwners(pod)
+		},
+	})
+
+	return rc, nil
 }
 
-func (rc *replicaController) routine() {
-	pods, err := GetAllPodFromAPIServer()
-
+func (rc *replicaController) enqueueReplicaSet(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
 	if err != nil {
+		k8log.ErrorLog("replicaController", "enqueue replicaset failed: "+err.Error())
 		return
 	}
+	rc.queue.Add(key)
+}
 
-	replicasets, err := rc.GetAllReplicasetsFromAPIServer()
-
-	if err != nil {
-		return
+// 用户自己创建的pod也可能满足replicaset的selector，所以把selector匹配这个pod的replicaset都加入队列
+func (rc *replicaController) enqueuePodOwners(pod *apiObject.PodStore) {
+	for _, rs := range rc.replicaSetLister.List(client.NamespaceAll) {
+		if CheckIfPodMeetRequirement(pod, rs.Spec.Selector.MatchLabels) {
+			rc.queue.Add(rs.Metadata.Namespace + "/" + rs.Metadata.Name)
+		}
 	}
+}
 
-	// 构造一个namespace/name的map，映射的value是replicasets的uuid
-	replicasetsMap := make(map[string]string, 0)
+// 根据缓存同步一个replicaset，返回错误的时候由队列退避之后重试
+func (rc *replicaController) syncReplicaSet(item interface{}) error {
+	key := item.(string)
+	namespace, name := cache.SplitMetaNamespaceKey(key)
+	rs, ok := rc.replicaSetLister.Get(namespace, name)
+	// 已经被删除的replicaset，pod由GC删除
+	if !ok {
+		return nil
+	}
 
-	for _, rs := range replicasets {
-		key := rs.Metadata.Namespace + "/" + rs.Metadata.Name
-		replicasetsMap[key] = rs.Metadata.UUID
+	// 正在删除的replicaset不再调整pod的数量，pod由GC删除
+	if rs.Metadata.IsBeingDeleted() {
+		return nil
 	}
 
-	// 1. 遍历所有的replicasets
-	for _, rs := range replicasets {
-		meetRequirementPods := make([]apiObject.PodStore, 0)
-		for _, pod := range pods {
-			if CheckIfPodMeetRequirement(&pod, rs.Spec.Selector.MatchLabels) {
-				meetRequirementPods = append(meetRequirementPods, pod)
-			}
-		}
+	// 上一次创建或者删除的pod还没有出现在缓存里面，等缓存跟上之后再调整，避免重复创建
+	// 期望被满足的时候pod的事件会再次把replicaset加入队列
+	if !rc.expectations.satisfied(key) {
+		return nil
+	}
 
-		// 2. 根据pod的数量，调整replicasets的数量
-		if len(meetRequirementPods) < rs.Spec.Replicas {
-			// 需要增加replicasets的数量
-			rc.AddReplicaPodsNums(&rs.Metadata, &rs.Spec.Template, rs.Spec.Replicas-len(meetRequirementPods))
-		} else if len(meetRequirementPods) > rs.Spec.Replicas {
-			// 需要减少replicasets的数量
-			rc.ReduceReplicaPodsNums(meetRequirementPods, len(meetRequirementPods)-rs.Spec.Replicas)
+	// 1. 找到满足selector的pod，正在删除的pod不再计入副本数
+	meetRequirementPods := make([]apiObject.PodStore, 0)
+	for _, pod := range rc.podLister.List(client.NamespaceAll) {
+		if !pod.Metadata.IsBeingDeleted() && CheckIfPodMeetRequirement(&pod, rs.Spec.Selector.MatchLabels) {
+			meetRequirementPods = append(meetRequirementPods, pod)
 		}
-
-		// 3. 根据选择好的pod的状态，更新replicasets的状态
-		// 注意，以上对replicaset的修改不会马上反映在replicaset的status里
-		rc.UpdateReplicaSetStatus(meetRequirementPods, &rs)
 	}
 
-	// 2. 对于已经删除的replicasets，如果发现其对应的pod还存在，那么就删除这些pod
-	for _, pod := range pods {
-		if pod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_UUID] != "" {
-			if pod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_Namespace] == "" {
-				continue
-			}
-			if pod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_Name] == "" {
-				continue
-			}
+	// 2. 根据pod的数量，调整replicasets的数量
+	var err error
+	if len(meetRequirementPods) < rs.Spec.Replicas {
+		// 需要增加replicasets的数量
+		err = rc.AddReplicaPodsNums(&rs.Metadata, &rs.Spec.Template, rs.Spec.Replicas-len(meetRequirementPods))
+	} else if len(meetRequirementPods) > rs.Spec.Replicas {
+		// 需要减少replicasets的数量
+		err = rc.ReduceReplicaPodsNums(&rs.Metadata, meetRequirementPods, len(meetRequirementPods)-rs.Spec.Replicas)
+	}
 
-			key := pod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_Namespace] + "/" + pod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_Name]
-			if _, ok := replicasetsMap[key]; !ok {
-				// 说明这个pod对应的replicasets已经被删除了，那么就删除这个pod
-				rc.ReduceReplicaPodsNums([]apiObject.PodStore{pod}, 1)
-			}
-		}
+	// 3. 根据选择好的pod的状态，更新replicasets的状态
+	// 注意，以上对replicaset的修改不会马上反映在replicaset的status里
+	statusErr := rc.UpdateReplicaSetStatus(meetRequirementPods, rs)
+	if err != nil {
+		return err
 	}
+	return statusErr
 }
 
 // 增加或者减少pod的数量
@@ -114,9 +157,21 @@ func (rc *replicaController) AddReplicaPodsNums(replicaMeta *apiObject.Metadata,
 	newPod.Kind = apiObject.PodKind
 	newPod.APIVersion = serverconfig.APIVersion
 	newPod.Spec = pod.Spec
+	// template来自informer的缓存，labels和containers复制一份再修改
+	newPod.Metadata.Labels = make(map[string]string)
+	for key, value := range pod.Metadata.Labels {
+		newPod.Metadata.Labels[key] = value
+	}
+	newPod.Spec.Containers = append([]apiObject.Container{}, pod.Spec.Containers...)
+	// template里面没有写namespace的时候，和replicaset在同一个namespace
+	if newPod.Metadata.Namespace == "" {
+		newPod.Metadata.Namespace = replicaMeta.Namespace
+	}
 	newPod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_Name] = replicaMeta.Name
 	newPod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_Namespace] = replicaMeta.Namespace
 	newPod.Metadata.Labels[minik8stypes.Pod_ReplicaSet_UUID] = replicaMeta.UUID
+	// replicaset删除之后，GC根据ownerReferences删除它创建的pod
+	newPod.Metadata.OwnerReferences = []apiObject.OwnerReference{apiObject.NewOwnerReference(apiObject.ReplicaSetKind, replicaMeta)}
 
 	originalPodName := newPod.Metadata.Name
 
@@ -128,7 +183,8 @@ func (rc *replicaController) AddReplicaPodsNums(replicaMeta *apiObject.Metadata,
 	}
 
 	// 通过api server创建pod
-	url := config.GetAPIServerURLPrefix() + config.PodsURL
+	rsRef := apiObject.NewObjectReference(apiObject.ReplicaSetKind, replicaMeta)
+	rsKey := replicaMeta.Namespace + "/" + replicaMeta.Name
 
 	errStr := ""
 	for i := 0; i < num; i++ {
@@ -139,18 +195,17 @@ func (rc *replicaController) AddReplicaPodsNums(replicaMeta *apiObject.Metadata,
 			newPod.Spec.Containers[index].Name = originalContainerNames[index] + "-" + stringutil.GenerateRandomStr(5)
 		}
 
-		url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, pod.Metadata.Namespace)
-		// url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, pod.Metadata.Name+"-"+strconv.Itoa(i)+"-"+stringutil.GenerateRandomStr(5))
-		code, _, err := netrequest.PostRequestByTarget(url, &newPod)
+		// 先记录期望，避免informer在Create返回之前就推送了这个pod
+		rc.expectations.expect(podExpectationAdd, rsKey, newPod.Metadata.Namespace, newPod.Metadata.Name)
+		err := rc.clientset.Pods().Create(&newPod)
 
 		if err != nil {
-			k8log.ErrorLog("replicaController", "AddPodsNums"+err.Error())
+			k8log.ErrorLog("replicaController", "AddPodsNums "+err.Error())
 			errStr += err.Error()
-		}
-
-		if code != http.StatusCreated {
-			k8log.ErrorLog("replicaController", "AddPodsNums code is not 201")
-			errStr += "code is not 200"
+			rc.expectations.lower(podExpectationAdd, rsKey, newPod.Metadata.Namespace, newPod.Metadata.Name)
+			rc.recorder.Eventf(rsRef, apiObject.EventTypeWarning, "FailedCreate", "Error creating pod %s: %s", newPod.Metadata.Name, err.Error())
+		} else {
+			rc.recorder.Eventf(rsRef, apiObject.EventTypeNormal, "SuccessfulCreate", "Created pod: %s", newPod.Metadata.Name)
 		}
 	}
 
@@ -161,26 +216,32 @@ func (rc *replicaController) AddReplicaPodsNums(replicaMeta *apiObject.Metadata,
 	return nil
 }
 
-func (rc *replicaController) ReduceReplicaPodsNums(meetRequirePods []apiObject.PodStore, num int) error {
+func (rc *replicaController) ReduceReplicaPodsNums(replicaMeta *apiObject.Metadata, meetRequirePods []apiObject.PodStore, num int) error {
 	// 遍历删除pod
 	if len(meetRequirePods) < num {
 		return errors.New("reduce pod nums failed")
 	}
 
+	rsRef := apiObject.NewObjectReference(apiObject.ReplicaSetKind, replicaMeta)
+	rsKey := replicaMeta.Namespace + "/" + replicaMeta.Name
+	errStr := ""
 	for i := 0; i < num; i++ {
-		url := config.GetAPIServerURLPrefix() + config.PodSpecURL
-		url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, meetRequirePods[i].Metadata.Namespace)
-		url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, meetRequirePods[i].Metadata.Name)
-
-		code, err := netrequest.DelRequest(url)
+		pod := meetRequirePods[i]
+		rc.expectations.expect(podExpectationDelete, rsKey, pod.Metadata.Namespace, pod.Metadata.Name)
+		err := rc.clientset.Pods().Delete(pod.Metadata.Namespace, pod.Metadata.Name)
 
 		if err != nil {
-			k8log.ErrorLog("replicaController", "ReducePodsNums"+err.Error())
+			k8log.ErrorLog("replicaController", "ReducePodsNums "+err.Error())
+			errStr += err.Error()
+			rc.expectations.lower(podExpectationDelete, rsKey, pod.Metadata.Namespace, pod.Metadata.Name)
+			rc.recorder.Eventf(rsRef, apiObject.EventTypeWarning, "FailedDelete", "Error deleting pod %s: %s", pod.Metadata.Name, err.Error())
+		} else {
+			rc.recorder.Eventf(rsRef, apiObject.EventTypeNormal, "SuccessfulDelete", "Deleted pod: %s", pod.Metadata.Name)
 		}
+	}
 
-		if code != http.StatusNoContent {
-			k8log.ErrorLog("replicaController", "ReducePodsNums code is not 204")
-		}
+	if errStr != "" {
+		return errors.New(errStr)
 	}
 
 	return nil
@@ -211,33 +272,45 @@ func (rc *replicaController) UpdateReplicaSetStatus(meetRequirePods []apiObject.
 	newReplicaSetStatus.Replicas = replicaset.Spec.Replicas
 	newReplicaSetStatus.ReadyReplicas = ReadyNums
 
-	// 2. 更新replicaset的状态
-	url := stringutil.Replace(config.ReplicaSetSpecStatusURL, config.URL_PARAM_NAMESPACE_PART, replicaset.Metadata.Namespace)
-	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, replicaset.Metadata.Name)
-	url = config.GetAPIServerURLPrefix() + url
+	// 状态没有变化的时候不更新，否则更新状态触发的事件又会引起一次同步
+	if !replicaSetStatusChanged(&replicaset.Status, &newReplicaSetStatus) {
+		return nil
+	}
 
-	code, _, err := netrequest.PutRequestByTarget(url, &newReplicaSetStatus)
+	// 2. 更新replicaset的状态
+	err := rc.clientset.ReplicaSets().UpdateStatus(replicaset.Metadata.Namespace, replicaset.Metadata.Name, &newReplicaSetStatus)
 
 	if err != nil {
-		k8log.ErrorLog("replicaController", "UpdateReplicaSetStatus"+err.Error())
+		k8log.ErrorLog("replicaController", "UpdateReplicaSetStatus "+err.Error())
 		return err
 	}
 
-	if code != http.StatusOK {
-		k8log.ErrorLog("replicaController", "UpdateReplicaSetStatus code is not 200")
-		return errors.New("UpdateReplicaSetStatus code is not 200")
-	}
-
 	return nil
 }
 
-func (rc *replicaController) Run() {
-	// 定期执行
-	executor.Period(ReplicaControllerUpdateDelay, ReplicaControllerUpdateFrequency, rc.routine, ReplicaControllerUpdateLoop)
+// 比较副本数和每个pod的状态，不比较更新时间
+func replicaSetStatusChanged(oldStatus *apiObject.ReplicaSetStatus, newStatus *apiObject.ReplicaSetStatus) bool {
+	if oldStatus.Replicas != newStatus.Replicas || oldStatus.ReadyReplicas != newStatus.ReadyReplicas {
+		return true
+	}
+	if len(oldStatus.Conditions) != len(newStatus.Conditions) {
+		return true
+	}
+	for i := range oldStatus.Conditions {
+		if oldStatus.Conditions[i].Type != newStatus.Conditions[i].Type || oldStatus.Conditions[i].Status != newStatus.Conditions[i].Status {
+			return true
+		}
+	}
+	return false
 }
 
-var (
-	ReplicaControllerUpdateDelay     = 5 * time.Second
-	ReplicaControllerUpdateFrequency = []time.Duration{10 * time.Second}
-	ReplicaControllerUpdateLoop      = true
-)
+// 等待informer同步完成之后，由多个worker处理队列里面的replicaset
+// controller一直运行到进程退出，所以不需要stopCh
+func (rc *replicaController) Run() {
+	if !cache.WaitForCacheSync(nil, rc.cacheSynced...) {
+		return
+	}
+	k8log.InfoLog("replicaController", "cache synced, start to sync replicasets")
+
+	workqueue.Run("replicaController", rc.queue, ReplicaControllerWorkers, workqueue.DefaultMaxRetries, rc.syncReplicaSet, nil)
+}
@@ -1,19 +1,33 @@
 package ctrlmanager
 
 import (
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/informers"
+	"miniK8s/pkg/config"
 	"miniK8s/pkg/controller/allcontollers"
+	"miniK8s/pkg/healthz"
 	"miniK8s/pkg/k8log"
+	"miniK8s/pkg/leaderelection"
+	"time"
 )
 
+// informer定期把缓存里面的对象重新通知一遍，保证漏掉或者处理失败的变化最终会被处理
+const informerResyncPeriod = 30 * time.Second
+
 type CtrlManager interface {
 	Run(stopCh <-chan struct{})
 }
 
 type ctrlManager struct {
 	jobController     allcontollers.JobController
-	replicaController allcontollers.HpaController
+	replicaController allcontollers.ReplicaController
 	dnsController     allcontollers.DnsController
 	hpaController     allcontollers.HpaController
+	nsController      allcontollers.NamespaceController
+	gcController      allcontollers.GCController
+
+	// ReplicaSet和HPA的controller共用的informer
+	informerFactory informers.SharedInformerFactory
 }
 
 func NewCtrlManager() CtrlManager {
@@ -22,7 +36,10 @@ func NewCtrlManager() CtrlManager {
 		panic(err)
 	}
 
-	newrc, err := allcontollers.NewReplicaController()
+	clientset := client.NewClientset()
+	informerFactory := informers.NewSharedInformerFactory(clientset, informerResyncPeriod)
+
+	newrc, err := allcontollers.NewReplicaController(clientset, informerFactory)
 
 	if err != nil {
 		panic(err)
@@ -33,7 +50,17 @@ func NewCtrlManager() CtrlManager {
 		panic(err)
 	}
 
-	newhc, err := allcontollers.NewHpaController()
+	newhc, err := allcontollers.NewHpaController(clientset, informerFactory)
+	if err != nil {
+		panic(err)
+	}
+
+	newnc, err := allcontollers.NewNamespaceController()
+	if err != nil {
+		panic(err)
+	}
+
+	newgc, err := allcontollers.NewGCController()
 	if err != nil {
 		panic(err)
 	}
@@ -43,21 +70,41 @@ func NewCtrlManager() CtrlManager {
 		dnsController:     newdc,
 		replicaController: newrc,
 		hpaController:     newhc,
+		nsController:      newnc,
+		gcController:      newgc,
+		informerFactory:   informerFactory,
 	}
 }
 
 func (cm *ctrlManager) Run(stopCh <-chan struct{}) {
-	// TODO
-	go cm.jobController.Run()
-	go cm.dnsController.Run()
-	go cm.replicaController.Run()
-	go cm.hpaController.Run()
-
-	// wait for stop signal
-	_, ok := <-stopCh
-	if !ok {
-		k8log.ErrorLog("CtrlManager", "stopCh closed")
-	}
-	k8log.InfoLog("CtrlManager", "stop signal received")
+	// job和dns的controller通过RabbitMQ监听变化，ReplicaSet和HPA的controller通过informer监听变化
+	// namespace和gc的controller周期性地轮询API Server
+	go healthz.ListenAndServe("CtrlManager", config.Controller_Manager_Healthz_Port, nil, []healthz.HealthChecker{
+		healthz.NamedCheck("job-controller-rabbitmq", cm.jobController.Healthy),
+		healthz.NamedCheck("dns-controller-rabbitmq", cm.dnsController.Healthy),
+	})
+
+	// 启动多个副本的时候只有leader运行controller，避免ReplicaSet的Pod被创建两次、HPA扩容两次
+	// 失去leader之后直接退出，由外部重启之后重新参与选举
+	leaderelection.RunOrDie(leaderelection.DefaultLeaderElectionConfig(leaderelection.LeaseNameControllerManager), leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(leaderStopCh <-chan struct{}) {
+			// 只有leader需要缓存，成为leader之后才开始list和watch
+			cm.informerFactory.Start(leaderStopCh)
+			go cm.jobController.Run()
+			go cm.dnsController.Run()
+			go cm.replicaController.Run()
+			go cm.hpaController.Run()
+			go cm.nsController.Run()
+			go cm.gcController.Run()
+		},
+		OnStoppedLeading: func() {
+			select {
+			case <-stopCh:
+			default:
+				k8log.FatalLog("CtrlManager", "leader election lost")
+			}
+		},
+	}, stopCh)
 
+	k8log.InfoLog("CtrlManager", "stop signal received")
 }
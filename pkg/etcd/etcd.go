@@ -2,9 +2,11 @@ package etcd
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
 	etcd "go.etcd.io/etcd/client/v3"
 )
 
@@ -16,6 +18,8 @@ type WatchResType int
 const (
 	PUT    WatchResType = 0
 	DELETE WatchResType = 1
+	// watch失败，原因在WatchRes.Err里面，之后channel会被关闭
+	ERROR WatchResType = 2
 )
 
 type String interface {
@@ -30,6 +34,11 @@ type WatchRes struct {
 	IsModify        bool // true when ResType == PUT and the key is old
 	Key             string
 	Value           string
+	// 开启了WithPrevKV的时候是写入或者删除之前的值，新创建的key为空
+	// 可以用来判断对象是不是从满足selector变成了不满足
+	PrevValue string
+	// ResType为ERROR的时候是watch失败的原因，revision被压缩的时候是ErrCompacted
+	Err error
 }
 
 type ListRes struct {
@@ -57,8 +66,6 @@ func NewEtcdStore(endpoints []string, timeout time.Duration) (*Store, error) {
 	return &Store{client: cli}, nil
 }
 
-
-
 // 修复了一下逻辑，通过返回的时候创建一个切片，而不是直接组装
 func (s *Store) Get(key string) ([]ListRes, error) {
 	response, err := s.client.Get(context.TODO(), key)
@@ -74,7 +81,7 @@ func (s *Store) Get(key string) ([]ListRes, error) {
 	// 遍历response.Kvs，将每一个key-value转换为ListRes
 	for id, kv := range response.Kvs {
 		res = append(res, ListRes{
-			ResourceVersion: response.Header.Revision,
+			ResourceVersion: kv.ModRevision,
 			CreateVersion:   response.Kvs[id].CreateRevision,
 			Key:             string(kv.Key),
 			Value:           string(kv.Value),
@@ -94,12 +101,73 @@ func (s *Store) Put(key string, val []byte) error {
 	return err
 }
 
+// 带版本检查的Put，只有当key当前的ModRevision等于resourceVersion的时候才会写入
+// 返回值表示是否写入成功，写入失败说明在这期间有其他人修改了这个key
+func (s *Store) CompareAndSwap(key string, resourceVersion int64, val []byte) (bool, error) {
+	response, err := s.client.Txn(context.TODO()).
+		If(etcd.Compare(etcd.ModRevision(key), "=", resourceVersion)).
+		Then(etcd.OpPut(key, string(val))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return response.Succeeded, nil
+}
+
+// 带版本检查和过期时间的Put，ttl为0的时候和CompareAndSwap一样
+// 每次写入都会绑定一个新的lease，所以过期时间从最后一次写入开始计算
+// 写入失败的时候撤销新的lease，写入成功的时候撤销key原来的lease，避免etcd中堆积没有用的lease
+func (s *Store) CompareAndSwapWithTTL(key string, resourceVersion int64, val []byte, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return s.CompareAndSwap(key, resourceVersion, val)
+	}
+
+	// lease的最小单位是秒
+	seconds := int64(ttl / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	lease, err := s.client.Grant(context.TODO(), seconds)
+	if err != nil {
+		return false, err
+	}
+
+	// WithPrevKV拿到写入之前的值，里面有原来的lease
+	response, err := s.client.Txn(context.TODO()).
+		If(etcd.Compare(etcd.ModRevision(key), "=", resourceVersion)).
+		Then(etcd.OpPut(key, string(val), etcd.WithLease(lease.ID), etcd.WithPrevKV())).
+		Commit()
+	if err != nil || !response.Succeeded {
+		// 撤销失败的时候lease到期之后也会被etcd回收
+		s.client.Revoke(context.TODO(), lease.ID)
+		return false, err
+	}
+
+	// 每个lease只绑定了一个key，key已经换成了新的lease，撤销原来的lease不会删除其他的key
+	if put := response.Responses[0].GetResponsePut(); put != nil && put.PrevKv != nil && put.PrevKv.Lease != 0 {
+		s.client.Revoke(context.TODO(), etcd.LeaseID(put.PrevKv.Lease))
+	}
+	return true, nil
+}
+
 // 删除指定的Key
 func (s *Store) Del(key string) error {
 	_, err := s.client.Delete(context.TODO(), key)
 	return err
 }
 
+// 带版本检查的Del，只有当key当前的ModRevision等于resourceVersion的时候才会删除
+func (s *Store) CompareAndDelete(key string, resourceVersion int64) (bool, error) {
+	response, err := s.client.Txn(context.TODO()).
+		If(etcd.Compare(etcd.ModRevision(key), "=", resourceVersion)).
+		Then(etcd.OpDelete(key)).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return response.Succeeded, nil
+}
+
 // 删除所有的key
 func (s *Store) DelAll() error {
 	_, err := s.client.Delete(context.TODO(), "", etcd.WithPrefix())
@@ -120,6 +188,13 @@ func convertEventToWatchRes(event *etcd.Event) WatchRes { // 根据event的类
 		res.Value = string(event.Kv.Value)
 	case etcd.EventTypeDelete:
 		res.ResType = DELETE
+		// 开启了WithPrevKV的时候，可以拿到被删除之前的值
+		if event.PrevKv != nil {
+			res.Value = string(event.PrevKv.Value)
+		}
+	}
+	if event.PrevKv != nil {
+		res.PrevValue = string(event.PrevKv.Value)
 	}
 	return res
 }
@@ -155,6 +230,92 @@ func (s *Store) PrefixWatch(key string) (context.CancelFunc, <-chan WatchRes) {
 	return cancel, watchResChan
 }
 
+// 从指定的revision开始监听某个前缀下的变化(包含revision本身)，事件会带上修改或者删除之前的值
+// revision小于等于0的时候，从当前时刻开始监听
+func (s *Store) PrefixWatchFromRevision(key string, revision int64) (context.CancelFunc, <-chan WatchRes) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	watchResChan := make(chan WatchRes)
+	opts := []etcd.OpOption{etcd.WithPrefix(), etcd.WithPrevKV()}
+	if revision > 0 {
+		opts = append(opts, etcd.WithRev(revision))
+	}
+	go func(c chan<- WatchRes) {
+		defer close(c)
+		for watchResponse := range s.client.Watch(ctx, key, opts...) {
+			// revision已经被压缩等情况，推送一个ERROR之后结束监听
+			if err := watchResponse.Err(); err != nil {
+				if errors.Is(err, rpctypes.ErrCompacted) {
+					err = ErrCompacted
+				}
+				select {
+				case c <- WatchRes{ResType: ERROR, Err: err}:
+				case <-ctx.Done():
+				}
+				return
+			}
+			for _, event := range watchResponse.Events {
+				select {
+				case c <- convertEventToWatchRes(event):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}(watchResChan)
+	return cancel, watchResChan
+}
+
+// 和PrefixGet一样，额外返回本次读取时etcd的revision，可以用来接着做watch
+func (s *Store) PrefixGetWithRevision(key string) ([]ListRes, int64, error) {
+	response, err := s.client.Get(context.TODO(), key, etcd.WithPrefix())
+	if err != nil {
+		return []ListRes{}, 0, err
+	}
+	var ret []ListRes
+	for _, kv := range response.Kvs {
+		ret = append(ret, ListRes{
+			ResourceVersion: kv.ModRevision,
+			CreateVersion:   kv.CreateRevision,
+			Key:             string(kv.Key),
+			Value:           string(kv.Value),
+		})
+	}
+	return ret, response.Header.Revision, nil
+}
+
+// 分页读取某个前缀下的key，从startKey开始(包含startKey)，最多读取limit个
+// revision大于0的时候读取的是这个revision时刻的快照，保证多次分页读取的结果一致
+// 返回读取的结果、后面是否还有数据、本次读取使用的revision
+func (s *Store) PrefixGetPage(prefix string, startKey string, revision int64, limit int64) ([]ListRes, bool, int64, error) {
+	if startKey == "" {
+		startKey = prefix
+	}
+	opts := []etcd.OpOption{etcd.WithRange(etcd.GetPrefixRangeEnd(prefix)), etcd.WithLimit(limit)}
+	if revision > 0 {
+		opts = append(opts, etcd.WithRev(revision))
+	}
+	response, err := s.client.Get(context.TODO(), startKey, opts...)
+	if err != nil {
+		if errors.Is(err, rpctypes.ErrCompacted) {
+			err = ErrCompacted
+		}
+		return []ListRes{}, false, 0, err
+	}
+	var ret []ListRes
+	for _, kv := range response.Kvs {
+		ret = append(ret, ListRes{
+			ResourceVersion: kv.ModRevision,
+			CreateVersion:   kv.CreateRevision,
+			Key:             string(kv.Key),
+			Value:           string(kv.Value),
+		})
+	}
+	if revision <= 0 {
+		revision = response.Header.Revision
+	}
+	return ret, response.More, revision, nil
+}
+
 // 之前写的是直接赋值ret[i],这样会寄!,应该调用append
 func (s *Store) PrefixGet(key string) ([]ListRes, error) {
 	response, err := s.client.Get(context.TODO(), key, etcd.WithPrefix())
@@ -166,7 +327,7 @@ func (s *Store) PrefixGet(key string) ([]ListRes, error) {
 	// 遍历response.Kvs，将每一个key-value转换为ListRes
 	for id, kv := range response.Kvs {
 		ret = append(ret, ListRes{
-			ResourceVersion: response.Header.Revision,
+			ResourceVersion: kv.ModRevision,
 			CreateVersion:   response.Kvs[id].CreateRevision,
 			Key:             string(kv.Key),
 			Value:           string(kv.Value),
@@ -211,7 +372,7 @@ func (s *Store) PrefixGetWithExcude(key string, excude []string) ([]ListRes, err
 		}
 
 		ret = append(ret, ListRes{
-			ResourceVersion: response.Header.Revision,
+			ResourceVersion: kv.ModRevision,
 			CreateVersion:   response.Kvs[id].CreateRevision,
 			Key:             string(kv.Key),
 			Value:           string(kv.Value),
@@ -2,6 +2,7 @@
 package etcd
 
 import (
+	"context"
 	"testing"
 	"time"
 )
@@ -64,6 +65,71 @@ func TestPrefixGet(t *testing.T) {
 	t.Log(res)
 }
 
+func TestCompareAndSwap(t *testing.T) {
+	err := testEtcdStore.Put("/test/cas", []byte("casValue1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := testEtcdStore.Get("/test/cas")
+	if err != nil || len(res) != 1 {
+		t.Fatal("get error")
+	}
+	staleVersion := res[0].ResourceVersion
+
+	// 版本一致，应该写入成功
+	ok, err := testEtcdStore.CompareAndSwap("/test/cas", staleVersion, []byte("casValue2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Fatal("compare and swap should succeed")
+	}
+
+	// 版本已经过期，应该写入失败
+	ok, err = testEtcdStore.CompareAndSwap("/test/cas", staleVersion, []byte("casValue3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatal("compare and swap should fail with stale resourceVersion")
+	}
+
+	res, err = testEtcdStore.Get("/test/cas")
+	if err != nil || len(res) != 1 || res[0].Value != "casValue2" {
+		t.Fatal("value should not be overwritten")
+	}
+}
+
+func TestPrefixGetPage(t *testing.T) {
+	for i := 0; i < 5; i++ {
+		err := testEtcdStore.Put("/testpage/child"+string(rune('a'+i)), []byte("value"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, more, revision, err := testEtcdStore.PrefixGetPage("/testpage/", "", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 || !more {
+		t.Fatal("first page should have 3 keys and more data")
+	}
+
+	res, more, _, err = testEtcdStore.PrefixGetPage("/testpage/", res[2].Key+"\x00", revision, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || more {
+		t.Fatal("second page should have 2 keys and no more data")
+	}
+
+	err = testEtcdStore.PrefixDel("/testpage/")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDel(t *testing.T) {
 	err := testEtcdStore.Del("/test/child1")
 	if err != nil {
@@ -77,3 +143,50 @@ func TestPrefixDel(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// 当前etcd中lease的数量
+func countLeases(t *testing.T) int {
+	res, err := testEtcdStore.client.Leases(context.TODO())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(res.Leases)
+}
+
+func TestCompareAndSwapWithTTL(t *testing.T) {
+	leases := countLeases(t)
+
+	ok, err := testEtcdStore.CompareAndSwapWithTTL("/test/ttl", 0, []byte("ttlValue1"), time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("compare and swap should succeed, got %v, %v", ok, err)
+	}
+	if countLeases(t) != leases+1 {
+		t.Fatal("expected one lease for the key")
+	}
+
+	// 版本不对，写入失败，新的lease被撤销
+	ok, err = testEtcdStore.CompareAndSwapWithTTL("/test/ttl", 0, []byte("ttlValue2"), time.Minute)
+	if err != nil || ok {
+		t.Fatalf("compare and swap should fail, got %v, %v", ok, err)
+	}
+	if countLeases(t) != leases+1 {
+		t.Fatal("lease of the failed write should be revoked")
+	}
+
+	// 覆盖之后原来的lease被撤销，key仍然存在
+	res, err := testEtcdStore.Get("/test/ttl")
+	if err != nil || len(res) != 1 {
+		t.Fatal("get error")
+	}
+	ok, err = testEtcdStore.CompareAndSwapWithTTL("/test/ttl", res[0].ResourceVersion, []byte("ttlValue3"), time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("compare and swap should succeed, got %v, %v", ok, err)
+	}
+	if countLeases(t) != leases+1 {
+		t.Fatal("previous lease of the key should be revoked")
+	}
+	res, err = testEtcdStore.Get("/test/ttl")
+	if err != nil || len(res) != 1 || res[0].Value != "ttlValue3" {
+		t.Fatal("value should be overwritten")
+	}
+}
@@ -0,0 +1,122 @@
+package etcd
+
+import (
+	"context"
+	"miniK8s/pkg/metrics"
+	"time"
+)
+
+// 包装一个Storage，记录每种操作的耗时和出错的次数
+type instrumentedStorage struct {
+	storage Storage
+}
+
+func NewInstrumentedStorage(storage Storage) Storage {
+	return &instrumentedStorage{storage: storage}
+}
+
+func observe(operation string, start time.Time, err error) {
+	metrics.StorageRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.StorageRequestErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (s *instrumentedStorage) Get(key string) ([]ListRes, error) {
+	start := time.Now()
+	res, err := s.storage.Get(key)
+	observe("get", start, err)
+	return res, err
+}
+
+func (s *instrumentedStorage) Put(key string, val []byte) error {
+	start := time.Now()
+	err := s.storage.Put(key, val)
+	observe("put", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Del(key string) error {
+	start := time.Now()
+	err := s.storage.Del(key)
+	observe("delete", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) DelAll() error {
+	start := time.Now()
+	err := s.storage.DelAll()
+	observe("deleteAll", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) CompareAndSwap(key string, resourceVersion int64, val []byte) (bool, error) {
+	start := time.Now()
+	ok, err := s.storage.CompareAndSwap(key, resourceVersion, val)
+	observe("compareAndSwap", start, err)
+	return ok, err
+}
+
+func (s *instrumentedStorage) CompareAndSwapWithTTL(key string, resourceVersion int64, val []byte, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	ok, err := s.storage.CompareAndSwapWithTTL(key, resourceVersion, val, ttl)
+	observe("compareAndSwapWithTTL", start, err)
+	return ok, err
+}
+
+func (s *instrumentedStorage) CompareAndDelete(key string, resourceVersion int64) (bool, error) {
+	start := time.Now()
+	ok, err := s.storage.CompareAndDelete(key, resourceVersion)
+	observe("compareAndDelete", start, err)
+	return ok, err
+}
+
+func (s *instrumentedStorage) PrefixGet(key string) ([]ListRes, error) {
+	start := time.Now()
+	res, err := s.storage.PrefixGet(key)
+	observe("list", start, err)
+	return res, err
+}
+
+func (s *instrumentedStorage) PrefixGetWithRevision(key string) ([]ListRes, int64, error) {
+	start := time.Now()
+	res, revision, err := s.storage.PrefixGetWithRevision(key)
+	observe("list", start, err)
+	return res, revision, err
+}
+
+func (s *instrumentedStorage) PrefixGetPage(prefix string, startKey string, revision int64, limit int64) ([]ListRes, bool, int64, error) {
+	start := time.Now()
+	res, more, rev, err := s.storage.PrefixGetPage(prefix, startKey, revision, limit)
+	observe("listPage", start, err)
+	return res, more, rev, err
+}
+
+func (s *instrumentedStorage) PrefixDel(key string) error {
+	start := time.Now()
+	err := s.storage.PrefixDel(key)
+	observe("deletePrefix", start, err)
+	return err
+}
+
+// watch是长连接，只记录建立监听的耗时
+func (s *instrumentedStorage) Watch(key string) (context.CancelFunc, <-chan WatchRes) {
+	start := time.Now()
+	cancel, ch := s.storage.Watch(key)
+	observe("watch", start, nil)
+	return cancel, ch
+}
+
+func (s *instrumentedStorage) PrefixWatch(key string) (context.CancelFunc, <-chan WatchRes) {
+	start := time.Now()
+	cancel, ch := s.storage.PrefixWatch(key)
+	observe("watch", start, nil)
+	return cancel, ch
+}
+
+func (s *instrumentedStorage) PrefixWatchFromRevision(key string, revision int64) (context.CancelFunc, <-chan WatchRes) {
+	start := time.Now()
+	cancel, ch := s.storage.PrefixWatchFromRevision(key, revision)
+	observe("watch", start, nil)
+	return cancel, ch
+}
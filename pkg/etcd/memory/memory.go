@@ -0,0 +1,471 @@
+package memory
+
+import (
+	"context"
+	"miniK8s/pkg/etcd"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 读取或者监听的revision已经被压缩，和etcd的行为保持一致
+var ErrCompacted = etcd.ErrCompacted
+
+// 默认保留的历史revision的个数，超过之后压缩更早的历史
+const DefaultHistoryLimit = 10000
+
+// 内存中的存储，实现了etcd.Storage，用于开发和测试的时候不依赖外部的etcd
+// 和etcd一样保存每个key的历史版本，支持按照revision读取和从某个revision开始监听
+// 设置了TTL的key在下一次访问存储的时候检查是否过期，过期之后和普通的删除一样产生DELETE事件
+type Store struct {
+	mu       sync.Mutex
+	revision int64
+	// 小于等于compacted的revision已经不能读取
+	compacted    int64
+	historyLimit int64
+	// 每个key的历史版本，按照revision从小到大排列
+	keys map[string][]version
+	// 带有TTL的key和过期的时间
+	leases   map[string]time.Time
+	watchers map[*watcher]struct{}
+}
+
+// key的一个版本
+type version struct {
+	modRevision    int64
+	createRevision int64
+	value          string
+	// 这次写入之前的值，新创建的key为空
+	prevValue string
+	// 为true的时候这个版本是一次删除，value是删除之前的值
+	deleted bool
+}
+
+func NewStore() *Store {
+	return NewStoreWithHistoryLimit(DefaultHistoryLimit)
+}
+
+func NewStoreWithHistoryLimit(historyLimit int64) *Store {
+	if historyLimit < 1 {
+		historyLimit = 1
+	}
+	return &Store{
+		historyLimit: historyLimit,
+		keys:         make(map[string][]version),
+		leases:       make(map[string]time.Time),
+		watchers:     make(map[*watcher]struct{}),
+	}
+}
+
+var _ etcd.Storage = &Store{}
+
+// ==============================================
+// 读取
+// ==============================================
+
+func (s *Store) Get(key string) ([]etcd.ListRes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	v, ok := s.current(key)
+	if !ok {
+		return nil, nil
+	}
+	return []etcd.ListRes{toListRes(key, v)}, nil
+}
+
+func (s *Store) PrefixGet(key string) ([]etcd.ListRes, error) {
+	res, _, err := s.PrefixGetWithRevision(key)
+	return res, err
+}
+
+func (s *Store) PrefixGetWithRevision(key string) ([]etcd.ListRes, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	var ret []etcd.ListRes
+	for _, k := range s.sortedKeys(key, key) {
+		if v, ok := s.current(k); ok {
+			ret = append(ret, toListRes(k, v))
+		}
+	}
+	return ret, s.revision, nil
+}
+
+func (s *Store) PrefixGetPage(prefix string, startKey string, revision int64, limit int64) ([]etcd.ListRes, bool, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	if startKey == "" {
+		startKey = prefix
+	}
+	if revision <= 0 {
+		revision = s.revision
+	}
+	if revision <= s.compacted {
+		return []etcd.ListRes{}, false, 0, ErrCompacted
+	}
+
+	var ret []etcd.ListRes
+	more := false
+	for _, k := range s.sortedKeys(prefix, startKey) {
+		v, ok := s.at(k, revision)
+		if !ok {
+			continue
+		}
+		if limit > 0 && int64(len(ret)) >= limit {
+			more = true
+			break
+		}
+		ret = append(ret, toListRes(k, v))
+	}
+	return ret, more, revision, nil
+}
+
+// ==============================================
+// 修改
+// ==============================================
+
+func (s *Store) Put(key string, val []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	s.put(key, string(val), 0)
+	return nil
+}
+
+func (s *Store) CompareAndSwap(key string, resourceVersion int64, val []byte) (bool, error) {
+	return s.CompareAndSwapWithTTL(key, resourceVersion, val, 0)
+}
+
+func (s *Store) CompareAndSwapWithTTL(key string, resourceVersion int64, val []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	if s.modRevision(key) != resourceVersion {
+		return false, nil
+	}
+	// 和etcd的lease一样，最小单位是秒
+	if ttl > 0 && ttl < time.Second {
+		ttl = time.Second
+	}
+	s.put(key, string(val), ttl)
+	return true, nil
+}
+
+func (s *Store) Del(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	if _, ok := s.current(key); ok {
+		s.revision++
+		s.delete(key)
+		s.compact()
+	}
+	return nil
+}
+
+func (s *Store) CompareAndDelete(key string, resourceVersion int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	if s.modRevision(key) != resourceVersion {
+		return false, nil
+	}
+	if _, ok := s.current(key); ok {
+		s.revision++
+		s.delete(key)
+		s.compact()
+	}
+	return true, nil
+}
+
+// 和etcd一样，一次删除多个key只使用一个revision
+func (s *Store) PrefixDel(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expire()
+
+	s.deletePrefix(key)
+	return nil
+}
+
+func (s *Store) DelAll() error {
+	return s.PrefixDel("")
+}
+
+// ==============================================
+// 监听
+// ==============================================
+
+func (s *Store) Watch(key string) (context.CancelFunc, <-chan etcd.WatchRes) {
+	return s.watch(key, false, 0, false)
+}
+
+func (s *Store) PrefixWatch(key string) (context.CancelFunc, <-chan etcd.WatchRes) {
+	return s.watch(key, true, 0, false)
+}
+
+// revision已经被压缩的时候直接关闭channel，和etcd的实现一致
+func (s *Store) PrefixWatchFromRevision(key string, revision int64) (context.CancelFunc, <-chan etcd.WatchRes) {
+	return s.watch(key, true, revision, true)
+}
+
+func (s *Store) watch(key string, prefix bool, revision int64, prevKV bool) (context.CancelFunc, <-chan etcd.WatchRes) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watcher{
+		key:    key,
+		prefix: prefix,
+		prevKV: prevKV,
+		notify: make(chan struct{}, 1),
+	}
+
+	s.mu.Lock()
+	s.expire()
+	if revision > 0 && revision <= s.compacted {
+		s.mu.Unlock()
+		// 和etcd一样推送一个ERROR之后结束监听
+		out := make(chan etcd.WatchRes, 1)
+		out <- etcd.WatchRes{ResType: etcd.ERROR, Err: ErrCompacted}
+		close(out)
+		return cancel, out
+	}
+	if revision > 0 {
+		w.enqueue(s.history(w, revision))
+	}
+	s.watchers[w] = struct{}{}
+	s.mu.Unlock()
+
+	out := make(chan etcd.WatchRes)
+	go func() {
+		defer close(out)
+		defer func() {
+			s.mu.Lock()
+			delete(s.watchers, w)
+			s.mu.Unlock()
+		}()
+		w.run(ctx, out)
+	}()
+	return cancel, out
+}
+
+// ==============================================
+// 内部实现，调用的时候需要持有锁
+// ==============================================
+
+// key当前的版本，不存在或者已经删除的时候返回false
+func (s *Store) current(key string) (version, bool) {
+	versions := s.keys[key]
+	if len(versions) == 0 {
+		return version{}, false
+	}
+	v := versions[len(versions)-1]
+	return v, !v.deleted
+}
+
+// key在revision时刻的版本
+func (s *Store) at(key string, revision int64) (version, bool) {
+	versions := s.keys[key]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].modRevision <= revision {
+			return versions[i], !versions[i].deleted
+		}
+	}
+	return version{}, false
+}
+
+// key不存在的时候是0
+func (s *Store) modRevision(key string) int64 {
+	v, ok := s.current(key)
+	if !ok {
+		return 0
+	}
+	return v.modRevision
+}
+
+func (s *Store) put(key string, value string, ttl time.Duration) {
+	s.revision++
+	createRevision := s.revision
+	prevValue := ""
+	if cur, ok := s.current(key); ok {
+		createRevision = cur.createRevision
+		prevValue = cur.value
+	}
+	v := version{modRevision: s.revision, createRevision: createRevision, value: value, prevValue: prevValue}
+	s.keys[key] = append(s.keys[key], v)
+
+	// 每次写入都重新计算过期时间，没有TTL的写入会去掉原来的TTL
+	if ttl > 0 {
+		s.leases[key] = time.Now().Add(ttl)
+	} else {
+		delete(s.leases, key)
+	}
+
+	s.broadcast(key, v)
+	s.compact()
+}
+
+// 在当前的revision删除key，调用之前需要增加revision
+func (s *Store) delete(key string) {
+	cur, ok := s.current(key)
+	if !ok {
+		return
+	}
+	v := version{modRevision: s.revision, createRevision: cur.createRevision, value: cur.value, deleted: true}
+	s.keys[key] = append(s.keys[key], v)
+	delete(s.leases, key)
+	s.broadcast(key, v)
+}
+
+func (s *Store) deletePrefix(prefix string) {
+	keys := make([]string, 0)
+	for _, k := range s.sortedKeys(prefix, prefix) {
+		if _, ok := s.current(k); ok {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	s.revision++
+	for _, k := range keys {
+		s.delete(k)
+	}
+	s.compact()
+}
+
+// 删除已经过期的key，同一时刻过期的key使用同一个revision
+func (s *Store) expire() {
+	now := time.Now()
+	expired := make([]string, 0)
+	for key, expireAt := range s.leases {
+		if !now.Before(expireAt) {
+			expired = append(expired, key)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	sort.Strings(expired)
+	s.revision++
+	for _, key := range expired {
+		s.delete(key)
+	}
+	s.compact()
+}
+
+// 历史超过historyLimit的时候，压缩掉一半的历史
+// 每个key保留压缩点之前的最后一个版本，这样压缩点之后的revision还可以正常读取
+func (s *Store) compact() {
+	if s.revision-s.compacted <= s.historyLimit {
+		return
+	}
+	s.compacted = s.revision - s.historyLimit/2
+	for key, versions := range s.keys {
+		i := sort.Search(len(versions), func(i int) bool {
+			return versions[i].modRevision > s.compacted
+		})
+		// versions[:i]都在压缩点之前，只需要保留最后一个
+		if i > 1 {
+			versions = append([]version{}, versions[i-1:]...)
+		}
+		if len(versions) == 1 && versions[0].deleted && versions[0].modRevision <= s.compacted {
+			delete(s.keys, key)
+			continue
+		}
+		s.keys[key] = versions
+	}
+}
+
+// 前缀为prefix，并且大于等于start的所有key，按照字典序排列
+func (s *Store) sortedKeys(prefix string, start string) []string {
+	keys := make([]string, 0)
+	for k := range s.keys {
+		if strings.HasPrefix(k, prefix) && k >= start {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 从revision开始(包含revision)，w关心的所有历史事件
+func (s *Store) history(w *watcher, revision int64) []etcd.WatchRes {
+	type event struct {
+		key string
+		v   version
+	}
+	events := make([]event, 0)
+	for k, versions := range s.keys {
+		if !w.match(k) {
+			continue
+		}
+		for _, v := range versions {
+			if v.modRevision >= revision {
+				events = append(events, event{key: k, v: v})
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].v.modRevision != events[j].v.modRevision {
+			return events[i].v.modRevision < events[j].v.modRevision
+		}
+		return events[i].key < events[j].key
+	})
+
+	res := make([]etcd.WatchRes, 0, len(events))
+	for _, e := range events {
+		res = append(res, toWatchRes(e.key, e.v, w.prevKV))
+	}
+	return res
+}
+
+func (s *Store) broadcast(key string, v version) {
+	for w := range s.watchers {
+		if w.match(key) {
+			w.enqueue([]etcd.WatchRes{toWatchRes(key, v, w.prevKV)})
+		}
+	}
+}
+
+func toListRes(key string, v version) etcd.ListRes {
+	return etcd.ListRes{
+		ResourceVersion: v.modRevision,
+		CreateVersion:   v.createRevision,
+		Key:             key,
+		Value:           v.value,
+	}
+}
+
+func toWatchRes(key string, v version, prevKV bool) etcd.WatchRes {
+	res := etcd.WatchRes{
+		ResourceVersion: v.modRevision,
+		CreateVersion:   v.createRevision,
+		Key:             key,
+	}
+	if v.deleted {
+		res.ResType = etcd.DELETE
+		// 和etcd一样，只有开启了prevKV的时候才能拿到删除之前的值
+		if prevKV {
+			res.Value = v.value
+			res.PrevValue = v.value
+		}
+		return res
+	}
+	res.ResType = etcd.PUT
+	res.Value = v.value
+	res.IsCreate = v.createRevision == v.modRevision
+	res.IsModify = !res.IsCreate
+	if prevKV {
+		res.PrevValue = v.prevValue
+	}
+	return res
+}
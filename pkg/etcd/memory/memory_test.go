@@ -0,0 +1,167 @@
+package memory
+
+import (
+	"miniK8s/pkg/etcd"
+	"testing"
+	"time"
+)
+
+func TestPutGetDel(t *testing.T) {
+	s := NewStore()
+
+	if res, err := s.Get("/test/a"); err != nil || res != nil {
+		t.Fatalf("expected nil but got %v, %v", res, err)
+	}
+
+	s.Put("/test/a", []byte("1"))
+	s.Put("/test/a", []byte("2"))
+	s.Put("/test/b", []byte("3"))
+	s.Put("/other", []byte("4"))
+
+	res, _ := s.Get("/test/a")
+	if len(res) != 1 || res[0].Value != "2" || res[0].ResourceVersion != 2 || res[0].CreateVersion != 1 {
+		t.Errorf("unexpected get result %+v", res)
+	}
+
+	list, revision, _ := s.PrefixGetWithRevision("/test/")
+	if len(list) != 2 || list[0].Key != "/test/a" || list[1].Key != "/test/b" || revision != 4 {
+		t.Errorf("unexpected prefix get result %+v, revision %d", list, revision)
+	}
+
+	s.PrefixDel("/test/")
+	if list, _ := s.PrefixGet("/test/"); len(list) != 0 {
+		t.Errorf("expected empty but got %+v", list)
+	}
+	s.Del("/other")
+	if res, _ := s.Get("/other"); res != nil {
+		t.Errorf("expected nil but got %+v", res)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	s := NewStore()
+
+	// 版本为0表示key不存在的时候才写入
+	if ok, _ := s.CompareAndSwap("/a", 0, []byte("1")); !ok {
+		t.Fatalf("create should succeed")
+	}
+	if ok, _ := s.CompareAndSwap("/a", 0, []byte("2")); ok {
+		t.Fatalf("create an existing key should fail")
+	}
+	res, _ := s.Get("/a")
+	if ok, _ := s.CompareAndSwap("/a", res[0].ResourceVersion, []byte("2")); !ok {
+		t.Fatalf("update with the right version should succeed")
+	}
+	if ok, _ := s.CompareAndDelete("/a", res[0].ResourceVersion); ok {
+		t.Fatalf("delete with a stale version should fail")
+	}
+	res, _ = s.Get("/a")
+	if ok, _ := s.CompareAndDelete("/a", res[0].ResourceVersion); !ok {
+		t.Fatalf("delete with the right version should succeed")
+	}
+}
+
+func TestTTL(t *testing.T) {
+	s := NewStore()
+	cancel, ch := s.PrefixWatch("/events/")
+	defer cancel()
+
+	s.CompareAndSwapWithTTL("/events/a", 0, []byte("1"), time.Second)
+	if res, _ := s.Get("/events/a"); res == nil {
+		t.Fatalf("key should exist before expired")
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if res, _ := s.Get("/events/a"); res != nil {
+		t.Fatalf("key should be expired")
+	}
+
+	for _, expected := range []etcd.WatchResType{etcd.PUT, etcd.DELETE} {
+		select {
+		case event := <-ch:
+			if event.ResType != expected {
+				t.Errorf("expected %v but got %v", expected, event.ResType)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for event")
+		}
+	}
+}
+
+func TestPrefixGetPage(t *testing.T) {
+	s := NewStore()
+	for _, key := range []string{"/p/a", "/p/b", "/p/c", "/q/a"} {
+		s.Put(key, []byte(key))
+	}
+
+	page, more, revision, err := s.PrefixGetPage("/p/", "", 0, 2)
+	if err != nil || len(page) != 2 || !more || revision != 4 {
+		t.Fatalf("unexpected first page %+v, %v, %d, %v", page, more, revision, err)
+	}
+
+	// 后面的修改不影响同一个revision的分页
+	s.Put("/p/d", []byte("d"))
+	s.Del("/p/c")
+	page, more, _, err = s.PrefixGetPage("/p/", page[1].Key+"\x00", revision, 2)
+	if err != nil || len(page) != 1 || page[0].Key != "/p/c" || more {
+		t.Fatalf("unexpected second page %+v, %v, %v", page, more, err)
+	}
+}
+
+func TestWatchFromRevision(t *testing.T) {
+	s := NewStore()
+	s.Put("/w/a", []byte("1"))
+	s.Put("/w/a", []byte("2"))
+	s.Del("/w/a")
+	s.Put("/x/a", []byte("3"))
+
+	cancel, ch := s.PrefixWatchFromRevision("/w/", 2)
+	defer cancel()
+	s.Put("/w/b", []byte("4"))
+
+	expected := []etcd.WatchRes{
+		{ResType: etcd.PUT, ResourceVersion: 2, CreateVersion: 1, IsModify: true, Key: "/w/a", Value: "2", PrevValue: "1"},
+		{ResType: etcd.DELETE, ResourceVersion: 3, CreateVersion: 1, Key: "/w/a", Value: "2", PrevValue: "2"},
+		{ResType: etcd.PUT, ResourceVersion: 5, CreateVersion: 5, IsCreate: true, Key: "/w/b", Value: "4"},
+	}
+	for _, e := range expected {
+		select {
+		case event := <-ch:
+			if event != e {
+				t.Errorf("expected %+v but got %+v", e, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %+v", e)
+		}
+	}
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Errorf("channel should be closed after cancel")
+	}
+}
+
+func TestCompact(t *testing.T) {
+	s := NewStoreWithHistoryLimit(4)
+	for i := 0; i < 10; i++ {
+		s.Put("/c/a", []byte{byte('0' + i)})
+	}
+
+	if _, _, _, err := s.PrefixGetPage("/c/", "", 1, 0); err != ErrCompacted {
+		t.Errorf("expected ErrCompacted but got %v", err)
+	}
+	cancel, ch := s.PrefixWatchFromRevision("/c/", 1)
+	defer cancel()
+	if res, ok := <-ch; !ok || res.ResType != etcd.ERROR || res.Err != ErrCompacted {
+		t.Errorf("watch from a compacted revision should return ErrCompacted but got %+v", res)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf("watch from a compacted revision should be closed")
+	}
+
+	// 压缩之后的revision还可以读取
+	page, _, _, err := s.PrefixGetPage("/c/", "", 9, 0)
+	if err != nil || len(page) != 1 || page[0].Value != "8" {
+		t.Errorf("unexpected page %+v, %v", page, err)
+	}
+}
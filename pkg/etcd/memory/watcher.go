@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"miniK8s/pkg/etcd"
+	"strings"
+	"sync"
+)
+
+// 一个监听者，事件先放到队列里面，再由单独的goroutine发送
+// 这样修改存储的时候不会因为监听者没有及时读取而阻塞
+type watcher struct {
+	key    string
+	prefix bool
+	prevKV bool
+
+	mu     sync.Mutex
+	queue  []etcd.WatchRes
+	notify chan struct{}
+}
+
+func (w *watcher) match(key string) bool {
+	if w.prefix {
+		return strings.HasPrefix(key, w.key)
+	}
+	return key == w.key
+}
+
+func (w *watcher) enqueue(events []etcd.WatchRes) {
+	if len(events) == 0 {
+		return
+	}
+	w.mu.Lock()
+	w.queue = append(w.queue, events...)
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 把队列里面的事件发送到out，ctx取消的时候退出
+func (w *watcher) run(ctx context.Context, out chan<- etcd.WatchRes) {
+	for {
+		w.mu.Lock()
+		events := w.queue
+		w.queue = nil
+		w.mu.Unlock()
+
+		for _, event := range events {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-w.notify:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 读取的revision已经被压缩，所有的实现都返回这个错误，APIServer据此返回410
+var ErrCompacted = errors.New("required revision has been compacted")
+
+// APIServer使用的存储接口，除了etcd以外还有pkg/etcd/memory里面的内存实现
+// 所有的实现都需要保证：
+//   - 每次修改都会让revision增加，ResourceVersion是key最后一次修改时的revision
+//   - CompareAndXXX在resourceVersion等于key当前的ResourceVersion的时候才执行，key不存在时的ResourceVersion为0
+//   - Get在key不存在的时候返回nil
+type Storage interface {
+	Get(key string) ([]ListRes, error)
+	Put(key string, val []byte) error
+	Del(key string) error
+	DelAll() error
+
+	// 事务操作
+	CompareAndSwap(key string, resourceVersion int64, val []byte) (bool, error)
+	CompareAndSwapWithTTL(key string, resourceVersion int64, val []byte, ttl time.Duration) (bool, error)
+	CompareAndDelete(key string, resourceVersion int64) (bool, error)
+
+	PrefixGet(key string) ([]ListRes, error)
+	PrefixGetWithRevision(key string) ([]ListRes, int64, error)
+	PrefixGetPage(prefix string, startKey string, revision int64, limit int64) ([]ListRes, bool, int64, error)
+	PrefixDel(key string) error
+
+	Watch(key string) (context.CancelFunc, <-chan WatchRes)
+	PrefixWatch(key string) (context.CancelFunc, <-chan WatchRes)
+	PrefixWatchFromRevision(key string, revision int64) (context.CancelFunc, <-chan WatchRes)
+}
+
+var _ Storage = &Store{}
@@ -0,0 +1,184 @@
+package event
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 各个组件通过Recorder记录事件，kubectl describe的时候可以看到对象最近的事件
+// 同一个对象相同的事件重复发生的时候只增加次数，不会创建很多个事件
+// 事件异步地发送给APIServer，APIServer不可用的时候事件会被丢掉，不会阻塞调用者
+
+// 产生事件的组件的名字
+const (
+	ComponentScheduler            = "scheduler"
+	ComponentKubelet              = "kubelet"
+	ComponentReplicaSetController = "replicaset-controller"
+	ComponentHPAController        = "hpa-controller"
+	ComponentJobController        = "job-controller"
+	ComponentServerlessController = "serverless-controller"
+)
+
+type Recorder interface {
+	// 记录一个事件，eventType是apiObject.EventTypeNormal或者apiObject.EventTypeWarning
+	Event(ref apiObject.ObjectReference, eventType string, reason string, message string)
+	Eventf(ref apiObject.ObjectReference, eventType string, reason string, format string, args ...interface{})
+}
+
+// 事件最终写到哪里，返回HTTP状态码
+type Sink interface {
+	Create(event *apiObject.Event) (int, error)
+	Update(event *apiObject.Event) (int, error)
+}
+
+const (
+	// 发送队列的长度，满了之后新的事件会被丢掉
+	queueSize = 256
+	// 最多缓存多少个不同的事件用来合并，超过之后清空重新开始
+	maxCacheSize = 4096
+)
+
+// 需要发送的事件，update为true的时候是已经存在的事件又发生了一次
+type eventUpdate struct {
+	event  apiObject.Event
+	update bool
+}
+
+type recorder struct {
+	source apiObject.EventSource
+	sink   Sink
+	// 事件在APIServer中保存的时间，超过之后重新创建
+	ttl time.Duration
+	now func() time.Time
+
+	lock  sync.Mutex
+	cache map[string]*apiObject.Event
+
+	queue chan *eventUpdate
+	once  sync.Once
+}
+
+// 创建一个把事件发送给APIServer的Recorder
+func NewRecorder(component string) Recorder {
+	host, _ := os.Hostname()
+	return newRecorder(apiObject.EventSource{Component: component, Host: host}, &apiServerSink{}, serverconfig.EventTTL, time.Now)
+}
+
+func newRecorder(source apiObject.EventSource, sink Sink, ttl time.Duration, now func() time.Time) *recorder {
+	return &recorder{
+		source: source,
+		sink:   sink,
+		ttl:    ttl,
+		now:    now,
+		cache:  make(map[string]*apiObject.Event),
+		queue:  make(chan *eventUpdate, queueSize),
+	}
+}
+
+func (r *recorder) Event(ref apiObject.ObjectReference, eventType string, reason string, message string) {
+	k8log.DebugLog("Event", fmt.Sprintf("%s %s/%s %s: %s", ref.Kind, ref.Namespace, ref.Name, reason, message))
+
+	u := r.generate(ref, eventType, reason, message)
+
+	// 第一次记录事件的时候才启动发送的协程
+	r.once.Do(func() {
+		go r.run()
+	})
+
+	select {
+	case r.queue <- u:
+	default:
+		k8log.WarnLog("Event", "event queue is full, drop event "+reason+" of "+ref.Name)
+	}
+}
+
+func (r *recorder) Eventf(ref apiObject.ObjectReference, eventType string, reason string, format string, args ...interface{}) {
+	r.Event(ref, eventType, reason, fmt.Sprintf(format, args...))
+}
+
+// 生成需要发送的事件，和缓存里面没有过期的事件相同的时候增加次数
+func (r *recorder) generate(ref apiObject.ObjectReference, eventType string, reason string, message string) *eventUpdate {
+	now := r.now()
+	key := ref.Kind + "/" + ref.Namespace + "/" + ref.Name + "/" + ref.UUID + "/" + eventType + "/" + reason + "/" + message
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if cached, ok := r.cache[key]; ok && now.Sub(cached.LastTimestamp) < r.ttl {
+		cached.Count++
+		cached.LastTimestamp = now
+		return &eventUpdate{event: *cached, update: true}
+	}
+
+	if len(r.cache) >= maxCacheSize {
+		r.cache = make(map[string]*apiObject.Event)
+	}
+
+	// Node这样集群级别的对象的事件放在默认的namespace下面
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+
+	event := &apiObject.Event{
+		Basic: apiObject.Basic{
+			APIVersion: serverconfig.APIVersion,
+			Kind:       apiObject.EventKind,
+			Metadata: apiObject.Metadata{
+				// 和Kubernetes一样，名字是对象的名字加上一个时间戳
+				Name:      ref.Name + "." + strconv.FormatInt(now.UnixNano(), 16),
+				Namespace: namespace,
+			},
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         r.source,
+		Count:          1,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+	}
+	r.cache[key] = event
+	return &eventUpdate{event: *event, update: false}
+}
+
+func (r *recorder) run() {
+	for u := range r.queue {
+		r.send(u)
+	}
+}
+
+// 发送一个事件，更新的时候事件已经过期被删除了，就重新创建
+func (r *recorder) send(u *eventUpdate) {
+	if u.update {
+		code, err := r.sink.Update(&u.event)
+		if err != nil {
+			k8log.ErrorLog("Event", "update event failed: "+err.Error())
+			return
+		}
+		if code != http.StatusNotFound {
+			if code != http.StatusOK {
+				k8log.ErrorLog("Event", "update event failed, code "+strconv.Itoa(code))
+			}
+			return
+		}
+	}
+
+	code, err := r.sink.Create(&u.event)
+	if err != nil {
+		k8log.ErrorLog("Event", "create event failed: "+err.Error())
+		return
+	}
+	if code != http.StatusCreated {
+		k8log.ErrorLog("Event", "create event failed, code "+strconv.Itoa(code))
+	}
+}
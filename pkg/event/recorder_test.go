@@ -0,0 +1,86 @@
+package event
+
+import (
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// 记录收到的事件，按照名字保存
+type fakeSink struct {
+	events  map[string]apiObject.Event
+	creates int
+	updates int
+}
+
+func (s *fakeSink) Create(event *apiObject.Event) (int, error) {
+	s.creates++
+	if _, ok := s.events[event.Metadata.Name]; ok {
+		return http.StatusConflict, nil
+	}
+	s.events[event.Metadata.Name] = *event
+	return http.StatusCreated, nil
+}
+
+func (s *fakeSink) Update(event *apiObject.Event) (int, error) {
+	s.updates++
+	if _, ok := s.events[event.Metadata.Name]; !ok {
+		return http.StatusNotFound, nil
+	}
+	s.events[event.Metadata.Name] = *event
+	return http.StatusOK, nil
+}
+
+func TestRecorderAggregate(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	sink := &fakeSink{events: make(map[string]apiObject.Event)}
+	r := newRecorder(apiObject.EventSource{Component: ComponentKubelet, Host: "node1"}, sink, time.Hour, func() time.Time { return now })
+
+	pod := apiObject.ObjectReference{Kind: apiObject.PodKind, Namespace: "default", Name: "pod1", UUID: "uuid-1"}
+
+	r.send(r.generate(pod, apiObject.EventTypeWarning, "Failed", "pull image failed"))
+	now = now.Add(time.Minute)
+	r.send(r.generate(pod, apiObject.EventTypeWarning, "Failed", "pull image failed"))
+	// 信息不同的是另一个事件
+	r.send(r.generate(pod, apiObject.EventTypeNormal, "Pulled", "image pulled"))
+
+	if len(sink.events) != 2 {
+		t.Fatalf("expected 2 events but got %d", len(sink.events))
+	}
+	for _, event := range sink.events {
+		if event.Reason != "Failed" {
+			continue
+		}
+		if event.Count != 2 || !event.LastTimestamp.Equal(now) || !event.FirstTimestamp.Equal(now.Add(-time.Minute)) {
+			t.Errorf("unexpected aggregated event %+v", event)
+		}
+		if event.Source.Host != "node1" || event.InvolvedObject != pod || event.Metadata.Namespace != "default" {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+
+	// APIServer里面的事件过期被删除了，更新的时候重新创建
+	sink.events = make(map[string]apiObject.Event)
+	r.send(r.generate(pod, apiObject.EventTypeNormal, "Pulled", "image pulled"))
+	if len(sink.events) != 1 || sink.creates != 3 {
+		t.Errorf("expired event should be created again, creates %d, events %v", sink.creates, sink.events)
+	}
+
+	// 超过TTL之后是一个新的事件
+	now = now.Add(2 * time.Hour)
+	u := r.generate(pod, apiObject.EventTypeNormal, "Pulled", "image pulled")
+	if u.update || u.event.Count != 1 {
+		t.Errorf("event older than ttl should not be aggregated, got %+v", u)
+	}
+}
+
+func TestRecorderClusterScopedObject(t *testing.T) {
+	sink := &fakeSink{events: make(map[string]apiObject.Event)}
+	r := newRecorder(apiObject.EventSource{Component: ComponentScheduler}, sink, time.Hour, time.Now)
+
+	u := r.generate(apiObject.ObjectReference{Kind: apiObject.NodeKind, Name: "node1"}, apiObject.EventTypeNormal, "NodeReady", "node is ready")
+	if u.event.Metadata.Namespace != "default" {
+		t.Errorf("event of cluster scoped object should be in default namespace, got %q", u.event.Metadata.Namespace)
+	}
+}
@@ -0,0 +1,29 @@
+package event
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+)
+
+// 通过APIServer创建和更新事件
+type apiServerSink struct {
+}
+
+func (s *apiServerSink) Create(event *apiObject.Event) (int, error) {
+	url := config.GetAPIServerURLPrefix() + config.EventsURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, event.Metadata.Namespace)
+
+	code, _, err := netrequest.PostRequestByTarget(url, event)
+	return code, err
+}
+
+func (s *apiServerSink) Update(event *apiObject.Event) (int, error) {
+	url := config.GetAPIServerURLPrefix() + config.EventSpecURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, event.Metadata.Namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, event.Metadata.Name)
+
+	code, _, err := netrequest.PutRequestByTarget(url, event)
+	return code, err
+}
@@ -209,6 +209,9 @@ func main() {
 		return
 	}
 
+	// 使用kubelet挂载进来的Job自己的token，只能访问这个Job
+	netrequest.SetBearerToken(config.LoadToken(config.JobTokenFile))
+
 	fmt.Printf("JobName is %s, JobNamespace is %s \n", args.JobName, args.JobNamespace)
 
 	// 和APi Server通讯，准备任务的文件
@@ -0,0 +1,108 @@
+package healthz
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 单个检查的超时时间，超时的检查按失败处理，避免依赖卡住的时候探针一直没有返回
+const DefaultCheckTimeout = 5 * time.Second
+
+// 带上?verbose之后会逐项输出每个检查的结果，失败的时候总是逐项输出
+const queryVerbose = "verbose"
+
+// HealthChecker 是一项健康检查，Check返回nil表示通过
+type HealthChecker struct {
+	Name  string
+	Check func() error
+}
+
+// PingHealthz 只要进程还能处理HTTP请求就通过
+var PingHealthz = HealthChecker{
+	Name:  "ping",
+	Check: func() error { return nil },
+}
+
+// NamedCheck 用一个函数构造检查项
+func NamedCheck(name string, check func() error) HealthChecker {
+	return HealthChecker{Name: name, Check: check}
+}
+
+// InstallHandlers 把/healthz、/livez和/readyz注册到router上
+// /livez只执行live里面的检查，用来判断进程是否需要重启
+// /readyz和/healthz执行live和ready里面所有的检查，用来判断组件是否可以正常工作
+// 需要在认证的中间件之前注册，探针不会带token
+func InstallHandlers(router gin.IRoutes, component string, live []HealthChecker, ready []HealthChecker) {
+	live = append([]HealthChecker{PingHealthz}, live...)
+	all := append(append([]HealthChecker{}, live...), ready...)
+
+	router.GET(config.HealthzURL, handleRootHealth(component, config.HealthzURL, all))
+	router.GET(config.LivezURL, handleRootHealth(component, config.LivezURL, live))
+	router.GET(config.ReadyzURL, handleRootHealth(component, config.ReadyzURL, all))
+}
+
+// ListenAndServe 给没有HTTP服务的组件单独启动一个只提供健康检查的服务，函数会阻塞，一般用go调用
+// 健康检查的服务退出不影响组件本身，只记录日志
+func ListenAndServe(component string, port int, live []HealthChecker, ready []HealthChecker) error {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	InstallHandlers(router, component, live, ready)
+	k8log.InfoLog(component, "health check server listen on port "+strconv.Itoa(port))
+	err := http.ListenAndServe(":"+strconv.Itoa(port), router)
+	k8log.ErrorLog(component, "health check server exit: "+err.Error())
+	return err
+}
+
+// 依次执行所有检查，全部通过返回200，否则返回500
+// 失败的原因只写到日志里面，不返回给调用者
+func handleRootHealth(component string, path string, checks []HealthChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var out bytes.Buffer
+		failed := false
+		for _, check := range checks {
+			if err := runCheck(check); err != nil {
+				k8log.WarnLog(component, fmt.Sprintf("%s check %s failed: %s", path, check.Name, err.Error()))
+				fmt.Fprintf(&out, "[-]%s failed: reason withheld\n", check.Name)
+				failed = true
+			} else {
+				fmt.Fprintf(&out, "[+]%s ok\n", check.Name)
+			}
+		}
+
+		_, verbose := c.GetQuery(queryVerbose)
+		if failed {
+			out.WriteString(path + " check failed\n")
+			c.String(http.StatusInternalServerError, out.String())
+			return
+		}
+		if !verbose {
+			c.String(http.StatusOK, "ok")
+			return
+		}
+		out.WriteString(path + " check passed\n")
+		c.String(http.StatusOK, out.String())
+	}
+}
+
+// 在单独的协程里面执行检查，超过DefaultCheckTimeout按失败处理
+func runCheck(check HealthChecker) error {
+	result := make(chan error, 1)
+	go func() {
+		result <- check.Check()
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(DefaultCheckTimeout):
+		return errors.New("timed out after " + DefaultCheckTimeout.String())
+	}
+}
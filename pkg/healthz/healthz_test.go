@@ -0,0 +1,77 @@
+package healthz
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestRouter(dependencyErr *error) *gin.Engine {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	InstallHandlers(r, "test",
+		[]HealthChecker{NamedCheck("live", func() error { return nil })},
+		[]HealthChecker{NamedCheck("dependency", func() error { return *dependencyErr })},
+	)
+	return r
+}
+
+func get(r *gin.Engine, uri string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri, nil))
+	return w
+}
+
+func TestHealthy(t *testing.T) {
+	var dependencyErr error
+	r := newTestRouter(&dependencyErr)
+
+	for _, uri := range []string{"/healthz", "/livez", "/readyz"} {
+		w := get(r, uri)
+		if w.Code != http.StatusOK || w.Body.String() != "ok" {
+			t.Errorf("%s: expected 200 ok but got %d %q", uri, w.Code, w.Body.String())
+		}
+	}
+
+	w := get(r, "/readyz?verbose")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 but got %d", w.Code)
+	}
+	for _, line := range []string{"[+]ping ok", "[+]live ok", "[+]dependency ok", "/readyz check passed"} {
+		if !strings.Contains(w.Body.String(), line) {
+			t.Errorf("expected %q in verbose output:\n%s", line, w.Body.String())
+		}
+	}
+}
+
+func TestUnready(t *testing.T) {
+	dependencyErr := errors.New("connection refused")
+	r := newTestRouter(&dependencyErr)
+
+	// 依赖不可用不影响存活检查
+	w := get(r, "/livez")
+	if w.Code != http.StatusOK {
+		t.Errorf("expected /livez to pass but got %d", w.Code)
+	}
+	if strings.Contains(get(r, "/livez?verbose").Body.String(), "dependency") {
+		t.Errorf("expected /livez not to run readiness checks")
+	}
+
+	for _, uri := range []string{"/healthz", "/readyz"} {
+		w := get(r, uri)
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("%s: expected 500 but got %d", uri, w.Code)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "[-]dependency failed: reason withheld") || !strings.Contains(body, "[+]live ok") {
+			t.Errorf("%s: expected every check to be listed:\n%s", uri, body)
+		}
+		if strings.Contains(body, "connection refused") {
+			t.Errorf("%s: expected the failure reason to be withheld:\n%s", uri, body)
+		}
+	}
+}
@@ -1,25 +1,36 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/config"
 	"miniK8s/pkg/kubectl/kubectlutil"
 	"miniK8s/util/file"
+	"miniK8s/util/jsonpatch"
+	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
 	"miniK8s/util/zip"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/jedib0t/go-pretty/table"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var applyCmd = &cobra.Command{
 	Use:   "apply",
-	Short: "Kubectl apply can create apiObject in a declarative way",
-	Long:  "Kubectl apply can create apiObject in a declarative way, usage kubectl apply -f [file]",
+	Short: "Kubectl apply can create or update apiObject in a declarative way",
+	Long:  "Kubectl apply can create or update apiObject in a declarative way, usage kubectl apply [file|dir] [--prune -l selector]",
 	Run:   applyHandler,
 }
 
@@ -35,6 +46,16 @@ const (
 	Apply_kind_Hpa        ApplyObject = "Hpa"
 	Apply_kind_Func       ApplyObject = "Function"
 	Apply_kind_Workflow   ApplyObject = "Workflow"
+	Apply_kind_Namespace  ApplyObject = "Namespace"
+	Apply_kind_CRD        ApplyObject = "CustomResourceDefinition"
+
+	Apply_kind_MutatingWebhook   ApplyObject = "MutatingWebhookConfiguration"
+	Apply_kind_ValidatingWebhook ApplyObject = "ValidatingWebhookConfiguration"
+
+	Apply_kind_Role               ApplyObject = "Role"
+	Apply_kind_ClusterRole        ApplyObject = "ClusterRole"
+	Apply_kind_RoleBinding        ApplyObject = "RoleBinding"
+	Apply_kind_ClusterRoleBinding ApplyObject = "ClusterRoleBinding"
 )
 
 // Apply的Result
@@ -47,41 +68,336 @@ const (
 	ApplyResult_Unknow  ApplyResult = "Unknow"
 )
 
+func init() {
+	applyCmd.Flags().Bool("prune", false, "Delete objects which are applied before but not in the manifests any more, must be used with -l")
+	applyCmd.Flags().StringP("selector", "l", "", "Label selector of the objects to be pruned")
+}
+
+// 一次apply的所有对象，用来在--prune的时候找出已经不在配置里面的对象
+type applySet struct {
+	// 所有apply过的对象的URL
+	objects map[string]bool
+	// 这次apply的所有kind和namespace，记录在每个对象的annotation里面，prune的时候从这些范围开始查找
+	scopes map[applyScope]bool
+}
+
+type applyScope struct {
+	kind      string
+	namespace string
+}
+
+// 对象所在的kind和namespace，没有写namespace的时候是default
+func manifestScope(manifest map[string]interface{}) (applyScope, bool) {
+	kind, _ := manifest["kind"].(string)
+	if kind == "" {
+		return applyScope{}, false
+	}
+	metadata, _ := manifest["metadata"].(map[string]interface{})
+	namespace, _ := metadata["namespace"].(string)
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	return applyScope{kind: kind, namespace: namespace}, true
+}
+
+// 把所有的范围排序之后拼成annotation的值，范围没有变化的时候annotation也不变
+func formatScopes(scopes map[applyScope]bool) string {
+	values := make([]string, 0, len(scopes))
+	for scope := range scopes {
+		values = append(values, scope.kind+"/"+scope.namespace)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ",")
+}
+
+func parseScopes(value string) []applyScope {
+	scopes := make([]applyScope, 0)
+	for _, item := range strings.Split(value, ",") {
+		kind, namespace, ok := strings.Cut(item, "/")
+		if !ok || kind == "" || namespace == "" {
+			continue
+		}
+		scopes = append(scopes, applyScope{kind: kind, namespace: namespace})
+	}
+	return scopes
+}
+
 func applyHandler(cmd *cobra.Command, args []string) {
-	// k8log.DebugLog("applyHandler", "args: "+strings.Join(args, " "))
-	// 打印出来所有的参数
 	// 检查参数的数量是否为1
 	if len(args) != 1 {
 		cmd.Usage()
 		return
 	}
-	// 检查参数是否是文件 读取文件
-	fileInfo, err := os.Stat(args[0])
+
+	prune, _ := cmd.Flags().GetBool("prune")
+	selector, _ := cmd.Flags().GetString("selector")
+	if prune && selector == "" {
+		fmt.Println(color.RedString("--prune must be used with -l [selector]"))
+		return
+	}
+
+	// 参数可以是文件或者目录，一个文件里面可以有多个用---分隔的对象
+	manifests, err := readManifests(args[0])
 	if err != nil {
 		fmt.Println(err.Error())
 		cmd.Usage()
 		return
 	}
+
+	set := &applySet{
+		objects: make(map[string]bool),
+		scopes:  make(map[applyScope]bool),
+	}
+	for _, manifest := range manifests {
+		if scope, ok := manifestScope(manifest); ok {
+			set.scopes[scope] = true
+		}
+	}
+	for _, manifest := range manifests {
+		applyManifest(manifest, set)
+	}
+
+	if prune {
+		pruneObjects(set, selector)
+	}
+}
+
+// 读取文件或者目录下面所有的yaml文件，返回其中所有的对象
+func readManifests(path string) ([]map[string]interface{}, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
 	if fileInfo.IsDir() {
-		fmt.Println("file is a directory")
-		cmd.Usage()
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = files[:0]
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+				continue
+			}
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	manifests := make([]map[string]interface{}, 0)
+	for _, f := range files {
+		fileContent, err := file.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(fileContent))
+		for {
+			var manifest map[string]interface{}
+			err := decoder.Decode(&manifest)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, errors.Wrap(err, f)
+			}
+			// 跳过空的文档
+			if len(manifest) != 0 {
+				manifests = append(manifests, manifest)
+			}
+		}
+	}
+	return manifests, nil
+}
+
+// 对象不存在的时候创建，存在的时候根据上次apply的配置做三路合并，用merge patch更新
+func applyManifest(manifest map[string]interface{}, set *applySet) {
+	kind, _ := manifest["kind"].(string)
+	if kind == "" {
+		fmt.Println("kind field not found")
 		return
 	}
-	// 读取文件的内容
-	fileContent, err := file.ReadFile(args[0])
+
+	// 这次apply的配置，保存在annotation里面
+	lastApplied, err := json.Marshal(manifest)
 	if err != nil {
-		fmt.Println(err.Error())
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "parse yaml failed", err.Error())
 		return
 	}
 
-	// 解析API对象的种类
-	Kind, err := kubectlutil.GetAPIObjectTypeFromYamlFile(fileContent)
+	metadata, ok := manifest["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		manifest["metadata"] = metadata
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		annotations = make(map[string]interface{})
+		metadata["annotations"] = annotations
+	}
+	annotations[apiObject.LastAppliedConfigAnnotation] = string(lastApplied)
+	annotations[apiObject.AppliedScopesAnnotation] = formatScopes(set.scopes)
 
+	fileContent, err := yaml.Marshal(manifest)
 	if err != nil {
-		fmt.Println(err.Error())
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "parse yaml failed", err.Error())
+		return
+	}
+
+	// 没有名字的时候交给创建的逻辑报错
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		createAPIObject(kind, fileContent)
 		return
 	}
+	namespace, _ := metadata["namespace"].(string)
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+
+	uri, err := objectURL(kind, name, namespace)
+	if err != nil {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "unsupported kind", err.Error())
+		return
+	}
+	set.objects[uri] = true
 
+	live := make(map[string]interface{})
+	code, err := netrequest.GetRequestByTarget(uri, &live, "data")
+	if err != nil {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "get obj failed", err.Error())
+		return
+	}
+
+	switch code {
+	case http.StatusNotFound:
+		createAPIObject(kind, fileContent)
+	case http.StatusOK:
+		modified, err := json.Marshal(manifest)
+		if err != nil {
+			printApplyResult(ApplyObject(kind), ApplyResult_Failed, "parse yaml failed", err.Error())
+			return
+		}
+		updateAPIObject(ApplyObject(kind), uri, live, modified, name, namespace)
+	default:
+		printApplyFailed(ApplyObject(kind), code, "get obj failed")
+	}
+}
+
+// 用上次apply的配置、这次的配置和服务器上的对象计算出patch
+// 配置里面删除的字段会被删除，控制器设置的字段(比如HPA修改的副本数之外的字段)保持不变
+func updateAPIObject(kind ApplyObject, uri string, live map[string]interface{}, modified []byte, name string, namespace string) {
+	original := ""
+	if metadata, ok := live["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			original, _ = annotations[apiObject.LastAppliedConfigAnnotation].(string)
+		}
+	}
+
+	current, err := json.Marshal(live)
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "parse obj failed", err.Error())
+		return
+	}
+
+	patch, err := jsonpatch.CreateThreeWayMergePatch([]byte(original), modified, current)
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "create patch failed", err.Error())
+		return
+	}
+	if string(patch) == "{}" {
+		printApplyResult(kind, ApplyResult_Success, "unchanged", "")
+		return
+	}
+
+	code, res, err := netrequest.PatchRequest(uri, jsonpatch.MergePatchType, patch)
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "patch obj failed", err.Error())
+		return
+	}
+
+	switch code {
+	case http.StatusOK:
+		printApplyResult(kind, ApplyResult_Success, "configured", string(patch))
+		fmt.Println()
+		printApplyObjectInfo(kind, name, namespace)
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		printApplyResult(kind, ApplyResult_Failed, "update not supported", "delete it and apply again")
+	default:
+		msg, _ := json.Marshal(res)
+		printApplyFailed(kind, code, string(msg))
+	}
+}
+
+// 删除带有last-applied-configuration，满足selector，但是这次没有apply的对象
+// 除了这次apply的kind和namespace，还会查找已有对象的annotation里面记录的之前apply过的kind和namespace
+func pruneObjects(set *applySet, selector string) {
+	scopes := parseScopes(formatScopes(set.scopes))
+	visited := make(map[applyScope]bool)
+	for _, scope := range scopes {
+		visited[scope] = true
+	}
+
+	for i := 0; i < len(scopes); i++ {
+		scope := scopes[i]
+		uri, err := objectsURL(scope.kind, scope.namespace)
+		if err != nil {
+			continue
+		}
+		query := url.Values{}
+		query.Set(config.URL_QUERY_LABEL_SELECTOR, selector)
+
+		objects := make([]map[string]interface{}, 0)
+		code, err := netrequest.GetRequestByTarget(uri+"?"+query.Encode(), &objects, "data")
+		if err != nil || code != http.StatusOK {
+			printApplyResult(ApplyObject(scope.kind), ApplyResult_Failed, "list obj failed", fmt.Sprint(code))
+			continue
+		}
+
+		for _, obj := range objects {
+			metadata, _ := obj["metadata"].(map[string]interface{})
+			annotations, _ := metadata["annotations"].(map[string]interface{})
+			// 不是通过apply创建的对象不会被prune
+			if _, ok := annotations[apiObject.LastAppliedConfigAnnotation]; !ok {
+				continue
+			}
+			stored, _ := annotations[apiObject.AppliedScopesAnnotation].(string)
+			for _, storedScope := range parseScopes(stored) {
+				if !visited[storedScope] {
+					visited[storedScope] = true
+					scopes = append(scopes, storedScope)
+				}
+			}
+			name, _ := metadata["name"].(string)
+			namespace, _ := metadata["namespace"].(string)
+			if namespace == "" {
+				namespace = scope.namespace
+			}
+
+			objURI, err := objectURL(scope.kind, name, namespace)
+			if err != nil || set.objects[objURI] {
+				continue
+			}
+
+			code, err := kubectlutil.DeleteAPIObjectToServer(objURI)
+			if err != nil {
+				printApplyResult(ApplyObject(scope.kind), ApplyResult_Failed, "prune failed", err.Error())
+				continue
+			}
+			if code != http.StatusNoContent && code != http.StatusAccepted && code != http.StatusNotFound {
+				printApplyResult(ApplyObject(scope.kind), ApplyResult_Failed, "prune failed", fmt.Sprint(code))
+				continue
+			}
+			printApplyResult(ApplyObject(scope.kind), ApplyResult_Success, "pruned", "")
+			fmt.Println()
+			printApplyObjectInfo(ApplyObject(scope.kind), name, namespace)
+		}
+	}
+}
+
+// 根据kind创建对象
+func createAPIObject(Kind string, fileContent []byte) {
 	switch Kind {
 	case string(Apply_Kind_Pod):
 		applyPodHandler(fileContent)
@@ -99,8 +415,27 @@ func applyHandler(cmd *cobra.Command, args []string) {
 		applyFuncHandler(fileContent)
 	case string(Apply_kind_Workflow):
 		applyWorkflowHandler(fileContent)
+	case string(Apply_kind_Namespace):
+		applyNamespaceHandler(fileContent)
+	case string(Apply_kind_CRD):
+		applyCRDHandler(fileContent)
+	case string(Apply_kind_MutatingWebhook):
+		var webhook apiObject.MutatingWebhookConfiguration
+		applyWebhookConfigurationHandler(Apply_kind_MutatingWebhook, config.MutatingWebhookConfigurationsURL, fileContent, &webhook)
+	case string(Apply_kind_ValidatingWebhook):
+		var webhook apiObject.ValidatingWebhookConfiguration
+		applyWebhookConfigurationHandler(Apply_kind_ValidatingWebhook, config.ValidatingWebhookConfigurationsURL, fileContent, &webhook)
+	case string(Apply_kind_Role):
+		applyRBACObjectHandler(Apply_kind_Role, fileContent, &apiObject.Role{})
+	case string(Apply_kind_ClusterRole):
+		applyRBACObjectHandler(Apply_kind_ClusterRole, fileContent, &apiObject.ClusterRole{})
+	case string(Apply_kind_RoleBinding):
+		applyRBACObjectHandler(Apply_kind_RoleBinding, fileContent, &apiObject.RoleBinding{})
+	case string(Apply_kind_ClusterRoleBinding):
+		applyRBACObjectHandler(Apply_kind_ClusterRoleBinding, fileContent, &apiObject.ClusterRoleBinding{})
 	default:
-		fmt.Println("default")
+		// 不是内置的资源，尝试作为CRD定义的自定义对象处理
+		applyCustomObjectHandler(Kind, fileContent)
 	}
 }
 
@@ -148,7 +483,7 @@ func applyPodHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_Kind_Pod, pod.GetObjectName(), pod.GetObjectNamespace())
 	} else {
-		printApplyResult(Apply_Kind_Pod, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_Kind_Pod, code, msg)
 	}
 }
 
@@ -195,7 +530,7 @@ func applyServiceHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_kind_Service, service.Metadata.Name, service.Metadata.Namespace)
 	} else {
-		printApplyResult(Apply_kind_Service, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_kind_Service, code, msg)
 	}
 }
 
@@ -259,7 +594,7 @@ func applyJobHandler(fileContent []byte) {
 		}
 
 		if code != http.StatusCreated {
-			printApplyResult(Apply_Kind_Job, ApplyResult_Failed, "failed", msg)
+			printApplyFailed(Apply_Kind_Job, code, msg)
 			return
 		}
 
@@ -371,7 +706,7 @@ func applyDnsHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_kind_Dns, dns.Metadata.Name, dns.Metadata.Namespace)
 	} else {
-		printApplyResult(Apply_kind_Dns, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_kind_Dns, code, msg)
 	}
 }
 
@@ -419,7 +754,7 @@ func applyRepliacasetHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_kind_Replicaset, repliaset.Metadata.Name, repliaset.Metadata.Namespace)
 	} else {
-		printApplyResult(Apply_kind_Replicaset, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_kind_Replicaset, code, msg)
 	}
 }
 
@@ -494,7 +829,7 @@ func applyFuncHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_kind_Func, function.Metadata.Name, function.Metadata.Namespace)
 	} else {
-		printApplyResult(Apply_kind_Func, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_kind_Func, code, msg)
 	}
 }
 
@@ -541,7 +876,7 @@ func applyHpaHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_kind_Hpa, hpa.Metadata.Name, hpa.Metadata.Namespace)
 	} else {
-		printApplyResult(Apply_kind_Hpa, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_kind_Hpa, code, msg)
 	}
 }
 
@@ -587,9 +922,223 @@ func applyWorkflowHandler(fileContent []byte) {
 		fmt.Println()
 		printApplyObjectInfo(Apply_kind_Workflow, workflow.Metadata.Name, workflow.Metadata.Namespace)
 	} else {
-		printApplyResult(Apply_kind_Workflow, ApplyResult_Failed, "failed", msg)
+		printApplyFailed(Apply_kind_Workflow, code, msg)
+	}
+
+}
+
+// ==============================================
+//
+// 处理Namespace的Apply
+//
+// ==============================================
+
+func applyNamespaceHandler(fileContent []byte) {
+	var namespace apiObject.Namespace
+	err := kubectlutil.ParseAPIObjectFromYamlfileContent(fileContent, &namespace)
+
+	if err != nil {
+		printApplyResult(Apply_kind_Namespace, ApplyResult_Failed, "parse yaml failed", err.Error())
+		return
+	}
+
+	// 检查Namespace的名字是否为空
+	if namespace.Metadata.Name == "" {
+		printApplyResult(Apply_kind_Namespace, ApplyResult_Failed, "empty name", "namespace name is empty")
+		return
+	}
+
+	// 发请求
+	URL := config.GetAPIServerURLPrefix() + config.NamespacesURL
+
+	code, err, msg := kubectlutil.PostAPIObjectToServer(URL, namespace)
+
+	if err != nil {
+		printApplyResult(Apply_kind_Namespace, ApplyResult_Failed, "post obj failed", err.Error())
+		return
+	}
+
+	if code == http.StatusCreated {
+		printApplyResult(Apply_kind_Namespace, ApplyResult_Success, "created", msg)
+		fmt.Println()
+		printApplyObjectInfo(Apply_kind_Namespace, namespace.Metadata.Name, "")
+	} else {
+		printApplyFailed(Apply_kind_Namespace, code, msg)
+	}
+}
+
+// ==============================================
+//
+// 处理CustomResourceDefinition的Apply
+//
+// ==============================================
+
+func applyCRDHandler(fileContent []byte) {
+	var crd apiObject.CustomResourceDefinition
+	err := kubectlutil.ParseAPIObjectFromYamlfileContent(fileContent, &crd)
+
+	if err != nil {
+		printApplyResult(Apply_kind_CRD, ApplyResult_Failed, "parse yaml failed", err.Error())
+		return
+	}
+
+	// CRD的名字可以省略，默认是<plural>.<group>
+	if crd.Metadata.Name == "" {
+		crd.Metadata.Name = crd.ExpectedName()
+	}
+
+	// 发请求
+	URL := config.GetAPIServerURLPrefix() + config.CRDsURL
+
+	code, err, msg := kubectlutil.PostAPIObjectToServer(URL, crd)
+
+	if err != nil {
+		printApplyResult(Apply_kind_CRD, ApplyResult_Failed, "post obj failed", err.Error())
+		return
+	}
+
+	if code == http.StatusCreated {
+		printApplyResult(Apply_kind_CRD, ApplyResult_Success, "created", msg)
+		fmt.Println()
+		printApplyObjectInfo(Apply_kind_CRD, crd.Metadata.Name, "")
+	} else {
+		printApplyFailed(Apply_kind_CRD, code, msg)
+	}
+}
+
+// ==============================================
+//
+// 处理MutatingWebhookConfiguration和ValidatingWebhookConfiguration的Apply
+//
+// ==============================================
+
+func applyWebhookConfigurationHandler(kind ApplyObject, url string, fileContent []byte, webhook apiObject.APIObject) {
+	err := kubectlutil.ParseAPIObjectFromYamlfileContent(fileContent, webhook)
+
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "parse yaml failed", err.Error())
+		return
+	}
+
+	if webhook.GetObjectName() == "" {
+		printApplyResult(kind, ApplyResult_Failed, "empty name", string(kind)+" name is empty")
+		return
+	}
+
+	// 发请求
+	URL := config.GetAPIServerURLPrefix() + url
+
+	code, err, msg := kubectlutil.PostAPIObjectToServer(URL, webhook)
+
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "post obj failed", err.Error())
+		return
 	}
 
+	if code == http.StatusCreated {
+		printApplyResult(kind, ApplyResult_Success, "created", msg)
+		fmt.Println()
+		printApplyObjectInfo(kind, webhook.GetObjectName(), "")
+	} else {
+		printApplyFailed(kind, code, msg)
+	}
+}
+
+// ==============================================
+//
+// 处理Role、ClusterRole、RoleBinding、ClusterRoleBinding的Apply
+// Role和RoleBinding没有指定namespace的时候放在default下面
+//
+// ==============================================
+
+func applyRBACObjectHandler(kind ApplyObject, fileContent []byte, obj apiObject.APIObject) {
+	err := kubectlutil.ParseAPIObjectFromYamlfileContent(fileContent, obj)
+
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "parse yaml failed", err.Error())
+		return
+	}
+
+	if obj.GetObjectName() == "" {
+		printApplyResult(kind, ApplyResult_Failed, "empty name", string(kind)+" name is empty")
+		return
+	}
+
+	// 集群级别的资源URL里面没有namespace，替换不会有影响
+	namespace := obj.GetObjectNamespace()
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	URL := config.GetAPIServerURLPrefix() + config.ApiResourceMap[string(kind)]
+	URL = stringutil.Replace(URL, config.URL_PARAM_NAMESPACE_PART, namespace)
+
+	code, err, msg := kubectlutil.PostAPIObjectToServer(URL, obj)
+
+	if err != nil {
+		printApplyResult(kind, ApplyResult_Failed, "post obj failed", err.Error())
+		return
+	}
+
+	if code == http.StatusCreated {
+		printApplyResult(kind, ApplyResult_Success, "created", msg)
+		fmt.Println()
+		printApplyObjectInfo(kind, obj.GetObjectName(), obj.GetObjectNamespace())
+	} else {
+		printApplyFailed(kind, code, msg)
+	}
+}
+
+// ==============================================
+//
+// 处理自定义对象的Apply
+//
+// ==============================================
+
+func applyCustomObjectHandler(kind string, fileContent []byte) {
+	crd, err := kubectlutil.FindCRD(kind)
+	if err != nil {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "get crd failed", err.Error())
+		return
+	}
+	if crd == nil {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "unknown kind", "no CustomResourceDefinition defines kind "+kind)
+		return
+	}
+
+	var obj apiObject.CustomObject
+	err = kubectlutil.ParseAPIObjectFromYamlfileContent(fileContent, &obj)
+
+	if err != nil {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "parse yaml failed", err.Error())
+		return
+	}
+
+	if obj.GetObjectName() == "" {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "empty name", kind+" name is empty")
+		return
+	}
+
+	if obj.GetObjectNamespace() == "" {
+		obj.Metadata.Namespace = config.DefaultNamespace
+	}
+
+	// 发请求
+	URL := kubectlutil.CustomObjectsURL(crd, obj.GetObjectNamespace())
+
+	code, err, msg := kubectlutil.PostAPIObjectToServer(URL, obj)
+
+	if err != nil {
+		printApplyResult(ApplyObject(kind), ApplyResult_Failed, "post obj failed", err.Error())
+		return
+	}
+
+	if code == http.StatusCreated {
+		printApplyResult(ApplyObject(kind), ApplyResult_Success, "created", msg)
+		fmt.Println()
+		printApplyObjectInfo(ApplyObject(kind), obj.GetObjectName(), obj.GetObjectNamespace())
+	} else {
+		printApplyFailed(ApplyObject(kind), code, msg)
+	}
 }
 
 // ==============================================
@@ -632,6 +1181,28 @@ func printApplyResult(kind ApplyObject, result ApplyResult, info string, reason
 	t.Render()
 }
 
+// APIServer拒绝了请求，对象不合法(422)的时候把每个不合法的字段单独打印出来
+func printApplyFailed(kind ApplyObject, code int, msg string) {
+	if code != http.StatusUnprocessableEntity {
+		printApplyResult(kind, ApplyResult_Failed, "failed", msg)
+		return
+	}
+
+	reason, fieldErrors := kubectlutil.ParseInvalidResponse(msg)
+	printApplyResult(kind, ApplyResult_Failed, "invalid", reason)
+	if len(fieldErrors) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Field", "Reason"})
+	for _, fieldError := range fieldErrors {
+		t.AppendRow(table.Row{color.YellowString(fieldError.Field), color.RedString(fieldError.Reason)})
+	}
+	t.Render()
+}
+
 func printApplyObjectInfo(kind ApplyObject, name string, namespace string) {
 	t := table.NewWriter()
 	t.SetOutputMirror(os.Stdout)
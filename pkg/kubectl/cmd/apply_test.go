@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -22,3 +24,67 @@ func TestPrintApplyObjInfo(t *testing.T) {
 	fmt.Println()
 	printApplyObjectInfo(Apply_Kind_Pod, "pod3", "default")
 }
+
+func TestReadManifests(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": "kind: Pod\nmetadata:\n  name: pod1\n---\n---\nkind: Service\nmetadata:\n  name: svc1\n",
+		"b.yml":  "kind: Replicaset\nmetadata:\n  name: rs1\n",
+		"c.txt":  "kind: Pod\nmetadata:\n  name: ignored\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifests, err := readManifests(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"pod1", "svc1", "rs1"}
+	if len(manifests) != len(expected) {
+		t.Fatalf("expected %d manifests but got %d", len(expected), len(manifests))
+	}
+	for i, manifest := range manifests {
+		metadata := manifest["metadata"].(map[string]interface{})
+		if metadata["name"] != expected[i] {
+			t.Errorf("expected %s but got %v", expected[i], metadata["name"])
+		}
+	}
+
+	manifests, err = readManifests(filepath.Join(dir, "b.yml"))
+	if err != nil || len(manifests) != 1 {
+		t.Errorf("expected 1 manifest but got %d, %v", len(manifests), err)
+	}
+	if _, err := readManifests(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("missing file should fail")
+	}
+}
+
+func TestAppliedScopes(t *testing.T) {
+	scopes := make(map[applyScope]bool)
+	for _, manifest := range []map[string]interface{}{
+		{"kind": "Service", "metadata": map[string]interface{}{"name": "svc1", "namespace": "test"}},
+		{"kind": "Pod", "metadata": map[string]interface{}{"name": "pod1"}},
+		{"kind": "Pod", "metadata": map[string]interface{}{"name": "pod2"}},
+		{"metadata": map[string]interface{}{"name": "noKind"}},
+	} {
+		if scope, ok := manifestScope(manifest); ok {
+			scopes[scope] = true
+		}
+	}
+
+	// 排序之后保存，范围相同的时候annotation不变
+	value := formatScopes(scopes)
+	if value != "Pod/default,Service/test" {
+		t.Fatalf("expected Pod/default,Service/test but got %q", value)
+	}
+	parsed := parseScopes(value + ",invalid,")
+	if len(parsed) != 2 || parsed[0] != (applyScope{kind: "Pod", namespace: "default"}) || parsed[1] != (applyScope{kind: "Service", namespace: "test"}) {
+		t.Fatalf("unexpected scopes %v", parsed)
+	}
+	if len(parseScopes("")) != 0 {
+		t.Fatal("empty annotation should have no scopes")
+	}
+}
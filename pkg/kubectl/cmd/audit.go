@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Kubectl audit can query the audit log of the API server",
+	Long:  "Kubectl audit can query the audit log of the API server, usage kubectl audit [--since 1h|2023-06-01T00:00:00Z] [--until time] [--kind ReplicaSet] [--namespace ns] [--name name] [--user admin]",
+	Run:   auditHandler,
+}
+
+func init() {
+	auditCmd.Flags().String("since", "", "Only show events after this time, a duration like 1h or a RFC3339 time")
+	auditCmd.Flags().String("until", "", "Only show events before this time, a duration like 30m or a RFC3339 time")
+	auditCmd.Flags().String("kind", "", "Kind of the object, like ReplicaSet")
+	auditCmd.Flags().String("namespace", "", "Namespace of the object")
+	auditCmd.Flags().String("name", "", "Name of the object")
+	auditCmd.Flags().String("user", "", "User who sent the request")
+}
+
+func auditHandler(cmd *cobra.Command, args []string) {
+	query := url.Values{}
+	now := time.Now()
+	for _, flag := range []string{config.URL_QUERY_SINCE, config.URL_QUERY_UNTIL} {
+		value, _ := cmd.Flags().GetString(flag)
+		if value == "" {
+			continue
+		}
+		t, err := parseAuditTime(value, now)
+		if err != nil {
+			fmt.Println(color.RedString("invalid --%s: %s", flag, err.Error()))
+			return
+		}
+		query.Set(flag, t.Format(time.RFC3339))
+	}
+	for _, flag := range []string{config.URL_QUERY_KIND, config.URL_PARAM_NAMESPACE, config.URL_PARAM_NAME, config.URL_QUERY_USER} {
+		if value, _ := cmd.Flags().GetString(flag); value != "" {
+			query.Set(flag, value)
+		}
+	}
+
+	uri := config.GetAPIServerURLPrefix() + config.AuditEventsURL
+	if len(query) != 0 {
+		uri += "?" + query.Encode()
+	}
+
+	code, res, err := netrequest.GetRequest(uri)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if code != http.StatusOK {
+		fmt.Println(color.RedString("query audit log failed, code: %d, %v", code, res["error"]))
+		return
+	}
+
+	events := make([]apiObject.AuditEvent, 0)
+	data, _ := res["data"].(string)
+	if err := json.Unmarshal([]byte(data), &events); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	printAuditEvents(events)
+}
+
+// 相对时间(比如1h表示一小时之前)或者RFC3339格式的时间
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func printAuditEvents(events []apiObject.AuditEvent) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Time", "User", "Verb", "Kind", "Namespace", "Name", "Code"})
+	for _, event := range events {
+		code := color.GreenString(fmt.Sprint(event.Code))
+		if event.Code >= http.StatusBadRequest {
+			code = color.RedString(fmt.Sprint(event.Code))
+		}
+		t.AppendRow(table.Row{
+			event.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			color.CyanString(event.User),
+			event.Verb,
+			event.Kind,
+			event.Namespace,
+			event.Name,
+			code,
+		})
+	}
+	t.Render()
+}
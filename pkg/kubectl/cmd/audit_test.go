@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAuditTime(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	since, err := parseAuditTime("1h", now)
+	if err != nil {
+		t.Error(err)
+	}
+	if !since.Equal(now.Add(-time.Hour)) {
+		t.Errorf("expected %v but got %v", now.Add(-time.Hour), since)
+	}
+
+	until, err := parseAuditTime("2023-06-01T08:00:00Z", now)
+	if err != nil {
+		t.Error(err)
+	}
+	if !until.Equal(time.Date(2023, 6, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected time %v", until)
+	}
+
+	if _, err := parseAuditTime("yesterday", now); err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
@@ -20,10 +20,21 @@ import (
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Kubectl delete can delete apiObject in a declarative way",
-	Long:  "Kubectl delete can delete apiObject in a declarative way, usage kubectl delete -f [file]",
+	Long:  "Kubectl delete can delete apiObject in a declarative way, usage kubectl delete [file] [--cascade background|foreground|orphan]",
 	Run:   deleteHandler,
 }
 
+func init() {
+	deleteCmd.Flags().String("cascade", "background", "Delete the dependents in the background or foreground, or orphan them")
+}
+
+// --cascade的取值对应APIServer的propagationPolicy
+var cascadePropagationPolicies = map[string]string{
+	"background": apiObject.DeletePropagationBackground,
+	"foreground": apiObject.DeletePropagationForeground,
+	"orphan":     apiObject.DeletePropagationOrphan,
+}
+
 type DeleteObject string
 type DeleteResult string
 
@@ -33,11 +44,12 @@ const (
 	DeleteResult_Unknow  DeleteResult = "Unknow"
 )
 
-func DeleteAPIObjectByKind(kind string, yamlContent []byte) error {
+func DeleteAPIObjectByKind(kind string, yamlContent []byte, propagationPolicy string) error {
 	// 根据 Kind 类型从映射中查找相应的结构体类型
 	structType, ok := apiObject.KindToStructType[kind]
 	if !ok {
-		return errors.Errorf("Unsupported Kind: %s", kind)
+		// 不是内置的资源，尝试作为CRD定义的自定义对象处理
+		return deleteCustomObject(kind, yamlContent, propagationPolicy)
 	}
 
 	// 根据结构体类型创建对应的空结构体
@@ -62,19 +74,58 @@ func DeleteAPIObjectByKind(kind string, yamlContent []byte) error {
 	url := config.GetAPIServerURLPrefix() + config.ApiSpecResourceMap[kind]
 	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
 	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, name)
+	url += "?" + config.URL_QUERY_PROPAGATION_POLICY + "=" + propagationPolicy
 
 	// 向服务器发送删除请求
 	code, err := kubectlutil.DeleteAPIObjectToServer(url)
 	if err != nil {
 		return errors.Wrapf(err, "Failed to delete %s %s", kind, obj.GetObjectName())
 	}
-	if code != http.StatusNoContent {
+	// Namespace的删除以及带有finalizers的对象的删除是异步的，APIServer会返回202
+	if code != http.StatusNoContent && code != http.StatusAccepted {
 		return errors.Errorf("Failed to delete %s %s, code: %d", kind, obj.GetObjectName(), code)
 	}
 
 	return nil
 }
 
+// 删除CRD定义的自定义对象
+func deleteCustomObject(kind string, yamlContent []byte, propagationPolicy string) error {
+	crd, err := kubectlutil.FindCRD(kind)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get CustomResourceDefinition of %s", kind)
+	}
+	if crd == nil {
+		return errors.Errorf("Unsupported Kind: %s", kind)
+	}
+
+	obj := &apiObject.CustomObject{}
+	err = kubectlutil.ParseAPIObjectFromYamlfileContent(yamlContent, obj)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to parse %s YAML file content", kind)
+	}
+
+	namespace := obj.GetObjectNamespace()
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	name := obj.GetObjectName()
+	if name == "" {
+		return errors.Errorf("Failed to get %s name", kind)
+	}
+
+	url := kubectlutil.CustomObjectURL(crd, namespace, name) + "?" + config.URL_QUERY_PROPAGATION_POLICY + "=" + propagationPolicy
+	code, err := kubectlutil.DeleteAPIObjectToServer(url)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to delete %s %s", kind, name)
+	}
+	if code != http.StatusNoContent && code != http.StatusAccepted {
+		return errors.Errorf("Failed to delete %s %s, code: %d", kind, name, code)
+	}
+
+	return nil
+}
+
 func deleteHandler(cmd *cobra.Command, args []string) {
 	// k8log.DebugLog("deleteHandler", "args: "+strings.Join(args, " "))
 
@@ -84,6 +135,12 @@ func deleteHandler(cmd *cobra.Command, args []string) {
 		cmd.Usage()
 		return
 	}
+	cascade, _ := cmd.Flags().GetString("cascade")
+	propagationPolicy, ok := cascadePropagationPolicies[cascade]
+	if !ok {
+		fmt.Println(color.RedString("invalid --cascade %s, should be background, foreground or orphan", cascade))
+		return
+	}
 	// 检查参数是否是文件 读取文件
 	fileInfo, err := os.Stat(args[0])
 	if err != nil {
@@ -112,7 +169,7 @@ func deleteHandler(cmd *cobra.Command, args []string) {
 	}
 
 	// 根据API对象的种类，删除API对象
-	err = DeleteAPIObjectByKind(Kind, fileContent)
+	err = DeleteAPIObjectByKind(Kind, fileContent, propagationPolicy)
 	if err != nil {
 		printDeleteResult(DeleteObject(Kind), DeleteResult_Failed, "delete obj failed", err.Error())
 		return
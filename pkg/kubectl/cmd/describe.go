@@ -8,9 +8,15 @@ import (
 	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
 	"net/http"
+	"net/url"
+	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/table"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/text/cases"
@@ -137,5 +143,60 @@ func describeSpecificObject(kind, namespace, name string) error {
 		return err
 	}
 	fmt.Println(string(indentedJSON))
+
+	// 对象的kind以APIServer返回的为准，命令行里面的kind大小写可能不一样
+	if obj.GetObjectKind() != "" {
+		kind = obj.GetObjectKind()
+	}
+	describeObjectEvents(kind, obj.GetObjectNamespace(), obj.GetObjectName())
 	return nil
 }
+
+// 输出和对象相关的最近的事件
+func describeObjectEvents(kind, namespace, name string) {
+	// Node这样集群级别的对象的事件在默认的namespace下面
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+
+	query := url.Values{}
+	query.Set(config.URL_QUERY_FIELD_SELECTOR, "involvedObject.kind="+kind+",involvedObject.name="+name)
+	uri := config.GetAPIServerURLPrefix() + stringutil.Replace(config.EventsURL, config.URL_PARAM_NAMESPACE_PART, namespace)
+	uri += "?" + query.Encode()
+
+	events := make([]apiObject.Event, 0)
+	code, err := netrequest.GetRequestByTarget(uri, &events, "data")
+	if err != nil || code != http.StatusOK || len(events) == 0 {
+		fmt.Println("Events: <none>")
+		return
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].LastTimestamp.Before(events[j].LastTimestamp)
+	})
+
+	fmt.Println("Events:")
+	now := time.Now()
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Type", "Reason", "Last Seen", "Count", "From", "Message"})
+	for _, event := range events {
+		eventType := color.GreenString(event.Type)
+		if event.Type == apiObject.EventTypeWarning {
+			eventType = color.YellowString(event.Type)
+		}
+		from := event.Source.Component
+		if event.Source.Host != "" {
+			from += ", " + event.Source.Host
+		}
+		t.AppendRow(table.Row{
+			eventType,
+			event.Reason,
+			now.Sub(event.LastTimestamp).Round(time.Second).String() + " ago",
+			event.Count,
+			from,
+			event.Message,
+		})
+	}
+	t.Render()
+}
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/config"
+	"miniK8s/pkg/kubectl/kubectlutil"
 	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
 	"net/http"
@@ -29,8 +31,12 @@ var getNamespaceObjectFuncMap = make(map[string]func(namespace string))
 var getSpecificObjectFunMap = make(map[string]func(namespace string, name string))
 var getNoNamespaceObjectFuncMap = make(map[string]func())
 
+// 获取列表的时候，每次向APIServer请求的对象个数
+var getChunkSize int
+
 func init() {
 	getCmd.PersistentFlags().StringP("namespace", "n", "", "Namespace")
+	getCmd.PersistentFlags().IntVar(&getChunkSize, "chunk-size", 500, "Return large lists in chunks rather than all at once")
 
 	// 构建kind到函数的映射
 	getNamespaceObjectFuncMap[string(Get_Kind_Pod)] = getNamespacePods
@@ -53,6 +59,8 @@ func init() {
 	getSpecificObjectFunMap[string(Get_Kind_Workflow)] = getSpecificWorkflow
 	
 	getNoNamespaceObjectFuncMap[string(Get_Kind_Node)] = getNodes
+	getNoNamespaceObjectFuncMap[string(Get_Kind_Namespace)] = getNamespaces
+	getNoNamespaceObjectFuncMap[string(Get_Kind_CRD)] = getCRDs
 }
 
 type GetObject string
@@ -67,6 +75,8 @@ const (
 	Get_Kind_Hpa        GetObject = "hpa"
 	Get_Kind_Function   GetObject = "function"
 	Get_Kind_Workflow   GetObject = "workflow"
+	Get_Kind_Namespace  GetObject = "namespace"
+	Get_Kind_CRD        GetObject = "crd"
 )
 
 func getObjectHandler(cmd *cobra.Command, args []string) {
@@ -84,9 +94,9 @@ func getObjectHandler(cmd *cobra.Command, args []string) {
 	}
 
 	if len(args) == 1 {
-		// 如果获取的资源是node，则直接获取node
-		if kind == string(Get_Kind_Node) {
-			getNodes()
+		// 如果获取的资源是node、namespace这种不属于namespace的资源，则直接获取
+		if getFunc, ok := getNoNamespaceObjectFuncMap[kind]; ok {
+			getFunc()
 			return 
 		}
 
@@ -99,7 +109,13 @@ func getObjectHandler(cmd *cobra.Command, args []string) {
 		}
 		
 		// 获取default namespace下的所有指定kind的对象
-		getNamespaceObjectFuncMap[kind](namespace)
+		if getFunc, ok := getNamespaceObjectFuncMap[kind]; ok {
+			getFunc(namespace)
+			return
+		}
+
+		// 不是内置的资源，尝试作为CRD定义的自定义对象处理
+		getNamespaceCustomObjects(kind, namespace)
 
 	} else if len(args) == 2 {
 		// 获取namespace和podName
@@ -117,7 +133,12 @@ func getObjectHandler(cmd *cobra.Command, args []string) {
 		}
 
 		// 获取指定的Pod
-		getSpecificObjectFunMap[kind](namespace, name)
+		if getFunc, ok := getSpecificObjectFunMap[kind]; ok {
+			getFunc(namespace, name)
+			return
+		}
+
+		getSpecificCustomObject(kind, namespace, name)
 
 	} else {
 		fmt.Println("getHandler: args mismatch, please specify " + apiObject.AllResourceKind)
@@ -161,7 +182,7 @@ func getNamespacePods(namespace string) {
 
 	pods := []apiObject.PodStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &pods, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &pods, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -181,7 +202,7 @@ func getNodes(){
 
 	nodes := []apiObject.NodeStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &nodes, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &nodes, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -196,6 +217,108 @@ func getNodes(){
 	printNodesResult(nodes)
 }
 
+func getNamespaces() {
+	url := config.GetAPIServerURLPrefix() + config.NamespacesURL
+
+	namespaces := []apiObject.NamespaceStore{}
+
+	code, err := netrequest.GetAllPagesByTarget(url, &namespaces, getChunkSize)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if code != http.StatusOK {
+		fmt.Println("getNamespaces: code:", code)
+		return
+	}
+
+	printNamespacesResult(namespaces)
+}
+
+func getCRDs() {
+	url := config.GetAPIServerURLPrefix() + config.CRDsURL
+
+	crds := []apiObject.CustomResourceDefinition{}
+
+	code, err := netrequest.GetAllPagesByTarget(url, &crds, getChunkSize)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if code != http.StatusOK {
+		fmt.Println("getCRDs: code:", code)
+		return
+	}
+
+	printCRDsResult(crds)
+}
+
+// ==============================================
+//
+// get custom object handler
+//
+// kubeclt get [kind/plural/singular] [namespace]/[name]
+// ==============================================
+
+func getNamespaceCustomObjects(kind string, namespace string) {
+	crd, err := kubectlutil.FindCRD(kind)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if crd == nil {
+		fmt.Println("getObjectHandler: unknown kind " + kind + ", please specify " + apiObject.AllResourceKind + " or a custom resource")
+		return
+	}
+
+	objects := []apiObject.CustomObject{}
+
+	code, err := netrequest.GetAllPagesByTarget(kubectlutil.CustomObjectsURL(crd, namespace), &objects, getChunkSize)
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if code != http.StatusOK {
+		fmt.Println("getNamespaceCustomObjects: code:", code)
+		return
+	}
+
+	printCustomObjectsResult(crd, objects)
+}
+
+func getSpecificCustomObject(kind string, namespace string, name string) {
+	crd, err := kubectlutil.FindCRD(kind)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	if crd == nil {
+		fmt.Println("getObjectHandler: unknown kind " + kind + ", please specify " + apiObject.AllResourceKind + " or a custom resource")
+		return
+	}
+
+	object := &apiObject.CustomObject{}
+	code, err := netrequest.GetRequestByTarget(kubectlutil.CustomObjectURL(crd, namespace, name), object, "data")
+
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if code != http.StatusOK {
+		fmt.Println("getSpecificCustomObject: code:", code)
+		return
+	}
+
+	printCustomObjectsResult(crd, []apiObject.CustomObject{*object})
+}
+
 // ==============================================
 //
 // get service handler
@@ -233,7 +356,7 @@ func getNamespaceServices(namespace string) {
 
 	services := []apiObject.ServiceStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &services, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &services, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -304,7 +427,7 @@ func getNamespaceJobs(namespace string) {
 
 	jobs := []apiObject.JobStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &jobs, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &jobs, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -354,7 +477,7 @@ func getNamespaceDns(namespace string) {
 	url = config.GetAPIServerURLPrefix() + url
 	dnsStores := []apiObject.HpaStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &dnsStores, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &dnsStores, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -404,7 +527,7 @@ func getNamespaceHpas(namespace string) {
 	url = config.GetAPIServerURLPrefix() + url
 	hpaStores := []apiObject.HPAStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &hpaStores, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &hpaStores, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -454,7 +577,7 @@ func getNamespaceReplicaSets(namespace string) {
 	url = config.GetAPIServerURLPrefix() + url
 	replicasetStores := []apiObject.ReplicaSetStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &replicasetStores, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &replicasetStores, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -504,7 +627,7 @@ func getNamespaceFunctions(namespace string) {
 	url = config.GetAPIServerURLPrefix() + url
 	functions := []apiObject.Function{}
 
-	code, err := netrequest.GetRequestByTarget(url, &functions, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &functions, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -552,7 +675,7 @@ func getNamespaceWorkflows(namespace string) {
 
 	workflows := []apiObject.WorkflowStore{}
 
-	code, err := netrequest.GetRequestByTarget(url, &workflows, "data")
+	code, err := netrequest.GetAllPagesByTarget(url, &workflows, getChunkSize)
 
 	if err != nil {
 		fmt.Println(err.Error())
@@ -956,6 +1079,74 @@ func printNodeResult(node *apiObject.NodeStore, t table.Writer) {
 	})
 }
 
+func printNamespacesResult(namespaces []apiObject.NamespaceStore) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Kind", "Name", "Status", "UpdateTime"})
+
+	// 遍历所有的NamespaceStore
+	for _, namespace := range namespaces {
+		printNamespaceResult(&namespace, t)
+	}
+
+	t.Render()
+}
+
+func printNamespaceResult(namespace *apiObject.NamespaceStore, t table.Writer) {
+	statusColor := color.GreenString
+	if namespace.Status.Phase == apiObject.NamespaceTerminating {
+		statusColor = color.YellowString
+	}
+	t.AppendRows([]table.Row{
+		{
+			color.BlueString(string(Get_Kind_Namespace)),
+			color.HiCyanString(namespace.Metadata.Name),
+			statusColor(namespace.Status.Phase),
+			color.GreenString(namespace.Status.UpdateTime.Format("2006-01-02 15:04:05")),
+		},
+	})
+}
+
+func printCRDsResult(crds []apiObject.CustomResourceDefinition) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Kind", "Name", "CustomKind", "APIVersion", "Plural"})
+
+	for _, crd := range crds {
+		t.AppendRows([]table.Row{
+			{
+				color.BlueString(string(Get_Kind_CRD)),
+				color.HiCyanString(crd.Metadata.Name),
+				color.GreenString(crd.Spec.Names.Kind),
+				color.GreenString(crd.APIVersion()),
+				color.GreenString(crd.Spec.Names.Plural),
+			},
+		})
+	}
+
+	t.Render()
+}
+
+func printCustomObjectsResult(crd *apiObject.CustomResourceDefinition, objects []apiObject.CustomObject) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Kind", "Namespace", "Name", "Spec"})
+
+	for _, object := range objects {
+		spec, _ := json.Marshal(object.Spec)
+		t.AppendRows([]table.Row{
+			{
+				color.BlueString(crd.SingularName()),
+				color.HiCyanString(object.GetObjectNamespace()),
+				color.HiCyanString(object.GetObjectName()),
+				color.GreenString(string(spec)),
+			},
+		})
+	}
+
+	t.Render()
+}
+
 // args: [podNamespace]/[podName]
 // 返回值: podNamespace, podName, error
 func parseNameAndNamespace(arg string) (string, string, error) {
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
 
 	"github.com/spf13/cobra"
 )
@@ -17,14 +19,26 @@ var commands = &cobra.Command{
 	Short: "Kubectl is a tool for controlling minik8s cluster.",
 	Long:  `Kubectl is a tool for controlling minik8s cluster. To see the help of a specific command, use: kubectl [command] --help`,
 	Run:   runRoot,
+	// 所有命令执行之前设置访问APIServer使用的token
+	PersistentPreRun: setupToken,
 }
 
+// 访问APIServer使用的token，没有指定的时候依次读取环境变量MINIK8S_TOKEN和config.AdminTokenFile
+var bearerToken string
+
 func init() {
+	commands.PersistentFlags().StringVar(&bearerToken, "token", "", "Bearer token for authentication to the API server")
+
 	commands.AddCommand(applyCmd)
 	commands.AddCommand(deleteCmd)
 	commands.AddCommand(getCmd)
 	commands.AddCommand(describeCmd)
 	commands.AddCommand(executeCmd)
+	commands.AddCommand(tokenCmd)
+	commands.AddCommand(auditCmd)
+	commands.AddCommand(patchCmd)
+	commands.AddCommand(labelCmd)
+	commands.AddCommand(annotateCmd)
 }
 
 func runRoot(cmd *cobra.Command, args []string) {
@@ -34,3 +48,11 @@ func runRoot(cmd *cobra.Command, args []string) {
 	fmt.Println("kubectl is for better control of minik8s")
 	fmt.Println(cmd.UsageString())
 }
+
+func setupToken(cmd *cobra.Command, args []string) {
+	token := bearerToken
+	if token == "" {
+		token = config.LoadToken(config.AdminTokenFile)
+	}
+	netrequest.SetBearerToken(token)
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/kubectl/kubectlutil"
+	"miniK8s/util/jsonpatch"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var patchCmd = &cobra.Command{
+	Use:   "patch",
+	Short: "Kubectl patch can update fields of an apiObject with a merge patch or a json patch",
+	Long:  `Kubectl patch can update fields of an apiObject, usage kubectl patch replicaset [namespace]/[name] -p '{"spec":{"replicas":3}}' [--type merge|json]`,
+	Run:   patchHandler,
+}
+
+var labelCmd = &cobra.Command{
+	Use:   "label",
+	Short: "Kubectl label can update the labels of an apiObject",
+	Long:  "Kubectl label can update the labels of an apiObject, usage kubectl label pod [namespace]/[name] app=web tier- [--overwrite]",
+	Run: func(cmd *cobra.Command, args []string) {
+		metadataMapHandler(cmd, args, "labels")
+	},
+}
+
+var annotateCmd = &cobra.Command{
+	Use:   "annotate",
+	Short: "Kubectl annotate can update the annotations of an apiObject",
+	Long:  "Kubectl annotate can update the annotations of an apiObject, usage kubectl annotate pod [namespace]/[name] key=value key- [--overwrite]",
+	Run: func(cmd *cobra.Command, args []string) {
+		metadataMapHandler(cmd, args, "annotations")
+	},
+}
+
+// --type的取值对应的Content-Type
+var patchTypes = map[string]string{
+	"merge": jsonpatch.MergePatchType,
+	"json":  jsonpatch.JSONPatchType,
+}
+
+func init() {
+	patchCmd.Flags().StringP("patch", "p", "", "The patch to be applied to the object")
+	patchCmd.Flags().String("type", "merge", "The type of patch, merge or json")
+	patchCmd.Flags().StringP("namespace", "n", "", "Namespace")
+
+	for _, c := range []*cobra.Command{labelCmd, annotateCmd} {
+		c.Flags().Bool("overwrite", false, "Allow to overwrite existing values")
+		c.Flags().StringP("namespace", "n", "", "Namespace")
+	}
+}
+
+func patchHandler(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		fmt.Println("patchHandler: args mismatch")
+		cmd.Usage()
+		return
+	}
+
+	patch, _ := cmd.Flags().GetString("patch")
+	if patch == "" {
+		fmt.Println(color.RedString("patch is empty, use -p to specify the patch"))
+		return
+	}
+	typeFlag, _ := cmd.Flags().GetString("type")
+	patchType, ok := patchTypes[typeFlag]
+	if !ok {
+		fmt.Println(color.RedString("invalid --type %s, must be merge or json", typeFlag))
+		return
+	}
+	namespace, _ := cmd.Flags().GetString("namespace")
+
+	uri, err := objectURL(args[0], args[1], namespace)
+	if err != nil {
+		fmt.Println(color.RedString(err.Error()))
+		return
+	}
+
+	sendPatch(uri, patchType, []byte(patch))
+}
+
+// kubectl label和kubectl annotate，field是labels或者annotations
+// key=value设置值，key-删除key
+func metadataMapHandler(cmd *cobra.Command, args []string, field string) {
+	if len(args) < 3 {
+		fmt.Println("args mismatch, please specify [kind] [namespace]/[name] key=value ...")
+		cmd.Usage()
+		return
+	}
+
+	values, err := parseMetadataMapArgs(args[2:])
+	if err != nil {
+		fmt.Println(color.RedString(err.Error()))
+		return
+	}
+
+	namespace, _ := cmd.Flags().GetString("namespace")
+	uri, err := objectURL(args[0], args[1], namespace)
+	if err != nil {
+		fmt.Println(color.RedString(err.Error()))
+		return
+	}
+
+	// 没有--overwrite的时候不允许修改已有的值
+	if overwrite, _ := cmd.Flags().GetBool("overwrite"); !overwrite {
+		existing, err := getMetadataMap(uri, field)
+		if err != nil {
+			fmt.Println(color.RedString(err.Error()))
+			return
+		}
+		for key, value := range values {
+			if old, ok := existing[key]; ok && value != nil && old != *value {
+				fmt.Println(color.RedString("'%s' already has a value (%s), and --overwrite is false", key, old))
+				return
+			}
+		}
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			field: values,
+		},
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	sendPatch(uri, jsonpatch.MergePatchType, patch)
+}
+
+// 解析key=value和key-，要删除的key对应的值是nil，在merge patch里面是null
+func parseMetadataMapArgs(args []string) (map[string]*string, error) {
+	values := make(map[string]*string)
+	for _, arg := range args {
+		if strings.HasSuffix(arg, "-") && !strings.Contains(arg, "=") {
+			key := strings.TrimSuffix(arg, "-")
+			if key == "" {
+				return nil, errors.Errorf("invalid argument %q", arg)
+			}
+			values[key] = nil
+			continue
+		}
+
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, errors.Errorf("invalid argument %q, use like key=value or key-", arg)
+		}
+		value := kv[1]
+		values[kv[0]] = &value
+	}
+	return values, nil
+}
+
+// 获取对象当前的labels或者annotations
+func getMetadataMap(uri string, field string) (map[string]string, error) {
+	obj := struct {
+		Metadata map[string]interface{} `json:"metadata"`
+	}{}
+	code, err := netrequest.GetRequestByTarget(uri, &obj, "data")
+	if err != nil {
+		return nil, err
+	}
+	if code != http.StatusOK {
+		return nil, errors.Errorf("get object failed, code: %d", code)
+	}
+
+	res := make(map[string]string)
+	if values, ok := obj.Metadata[field].(map[string]interface{}); ok {
+		for key, value := range values {
+			res[key] = fmt.Sprint(value)
+		}
+	}
+	return res, nil
+}
+
+// 根据kind和[namespace]/[name]得到对象的URL，Node这样集群级别的对象直接写名字
+// 不是内置的资源的时候，尝试作为CRD定义的自定义对象处理
+func objectURL(kind string, arg string, namespace string) (string, error) {
+	name := arg
+	if parts := strings.Split(arg, "/"); len(parts) == 2 {
+		namespace, name = parts[0], parts[1]
+	} else if len(parts) > 2 {
+		return "", errors.Errorf("invalid argument %q, use like [namespace]/[name]", arg)
+	}
+	if name == "" {
+		return "", errors.New("name is empty")
+	}
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+
+	for k, specURL := range config.ApiSpecResourceMap {
+		if strings.EqualFold(k, kind) || strings.EqualFold(k+"s", kind) {
+			uri := stringutil.Replace(specURL, config.URL_PARAM_NAMESPACE_PART, namespace)
+			uri = stringutil.Replace(uri, config.URL_PARAM_NAME_PART, name)
+			return config.GetAPIServerURLPrefix() + uri, nil
+		}
+	}
+
+	crd, err := kubectlutil.FindCRD(kind)
+	if err != nil {
+		return "", err
+	}
+	if crd == nil {
+		return "", errors.Errorf("Unsupported Kind: %s", kind)
+	}
+	return kubectlutil.CustomObjectURL(crd, namespace, name), nil
+}
+
+// 根据kind和namespace得到对象列表的URL
+func objectsURL(kind string, namespace string) (string, error) {
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+
+	for k, listURL := range config.ApiResourceMap {
+		if strings.EqualFold(k, kind) || strings.EqualFold(k+"s", kind) {
+			return config.GetAPIServerURLPrefix() + stringutil.Replace(listURL, config.URL_PARAM_NAMESPACE_PART, namespace), nil
+		}
+	}
+
+	crd, err := kubectlutil.FindCRD(kind)
+	if err != nil {
+		return "", err
+	}
+	if crd == nil {
+		return "", errors.Errorf("Unsupported Kind: %s", kind)
+	}
+	return kubectlutil.CustomObjectsURL(crd, namespace), nil
+}
+
+func sendPatch(uri string, patchType string, patch []byte) {
+	code, res, err := netrequest.PatchRequest(uri, patchType, patch)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	if code != http.StatusOK {
+		msg := ""
+		if body, ok := res.(map[string]interface{}); ok {
+			msg = fmt.Sprint(body["error"])
+		}
+		fmt.Println(color.RedString("patch failed, code: %d, %s", code, msg))
+		return
+	}
+	fmt.Println(color.GreenString("patched"))
+}
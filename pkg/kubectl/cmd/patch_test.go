@@ -0,0 +1,32 @@
+package cmd
+
+import "testing"
+
+func TestParseMetadataMapArgs(t *testing.T) {
+	values, err := parseMetadataMapArgs([]string{"app=web", "tier-", "empty=", "url=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(values) != 4 {
+		t.Fatalf("expected 4 values but got %d", len(values))
+	}
+	if values["app"] == nil || *values["app"] != "web" {
+		t.Errorf("app should be web")
+	}
+	if v, ok := values["tier"]; !ok || v != nil {
+		t.Errorf("tier should be removed")
+	}
+	if values["empty"] == nil || *values["empty"] != "" {
+		t.Errorf("empty should be an empty string")
+	}
+	if values["url"] == nil || *values["url"] != "a=b" {
+		t.Errorf("url should be a=b")
+	}
+
+	for _, arg := range []string{"app", "-", "=web"} {
+		if _, err := parseMetadataMapArgs([]string{arg}); err == nil {
+			t.Errorf("%q should be invalid", arg)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/kubectl/kubectlutil"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/table"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Kubectl token can create or delete bearer tokens of the API server",
+	Long:  "Kubectl token can create or delete bearer tokens of the API server, usage kubectl token create [--type user|node] [--user name] or kubectl token delete [token]",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Usage()
+	},
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a new bearer token",
+	Long:  "Create a new bearer token, node tokens are used by kubelet and kube-proxy, user tokens are used by kubectl",
+	Run:   tokenCreateHandler,
+}
+
+var tokenDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a bearer token",
+	Long:  "Delete a bearer token, usage kubectl token delete [token]",
+	Run:   tokenDeleteHandler,
+}
+
+func init() {
+	tokenCreateCmd.Flags().String("type", apiObject.TokenTypeUser, "Token type, user or node")
+	tokenCreateCmd.Flags().String("user", "", "User name of the token")
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenDeleteCmd)
+}
+
+func tokenCreateHandler(cmd *cobra.Command, args []string) {
+	tokenType, _ := cmd.Flags().GetString("type")
+	user, _ := cmd.Flags().GetString("user")
+	// 节点组件的token默认使用内置的用户名
+	if user == "" && tokenType == apiObject.TokenTypeNode {
+		user = config.NodeTokenUser
+	}
+
+	req := apiObject.TokenRequest{Type: tokenType, User: user}
+	code, res, err := netrequest.PostRequestByTarget(config.GetAPIServerURLPrefix()+config.TokensURL, req)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	body, _ := res.(map[string]interface{})
+	if code != http.StatusCreated {
+		bodyBytes, _ := json.Marshal(res)
+		reason, details := kubectlutil.ParseInvalidResponse(string(bodyBytes))
+		fmt.Println(color.RedString("create token failed, code: %d, %s", code, reason))
+		for _, detail := range details {
+			fmt.Println(color.RedString("  %s: %s", detail.Field, detail.Reason))
+		}
+		return
+	}
+
+	data, _ := body["data"].(string)
+	record := apiObject.Token{}
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	printTokenResult(&record)
+}
+
+func tokenDeleteHandler(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return
+	}
+
+	url := config.GetAPIServerURLPrefix() + stringutil.Replace(config.TokenSpecURL, config.URL_PARAM_NAME_PART, args[0])
+	code, err := netrequest.DelRequest(url)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+
+	switch code {
+	case http.StatusNoContent:
+		fmt.Println(color.GreenString("token deleted"))
+	case http.StatusNotFound:
+		fmt.Println(color.RedString("token not found"))
+	default:
+		fmt.Println(color.RedString("delete token failed, code: %d", code))
+	}
+}
+
+// 打印新创建的token，token只会在这里显示一次
+func printTokenResult(record *apiObject.Token) {
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Type", "User", "Token"})
+	t.AppendRow(table.Row{
+		color.GreenString(record.Type),
+		color.CyanString(record.User),
+		record.Token,
+	})
+	t.Render()
+}
@@ -3,6 +3,7 @@ package kubectlutil
 import (
 	"encoding/json"
 	"fmt"
+	"miniK8s/pkg/apiObject/validation"
 	netrequest "miniK8s/util/netRequest"
 
 	"github.com/pkg/errors"
@@ -54,6 +55,19 @@ func PostAPIObjectToServer(URL string, obj interface{}) (int, error, string) {
 	return code, nil, string(bodyBytes)
 }
 
+// APIServer返回422的时候，body里面的details是所有不合法的字段
+// 返回error里面的原因和所有不合法的字段，解析失败的时候原样返回msg
+func ParseInvalidResponse(msg string) (string, []validation.FieldError) {
+	body := struct {
+		Error   string                  `json:"error"`
+		Details []validation.FieldError `json:"details"`
+	}{}
+	if err := json.Unmarshal([]byte(msg), &body); err != nil {
+		return msg, nil
+	}
+	return body.Error, body.Details
+}
+
 // 发送删除API对象的请求到服务器
 func DeleteAPIObjectToServer(URL string) (int, error) {
 	// k8log.DebugLog("DeleteAPIObjectToServer", "URL: "+URL)
@@ -0,0 +1,56 @@
+package kubectlutil
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+)
+
+// 从APIServer获取所有的CRD
+func GetCRDsFromServer() ([]apiObject.CustomResourceDefinition, error) {
+	URL := config.GetAPIServerURLPrefix() + config.CRDsURL
+
+	crds := make([]apiObject.CustomResourceDefinition, 0)
+	code, err := netrequest.GetRequestByTarget(URL, &crds, "data")
+	if err != nil {
+		return nil, err
+	}
+
+	if code != http.StatusOK {
+		return nil, fmt.Errorf("get custom resource definitions failed, code: %d", code)
+	}
+	return crds, nil
+}
+
+// 根据kind、单数或者复数形式找到对应的CRD，没有找到的时候返回nil
+func FindCRD(name string) (*apiObject.CustomResourceDefinition, error) {
+	crds, err := GetCRDsFromServer()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range crds {
+		if crds[i].MatchName(name) {
+			return &crds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// 某个namespace下面所有自定义对象的URL
+func CustomObjectsURL(crd *apiObject.CustomResourceDefinition, namespace string) string {
+	URL := config.GetAPIServerURLPrefix() + config.CustomResourcesURL
+	URL = stringutil.Replace(URL, config.URL_PARAM_GROUP_PART, crd.Spec.Group)
+	URL = stringutil.Replace(URL, config.URL_PARAM_VERSION_PART, crd.Spec.Version)
+	URL = stringutil.Replace(URL, config.URL_PARAM_PLURAL_PART, crd.Spec.Names.Plural)
+	URL = stringutil.Replace(URL, config.URL_PARAM_NAMESPACE_PART, namespace)
+	return URL
+}
+
+// 某个特定自定义对象的URL
+func CustomObjectURL(crd *apiObject.CustomResourceDefinition, namespace string, name string) string {
+	return CustomObjectsURL(crd, namespace) + "/" + name
+}
@@ -1,6 +1,10 @@
 package dockerclient
 
-import "github.com/docker/docker/client"
+import (
+	"context"
+
+	"github.com/docker/docker/client"
+)
 
 /*
  * 创建一个docker client
@@ -12,3 +16,16 @@ func NewDockerClient() (*client.Client, error) {
 	}
 	return cli, nil
 }
+
+/*
+ * 检查docker daemon是否可用，用于readyz检查
+ */
+func Ping() error {
+	cli, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+	_, err = cli.Ping(context.Background())
+	return err
+}
@@ -2,8 +2,11 @@ package kubelet
 
 import (
 	"encoding/json"
+	"miniK8s/pkg/config"
 	"miniK8s/pkg/entity"
+	"miniK8s/pkg/healthz"
 	"miniK8s/pkg/k8log"
+	dockerclient "miniK8s/pkg/kubelet/dockerClient"
 	"miniK8s/pkg/kubelet/kubeletconfig"
 	"miniK8s/pkg/kubelet/pleg"
 	"miniK8s/pkg/kubelet/status"
@@ -95,6 +98,13 @@ func (k *Kubelet) Run() {
 	k8log.InfoLog("Kubelet", "Launch Kubelet")
 	k.RegisterNode()
 
+	// 健康检查，pod的创建依赖docker，状态缓存依赖redis，podUpdate消息依赖RabbitMQ
+	go healthz.ListenAndServe("Kubelet", config.Kubelet_Healthz_Port, nil, []healthz.HealthChecker{
+		healthz.NamedCheck("docker", dockerclient.Ping),
+		healthz.NamedCheck("redis", k.statusManager.CacheHealthy),
+		healthz.NamedCheck("rabbitmq", k.lw.Healthy),
+	})
+
 	// 创建一个通道来接收信号
 	sigs := make(chan os.Signal, 10)
 	// 注册一个信号接收函数，将接收到的信号发送到通道
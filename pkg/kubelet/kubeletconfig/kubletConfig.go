@@ -20,7 +20,7 @@ type KubeletConfig struct {
 func DefaultKubeletConfig() *KubeletConfig {
 	apiserverIP := config.GetMasterIP()
 	apiserverPort := config.API_Server_Port
-	apiserverScheme := config.API_Server_Scheme
+	apiserverScheme := config.GetAPIServerScheme()
 	apiserverURLPrefix := apiserverScheme + apiserverIP + ":" + strconv.Itoa(apiserverPort)
 	lwconf := listwatcher.DefaultListwatcherConfig()
 
@@ -37,7 +37,7 @@ func DefaultKubeletConfig() *KubeletConfig {
 func ProductionKubeletConfig() *KubeletConfig {
 	apiserverIP := config.GetMasterIP()
 	apiserverPort := config.API_Server_Port
-	apiserverScheme := config.API_Server_Scheme
+	apiserverScheme := config.GetAPIServerScheme()
 	apiserverURLPrefix := config.GetAPIServerURLPrefix()
 	lwconf := listwatcher.DefaultListwatcherConfig()
 
@@ -102,9 +102,11 @@ func (p *plegManager) plegGenerator(runtimePodStatus RunTimePodStatusMap, cacheP
 				case string(minik8stypes.Removing):
 					// break
 				case string(minik8stypes.Exited):
+					p.recordContainerDied(cachePods[podRecord.current.PodID], "BackOff", containerstatus.ExitCode)
 					p.AddPodNeedRestartEvent(podRecord.current.PodID)
 					// break
 				case string(minik8stypes.Dead):
+					p.recordContainerDied(cachePods[podRecord.current.PodID], "ContainerDied", containerstatus.ExitCode)
 					p.AddPodNeedRestartEvent(podRecord.current.PodID)
 					// break
 				default:
@@ -133,6 +135,16 @@ func (p *plegManager) plegGenerator(runtimePodStatus RunTimePodStatusMap, cacheP
 	return nil
 }
 
+// 容器退出之后需要重启Pod，记录一个Warning事件
+// 缓存里面没有这个Pod的时候不知道对应哪个对象，不记录
+func (p *plegManager) recordContainerDied(pod *apiObject.PodStore, reason string, exitCode int) {
+	if pod == nil {
+		return
+	}
+	ref := apiObject.NewObjectReference(apiObject.PodKind, &pod.Metadata)
+	p.recorder.Eventf(ref, apiObject.EventTypeWarning, reason, "Container exited with code %d, restarting pod", exitCode)
+}
+
 // func (p *plegManager) CompareOldAndCurrentPodStatus(oldStatus *runtime.RunTimePodStatus, newStatus *runtime.RunTimePodStatus) {
 // 	// 如果状态没有发生变化
 // 	if len(oldStatus.PodStatus.ContainerStatuses) != len(newStatus.PodStatus.ContainerStatuses) {
@@ -2,6 +2,7 @@ package pleg
 
 import (
 	"fmt"
+	"miniK8s/pkg/event"
 	"miniK8s/pkg/k8log"
 	"miniK8s/pkg/kubelet/runtime"
 	"miniK8s/pkg/kubelet/status"
@@ -20,6 +21,8 @@ type plegManager struct {
 	statusManager status.StatusManager
 	// podStatus是一个Pod的UUID到PodRecord的映射
 	podStatus podRecords
+	// 记录容器退出这样的事件
+	recorder event.Recorder
 }
 
 // 创建PlegManager的时候，必须要传递一个statusManager，以及PlegChannel
@@ -28,6 +31,7 @@ func NewPlegManager(statusManager status.StatusManager, plegchan chan *PodLifecy
 		PlegChannel:   plegchan,
 		statusManager: statusManager,
 		podStatus:     make(podRecords),
+		recorder:      event.NewRecorder(event.ComponentKubelet),
 	}
 }
 
@@ -175,14 +175,19 @@ func (r *runtimeManager) stopPodContainer(pod *apiObject.PodStore, container *ap
 func (r *runtimeManager) createPodContainer(pod *apiObject.PodStore, container *apiObject.Container, pauseContainerID string) (string, error) {
 	// TODO: 从容器管理器中创建一个普通的容器
 
+	podRef := apiObject.NewObjectReference(apiObject.PodKind, &pod.Metadata)
+
 	// [1] 拉取镜像
 	// 创建一个minik8stypes.ImagePullPolicy
 	imagePullPolicy := minik8sTypes.ImagePullPolicy(container.ImagePullPolicy)
 	// 根据镜像的拉取策略，拉取镜像
+	r.recorder.Eventf(podRef, apiObject.EventTypeNormal, "Pulling", "Pulling image %s", container.Image)
 	_, err := r.imageManager.PullImageWithPolicy(container.Image, imagePullPolicy)
 	if err != nil {
+		r.recorder.Eventf(podRef, apiObject.EventTypeWarning, "ErrImagePull", "Failed to pull image %s: %s", container.Image, err.Error())
 		return "", err
 	}
+	r.recorder.Eventf(podRef, apiObject.EventTypeNormal, "Pulled", "Successfully pulled image %s", container.Image)
 
 	// [2] 创建容器前，获取容器的配置
 	containerConfig, err := r.getPodContainerConfig(pod, container, pauseContainerID)
@@ -199,15 +204,19 @@ func (r *runtimeManager) createPodContainer(pod *apiObject.PodStore, container *
 	ID, err := r.containerManager.CreateContainer(container.Name, containerConfig)
 
 	if err != nil {
+		r.recorder.Eventf(podRef, apiObject.EventTypeWarning, "Failed", "Error creating container %s: %s", container.Name, err.Error())
 		return "", err
 	}
+	r.recorder.Eventf(podRef, apiObject.EventTypeNormal, "Created", "Created container %s", container.Name)
 
 	// [4] 启动容器
 	_, err = r.containerManager.StartContainer(ID)
 
 	if err != nil {
+		r.recorder.Eventf(podRef, apiObject.EventTypeWarning, "Failed", "Error starting container %s: %s", container.Name, err.Error())
 		return "", err
 	}
+	r.recorder.Eventf(podRef, apiObject.EventTypeNormal, "Started", "Started container %s", container.Name)
 
 	k8log.InfoLog("kubelet", fmt.Sprintf("create Pod Container %s success, ID is %s", container.Name, ID))
 	return ID, nil
@@ -277,42 +286,42 @@ func (r *runtimeManager) restartPodContainer(pod *apiObject.PodStore, container
 // 以下是一些辅助函数
 // ******************************************************************
 
-func (r *runtimeManager) parseVolumeBinds(podVolumes []apiObject.Volume, containerVolumeMounts []apiObject.VolumeMount) ([]string, error) {
+func (r *runtimeManager) parseVolumeBinds(pod *apiObject.PodStore, containerVolumeMounts []apiObject.VolumeMount) ([]string, error) {
 	// 我们知道Pod的配置文件里面Pod有自己的Volume，然后Container可以通过Pod挂在的Volume的名字来挂载Pod的Volume
 	// 所以我们需要把Pod的Volume和Container的VolumeMounts做一个映射，然后把Pod的Volume挂载到Container的VolumeMounts上面
 
-	// 创建一个Map解析pod的volume, 先把Pod级别的所有Mounts都放到Map中
-	// volumes := make(map[string]*apiObject.Volume)
-	// 创建一个map，把string映射到*apiObject.Volume
-	volumes := make(map[string]*apiObject.Volume)
+	// 创建一个Map解析pod的volume, 先把Pod级别的所有Volume在节点上的路径都放到Map中
+	volumes := make(map[string]string)
 
 	// 创建一个空的返回结果
 	volumeBinds := []string{}
 
 	// 遍历pod的volume，将pod的volume添加到volumes中
-	for _, volume := range podVolumes {
-		// 如果是hostPath类型的volume，那么就添加到volumes中
+	for _, volume := range pod.Spec.Volumes {
+		// hostPath类型的volume直接使用节点上的路径
 		if volume.HostPath.Path != "" {
-			volumes[volume.Name] = &volume
+			volumes[volume.Name] = volume.HostPath.Path
+		}
+		// jobCredential类型的volume在创建容器之前已经写到了节点上
+		if volume.JobCredential.JobName != "" {
+			volumes[volume.Name] = credentialVolumePath(pod, volume.Name)
 		}
 	}
 
 	// 遍历container的volumeMounts，将container的volumeMounts添加到volumeBinds中
 	for _, volumeMount := range containerVolumeMounts {
 		// 需要手动的检查volumeMount.Name是否存在，如果不存在，那么就报错
-		if _, ok := volumes[volumeMount.Name]; !ok {
+		hostPath, ok := volumes[volumeMount.Name]
+		if !ok {
 			return nil, fmt.Errorf("volumeMount.Name %s not found in pod volumes", volumeMount.Name)
 		}
-		volumesValue := volumes[volumeMount.Name]
 
-		if volumesValue.HostPath.Path == "" {
-			return nil, fmt.Errorf("volumesValue.HostPath %s is not hostPath type", volumesValue.HostPath)
+		// 如果存在，那么就把节点上的路径和volumeMount.MountPath拼接成一个字符串，添加到volumeBinds中
+		volumeBindValue := hostPath + ":" + volumeMount.MountPath
+		if volumeMount.ReadOnly {
+			volumeBindValue += ":ro"
 		}
 
-		// volumesValue.HostPath.Pat
-		// 如果存在，那么就把volumeMount.Name和volumeMount.MountPath拼接成一个字符串，添加到volumeBinds中
-		volumeBindValue := volumesValue.HostPath.Path + ":" + volumeMount.MountPath
-
 		// 简单处理，就直接把映射的字符串添加到volumeBinds中
 		volumeBinds = append(volumeBinds, volumeBindValue)
 	}
@@ -347,7 +356,7 @@ func (r *runtimeManager) getPodContainerConfig(pod *apiObject.PodStore, containe
 	pauseName := PauseContainerNameBase + pod.Metadata.UUID
 
 	// [Binds] 处理好传入配置的container的volumeMounts和创建容器的volumeMounts的映射
-	contianerBinds, err := r.parseVolumeBinds(pod.Spec.Volumes, container.VolumeMounts)
+	contianerBinds, err := r.parseVolumeBinds(pod, container.VolumeMounts)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/k8log"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jobCredential类型的volume在节点上的目录，每个Pod一个子目录
+func credentialVolumePath(pod *apiObject.PodStore, volumeName string) string {
+	return filepath.Join(CredentialVolumeDir, pod.Metadata.UUID, volumeName)
+}
+
+// 创建容器之前向APIServer申请Job的凭证，写到credentialVolumePath下面
+// 目录的结构和容器里面的config.TokenDir一样，挂载到TokenDir之后job-server直接读取默认的路径
+func (r *runtimeManager) prepareCredentialVolumes(pod *apiObject.PodStore) error {
+	for _, volume := range pod.Spec.Volumes {
+		jobName := volume.JobCredential.JobName
+		if jobName == "" {
+			continue
+		}
+		if !ownedByJob(pod, jobName) {
+			return fmt.Errorf("volume %s: pod %s/%s is not owned by job %s", volume.Name, pod.Metadata.Namespace, pod.Metadata.Name, jobName)
+		}
+
+		credential, err := requestJobCredential(pod.Metadata.Namespace, jobName)
+		if err != nil {
+			return fmt.Errorf("volume %s: %s", volume.Name, err.Error())
+		}
+
+		if err := writeCredentialFiles(credentialVolumePath(pod, volume.Name), credential); err != nil {
+			return err
+		}
+		k8log.InfoLog("kubelet", fmt.Sprintf("credential of job %s/%s written for pod %s", pod.Metadata.Namespace, jobName, pod.Metadata.Name))
+	}
+	return nil
+}
+
+// 按照容器里面的路径写文件，没有开启TLS的时候只有token
+func writeCredentialFiles(dir string, credential *apiObject.JobCredential) error {
+	files := []struct {
+		file    string
+		content string
+	}{
+		{config.JobTokenFile, credential.Token},
+		{config.CACertFile, credential.CAData},
+		{config.ClientCertFile, credential.ClientCertData},
+		{config.ClientKeyFile, credential.ClientKeyData},
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if f.content == "" {
+			continue
+		}
+		path := filepath.Join(dir, strings.TrimPrefix(f.file, config.TokenDir))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(strings.TrimSpace(f.content)+"\n"), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Pod删除之后删除节点上的凭证
+func removeCredentialVolumes(podUUID string) {
+	if podUUID == "" {
+		return
+	}
+	if err := os.RemoveAll(filepath.Join(CredentialVolumeDir, podUUID)); err != nil {
+		k8log.ErrorLog("kubelet", "remove credential volumes of pod "+podUUID+" failed, "+err.Error())
+	}
+}
+
+// 只有Job创建的Pod才能拿到Job的凭证
+func ownedByJob(pod *apiObject.PodStore, jobName string) bool {
+	for _, ref := range pod.Metadata.OwnerReferences {
+		if ref.Kind == apiObject.JobKind && ref.Name == jobName {
+			return true
+		}
+	}
+	return false
+}
+
+func requestJobCredential(namespace string, jobName string) (*apiObject.JobCredential, error) {
+	url := stringutil.Replace(config.JobSpecCredentialURL, config.URL_PARAM_NAMESPACE_PART, namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, jobName)
+	url = config.GetAPIServerURLPrefix() + url
+
+	code, res, err := netrequest.PostRequestByTarget(url, struct{}{})
+	if err != nil {
+		return nil, err
+	}
+	body, _ := res.(map[string]interface{})
+	if code != http.StatusOK && code != http.StatusCreated {
+		return nil, fmt.Errorf("request credential of job %s/%s failed, code %d, %v", namespace, jobName, code, body["error"])
+	}
+
+	data, ok := body["data"].(string)
+	if !ok {
+		return nil, errors.New("credential not found in response")
+	}
+	credential := &apiObject.JobCredential{}
+	if err := json.Unmarshal([]byte(data), credential); err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
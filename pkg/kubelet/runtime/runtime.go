@@ -3,6 +3,7 @@ package runtime
 import (
 	"fmt"
 	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/event"
 	"miniK8s/pkg/k8log"
 	"miniK8s/pkg/kubelet/runtime/container"
 	"miniK8s/pkg/kubelet/runtime/image"
@@ -38,6 +39,8 @@ type runtimeManager struct {
 	// 用于管理容器、镜像的管理器
 	containerManager container.ContainerManager
 	imageManager     image.ImageManager
+	// 记录拉取镜像、创建容器这样的事件
+	recorder event.Recorder
 }
 
 // NewRuntimeManager 创建一个RuntimeManager
@@ -45,6 +48,7 @@ func NewRuntimeManager() RuntimeManager {
 	manager := &runtimeManager{
 		containerManager: container.ContainerManager{},
 		imageManager:     image.ImageManager{},
+		recorder:         event.NewRecorder(event.ComponentKubelet),
 	}
 	return manager
 }
@@ -54,6 +58,12 @@ func NewRuntimeManager() RuntimeManager {
 
 // CreatePod 创建pod
 func (r *runtimeManager) CreatePod(pod *apiObject.PodStore) error {
+	// 准备需要向APIServer申请的volume
+	if err := r.prepareCredentialVolumes(pod); err != nil {
+		k8log.ErrorLog("Runtime Manager", err.Error())
+		return err
+	}
+
 	// 创建pause容器
 	pauseID, err := r.createPauseContainer(pod)
 
@@ -92,6 +102,7 @@ func (r *runtimeManager) DeletePod(pod *apiObject.PodStore) error {
 	if err != nil {
 		return err
 	}
+	removeCredentialVolumes(pod.Metadata.UUID)
 
 	LogStr := "[Runtime Manager] delete pod success" + pod.GetPodName()
 	k8log.InfoLog("kubelet", LogStr)
@@ -185,6 +196,7 @@ func (r *runtimeManager) DelPodByPodID(podUUID string) error {
 			return err
 		}
 	}
+	removeCredentialVolumes(podUUID)
 
 	if err != nil {
 		return err
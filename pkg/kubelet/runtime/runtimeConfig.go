@@ -14,6 +14,9 @@ const (
 	// 比如你要引用容器的ID，就是container:xxxx
 	ContianerREfPrefix = "container:"
 
+	// jobCredential类型的volume在节点上的目录
+	CredentialVolumeDir = "/var/lib/minik8s/credentials/"
+
 	//
 )
 
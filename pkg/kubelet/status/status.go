@@ -28,6 +28,8 @@ type StatusManager interface {
 
 	// resetCache 重置缓存
 	ResetCache() error
+	// CacheHealthy 检查缓存使用的redis是否可用
+	CacheHealthy() error
 
 	// 获取运行时候的Pod的状态信息
 	GetAllPodFromRuntime() (map[string]*runtime.RunTimePodStatus, error)
@@ -110,6 +112,10 @@ func (s *statusManager) ResetCache() error {
 	return s.cache.InitCache()
 }
 
+func (s *statusManager) CacheHealthy() error {
+	return s.cache.Ping()
+}
+
 // ************************************************************
 
 func (s *statusManager) GetAllPodFromRuntime() (map[string]*runtime.RunTimePodStatus, error) {
@@ -32,6 +32,8 @@ type IptableManager interface {
 	UpdateService(serviceUpdate *entity.ServiceUpdate)
 	SaveIPTables(path string) error
 	GetPodsBySvcName(svcName string) []string
+	// Healthy 检查能否访问iptables，并且NAT表里面的KUBE-SERVICES链存在
+	Healthy() error
 }
 
 type iptableManager struct {
@@ -114,6 +116,20 @@ func (im *iptableManager) UpdateService(serviceUpdate *entity.ServiceUpdate) {
 	}
 }
 
+func (im *iptableManager) Healthy() error {
+	if im.ipt == nil {
+		return fmt.Errorf("im.iptables is nil")
+	}
+	exists, err := im.ipt.ChainExists("nat", "KUBE-SERVICES")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("chain KUBE-SERVICES not found in nat table")
+	}
+	return nil
+}
+
 func (im *iptableManager) Init_iptables() {
 	// 创建 iptables 的实例
 	im.ipt, _ = iptables.New()
@@ -5,6 +5,7 @@ import (
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/config"
 	"miniK8s/pkg/entity"
+	"miniK8s/pkg/healthz"
 	"miniK8s/pkg/k8log"
 	"miniK8s/pkg/listwatcher"
 	"miniK8s/pkg/message"
@@ -36,7 +37,6 @@ func NewKubeProxy(lsConfig *listwatcher.ListwatcherConfig) *KubeProxy {
 	if err != nil {
 		k8log.ErrorLog("Kubeproxy", "NewKubeProxy: new watcher failed")
 	}
-	// TODO: health check server
 	iptableManager := NewIptableManager()
 	dnsManager := NewDnsManager()
 	proxy := &KubeProxy{
@@ -241,6 +241,12 @@ func (proxy *KubeProxy) CreateNginxPod() {
 }
 
 func (proxy *KubeProxy) Run() {
+	// 健康检查，service的规则写在iptables里面，service和host的变化通过RabbitMQ接收
+	go healthz.ListenAndServe("Kubeproxy", config.Kube_Proxy_Healthz_Port, nil, []healthz.HealthChecker{
+		healthz.NamedCheck("iptables", proxy.iptableManager.Healthy),
+		healthz.NamedCheck("rabbitmq", proxy.lw.Healthy),
+	})
+
 	// 创建nginx的pod
 	proxy.CreateNginxPod()
 	// serviceUpdate
@@ -0,0 +1,52 @@
+package leaderelection
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+)
+
+// 读写Lease，返回HTTP状态码
+type LeaseClient interface {
+	// 不存在的时候返回404和nil
+	Get(namespace string, name string) (*apiObject.Lease, int, error)
+	Create(lease *apiObject.Lease) (int, error)
+	// lease里面带有resourceVersion，被别人修改过的时候返回409
+	Update(lease *apiObject.Lease) (int, error)
+}
+
+// 通过APIServer读写Lease
+type apiServerLeaseClient struct {
+}
+
+func (c *apiServerLeaseClient) Get(namespace string, name string) (*apiObject.Lease, int, error) {
+	url := config.GetAPIServerURLPrefix() + config.LeaseSpecURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, name)
+
+	lease := &apiObject.Lease{}
+	code, err := netrequest.GetRequestByTarget(url, lease, "data")
+	if err != nil || code != http.StatusOK {
+		return nil, code, err
+	}
+	return lease, code, nil
+}
+
+func (c *apiServerLeaseClient) Create(lease *apiObject.Lease) (int, error) {
+	url := config.GetAPIServerURLPrefix() + config.LeasesURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, lease.Metadata.Namespace)
+
+	code, _, err := netrequest.PostRequestByTarget(url, lease)
+	return code, err
+}
+
+func (c *apiServerLeaseClient) Update(lease *apiObject.Lease) (int, error) {
+	url := config.GetAPIServerURLPrefix() + config.LeaseSpecURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, lease.Metadata.Namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, lease.Metadata.Name)
+
+	code, _, err := netrequest.PutRequestByTarget(url, lease)
+	return code, err
+}
@@ -0,0 +1,85 @@
+package leaderelection
+
+import (
+	"errors"
+	"miniK8s/pkg/config"
+	"miniK8s/util/uuid"
+	"os"
+	"strings"
+	"time"
+)
+
+// 各个组件使用的Lease的名字，同一个组件的所有副本使用同一个Lease
+const (
+	LeaseNameScheduler            = "kube-scheduler"
+	LeaseNameControllerManager    = "kube-controller-manager"
+	LeaseNameServerlessController = "serverless-controller"
+)
+
+const (
+	// 持有者超过这个时间没有续约，其他副本就可以接管，也就是故障转移最长需要的时间
+	DefaultLeaseDuration = 15 * time.Second
+
+	// 通过环境变量修改LeaseDuration，比如 MINIK8S_LEASE_DURATION=30s
+	LeaseDurationEnvName = "MINIK8S_LEASE_DURATION"
+)
+
+type LeaderElectionConfig struct {
+	// Lease所在的namespace和名字
+	Namespace string
+	Name      string
+	// 当前副本的标识，写在Lease的holderIdentity里面
+	Identity string
+
+	// 持有者超过LeaseDuration没有续约，其他副本就可以接管
+	LeaseDuration time.Duration
+	// leader在RenewDeadline之内一直续约失败就主动放弃，必须小于LeaseDuration，保证其他副本接管之前已经停止工作
+	RenewDeadline time.Duration
+	// 每隔RetryPeriod尝试获取或者续约一次
+	RetryPeriod time.Duration
+}
+
+// 默认的配置，RenewDeadline和RetryPeriod按照LeaseDuration的比例计算，默认是15s、10s、2s
+func DefaultLeaderElectionConfig(name string) *LeaderElectionConfig {
+	leaseDuration := DefaultLeaseDuration
+	if value := strings.TrimSpace(os.Getenv(LeaseDurationEnvName)); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			leaseDuration = d
+		}
+	}
+
+	return &LeaderElectionConfig{
+		Namespace:     config.DefaultNamespace,
+		Name:          name,
+		Identity:      defaultIdentity(),
+		LeaseDuration: leaseDuration,
+		RenewDeadline: leaseDuration * 2 / 3,
+		RetryPeriod:   leaseDuration * 2 / 15,
+	}
+}
+
+// 主机名加上一个随机的后缀，同一台机器上面的多个副本也不会重复
+func defaultIdentity() string {
+	hostname, _ := os.Hostname()
+	return hostname + "_" + uuid.NewUUID()
+}
+
+func (c *LeaderElectionConfig) validate() error {
+	if c.Name == "" {
+		return errors.New("lease name is empty")
+	}
+	if c.Identity == "" {
+		return errors.New("identity is empty")
+	}
+	// Lease的过期时间以秒为单位
+	if c.LeaseDuration < time.Second {
+		return errors.New("leaseDuration must be at least 1s")
+	}
+	if c.RenewDeadline >= c.LeaseDuration {
+		return errors.New("renewDeadline must be less than leaseDuration")
+	}
+	if c.RetryPeriod <= 0 || c.RetryPeriod >= c.RenewDeadline {
+		return errors.New("retryPeriod must be greater than 0 and less than renewDeadline")
+	}
+	return nil
+}
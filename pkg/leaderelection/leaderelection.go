@@ -0,0 +1,260 @@
+package leaderelection
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/apiserver/serverconfig"
+	"miniK8s/pkg/k8log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 基于Lease的选主，同一个组件启动多个副本的时候只有leader执行自己的循环
+// Lease保存在APIServer里面，etcd中的对象带有租约，leader停止续约之后自动删除
+// 所有副本每隔RetryPeriod读取一次Lease：
+//   - Lease不存在、已经被释放或者持有者超过leaseDurationSeconds没有续约的时候，尝试写入自己的标识
+//   - 写入的时候带上读到的resourceVersion，并发接管的时候只有一个副本能成功
+//   - leader在RenewDeadline之内一直续约失败，或者发现Lease被别人拿走了，就放弃leader
+// 是否过期按照本地观察到Lease变化的时间计算，不依赖各个节点的时钟一致
+
+type LeaderCallbacks struct {
+	// 成为leader之后在新的协程里面调用，stopCh关闭表示失去了leader
+	OnStartedLeading func(stopCh <-chan struct{})
+	// 失去leader或者退出选举的时候调用
+	OnStoppedLeading func()
+	// 观察到leader变化的时候调用，可以为nil
+	OnNewLeader func(identity string)
+}
+
+type LeaderElector struct {
+	config    *LeaderElectionConfig
+	callbacks LeaderCallbacks
+	client    LeaseClient
+	now       func() time.Time
+
+	lock sync.Mutex
+	// 最后一次观察到的Lease和观察到它变化的本地时间
+	observedSpec apiObject.LeaseSpec
+	observedTime time.Time
+	// 最后一次通知OnNewLeader的leader
+	reportedLeader string
+}
+
+// 创建一个通过APIServer读写Lease的LeaderElector
+func NewLeaderElector(config *LeaderElectionConfig, callbacks LeaderCallbacks) (*LeaderElector, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if callbacks.OnStartedLeading == nil || callbacks.OnStoppedLeading == nil {
+		return nil, fmt.Errorf("OnStartedLeading and OnStoppedLeading must not be nil")
+	}
+	return newLeaderElector(config, callbacks, &apiServerLeaseClient{}, time.Now), nil
+}
+
+func newLeaderElector(config *LeaderElectionConfig, callbacks LeaderCallbacks, client LeaseClient, now func() time.Time) *LeaderElector {
+	return &LeaderElector{
+		config:    config,
+		callbacks: callbacks,
+		client:    client,
+		now:       now,
+	}
+}
+
+// 创建LeaderElector并且运行，配置不合法的时候直接退出
+func RunOrDie(config *LeaderElectionConfig, callbacks LeaderCallbacks, stopCh <-chan struct{}) {
+	le, err := NewLeaderElector(config, callbacks)
+	if err != nil {
+		k8log.FatalLog("LeaderElection", "invalid leader election config: "+err.Error())
+	}
+	le.Run(stopCh)
+}
+
+// Run 一直尝试成为leader，成为leader之后持续续约，直到stopCh关闭或者失去leader，函数会阻塞
+// stopCh关闭的时候主动释放Lease，其他副本不需要等到过期就可以接管
+func (le *LeaderElector) Run(stopCh <-chan struct{}) {
+	defer le.callbacks.OnStoppedLeading()
+
+	if !le.acquire(stopCh) {
+		return
+	}
+
+	leaderStopCh := make(chan struct{})
+	go le.callbacks.OnStartedLeading(leaderStopCh)
+	le.renew(stopCh)
+	close(leaderStopCh)
+}
+
+// IsLeader 当前副本是否是leader
+func (le *LeaderElector) IsLeader() bool {
+	return le.GetLeader() == le.config.Identity
+}
+
+// GetLeader 最后一次观察到的leader，没有的时候为空
+func (le *LeaderElector) GetLeader() string {
+	le.lock.Lock()
+	defer le.lock.Unlock()
+	return le.observedSpec.HolderIdentity
+}
+
+// 每隔RetryPeriod尝试一次，成为leader之后返回true，stopCh关闭的时候返回false
+func (le *LeaderElector) acquire(stopCh <-chan struct{}) bool {
+	k8log.InfoLog("LeaderElection", fmt.Sprintf("%s try to acquire lease %s/%s", le.config.Identity, le.config.Namespace, le.config.Name))
+	for {
+		if le.tryAcquireOrRenew() {
+			k8log.InfoLog("LeaderElection", fmt.Sprintf("%s acquired lease %s/%s", le.config.Identity, le.config.Namespace, le.config.Name))
+			return true
+		}
+
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(le.config.RetryPeriod):
+		}
+	}
+}
+
+// 每隔RetryPeriod续约一次，失去leader的时候返回
+func (le *LeaderElector) renew(stopCh <-chan struct{}) {
+	lastRenew := le.now()
+	for {
+		select {
+		case <-stopCh:
+			le.release()
+			return
+		case <-time.After(le.config.RetryPeriod):
+		}
+
+		if le.tryAcquireOrRenew() {
+			lastRenew = le.now()
+			continue
+		}
+
+		if leader := le.GetLeader(); leader != "" && leader != le.config.Identity {
+			k8log.ErrorLog("LeaderElection", fmt.Sprintf("lease %s/%s is taken over by %s", le.config.Namespace, le.config.Name, leader))
+			return
+		}
+		if le.now().Sub(lastRenew) > le.config.RenewDeadline {
+			k8log.ErrorLog("LeaderElection", fmt.Sprintf("failed to renew lease %s/%s in %s", le.config.Namespace, le.config.Name, le.config.RenewDeadline))
+			return
+		}
+	}
+}
+
+// 读取Lease，没有持有者或者已经过期的时候写入自己，持有者是自己的时候续约
+// 写入成功返回true
+func (le *LeaderElector) tryAcquireOrRenew() bool {
+	now := le.now()
+	desired := apiObject.LeaseSpec{
+		HolderIdentity:       le.config.Identity,
+		LeaseDurationSeconds: le.leaseDurationSeconds(),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	lease, code, err := le.client.Get(le.config.Namespace, le.config.Name)
+	if err != nil {
+		k8log.ErrorLog("LeaderElection", "get lease failed: "+err.Error())
+		return false
+	}
+
+	// 不存在的时候创建，同时创建的时候只有一个能成功
+	if code == http.StatusNotFound {
+		lease = &apiObject.Lease{}
+		lease.APIVersion = serverconfig.APIVersion
+		lease.Kind = apiObject.LeaseKind
+		lease.Metadata.Name = le.config.Name
+		lease.Metadata.Namespace = le.config.Namespace
+		lease.Spec = desired
+
+		code, err = le.client.Create(lease)
+		if err != nil || code != http.StatusCreated {
+			k8log.DebugLog("LeaderElection", fmt.Sprintf("create lease failed, code: %d, err: %v", code, err))
+			return false
+		}
+		le.observe(desired, now)
+		return true
+	}
+
+	if code != http.StatusOK {
+		k8log.ErrorLog("LeaderElection", fmt.Sprintf("get lease failed, code: %d", code))
+		return false
+	}
+
+	le.observe(lease.Spec, now)
+	holder := lease.Spec.HolderIdentity
+	if holder != "" && holder != le.config.Identity && !le.expired(lease.Spec, now) {
+		return false
+	}
+
+	// 续约的时候保留获得Lease的时间，接管的时候增加持有者变化的次数
+	if holder == le.config.Identity {
+		desired.AcquireTime = lease.Spec.AcquireTime
+		desired.LeaseTransitions = lease.Spec.LeaseTransitions
+	} else {
+		desired.LeaseTransitions = lease.Spec.LeaseTransitions + 1
+	}
+	lease.Spec = desired
+
+	code, err = le.client.Update(lease)
+	if err != nil || code != http.StatusOK {
+		k8log.DebugLog("LeaderElection", fmt.Sprintf("update lease failed, code: %d, err: %v", code, err))
+		return false
+	}
+	le.observe(desired, now)
+	return true
+}
+
+// 主动释放Lease，把持有者清空，过期时间设为1秒
+func (le *LeaderElector) release() {
+	if !le.IsLeader() {
+		return
+	}
+
+	lease, code, err := le.client.Get(le.config.Namespace, le.config.Name)
+	if err != nil || code != http.StatusOK || lease.Spec.HolderIdentity != le.config.Identity {
+		return
+	}
+
+	lease.Spec.HolderIdentity = ""
+	lease.Spec.LeaseDurationSeconds = 1
+	lease.Spec.RenewTime = le.now()
+	code, err = le.client.Update(lease)
+	if err != nil || code != http.StatusOK {
+		k8log.ErrorLog("LeaderElection", fmt.Sprintf("release lease failed, code: %d, err: %v", code, err))
+		return
+	}
+	k8log.InfoLog("LeaderElection", fmt.Sprintf("%s released lease %s/%s", le.config.Identity, le.config.Namespace, le.config.Name))
+	le.observe(lease.Spec, le.now())
+}
+
+// 记录观察到的Lease，持有者或者续约时间变化的时候重新计时
+func (le *LeaderElector) observe(spec apiObject.LeaseSpec, now time.Time) {
+	le.lock.Lock()
+	if spec.HolderIdentity != le.observedSpec.HolderIdentity || !spec.RenewTime.Equal(le.observedSpec.RenewTime) {
+		le.observedTime = now
+	}
+	le.observedSpec = spec
+
+	leader := spec.HolderIdentity
+	changed := leader != le.reportedLeader
+	le.reportedLeader = leader
+	le.lock.Unlock()
+
+	if changed && le.callbacks.OnNewLeader != nil {
+		go le.callbacks.OnNewLeader(leader)
+	}
+}
+
+// 从观察到Lease变化开始，超过持有者的leaseDurationSeconds没有再变化就认为过期了
+func (le *LeaderElector) expired(spec apiObject.LeaseSpec, now time.Time) bool {
+	le.lock.Lock()
+	defer le.lock.Unlock()
+	duration := time.Duration(spec.LeaseDurationSeconds) * time.Second
+	return !le.observedTime.Add(duration).After(now)
+}
+
+// Lease里面的过期时间以秒为单位，向上取整
+func (le *LeaderElector) leaseDurationSeconds() int {
+	return int((le.config.LeaseDuration + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,242 @@
+package leaderelection
+
+import (
+	"miniK8s/pkg/apiObject"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 在内存里面保存Lease，和APIServer一样通过resourceVersion检查并发的修改
+type fakeLeaseClient struct {
+	lock    sync.Mutex
+	lease   *apiObject.Lease
+	version int
+	// 更新之前调用，用来模拟别的副本先写入了
+	beforeUpdate func()
+}
+
+func (c *fakeLeaseClient) Get(namespace string, name string) (*apiObject.Lease, int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.lease == nil {
+		return nil, http.StatusNotFound, nil
+	}
+	lease := *c.lease
+	lease.Metadata.ResourceVersion = strconv.Itoa(c.version)
+	return &lease, http.StatusOK, nil
+}
+
+func (c *fakeLeaseClient) Create(lease *apiObject.Lease) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.lease != nil {
+		return http.StatusConflict, nil
+	}
+	stored := *lease
+	c.lease = &stored
+	c.version++
+	return http.StatusCreated, nil
+}
+
+func (c *fakeLeaseClient) Update(lease *apiObject.Lease) (int, error) {
+	if c.beforeUpdate != nil {
+		c.beforeUpdate()
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.lease == nil {
+		return http.StatusNotFound, nil
+	}
+	if lease.Metadata.ResourceVersion != strconv.Itoa(c.version) {
+		return http.StatusConflict, nil
+	}
+	stored := *lease
+	c.lease = &stored
+	c.version++
+	return http.StatusOK, nil
+}
+
+func (c *fakeLeaseClient) holder() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.lease == nil {
+		return ""
+	}
+	return c.lease.Spec.HolderIdentity
+}
+
+func testConfig(identity string) *LeaderElectionConfig {
+	return &LeaderElectionConfig{
+		Namespace:     "default",
+		Name:          LeaseNameScheduler,
+		Identity:      identity,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	}
+}
+
+func TestAcquireAndRenew(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	client := &fakeLeaseClient{}
+	le1 := newLeaderElector(testConfig("node1"), LeaderCallbacks{}, client, clock)
+	le2 := newLeaderElector(testConfig("node2"), LeaderCallbacks{}, client, clock)
+
+	if !le1.tryAcquireOrRenew() || !le1.IsLeader() {
+		t.Fatalf("expected node1 to create the lease")
+	}
+	if le2.tryAcquireOrRenew() {
+		t.Fatalf("expected node2 not to acquire a lease held by node1")
+	}
+	if le2.GetLeader() != "node1" {
+		t.Errorf("expected node2 to observe node1 as leader but got %q", le2.GetLeader())
+	}
+
+	// 续约的时候保留获得Lease的时间
+	now = now.Add(2 * time.Second)
+	if !le1.tryAcquireOrRenew() {
+		t.Fatalf("expected node1 to renew the lease")
+	}
+	spec := client.lease.Spec
+	if !spec.RenewTime.Equal(now) || spec.AcquireTime.Equal(now) || spec.LeaseTransitions != 0 || spec.LeaseDurationSeconds != 15 {
+		t.Errorf("unexpected lease after renew: %+v", spec)
+	}
+}
+
+func TestTakeOverExpiredLease(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	client := &fakeLeaseClient{}
+	le1 := newLeaderElector(testConfig("node1"), LeaderCallbacks{}, client, clock)
+	le2 := newLeaderElector(testConfig("node2"), LeaderCallbacks{}, client, clock)
+
+	if !le1.tryAcquireOrRenew() {
+		t.Fatalf("expected node1 to create the lease")
+	}
+	le2.tryAcquireOrRenew()
+
+	// node1停止续约，没有超过leaseDuration之前不能接管
+	now = now.Add(14 * time.Second)
+	if le2.tryAcquireOrRenew() {
+		t.Fatalf("expected node2 not to take over before the lease expires")
+	}
+
+	now = now.Add(2 * time.Second)
+	if !le2.tryAcquireOrRenew() || !le2.IsLeader() {
+		t.Fatalf("expected node2 to take over the expired lease")
+	}
+	if spec := client.lease.Spec; spec.LeaseTransitions != 1 || !spec.AcquireTime.Equal(now) {
+		t.Errorf("unexpected lease after take over: %+v", spec)
+	}
+
+	// node1恢复之后发现Lease已经被拿走了
+	if le1.tryAcquireOrRenew() || le1.IsLeader() {
+		t.Errorf("expected node1 to lose the lease")
+	}
+}
+
+func TestConcurrentTakeOver(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	client := &fakeLeaseClient{}
+
+	// 已经释放的Lease可以立刻接管
+	client.Create(&apiObject.Lease{Spec: apiObject.LeaseSpec{LeaseDurationSeconds: 1}})
+
+	le := newLeaderElector(testConfig("node1"), LeaderCallbacks{}, client, clock)
+	// 读取之后另一个副本先写入了
+	client.beforeUpdate = func() {
+		client.beforeUpdate = nil
+		lease, _, _ := client.Get("default", LeaseNameScheduler)
+		lease.Spec.HolderIdentity = "node2"
+		lease.Spec.LeaseDurationSeconds = 15
+		client.Update(lease)
+	}
+	if le.tryAcquireOrRenew() {
+		t.Fatalf("expected the update with a stale resourceVersion to fail")
+	}
+	if client.holder() != "node2" {
+		t.Errorf("expected node2 to hold the lease but got %q", client.holder())
+	}
+}
+
+func TestRunAndRelease(t *testing.T) {
+	client := &fakeLeaseClient{}
+	config := testConfig("node1")
+	config.LeaseDuration = time.Second
+	config.RenewDeadline = 500 * time.Millisecond
+	config.RetryPeriod = 20 * time.Millisecond
+
+	started := make(chan struct{})
+	leaderStopped := make(chan struct{})
+	stopped := make(chan struct{})
+	var newLeader string
+	var lock sync.Mutex
+	le := newLeaderElector(config, LeaderCallbacks{
+		OnStartedLeading: func(stopCh <-chan struct{}) {
+			close(started)
+			<-stopCh
+			close(leaderStopped)
+		},
+		OnStoppedLeading: func() { close(stopped) },
+		OnNewLeader: func(identity string) {
+			lock.Lock()
+			defer lock.Unlock()
+			if identity != "" {
+				newLeader = identity
+			}
+		},
+	}, client, time.Now)
+
+	stopCh := make(chan struct{})
+	go le.Run(stopCh)
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatalf("expected to become leader")
+	}
+
+	// 持续续约，不会失去leader
+	time.Sleep(100 * time.Millisecond)
+	if !le.IsLeader() {
+		t.Fatalf("expected to stay leader")
+	}
+
+	close(stopCh)
+	for _, ch := range []chan struct{}{leaderStopped, stopped} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatalf("expected the callbacks to be notified")
+		}
+	}
+	if client.holder() != "" || client.lease.Spec.LeaseDurationSeconds != 1 {
+		t.Errorf("expected the lease to be released, got %+v", client.lease.Spec)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if newLeader != "node1" {
+		t.Errorf("expected OnNewLeader to be called with node1 but got %q", newLeader)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	config := DefaultLeaderElectionConfig(LeaseNameControllerManager)
+	if err := config.validate(); err != nil {
+		t.Fatalf("expected the default config to be valid, got %v", err)
+	}
+	if config.RenewDeadline != 10*time.Second || config.RetryPeriod != 2*time.Second {
+		t.Errorf("unexpected default config: %+v", config)
+	}
+
+	config.RenewDeadline = config.LeaseDuration
+	if err := config.validate(); err == nil {
+		t.Errorf("expected renewDeadline equal to leaseDuration to be rejected")
+	}
+}
@@ -46,3 +46,8 @@ func (ls *Listwatcher) WatchQueue_NoBlock(queueName string, handleFunc func(amqp
 	return cancelFunc, nil
 }
 
+
+// Healthy 检查订阅消息使用的RabbitMQ链接是否可用，用于组件的readyz检查
+func (ls *Listwatcher) Healthy() error {
+	return ls.subscriber.Healthy()
+}
@@ -6,6 +6,7 @@ import (
 	"miniK8s/pkg/config"
 	"miniK8s/pkg/entity"
 	"miniK8s/pkg/k8log"
+	"miniK8s/pkg/metrics"
 	"miniK8s/util/stringutil"
 )
 
@@ -28,7 +29,9 @@ func init() {
 
 // 发布消息的组件
 func PublishMsg(queueName string, msg []byte) error {
-	return ServerMsgUtil.Publisher.Publish(queueName, ContentTypeJson, msg)
+	err := ServerMsgUtil.Publisher.Publish(queueName, ContentTypeJson, msg)
+	metrics.ObservePublish(queueName, err)
+	return err
 }
 
 // 发布消息的组件函数
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"errors"
 	"fmt"
 	"miniK8s/pkg/k8log"
 	"strings"
@@ -175,3 +176,11 @@ func (s *Subscriber) UnSubscribe(stopChannelCh chan<- struct{}) {
 	close(stopChannelCh)
 	s.conn.Close()
 }
+
+// 检查和RabbitMQ的链接是否可用，断开之后在重连成功之前都会返回错误
+func (s *Subscriber) Healthy() error {
+	if s.conn == nil || s.conn.IsClosed() {
+		return errors.New("rabbitmq connection is closed")
+	}
+	return nil
+}
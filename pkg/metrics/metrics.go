@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// 所有组件的指标都注册在这个Registry里面，APIServer通过/metrics以Prometheus的文本格式输出
+var Registry = prometheus.NewRegistry()
+
+var (
+	// APIServer处理的请求，route是gin的路由模板，比如/api/v1/namespaces/:namespace/pods/:name
+	RequestTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "apiserver_request_total",
+		Help: "Number of requests handled by the api server, partitioned by route, verb and status code.",
+	}, []string{"route", "verb", "code"})
+
+	RequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "apiserver_request_duration_seconds",
+		Help:    "Latency of the requests handled by the api server, watch requests are not included.",
+		Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"route", "verb", "code"})
+
+	InflightRequests = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "apiserver_current_inflight_requests",
+		Help: "Number of requests currently being handled by the api server, watch requests are not included.",
+	})
+
+	// 存储的每种操作的耗时和出错的次数
+	StorageRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "etcd_request_duration_seconds",
+		Help:    "Latency of the storage operations.",
+		Buckets: []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"operation"})
+
+	StorageRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "etcd_request_errors_total",
+		Help: "Number of failed storage operations.",
+	}, []string{"operation"})
+
+	// message.PublishMsg发送的消息
+	PublishTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_publish_total",
+		Help: "Number of messages published to rabbitmq, partitioned by queue.",
+	}, []string{"queue"})
+
+	PublishFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rabbitmq_publish_failures_total",
+		Help: "Number of messages failed to be published to rabbitmq, partitioned by queue.",
+	}, []string{"queue"})
+)
+
+func init() {
+	Registry.MustRegister(
+		RequestTotal,
+		RequestDuration,
+		InflightRequests,
+		StorageRequestDuration,
+		StorageRequestErrors,
+		PublishTotal,
+		PublishFailures,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+}
+
+// 以Prometheus的文本格式输出Registry里面的所有指标
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// 记录一次消息的发送
+func ObservePublish(queue string, err error) {
+	PublishTotal.WithLabelValues(queue).Inc()
+	if err != nil {
+		PublishFailures.WithLabelValues(queue).Inc()
+	}
+}
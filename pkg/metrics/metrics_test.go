@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.ReleaseMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/api/v1/namespaces/:namespace/pods", func(c *gin.Context) {
+		// watch请求不计入正在处理的请求
+		expected := 1.0
+		if c.Query("watch") == "true" {
+			expected = 0
+		}
+		if v := testutil.ToFloat64(InflightRequests); v != expected {
+			t.Errorf("expected %v inflight request but got %v", expected, v)
+		}
+		c.JSON(http.StatusOK, gin.H{"data": ""})
+	})
+
+	for _, uri := range []string{
+		"/api/v1/namespaces/default/pods",
+		"/api/v1/namespaces/test/pods",
+		"/api/v1/namespaces/default/pods?watch=true",
+		"/not/found",
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, uri, nil))
+	}
+
+	route := "/api/v1/namespaces/:namespace/pods"
+	if v := testutil.ToFloat64(RequestTotal.WithLabelValues(route, http.MethodGet, "200")); v != 2 {
+		t.Errorf("expected 2 requests but got %v", v)
+	}
+	if v := testutil.ToFloat64(RequestTotal.WithLabelValues(route, "WATCH", "200")); v != 1 {
+		t.Errorf("expected 1 watch request but got %v", v)
+	}
+	if v := testutil.ToFloat64(RequestTotal.WithLabelValues(unknownRoute, http.MethodGet, "404")); v != 1 {
+		t.Errorf("expected 1 unknown request but got %v", v)
+	}
+	if v := testutil.ToFloat64(InflightRequests); v != 0 {
+		t.Errorf("expected 0 inflight request but got %v", v)
+	}
+	// watch请求不记录耗时
+	if n := testutil.CollectAndCount(RequestDuration); n != 2 {
+		t.Errorf("expected 2 duration series but got %d", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ObservePublish("testQueue", nil)
+	ObservePublish("testQueue", errors.New("closed"))
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(recorder.Body)
+
+	for _, line := range []string{
+		`rabbitmq_publish_total{queue="testQueue"} 2`,
+		`rabbitmq_publish_failures_total{queue="testQueue"} 1`,
+	} {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("expected %s in the output", line)
+		}
+	}
+}
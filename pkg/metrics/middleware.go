@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"miniK8s/pkg/config"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 没有匹配到路由的请求，避免把任意的URL作为label
+const unknownRoute = "unknown"
+
+// 记录APIServer处理的每个请求，需要在其他中间件之前注册，这样被拒绝的请求也会被记录
+// watch请求是长连接，只记录次数，不记录耗时和正在处理的请求数
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verb := c.Request.Method
+		watch := c.Request.Method == http.MethodGet && c.Query(config.URL_QUERY_WATCH) == "true"
+		if watch {
+			verb = "WATCH"
+		} else {
+			InflightRequests.Inc()
+			defer InflightRequests.Dec()
+		}
+
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unknownRoute
+		}
+		code := strconv.Itoa(c.Writer.Status())
+
+		RequestTotal.WithLabelValues(route, verb, code).Inc()
+		if !watch {
+			RequestDuration.WithLabelValues(route, verb, code).Observe(time.Since(start).Seconds())
+		}
+	}
+}
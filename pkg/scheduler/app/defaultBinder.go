@@ -0,0 +1,48 @@
+package scheduler
+
+import (
+	"fmt"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/entity"
+	"miniK8s/pkg/message"
+	"miniK8s/pkg/scheduler/framework"
+	netrequest "miniK8s/util/netRequest"
+	"miniK8s/util/stringutil"
+	"net/http"
+)
+
+// 调度器默认使用的Bind插件，依赖RabbitMQ，所以不放在plugins里面
+const DefaultBinderName = "DefaultBinder"
+
+// 把spec.nodeName写回APIServer，然后通知节点上的kubelet创建pod
+type defaultBinder struct{}
+
+func (p *defaultBinder) Name() string {
+	return DefaultBinderName
+}
+
+func (p *defaultBinder) Bind(state *framework.CycleState, pod *apiObject.PodStore, nodeName string) error {
+	podStore := *pod
+	podStore.Spec.NodeName = nodeName
+
+	// 更新Apiserver中的Pod信息
+	URL := stringutil.Replace(config.PodSpecURL, config.URL_PARAM_NAMESPACE_PART, podStore.GetPodNamespace())
+	URL = stringutil.Replace(URL, config.URL_PARAM_NAME_PART, podStore.GetPodName())
+	URL = config.GetAPIServerURLPrefix() + URL
+
+	code, _, err := netrequest.PutRequestByTarget(URL, &podStore)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusOK {
+		return fmt.Errorf("update pod failed, code %d", code)
+	}
+
+	podUpdate := &entity.PodUpdate{
+		Action:    message.CREATE,
+		PodTarget: podStore,
+		Node:      nodeName,
+	}
+	return message.PublishUpdatePod(podUpdate)
+}
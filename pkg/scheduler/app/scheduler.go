@@ -2,29 +2,23 @@ package scheduler
 
 import (
 	"encoding/json"
+	"fmt"
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/config"
-	"miniK8s/pkg/entity"
+	"miniK8s/pkg/event"
+	"miniK8s/pkg/healthz"
 	"miniK8s/pkg/k8log"
+	"miniK8s/pkg/leaderelection"
 	"miniK8s/pkg/listwatcher"
 	"miniK8s/pkg/message"
-	netrequest "miniK8s/util/netRequest"
-	"miniK8s/util/stringutil"
-	"net/http"
-	"strconv"
-
-	"math/rand"
-	"sync"
-	"time"
+	"miniK8s/pkg/scheduler/framework"
+	"miniK8s/pkg/scheduler/plugins"
 
 	"github.com/streadway/amqp"
 )
 
 type SchedulePolicy string
 
-var globalCount int
-var lock sync.Mutex
-
 const (
 	RoundRobin SchedulePolicy = "RoundRobin" // 轮询调度策略
 	Random     SchedulePolicy = "Random"     // Random调度,产生一个随机数
@@ -40,10 +34,14 @@ type Scheduler struct {
 	publisher *message.Publisher
 	// 调度策略
 	polocy SchedulePolicy
+	// 按照调度策略和配置启用的插件进行调度
+	framework *framework.Framework
 	// apiServer的地址
 	apiServerHost string
 	// apiServer的端口
 	apiServerPort int
+	// 记录调度的结果
+	recorder event.Recorder
 }
 
 // 创建一个调度器
@@ -52,7 +50,21 @@ func NewScheduler() (*Scheduler, error) {
 	if err != nil {
 		return nil, err
 	}
-	schedulerConfig := DefaultSchedulerConfig()
+	schedulerConfig, err := LoadSchedulerConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	enabledPlugins, err := schedulerConfig.EnabledPlugins()
+	if err != nil {
+		return nil, err
+	}
+	registry := plugins.NewInTreeRegistry()
+	registry.Register(DefaultBinderName, func() (framework.Plugin, error) { return &defaultBinder{}, nil })
+	newFramework, err := framework.NewFramework(registry, enabledPlugins)
+	if err != nil {
+		return nil, err
+	}
 
 	newPublisher, err := message.NewPublisher(message.DefaultMsgConfig())
 	if err != nil {
@@ -61,99 +73,18 @@ func NewScheduler() (*Scheduler, error) {
 	scheduler := &Scheduler{
 		lw:            newlistwatcher,
 		polocy:        schedulerConfig.Policy,
+		framework:     newFramework,
 		apiServerHost: schedulerConfig.ApiServerHost,
 		apiServerPort: schedulerConfig.ApiServerPort,
 		publisher:     newPublisher,
+		recorder:      event.NewRecorder(event.ComponentScheduler),
 	}
-	k8log.InfoLog("scheduler start with config: %s", string(schedulerConfig.Policy))
+	k8log.InfoLog("Scheduler", fmt.Sprintf("scheduler start with policy %s, plugins %+v", schedulerConfig.Policy, *enabledPlugins))
 	return scheduler, nil
 }
 
-/* 根据具体的调度策略选择一个节点 */
-/*********************************************************************/
-/*********************************************************************/
-func schRoundRobin(nodes []apiObject.NodeStore) string {
-	lock.Lock()
-	defer lock.Unlock()
-	cnt := len(nodes)
-	if cnt == 0 {
-		return ""
-	}
-	idx := globalCount % cnt
-	globalCount++
-	k8log.DebugLog("Scheduler", "RoundRobin调度策略选择节点"+nodes[idx].GetName())
-	return nodes[idx].GetName()
-}
-func schRandom(nodes []apiObject.NodeStore) string {
-	lock.Lock()
-	defer lock.Unlock()
-	cnt := len(nodes)
-	if cnt == 0 {
-		return ""
-	}
-	// seconds := time.Now().Unix() //获取当前日期和时间的整数形式
-	r := rand.New(rand.NewSource(time.Now().Unix()))
-	idx := r.Intn(cnt)
-	return nodes[idx].GetName()
-}
-func schLeastPod(nodes []apiObject.NodeStore) string {
-	lock.Lock()
-	defer lock.Unlock()
-	cnt := len(nodes)
-	if cnt == 0 {
-		return ""
-	}
-	//to do
-	return ""
-}
-func schLeastCpu(nodes []apiObject.NodeStore) string {
-	lock.Lock()
-	defer lock.Unlock()
-	cnt := len(nodes)
-	if cnt == 0 {
-		return ""
-	}
-	//to do
-	return ""
-}
-func schLeastMem(nodes []apiObject.NodeStore) string {
-	lock.Lock()
-	defer lock.Unlock()
-	cnt := len(nodes)
-	if cnt == 0 {
-		return ""
-	}
-	//to do
-	return ""
-}
-
-/*********************************************************************/
-/*********************************************************************/
-// 从所有的节点里面选择一个节点
-func (sch *Scheduler) ChooseFromNodes(nodes []apiObject.NodeStore) string {
-	if len(nodes) == 0 {
-		return ""
-	}
-	switch sch.polocy {
-	case RoundRobin:
-		return schRoundRobin(nodes)
-	case Random:
-		return schRandom(nodes)
-	case LeastPod:
-		return schLeastPod(nodes)
-	case LeastCpu:
-		return schLeastCpu(nodes)
-	case LeastMem:
-		return schLeastMem(nodes)
-	default:
-	}
-	// TODO:
-	return "ubuntu"
-}
-
 // 处理调度请求的消息
 func (sch *Scheduler) RequestSchedule(parsedMsg *message.Message) {
-	// TODO
 	k8log.DebugLog("Scheduler", "收到调度请求消息"+parsedMsg.Content)
 
 	allNodes, err := sch.GetAllNodes()
@@ -162,14 +93,6 @@ func (sch *Scheduler) RequestSchedule(parsedMsg *message.Message) {
 		k8log.ErrorLog("Scheduler", "获取所有节点失败"+err.Error())
 	}
 
-	// 调度的时候筛选存活的节点
-	nodes := make([]apiObject.NodeStore, 0)
-	for _, node := range allNodes {
-		if node.Status.Condition == apiObject.Ready {
-			nodes = append(nodes, node)
-		}
-	}
-
 	// 反序列化pod
 	podStore := &apiObject.PodStore{}
 	err = json.Unmarshal([]byte(parsedMsg.Content), &podStore)
@@ -178,52 +101,25 @@ func (sch *Scheduler) RequestSchedule(parsedMsg *message.Message) {
 		return
 	}
 
-	var scheduledNode string
-
-	// 如果在pod中指定了node
-	if podStore.Spec.NodeName != "" {
-		// 检查node是否存在
-		for _, node := range nodes {
-			if node.GetName() == podStore.Spec.NodeName {
-				scheduledNode = podStore.Spec.NodeName
-			}
-		}
-	}
-
-	// 如果未指定node或者指定的node无效，则选择一个节点
-	if scheduledNode == "" {
-		scheduledNode = sch.ChooseFromNodes(nodes)
-	}
+	podRef := apiObject.NewObjectReference(apiObject.PodKind, &podStore.Metadata)
 
-	if scheduledNode == "" {
-		k8log.ErrorLog("Scheduler", "没有可用的节点")
+	// 过滤掉不能调度的节点，比如不是Ready状态的节点，再给剩下的节点打分，选择分数最高的节点
+	state := framework.NewCycleState()
+	scheduledNode, err := sch.framework.Schedule(state, podStore, allNodes)
+	if err != nil {
+		k8log.ErrorLog("Scheduler", "没有可用的节点: "+err.Error())
+		sch.recorder.Eventf(podRef, apiObject.EventTypeWarning, "FailedScheduling", "%s", err.Error())
 		return
 	}
 
-	// 为pod添加node信息
-	podStore.Spec.NodeName = scheduledNode
-
-	// 更新Apiserver中的Pod信息
-	URL := stringutil.Replace(config.PodSpecURL, config.URL_PARAM_NAMESPACE_PART, podStore.GetPodNamespace())
-	URL = stringutil.Replace(URL, config.URL_PARAM_NAME_PART, podStore.GetPodName())
-	URL = config.GetAPIServerURLPrefix() + URL
-
-	code, _, err := netrequest.PutRequestByTarget(URL, podStore)
+	// 为pod添加node信息，更新Apiserver中的Pod信息并通知节点
+	err = sch.framework.RunBindPlugins(state, podStore, scheduledNode)
 	if err != nil {
-		k8log.ErrorLog("Scheduler", "更新Pod信息失败"+err.Error())
-		return
-	}
-	if code != http.StatusOK {
-		k8log.ErrorLog("Scheduler", "更新Pod信息失败,code: "+strconv.Itoa(code))
+		k8log.ErrorLog("Scheduler", "绑定Pod失败"+err.Error())
+		sch.recorder.Eventf(podRef, apiObject.EventTypeWarning, "FailedScheduling", "Binding to node %s failed: %s", scheduledNode, err.Error())
 		return
 	}
-
-	podUpdate := &entity.PodUpdate{
-		Action:    message.CREATE,
-		PodTarget: *podStore,
-		Node:      scheduledNode,
-	}
-	message.PublishUpdatePod(podUpdate)
+	sch.recorder.Eventf(podRef, apiObject.EventTypeNormal, "Scheduled", "Successfully assigned %s/%s to %s", podStore.GetPodNamespace(), podStore.GetPodName(), scheduledNode)
 }
 
 // 调度器的消息处理函数,分发给不同的消息处理函数
@@ -232,6 +128,7 @@ func (sch *Scheduler) MsgHandler(msg amqp.Delivery) {
 	parsedMsg, err := message.ParseJsonMessageFromBytes(msg.Body)
 	if err != nil {
 		k8log.ErrorLog("Scheduler", "消息格式错误,无法转换为Message")
+		return
 	}
 
 	// 处理调度请求的消息
@@ -239,7 +136,24 @@ func (sch *Scheduler) MsgHandler(msg amqp.Delivery) {
 }
 
 func (sch *Scheduler) Run() {
-	// TODO
+	// 调度器依赖RabbitMQ接收调度请求
+	go healthz.ListenAndServe("Scheduler", config.Scheduler_Healthz_Port, nil, []healthz.HealthChecker{
+		healthz.NamedCheck("rabbitmq", sch.lw.Healthy),
+	})
+
+	// 启动多个副本的时候只有leader监听调度请求，避免同一个Pod被调度两次
+	// 失去leader之后直接退出，由外部重启之后重新参与选举
+	leaderelection.RunOrDie(leaderelection.DefaultLeaderElectionConfig(leaderelection.LeaseNameScheduler), leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(stopCh <-chan struct{}) {
+			sch.schedule()
+		},
+		OnStoppedLeading: func() {
+			k8log.FatalLog("Scheduler", "leader election lost")
+		},
+	}, make(chan struct{}))
+}
+
+func (sch *Scheduler) schedule() {
 	// 监听队列
 	for {
 		// 监听队列
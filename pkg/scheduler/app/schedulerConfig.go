@@ -1,19 +1,85 @@
 package scheduler
 
+import (
+	"fmt"
+	"miniK8s/pkg/config"
+	"miniK8s/pkg/scheduler/framework"
+	"miniK8s/pkg/scheduler/plugins"
+
+	"gopkg.in/yaml.v2"
+)
+
 type SchedulerConfig struct {
 	// 调度策略
-	Policy SchedulePolicy
+	Policy SchedulePolicy `yaml:"policy"`
+	// 每个扩展点的插件，配置了的扩展点覆盖Policy对应的默认插件
+	// 比如同时按照CPU和内存打分：score: [{name: LeastCpu, weight: 2}, {name: LeastMem, weight: 1}]
+	Plugins framework.Plugins `yaml:"plugins"`
 	// apiServer的地址
-	ApiServerHost string
+	ApiServerHost string `yaml:"-"`
 	// apiServer的端口
-	ApiServerPort int
+	ApiServerPort int `yaml:"-"`
 }
 
 func DefaultSchedulerConfig() *SchedulerConfig {
-	config := SchedulerConfig{
+	schedulerConfig := SchedulerConfig{
 		Policy:        RoundRobin,
 		ApiServerHost: "localhost",
 		ApiServerPort: 8090,
 	}
-	return &config
+	return &schedulerConfig
+}
+
+// 读取集群的调度器配置，环境变量优先，都没有的时候使用默认配置
+func LoadSchedulerConfig() (*SchedulerConfig, error) {
+	schedulerConfig := DefaultSchedulerConfig()
+	content := config.LoadEnvOrFile(config.SchedulerConfigEnvName, config.SchedulerConfigFile)
+	if content == "" {
+		return schedulerConfig, nil
+	}
+	if err := yaml.Unmarshal([]byte(content), schedulerConfig); err != nil {
+		return nil, fmt.Errorf("parse scheduler config failed: %s", err.Error())
+	}
+	if schedulerConfig.Policy == "" {
+		schedulerConfig.Policy = RoundRobin
+	}
+	return schedulerConfig, nil
+}
+
+// 根据调度策略得到默认的插件，再用配置里面的插件覆盖
+// Random策略没有打分插件，所有节点分数相同，随机选择一个
+func (c *SchedulerConfig) EnabledPlugins() (*framework.Plugins, error) {
+	enabled := &framework.Plugins{
+		PreFilter: []string{plugins.NodeNameName},
+		Filter:    []string{plugins.NodeReadyName, plugins.NodeNameName},
+		Bind:      []string{DefaultBinderName},
+	}
+
+	switch c.Policy {
+	case RoundRobin:
+		enabled.Score = []framework.PluginWeight{{Name: plugins.RoundRobinName, Weight: 1}}
+	case Random:
+	case LeastPod:
+		enabled.Score = []framework.PluginWeight{{Name: plugins.LeastPodName, Weight: 1}}
+	case LeastCpu:
+		enabled.Score = []framework.PluginWeight{{Name: plugins.LeastCpuName, Weight: 1}}
+	case LeastMem:
+		enabled.Score = []framework.PluginWeight{{Name: plugins.LeastMemName, Weight: 1}}
+	default:
+		return nil, fmt.Errorf("unknown schedule policy %s", c.Policy)
+	}
+
+	if c.Plugins.PreFilter != nil {
+		enabled.PreFilter = c.Plugins.PreFilter
+	}
+	if c.Plugins.Filter != nil {
+		enabled.Filter = c.Plugins.Filter
+	}
+	if c.Plugins.Score != nil {
+		enabled.Score = c.Plugins.Score
+	}
+	if c.Plugins.Bind != nil {
+		enabled.Bind = c.Plugins.Bind
+	}
+	return enabled, nil
 }
@@ -0,0 +1,265 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"miniK8s/pkg/apiObject"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Framework 按照配置依次调用各个扩展点的插件，完成一次调度
+// PreFilter -> Filter -> Score -> 选出分数最高的节点 -> Bind
+type Framework struct {
+	preFilterPlugins []PreFilterPlugin
+	filterPlugins    []FilterPlugin
+	scorePlugins     []ScorePlugin
+	scoreWeights     map[string]int64
+	bindPlugins      []BindPlugin
+
+	// 分数相同的节点随机选择一个，避免pod都调度到第一个节点上
+	randLock sync.Mutex
+	rand     *rand.Rand
+}
+
+// FitError 没有节点能通过过滤的时候返回，记录每个节点没有通过的原因
+type FitError struct {
+	NumAllNodes int
+	// 节点名字 -> 没有通过过滤的原因
+	FailedNodes map[string]string
+}
+
+// 格式和k8s类似：0/3 nodes are available: 1 node is not ready, 2 node(s) didn't match the requested node name
+func (f *FitError) Error() string {
+	reasons := make(map[string]int)
+	for _, reason := range f.FailedNodes {
+		reasons[reason]++
+	}
+	strs := make([]string, 0, len(reasons))
+	for reason, count := range reasons {
+		strs = append(strs, fmt.Sprintf("%d %s", count, reason))
+	}
+	sort.Strings(strs)
+	msg := fmt.Sprintf("0/%d nodes are available", f.NumAllNodes)
+	if len(strs) > 0 {
+		msg += ": " + strings.Join(strs, ", ")
+	}
+	return msg
+}
+
+// 根据plugins里面的名字从registry创建插件，插件没有实现对应扩展点的接口的时候返回错误
+func NewFramework(registry Registry, plugins *Plugins) (*Framework, error) {
+	f := &Framework{
+		scoreWeights: make(map[string]int64),
+		rand:         rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	instances := make(map[string]Plugin)
+	getPlugin := func(name string) (Plugin, error) {
+		if plugin, ok := instances[name]; ok {
+			return plugin, nil
+		}
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("plugin %s is not registered", name)
+		}
+		plugin, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("create plugin %s failed: %s", name, err.Error())
+		}
+		instances[name] = plugin
+		return plugin, nil
+	}
+
+	for _, name := range plugins.PreFilter {
+		plugin, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		preFilter, ok := plugin.(PreFilterPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s is not a PreFilter plugin", name)
+		}
+		f.preFilterPlugins = append(f.preFilterPlugins, preFilter)
+	}
+
+	for _, name := range plugins.Filter {
+		plugin, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		filter, ok := plugin.(FilterPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s is not a Filter plugin", name)
+		}
+		f.filterPlugins = append(f.filterPlugins, filter)
+	}
+
+	for _, pw := range plugins.Score {
+		plugin, err := getPlugin(pw.Name)
+		if err != nil {
+			return nil, err
+		}
+		score, ok := plugin.(ScorePlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s is not a Score plugin", pw.Name)
+		}
+		if _, ok := f.scoreWeights[pw.Name]; ok {
+			return nil, fmt.Errorf("score plugin %s is configured more than once", pw.Name)
+		}
+		weight := pw.Weight
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of score plugin %s must not be negative", pw.Name)
+		}
+		if weight == 0 {
+			weight = 1
+		}
+		f.scorePlugins = append(f.scorePlugins, score)
+		f.scoreWeights[pw.Name] = weight
+	}
+
+	for _, name := range plugins.Bind {
+		plugin, err := getPlugin(name)
+		if err != nil {
+			return nil, err
+		}
+		bind, ok := plugin.(BindPlugin)
+		if !ok {
+			return nil, fmt.Errorf("plugin %s is not a Bind plugin", name)
+		}
+		f.bindPlugins = append(f.bindPlugins, bind)
+	}
+	if len(f.bindPlugins) == 0 {
+		return nil, errors.New("at least one bind plugin is required")
+	}
+
+	return f, nil
+}
+
+// 为pod选择一个节点，没有节点能通过过滤的时候返回*FitError
+func (f *Framework) Schedule(state *CycleState, pod *apiObject.PodStore, nodes []apiObject.NodeStore) (string, error) {
+	if err := f.RunPreFilterPlugins(state, pod, nodes); err != nil {
+		return "", err
+	}
+
+	feasibleNodes, failedNodes := f.RunFilterPlugins(state, pod, nodes)
+	if len(feasibleNodes) == 0 {
+		return "", &FitError{NumAllNodes: len(nodes), FailedNodes: failedNodes}
+	}
+	// 只有一个节点的时候不需要打分
+	if len(feasibleNodes) == 1 {
+		return feasibleNodes[0].GetName(), nil
+	}
+
+	scores, err := f.RunScorePlugins(state, pod, feasibleNodes)
+	if err != nil {
+		return "", err
+	}
+	return f.selectHost(scores), nil
+}
+
+func (f *Framework) RunPreFilterPlugins(state *CycleState, pod *apiObject.PodStore, nodes []apiObject.NodeStore) error {
+	for _, plugin := range f.preFilterPlugins {
+		if err := plugin.PreFilter(state, pod, nodes); err != nil {
+			return fmt.Errorf("prefilter plugin %s: %s", plugin.Name(), err.Error())
+		}
+	}
+	return nil
+}
+
+// 返回通过所有Filter插件的节点，以及没有通过的节点和第一个不通过的原因
+func (f *Framework) RunFilterPlugins(state *CycleState, pod *apiObject.PodStore, nodes []apiObject.NodeStore) ([]apiObject.NodeStore, map[string]string) {
+	feasibleNodes := make([]apiObject.NodeStore, 0, len(nodes))
+	failedNodes := make(map[string]string)
+	for index := range nodes {
+		node := &nodes[index]
+		fit := true
+		for _, plugin := range f.filterPlugins {
+			if err := plugin.Filter(state, pod, node); err != nil {
+				failedNodes[node.GetName()] = err.Error()
+				fit = false
+				break
+			}
+		}
+		if fit {
+			feasibleNodes = append(feasibleNodes, *node)
+		}
+	}
+	return feasibleNodes, failedNodes
+}
+
+// 每个打分插件的分数乘以权重之后相加，得到每个节点的总分，顺序和nodes相同
+func (f *Framework) RunScorePlugins(state *CycleState, pod *apiObject.PodStore, nodes []apiObject.NodeStore) (NodeScoreList, error) {
+	total := make(NodeScoreList, len(nodes))
+	for index := range nodes {
+		total[index].Name = nodes[index].GetName()
+	}
+
+	for _, plugin := range f.scorePlugins {
+		scores := make(NodeScoreList, len(nodes))
+		for index := range nodes {
+			score, err := plugin.Score(state, pod, &nodes[index])
+			if err != nil {
+				return nil, fmt.Errorf("score plugin %s: %s", plugin.Name(), err.Error())
+			}
+			scores[index] = NodeScore{Name: nodes[index].GetName(), Score: score}
+		}
+
+		if normalizer, ok := plugin.(ScoreNormalizer); ok {
+			if err := normalizer.NormalizeScore(state, pod, scores); err != nil {
+				return nil, fmt.Errorf("normalize score of plugin %s: %s", plugin.Name(), err.Error())
+			}
+		}
+
+		weight := f.scoreWeights[plugin.Name()]
+		for index, score := range scores {
+			if score.Score < MinNodeScore || score.Score > MaxNodeScore {
+				return nil, fmt.Errorf("score plugin %s returns an invalid score %d for node %s", plugin.Name(), score.Score, score.Name)
+			}
+			total[index].Score += score.Score * weight
+		}
+	}
+	return total, nil
+}
+
+// 按照顺序调用Bind插件，直到有一个插件不返回ErrSkip
+func (f *Framework) RunBindPlugins(state *CycleState, pod *apiObject.PodStore, nodeName string) error {
+	for _, plugin := range f.bindPlugins {
+		err := plugin.Bind(state, pod, nodeName)
+		if err == ErrSkip {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("bind plugin %s: %s", plugin.Name(), err.Error())
+		}
+		return nil
+	}
+	return errors.New("no bind plugin handled the pod")
+}
+
+// 选择总分最高的节点，分数相同的时候随机选择一个
+func (f *Framework) selectHost(scores NodeScoreList) string {
+	f.randLock.Lock()
+	defer f.randLock.Unlock()
+
+	selected := scores[0].Name
+	maxScore := scores[0].Score
+	ties := 1
+	for _, score := range scores[1:] {
+		if score.Score > maxScore {
+			selected = score.Name
+			maxScore = score.Score
+			ties = 1
+		} else if score.Score == maxScore {
+			// 蓄水池抽样，每个分数最高的节点被选中的概率相同
+			ties++
+			if f.rand.Intn(ties) == 0 {
+				selected = score.Name
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,174 @@
+package framework
+
+import (
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"testing"
+)
+
+func newNode(name string, numPods int) apiObject.NodeStore {
+	node := apiObject.NodeStore{}
+	node.NodeMetadata.Name = name
+	node.Status.NumPods = numPods
+	return node
+}
+
+// 过滤掉名字在excluded里面的节点
+type fakeFilter struct {
+	excluded map[string]bool
+}
+
+func (p *fakeFilter) Name() string { return "FakeFilter" }
+
+func (p *fakeFilter) Filter(state *CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) error {
+	if p.excluded[node.GetName()] {
+		return errors.New("node(s) were excluded")
+	}
+	return nil
+}
+
+// 分数是固定的
+type fakeScore struct {
+	name   string
+	scores map[string]int64
+}
+
+func (p *fakeScore) Name() string { return p.name }
+
+func (p *fakeScore) Score(state *CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) (int64, error) {
+	return p.scores[node.GetName()], nil
+}
+
+// 记录绑定的节点，skip为true的时候交给下一个插件
+type fakeBinder struct {
+	name  string
+	skip  bool
+	bound []string
+}
+
+func (p *fakeBinder) Name() string { return p.name }
+
+func (p *fakeBinder) Bind(state *CycleState, pod *apiObject.PodStore, nodeName string) error {
+	if p.skip {
+		return ErrSkip
+	}
+	p.bound = append(p.bound, nodeName)
+	return nil
+}
+
+func newRegistry(plugins ...Plugin) Registry {
+	registry := Registry{}
+	for _, plugin := range plugins {
+		p := plugin
+		registry.Register(p.Name(), func() (Plugin, error) { return p, nil })
+	}
+	return registry
+}
+
+func TestWeightedScore(t *testing.T) {
+	cpu := &fakeScore{name: "Cpu", scores: map[string]int64{"a": 100, "b": 0, "c": 60}}
+	mem := &fakeScore{name: "Mem", scores: map[string]int64{"a": 0, "b": 100, "c": 60}}
+	filter := &fakeFilter{excluded: map[string]bool{}}
+	binder := &fakeBinder{name: "Binder"}
+	registry := newRegistry(cpu, mem, filter, binder)
+
+	nodes := []apiObject.NodeStore{newNode("a", 0), newNode("b", 0), newNode("c", 0)}
+	pod := &apiObject.PodStore{}
+
+	cases := []struct {
+		cpuWeight int64
+		memWeight int64
+		expected  string
+	}{
+		// a: 300, b: 100, c: 240
+		{3, 1, "a"},
+		// a: 100, b: 300, c: 240
+		{1, 3, "b"},
+		// a: 100, b: 100, c: 120
+		{1, 1, "c"},
+	}
+	for _, c := range cases {
+		f, err := NewFramework(registry, &Plugins{
+			Filter: []string{"FakeFilter"},
+			Score:  []PluginWeight{{Name: "Cpu", Weight: c.cpuWeight}, {Name: "Mem", Weight: c.memWeight}},
+			Bind:   []string{"Binder"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if node, err := f.Schedule(NewCycleState(), pod, nodes); err != nil || node != c.expected {
+			t.Errorf("expected node %s with weights %d/%d but got %s %v", c.expected, c.cpuWeight, c.memWeight, node, err)
+		}
+	}
+
+	// 被过滤掉的节点不参与打分
+	filter.excluded["c"] = true
+	f, _ := NewFramework(registry, &Plugins{
+		Filter: []string{"FakeFilter"},
+		Score:  []PluginWeight{{Name: "Cpu"}, {Name: "Mem", Weight: 2}},
+		Bind:   []string{"Binder"},
+	})
+	if node, err := f.Schedule(NewCycleState(), pod, nodes); err != nil || node != "b" {
+		t.Errorf("expected node b but got %s %v", node, err)
+	}
+
+	// 所有节点都被过滤掉的时候返回每个节点的原因
+	filter.excluded["a"] = true
+	filter.excluded["b"] = true
+	_, err := f.Schedule(NewCycleState(), pod, nodes)
+	fitErr, ok := err.(*FitError)
+	if !ok || len(fitErr.FailedNodes) != 3 || err.Error() != "0/3 nodes are available: 3 node(s) were excluded" {
+		t.Errorf("unexpected fit error %v", err)
+	}
+}
+
+func TestTieAndBind(t *testing.T) {
+	skipped := &fakeBinder{name: "Skipped", skip: true}
+	binder := &fakeBinder{name: "Binder"}
+	registry := newRegistry(skipped, binder)
+
+	f, err := NewFramework(registry, &Plugins{Bind: []string{"Skipped", "Binder"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有打分插件的时候随机选择节点，次数足够多的时候每个节点都会被选中
+	nodes := []apiObject.NodeStore{newNode("a", 0), newNode("b", 0), newNode("c", 0)}
+	chosen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		node, err := f.Schedule(NewCycleState(), &apiObject.PodStore{}, nodes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chosen[node] = true
+	}
+	if len(chosen) != 3 {
+		t.Errorf("expected all nodes to be chosen but got %v", chosen)
+	}
+
+	if err := f.RunBindPlugins(NewCycleState(), &apiObject.PodStore{}, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if len(binder.bound) != 1 || binder.bound[0] != "a" {
+		t.Errorf("expected the pod to be bound by the second binder but got %v", binder.bound)
+	}
+}
+
+func TestInvalidPlugins(t *testing.T) {
+	registry := newRegistry(&fakeFilter{}, &fakeBinder{name: "Binder"})
+	cases := []*Plugins{
+		// 没有注册的插件
+		{Filter: []string{"Unknown"}, Bind: []string{"Binder"}},
+		// 插件没有实现对应的扩展点
+		{Score: []PluginWeight{{Name: "FakeFilter"}}, Bind: []string{"Binder"}},
+		// 负数的权重
+		{Bind: []string{"Binder"}, Score: []PluginWeight{{Name: "Binder", Weight: -1}}},
+		// 没有Bind插件
+		{Filter: []string{"FakeFilter"}},
+	}
+	for _, c := range cases {
+		if _, err := NewFramework(registry, c); err == nil {
+			t.Errorf("expected an error for plugins %+v", *c)
+		}
+	}
+}
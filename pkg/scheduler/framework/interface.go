@@ -0,0 +1,82 @@
+package framework
+
+import (
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"sync"
+)
+
+// 每个打分插件给节点打的分数都在[MinNodeScore, MaxNodeScore]之间，乘以权重之后相加
+const (
+	MinNodeScore int64 = 0
+	MaxNodeScore int64 = 100
+)
+
+// Bind插件不处理这个pod的时候返回ErrSkip，交给下一个Bind插件
+var ErrSkip = errors.New("skip")
+
+// Plugin 所有插件都有一个唯一的名字，配置里面通过名字引用插件
+type Plugin interface {
+	Name() string
+}
+
+// PreFilterPlugin 在过滤节点之前调用一次，可以检查pod或者预先计算一些数据保存在CycleState里面
+// 返回错误的时候这个pod调度失败
+type PreFilterPlugin interface {
+	Plugin
+	PreFilter(state *CycleState, pod *apiObject.PodStore, nodes []apiObject.NodeStore) error
+}
+
+// FilterPlugin 对每个节点调用，返回错误表示pod不能调度到这个节点上，错误信息是原因
+type FilterPlugin interface {
+	Plugin
+	Filter(state *CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) error
+}
+
+// ScorePlugin 对每个通过过滤的节点打分，分数越高越优先
+type ScorePlugin interface {
+	Plugin
+	Score(state *CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) (int64, error)
+}
+
+// ScoreNormalizer 打分插件可以选择实现，所有节点打完分之后调用，把分数调整到[MinNodeScore, MaxNodeScore]之间
+// 比如按照pod数量打分的时候，只有知道所有节点的pod数量才能算出分数
+type ScoreNormalizer interface {
+	NormalizeScore(state *CycleState, pod *apiObject.PodStore, scores NodeScoreList) error
+}
+
+// BindPlugin 把pod绑定到选中的节点上，按照配置的顺序调用，直到有一个插件不返回ErrSkip
+type BindPlugin interface {
+	Plugin
+	Bind(state *CycleState, pod *apiObject.PodStore, nodeName string) error
+}
+
+type NodeScore struct {
+	Name  string
+	Score int64
+}
+
+type NodeScoreList []NodeScore
+
+// CycleState 保存一次调度过程中插件之间共享的数据，每次调度都会新建一个
+type CycleState struct {
+	lock    sync.RWMutex
+	storage map[string]interface{}
+}
+
+func NewCycleState() *CycleState {
+	return &CycleState{storage: make(map[string]interface{})}
+}
+
+func (c *CycleState) Read(key string) (interface{}, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	value, ok := c.storage[key]
+	return value, ok
+}
+
+func (c *CycleState) Write(key string, value interface{}) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.storage[key] = value
+}
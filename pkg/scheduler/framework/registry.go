@@ -0,0 +1,34 @@
+package framework
+
+import (
+	"fmt"
+)
+
+// PluginFactory 创建一个插件
+type PluginFactory func() (Plugin, error)
+
+// Registry 插件名字到插件工厂的映射，配置里面只能使用注册过的插件
+type Registry map[string]PluginFactory
+
+func (r Registry) Register(name string, factory PluginFactory) error {
+	if _, ok := r[name]; ok {
+		return fmt.Errorf("plugin %s already exists", name)
+	}
+	r[name] = factory
+	return nil
+}
+
+// PluginWeight 打分插件和它的权重，权重为0的时候当作1
+type PluginWeight struct {
+	Name   string `json:"name" yaml:"name"`
+	Weight int64  `json:"weight" yaml:"weight"`
+}
+
+// Plugins 每个扩展点启用的插件，按照顺序调用
+// 同一个插件可以出现在多个扩展点里面，只会创建一个实例
+type Plugins struct {
+	PreFilter []string       `json:"preFilter" yaml:"preFilter"`
+	Filter    []string       `json:"filter" yaml:"filter"`
+	Score     []PluginWeight `json:"score" yaml:"score"`
+	Bind      []string       `json:"bind" yaml:"bind"`
+}
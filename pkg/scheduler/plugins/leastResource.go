@@ -0,0 +1,66 @@
+package plugins
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/scheduler/framework"
+)
+
+// 按照节点上报的负载打分，负载越低分数越高
+// 节点的CPU使用率是所有核心的和，可能超过100，所以和负载最高的节点比较：分数 = (最高负载 - 负载) / 最高负载 * 100
+type leastResource struct {
+	name string
+	// 节点的负载，CPU和内存的百分比乘以100，保留两位小数
+	load func(node *apiObject.NodeStore) int64
+}
+
+func newLeastPod() *leastResource {
+	return &leastResource{
+		name: LeastPodName,
+		load: func(node *apiObject.NodeStore) int64 { return int64(node.Status.NumPods) },
+	}
+}
+
+func newLeastCpu() *leastResource {
+	return &leastResource{
+		name: LeastCpuName,
+		load: func(node *apiObject.NodeStore) int64 { return int64(node.Status.CpuPercent * 100) },
+	}
+}
+
+func newLeastMem() *leastResource {
+	return &leastResource{
+		name: LeastMemName,
+		load: func(node *apiObject.NodeStore) int64 { return int64(node.Status.MemPercent * 100) },
+	}
+}
+
+func (p *leastResource) Name() string {
+	return p.name
+}
+
+// 先返回负载，所有节点的负载都拿到之后再在NormalizeScore里面换算成分数
+func (p *leastResource) Score(state *framework.CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) (int64, error) {
+	load := p.load(node)
+	// 获取不到负载的节点会上报负数
+	if load < 0 {
+		load = 0
+	}
+	return load, nil
+}
+
+func (p *leastResource) NormalizeScore(state *framework.CycleState, pod *apiObject.PodStore, scores framework.NodeScoreList) error {
+	var maxLoad int64
+	for _, score := range scores {
+		if score.Score > maxLoad {
+			maxLoad = score.Score
+		}
+	}
+	for index := range scores {
+		if maxLoad == 0 {
+			scores[index].Score = framework.MaxNodeScore
+			continue
+		}
+		scores[index].Score = (maxLoad - scores[index].Score) * framework.MaxNodeScore / maxLoad
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package plugins
+
+import (
+	"errors"
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/k8log"
+	"miniK8s/pkg/scheduler/framework"
+)
+
+// 过滤掉不是Ready状态的节点，比如心跳超时的节点
+type nodeReady struct{}
+
+func (p *nodeReady) Name() string {
+	return NodeReadyName
+}
+
+func (p *nodeReady) Filter(state *framework.CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) error {
+	if node.Status.Condition != apiObject.Ready {
+		return errors.New("node(s) were not ready")
+	}
+	return nil
+}
+
+// CycleState里面保存pod指定的节点
+const nodeNameStateKey = "PreFilter" + NodeNameName
+
+// pod指定了spec.nodeName的时候只调度到这个节点上
+// 指定的节点不存在或者不是Ready状态的时候忽略nodeName，和其他pod一样选择节点
+type nodeName struct{}
+
+func (p *nodeName) Name() string {
+	return NodeNameName
+}
+
+func (p *nodeName) PreFilter(state *framework.CycleState, pod *apiObject.PodStore, nodes []apiObject.NodeStore) error {
+	if pod.Spec.NodeName == "" {
+		return nil
+	}
+	for _, node := range nodes {
+		if node.GetName() == pod.Spec.NodeName && node.Status.Condition == apiObject.Ready {
+			state.Write(nodeNameStateKey, pod.Spec.NodeName)
+			return nil
+		}
+	}
+	k8log.WarnLog("Scheduler", "node "+pod.Spec.NodeName+" is not available, ignore the nodeName of pod "+pod.GetPodName())
+	return nil
+}
+
+func (p *nodeName) Filter(state *framework.CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) error {
+	requested, ok := state.Read(nodeNameStateKey)
+	if !ok {
+		return nil
+	}
+	if node.GetName() != requested.(string) {
+		return errors.New("node(s) didn't match the requested node name")
+	}
+	return nil
+}
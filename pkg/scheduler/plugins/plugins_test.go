@@ -0,0 +1,122 @@
+package plugins
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/scheduler/framework"
+	"testing"
+)
+
+func newNode(name string, condition apiObject.NodeCondition, numPods int, cpu float64, mem float64) apiObject.NodeStore {
+	node := apiObject.NodeStore{}
+	node.NodeMetadata.Name = name
+	node.Status.Condition = condition
+	node.Status.NumPods = numPods
+	node.Status.CpuPercent = cpu
+	node.Status.MemPercent = mem
+	return node
+}
+
+// 用内置的插件调度，不绑定
+func schedule(t *testing.T, f *framework.Framework, pod *apiObject.PodStore, nodes []apiObject.NodeStore) string {
+	t.Helper()
+	node, err := f.Schedule(framework.NewCycleState(), pod, nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return node
+}
+
+type fakeBinder struct{}
+
+func (p *fakeBinder) Name() string { return "FakeBinder" }
+
+func (p *fakeBinder) Bind(state *framework.CycleState, pod *apiObject.PodStore, nodeName string) error {
+	return nil
+}
+
+func newFramework(t *testing.T, score ...framework.PluginWeight) *framework.Framework {
+	t.Helper()
+	registry := NewInTreeRegistry()
+	registry.Register("FakeBinder", func() (framework.Plugin, error) { return &fakeBinder{}, nil })
+	f, err := framework.NewFramework(registry, &framework.Plugins{
+		PreFilter: []string{NodeNameName},
+		Filter:    []string{NodeReadyName, NodeNameName},
+		Score:     score,
+		Bind:      []string{"FakeBinder"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestLeastResource(t *testing.T) {
+	nodes := []apiObject.NodeStore{
+		newNode("a", apiObject.Ready, 5, 150, 20),
+		newNode("b", apiObject.Ready, 2, 80, 60),
+		newNode("c", apiObject.Ready, 8, 10, 50),
+		// 负载最低，但是不是Ready状态
+		newNode("d", apiObject.Unknown, 0, 0, 0),
+	}
+	pod := &apiObject.PodStore{}
+
+	if node := schedule(t, newFramework(t, framework.PluginWeight{Name: LeastPodName}), pod, nodes); node != "b" {
+		t.Errorf("expected LeastPod to choose b but got %s", node)
+	}
+	if node := schedule(t, newFramework(t, framework.PluginWeight{Name: LeastCpuName}), pod, nodes); node != "c" {
+		t.Errorf("expected LeastCpu to choose c but got %s", node)
+	}
+	if node := schedule(t, newFramework(t, framework.PluginWeight{Name: LeastMemName}), pod, nodes); node != "a" {
+		t.Errorf("expected LeastMem to choose a but got %s", node)
+	}
+
+	// LeastPod: a 37, b 75, c 0；LeastCpu: a 0, b 46, c 93
+	// 权重1:1的时候b最高，LeastCpu权重为3的时候c最高
+	if node := schedule(t, newFramework(t, framework.PluginWeight{Name: LeastPodName}, framework.PluginWeight{Name: LeastCpuName}), pod, nodes); node != "b" {
+		t.Errorf("expected b with equal weights but got %s", node)
+	}
+	if node := schedule(t, newFramework(t, framework.PluginWeight{Name: LeastPodName}, framework.PluginWeight{Name: LeastCpuName, Weight: 3}), pod, nodes); node != "c" {
+		t.Errorf("expected c with a higher cpu weight but got %s", node)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	nodes := []apiObject.NodeStore{
+		newNode("a", apiObject.Ready, 0, 0, 0),
+		newNode("b", apiObject.Unknown, 0, 0, 0),
+		newNode("c", apiObject.Ready, 0, 0, 0),
+		newNode("d", apiObject.Ready, 0, 0, 0),
+	}
+	f := newFramework(t, framework.PluginWeight{Name: RoundRobinName})
+	expected := []string{"a", "c", "d", "a"}
+	for _, e := range expected {
+		if node := schedule(t, f, &apiObject.PodStore{}, nodes); node != e {
+			t.Errorf("expected node %s but got %s", e, node)
+		}
+	}
+}
+
+func TestNodeName(t *testing.T) {
+	nodes := []apiObject.NodeStore{
+		newNode("a", apiObject.Ready, 0, 0, 0),
+		newNode("b", apiObject.Ready, 10, 0, 0),
+		newNode("c", apiObject.Unknown, 0, 0, 0),
+	}
+	f := newFramework(t, framework.PluginWeight{Name: LeastPodName})
+
+	pod := &apiObject.PodStore{}
+	pod.Spec.NodeName = "b"
+	if node := schedule(t, f, pod, nodes); node != "b" {
+		t.Errorf("expected the requested node b but got %s", node)
+	}
+
+	// 指定的节点不可用的时候忽略nodeName
+	pod.Spec.NodeName = "c"
+	if node := schedule(t, f, pod, nodes); node != "a" {
+		t.Errorf("expected node a when the requested node is not ready but got %s", node)
+	}
+	pod.Spec.NodeName = "unknown"
+	if node := schedule(t, f, pod, nodes); node != "a" {
+		t.Errorf("expected node a when the requested node does not exist but got %s", node)
+	}
+}
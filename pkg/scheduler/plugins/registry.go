@@ -0,0 +1,27 @@
+package plugins
+
+import (
+	"miniK8s/pkg/scheduler/framework"
+)
+
+// 内置插件的名字，调度器的配置里面通过名字启用插件
+const (
+	NodeReadyName  = "NodeReady"
+	NodeNameName   = "NodeName"
+	LeastPodName   = "LeastPod"
+	LeastCpuName   = "LeastCpu"
+	LeastMemName   = "LeastMem"
+	RoundRobinName = "RoundRobin"
+)
+
+// 包含所有内置的过滤和打分插件的Registry，Bind插件由调度器自己注册
+func NewInTreeRegistry() framework.Registry {
+	return framework.Registry{
+		NodeReadyName:  func() (framework.Plugin, error) { return &nodeReady{}, nil },
+		NodeNameName:   func() (framework.Plugin, error) { return &nodeName{}, nil },
+		LeastPodName:   func() (framework.Plugin, error) { return newLeastPod(), nil },
+		LeastCpuName:   func() (framework.Plugin, error) { return newLeastCpu(), nil },
+		LeastMemName:   func() (framework.Plugin, error) { return newLeastMem(), nil },
+		RoundRobinName: func() (framework.Plugin, error) { return &roundRobin{}, nil },
+	}
+}
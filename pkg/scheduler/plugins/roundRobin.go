@@ -0,0 +1,32 @@
+package plugins
+
+import (
+	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/scheduler/framework"
+	"sync"
+)
+
+// 轮流选择通过过滤的节点：轮到的节点得到最高分，其余节点0分
+type roundRobin struct {
+	lock  sync.Mutex
+	count int
+}
+
+func (p *roundRobin) Name() string {
+	return RoundRobinName
+}
+
+func (p *roundRobin) Score(state *framework.CycleState, pod *apiObject.PodStore, node *apiObject.NodeStore) (int64, error) {
+	return framework.MinNodeScore, nil
+}
+
+func (p *roundRobin) NormalizeScore(state *framework.CycleState, pod *apiObject.PodStore, scores framework.NodeScoreList) error {
+	if len(scores) == 0 {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	scores[p.count%len(scores)].Score = framework.MaxNodeScore
+	p.count++
+	return nil
+}
@@ -6,4 +6,6 @@ var (
 	FuncControllerUpdateDelay     = 5 * time.Second
 	FuncControllerUpdateFrequency = []time.Duration{10 * time.Second}
 	FuncControllerUpdateLoop      = true
+	// 并行构建镜像、创建副本的worker数量
+	FuncControllerWorkers = 2
 )
@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"miniK8s/pkg/apiObject"
 	"miniK8s/pkg/config"
+	"miniK8s/pkg/event"
 	"miniK8s/pkg/k8log"
+	"miniK8s/pkg/workqueue"
 	"miniK8s/util/executor"
 	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -34,17 +37,35 @@ type FuncController interface {
 }
 
 type funcController struct {
-	cache      map[string]*apiObject.Function
+	// 保护下面的map，worker和处理函数调用的请求会同时访问
+	lock sync.Mutex
+	// 已经创建好镜像和副本的function，key: uuid
+	cache map[string]*apiObject.Function
+	// 最近一次从apiserver读取到的function，key: uuid
+	desired    map[string]*apiObject.Function
 	CallRecord map[string]*LaunchRecord // key: namespace/funcName
+	// 记录构建镜像、扩缩容这样的事件
+	recorder event.Recorder
+
+	// 需要处理的function的uuid，构建镜像或者创建副本失败之后退避重试
+	queue workqueue.RateLimitingInterface
 }
 
 func NewFuncController() FuncController {
 	return &funcController{
 		cache:      make(map[string]*apiObject.Function),
+		desired:    make(map[string]*apiObject.Function),
 		CallRecord: make(map[string]*LaunchRecord),
+		recorder:   event.NewRecorder(event.ComponentServerlessController),
+		queue:      workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
 	}
 }
 
+// 函数对应的ObjectReference，扩缩容的时候只知道名字
+func funcReference(funcName, funcNamespace string) apiObject.ObjectReference {
+	return apiObject.ObjectReference{Kind: apiObject.FunctionKind, Namespace: funcNamespace, Name: funcName}
+}
+
 func (c *funcController) getAllFunc() ([]apiObject.Function, error) {
 	url := config.GetAPIServerURLPrefix() + config.GlobalFunctionsURL
 
@@ -63,73 +84,128 @@ func (c *funcController) getAllFunc() ([]apiObject.Function, error) {
 	return allFuncs, nil
 }
 
+// 读取所有的function，和缓存里面的function一起加入队列，由worker创建、更新、删除和扩缩容
 func (c *funcController) routine() {
 	res, err := c.getAllFunc()
 	if err != nil {
 		return
 	}
 
-	remoteFuncs := make(map[string]bool)
+	c.lock.Lock()
+	c.desired = make(map[string]*apiObject.Function)
 	for id, f := range res {
-		remoteFuncs[f.Metadata.UUID] = true
-		// 检查f是否在cache中
-		if _, ok := c.cache[f.Metadata.UUID]; !ok {
-			// 如果不在cache中，说明是新的function，需要创建
-			// 【TODO】
-			c.cache[f.Metadata.UUID] = &res[id]
-			c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name] = &LaunchRecord{
-				FuncName:      f.Metadata.Name,
-				FuncNamespace: f.Metadata.Namespace,
-				StartTime:     time.Now(),
-				EndTime:       time.Now().Add(time.Duration(1) * time.Minute),
-			}
-			c.CreateFunction(&res[id])
-
-		} else {
-			// 检查c.CallRecord是否过期
-			if c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name] != nil {
-				if time.Now().After(c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].EndTime) {
-
-					if c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].FuncCallTime == 0 {
-						// 缩容
-						c.ScaleDown(f.Metadata.Name, f.Metadata.Namespace)
-					} else if c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].FuncCallTime > 100 {
-						newSize := 2 + c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].FuncCallTime/100
-						// 扩容
-						c.ScaleUp(f.Metadata.Name, f.Metadata.Namespace, newSize)
-					}
-
-					// 过期了，需要重置
-					c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].FuncCallTime = 0
-					c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].StartTime = time.Now()
-					c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name].EndTime = time.Now().Add(time.Duration(1) * time.Minute)
-				}
-			}
-
-			// 如果在cache中，说明是已经存在的function，需要检查是否需要更新
-			// 【TODO】
-			if !c.ComplareTwoFunc(c.cache[f.Metadata.UUID], &res[id]) {
-				c.cache[f.Metadata.UUID] = &res[id]
-				c.UpdateFunction(&res[id])
-				fmt.Println("update function")
-			} else {
-				c.cache[f.Metadata.UUID] = &res[id]
-			}
+		c.desired[f.Metadata.UUID] = &res[id]
+	}
+	uuids := make([]string, 0, len(c.desired)+len(c.cache))
+	for uuid := range c.desired {
+		uuids = append(uuids, uuid)
+	}
+	// 缓存里面有但是apiserver里面没有的function需要删除
+	for uuid := range c.cache {
+		if _, ok := c.desired[uuid]; !ok {
+			uuids = append(uuids, uuid)
 		}
 	}
+	c.lock.Unlock()
+
+	// 这样保证这边的缓存和apiserver中的缓存一致
+	for _, uuid := range uuids {
+		c.queue.Add(uuid)
+	}
+}
 
-	// 检查cache中的function是否在remoteFuncs中
-	for uuid, f := range c.cache {
-		if _, ok := remoteFuncs[uuid]; !ok {
-			// 如果不在remoteFuncs中，说明需要删除
-			//
-			c.DeleteFunction(f)
-			delete(c.cache, uuid)
-			delete(c.CallRecord, f.Metadata.Namespace+"/"+f.Metadata.Name)
+// 处理一个function，返回错误的时候由队列退避之后重试
+func (c *funcController) syncFunction(item interface{}) error {
+	uuid := item.(string)
+
+	c.lock.Lock()
+	f, isDesired := c.desired[uuid]
+	cached, isCached := c.cache[uuid]
+	c.lock.Unlock()
+
+	switch {
+	case !isDesired && !isCached:
+		return nil
+
+	case !isDesired:
+		// 如果不在remoteFuncs中，说明需要删除
+		if err := c.DeleteFunction(cached); err != nil {
+			return err
 		}
+		c.lock.Lock()
+		delete(c.cache, uuid)
+		delete(c.CallRecord, cached.Metadata.Namespace+"/"+cached.Metadata.Name)
+		c.lock.Unlock()
+		return nil
+
+	case !isCached:
+		// 如果不在cache中，说明是新的function，需要创建
+		// 创建成功之后才放入cache，失败的时候重试会重新创建
+		if err := c.CreateFunction(f); err != nil {
+			return err
+		}
+		c.lock.Lock()
+		c.cache[uuid] = f
+		c.CallRecord[f.Metadata.Namespace+"/"+f.Metadata.Name] = &LaunchRecord{
+			FuncName:      f.Metadata.Name,
+			FuncNamespace: f.Metadata.Namespace,
+			StartTime:     time.Now(),
+			EndTime:       time.Now().Add(time.Duration(1) * time.Minute),
+		}
+		c.lock.Unlock()
+		return nil
 	}
 
-	// 这样保证这边的缓存和apiserver中的缓存一致
+	// 检查c.CallRecord是否过期
+	if err := c.checkCallRecord(f.Metadata.Name, f.Metadata.Namespace); err != nil {
+		return err
+	}
+
+	// 如果在cache中，说明是已经存在的function，需要检查是否需要更新
+	if !c.ComplareTwoFunc(cached, f) {
+		if err := c.UpdateFunction(f); err != nil {
+			return err
+		}
+		fmt.Println("update function")
+	}
+	c.lock.Lock()
+	c.cache[uuid] = f
+	c.lock.Unlock()
+	return nil
+}
+
+// 一分钟的统计周期结束之后，根据调用次数扩缩容，然后重新开始计数
+func (c *funcController) checkCallRecord(funcName, funcNamespace string) error {
+	c.lock.Lock()
+	record := c.CallRecord[funcNamespace+"/"+funcName]
+	if record == nil || !time.Now().After(record.EndTime) {
+		c.lock.Unlock()
+		return nil
+	}
+	callTime := record.FuncCallTime
+	c.lock.Unlock()
+
+	var err error
+	if callTime == 0 {
+		// 缩容
+		err = c.ScaleDown(funcName, funcNamespace)
+	} else if callTime > 100 {
+		newSize := 2 + callTime/100
+		// 扩容
+		err = c.ScaleUp(funcName, funcNamespace, newSize)
+	}
+	// 扩缩容失败的时候保留这个周期的计数，重试的时候再扩缩容
+	if err != nil {
+		return err
+	}
+
+	// 过期了，需要重置
+	c.lock.Lock()
+	record.FuncCallTime = 0
+	record.StartTime = time.Now()
+	record.EndTime = time.Now().Add(time.Duration(1) * time.Minute)
+	c.lock.Unlock()
+	return nil
 }
 
 // 比较两个function是否相同
@@ -151,14 +227,19 @@ func (c *funcController) ComplareTwoFunc(old *apiObject.Function, new *apiObject
 }
 
 func (c *funcController) Run() {
-	executor.Period(FuncControllerUpdateDelay, FuncControllerUpdateFrequency, c.routine, FuncControllerUpdateLoop)
+	go executor.Period(FuncControllerUpdateDelay, FuncControllerUpdateFrequency, c.routine, FuncControllerUpdateLoop)
+	workqueue.Run("funcController", c.queue, FuncControllerWorkers, workqueue.DefaultMaxRetries, c.syncFunction, nil)
 }
 
 func (c *funcController) GetFuncRecord(funcName, funcNamespace string) *LaunchRecord {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 	return c.CallRecord[funcNamespace+"/"+funcName]
 }
 
 func (c *funcController) AddCallRecord(funcName, funcNamespace string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
 	if c.CallRecord[funcNamespace+"/"+funcName] != nil {
 		c.CallRecord[funcNamespace+"/"+funcName].FuncCallTime++
@@ -193,6 +274,7 @@ func (c *funcController) ScaleDown(funcName, funcNamespace string) error {
 		return errors.New("get function from apiserver failed, not 200")
 	}
 
+	oldReplicas := replica.Spec.Replicas
 	if replica.Spec.Replicas > 0 {
 		replica.Spec.Replicas = replica.Spec.Replicas / 2
 	}
@@ -209,6 +291,10 @@ func (c *funcController) ScaleDown(funcName, funcNamespace string) error {
 		return errors.New("put function from apiserver failed, not 200")
 	}
 
+	if replica.Spec.Replicas != oldReplicas {
+		c.recorder.Eventf(funcReference(funcName, funcNamespace), apiObject.EventTypeNormal, "ScaledDown", "Scaled down replicas from %d to %d", oldReplicas, replica.Spec.Replicas)
+	}
+
 	fmt.Println("scale down end")
 	return nil
 }
@@ -232,6 +318,7 @@ func (c *funcController) ScaleUp(funcName, funcNamespace string, num int) error
 		return errors.New("get function from apiserver failed, not 200")
 	}
 
+	oldReplicas := replica.Spec.Replicas
 	if num > 0 {
 		replica.Spec.Replicas = num
 	}
@@ -246,5 +333,9 @@ func (c *funcController) ScaleUp(funcName, funcNamespace string, num int) error
 		return errors.New("put function from apiserver failed, not 200")
 	}
 
+	if replica.Spec.Replicas != oldReplicas {
+		c.recorder.Eventf(funcReference(funcName, funcNamespace), apiObject.EventTypeNormal, "ScaledUp", "Scaled up replicas from %d to %d", oldReplicas, replica.Spec.Replicas)
+	}
+
 	return nil
 }
@@ -24,19 +24,25 @@ import (
 // 新来一个函数，就创建一个函数
 func (c *funcController) CreateFunction(f *apiObject.Function) error {
 	// 【TODO】
+	funcRef := apiObject.NewObjectReference(apiObject.FunctionKind, &f.Metadata)
+
 	// 构建镜像
 	err := c.BuildFuncImage(f)
 
 	if err != nil {
+		c.recorder.Eventf(funcRef, apiObject.EventTypeWarning, "FailedBuild", "Error building function image: %s", err.Error())
 		return err
 	}
+	c.recorder.Event(funcRef, apiObject.EventTypeNormal, "ImageBuilt", "Successfully built and pushed function image")
 
 	// 创建副本
 	err = c.CreateFuncReplica(f)
 
 	if err != nil {
+		c.recorder.Eventf(funcRef, apiObject.EventTypeWarning, "FailedCreate", "Error creating replicaset: %s", err.Error())
 		return err
 	}
+	c.recorder.Eventf(funcRef, apiObject.EventTypeNormal, "SuccessfulCreate", "Created replicaset: %s", f.Metadata.Name)
 
 	fmt.Println("create function success")
 	return nil
@@ -75,8 +81,14 @@ func (c *funcController) DeleteFunction(f *apiObject.Function) error {
 // 构建函数的镜像
 func (c *funcController) BuildFuncImage(f *apiObject.Function) error {
 	// 【TODO】
-	// 在当前目录新建一个文件夹
-	err := os.Mkdir(f.Metadata.UUID, 0777)
+	// 在当前目录新建一个文件夹，重试的时候先删除上一次失败留下的文件夹
+	err := os.RemoveAll(f.Metadata.UUID)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Mkdir(f.Metadata.UUID, 0777)
 
 	if err != nil {
 		return err
@@ -196,6 +208,8 @@ func (c *funcController) CreateFuncReplica(f *apiObject.Function) error {
 					minik8stypes.Replica_Func_Uuid:      f.Metadata.UUID,
 					minik8stypes.Replica_Func_Namespace: f.Metadata.Namespace,
 				},
+				// 函数删除之后，GC会删除replicaset，再由replicaset删除pod
+				OwnerReferences: []apiObject.OwnerReference{apiObject.NewOwnerReference(apiObject.FunctionKind, &f.Metadata)},
 			},
 		},
 		Spec: apiObject.ReplicaSetSpec{
@@ -262,7 +276,9 @@ func (c *funcController) DeleteFuncReplica(f *apiObject.Function) error {
 		return err
 	}
 
-	if code != http.StatusNoContent {
+	// 重试的时候副本集可能已经在上一次删除了，带有finalizers的副本集返回202
+	// 更新的时候旧的副本集还没有删除完，创建会失败，由队列重试
+	if code != http.StatusNoContent && code != http.StatusAccepted && code != http.StatusNotFound {
 		return errors.New("删除副本集失败, Code Not 204")
 	}
 
@@ -1,9 +0,0 @@
-package server
-
-import "time"
-
-var (
-	RouterUpdate_Delay    = 0 * time.Second
-	RouterUpdate_WaitTime = []time.Duration{10 * time.Second}
-	RouterUpdate_ifLoop   = true
-)
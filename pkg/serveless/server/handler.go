@@ -61,7 +61,7 @@ func (s *server) handleFuncRequest(c *gin.Context) {
 
 	// 查询routeTable，找到对应的pod的ip地址
 	key := funcNamespace + "/" + funcName
-	podIPs, ok := s.routeTable[key]
+	podIPs, ok := s.getRouteIPs(key)
 
 	if !ok {
 		s.funcController.ScaleUp(funcName, funcNamespace, 2)
@@ -200,7 +200,7 @@ func (s *server) checkFunction(c *gin.Context) {
 	}
 
 	// 判断function是否存在pod实例
-	ips := s.routeTable[funcNamespace + "/" + funcName]
+	ips, _ := s.getRouteIPs(funcNamespace + "/" + funcName)
 	if  len(ips) == 0{
 		c.JSON(http.StatusBadRequest, gin.H{
 			"message": "function has no pod running",
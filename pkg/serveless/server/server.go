@@ -2,12 +2,20 @@ package server
 
 import (
 	"fmt"
+	"miniK8s/pkg/client"
+	"miniK8s/pkg/client/cache"
+	"miniK8s/pkg/client/informers"
 	"miniK8s/pkg/config"
+	"miniK8s/pkg/healthz"
+	"miniK8s/pkg/k8log"
+	dockerclient "miniK8s/pkg/kubelet/dockerClient"
+	"miniK8s/pkg/leaderelection"
 	minik8stypes "miniK8s/pkg/minik8sTypes"
 	"miniK8s/pkg/serveless/function"
 	"miniK8s/pkg/serveless/workflow"
-	"miniK8s/util/executor"
+	"reflect"
 	"strconv"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,6 +30,12 @@ type server struct {
 	// routeTable 的key是 namespace/name ，value是一个数组，数组中的每个元素是一个pod的ip地址
 	// 当用户的请求到来了之后，首先会根据func的namespace/name找到对应的pod的ip地址，然后再将请求转发到这个pod上
 	// 如果发现这个value为空，那就需要创建一个新的pod，然后将请求转发到这个pod上
+	// routeTable由informer的协程更新，由处理请求的协程读取
+	routeLock sync.RWMutex
+
+	// 只缓存Function Pod的informer，Function Pod变化的时候更新routeTable
+	informerFactory informers.SharedInformerFactory
+	podLister       informers.PodLister
 
 	// func的controller
 	funcController function.FuncController
@@ -31,79 +45,62 @@ type server struct {
 }
 
 func NewServer() Server {
-	return &server{
+	// 只list和watch带有function label的pod，由APIServer根据label进行过滤
+	informerFactory := informers.NewFilteredSharedInformerFactory(client.NewClientset(), 0, client.ListOptions{
+		LabelSelector: minik8stypes.Pod_Func_Uuid,
+	})
+
+	s := &server{
 		httpServer:         gin.Default(),
 		routeTable:         make(map[string][]string),
+		informerFactory:    informerFactory,
+		podLister:          informerFactory.Pods().Lister(),
 		funcController:     function.NewFuncController(),
 		workflowController: workflow.NewWorkflowController(),
 	}
-}
-
-// 周期性的函数都放在这里
 
-// 从API Server获取所有的Pod信息，根据Label筛选出所有的Function Pod
-func (s *server) updateRouteTableFromAPIServer() {
-	// TODO
-	pods, err := GetAllPodFromAPIServer()
-
-	if err != nil {
-		return
-	}
+	informerFactory.Pods().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { s.updateRouteTable() },
+		UpdateFunc: func(oldObj interface{}, newObj interface{}) { s.updateRouteTable() },
+		DeleteFunc: func(obj interface{}) { s.updateRouteTable() },
+	})
+	return s
+}
 
-	remoteData := make(map[string][]string)
+// 根据informer缓存里面的Function Pod重新生成routeTable
+func (s *server) updateRouteTable() {
+	newTable := make(map[string][]string)
 
 	// 遍历所有的pod，将其加入到routeTable中
-	for _, pod := range pods {
+	for _, pod := range s.podLister.List(client.NamespaceAll) {
 		// 说明是一个Function Pod
-		if pod.Metadata.Labels[minik8stypes.Pod_Func_Uuid] != "" {
-			funcName := pod.Metadata.Labels[minik8stypes.Pod_Func_Name]
-			funcNamespace := pod.Metadata.Labels[minik8stypes.Pod_Func_Namespace]
-			key := funcNamespace + "/" + funcName
-			// 检查ip是否为空
-			if pod.Status.PodIP != "" {
-				remoteData[key] = append(remoteData[key], pod.Status.PodIP)
-				// 检查routeTable中是否有这个ip
-				ifExist := false
-				for _, ip := range s.routeTable[key] {
-					if ip == pod.Status.PodIP {
-						ifExist = true
-						break
-					}
-				}
-
-				if !ifExist {
-					// ip不为空，说明这个pod已经启动了，可以将其加入到routeTable中
-					s.routeTable[key] = append(s.routeTable[key], pod.Status.PodIP)
-					fmt.Println("update routeTable: ", s.routeTable)
-				}
-			}
+		if pod.Metadata.Labels[minik8stypes.Pod_Func_Uuid] == "" {
+			continue
 		}
+		// ip不为空，说明这个pod已经启动了，可以将其加入到routeTable中
+		if pod.Status.PodIP == "" {
+			continue
+		}
+		funcName := pod.Metadata.Labels[minik8stypes.Pod_Func_Name]
+		funcNamespace := pod.Metadata.Labels[minik8stypes.Pod_Func_Namespace]
+		key := funcNamespace + "/" + funcName
+		newTable[key] = append(newTable[key], pod.Status.PodIP)
 	}
 
-	// 遍历本地的routeTable，检查是否有需要删除的ip
-	for key, ips := range s.routeTable {
-		for _, ip := range ips {
-			// 检查ip是否在remoteData中
-			ifExist := false
-			for _, remoteIp := range remoteData[key] {
-				if remoteIp == ip {
-					ifExist = true
-					break
-				}
-			}
-
-			if !ifExist {
-				// 说明这个ip已经不存在了，需要将其删除
-				fmt.Println("delete ip: ", ip)
-				for i, localIp := range s.routeTable[key] {
-					if localIp == ip {
-						s.routeTable[key] = append(s.routeTable[key][:i], s.routeTable[key][i+1:]...)
-						break
-					}
-				}
-			}
-		}
+	s.routeLock.Lock()
+	defer s.routeLock.Unlock()
+	if !reflect.DeepEqual(s.routeTable, newTable) {
+		k8log.InfoLog("serveless", fmt.Sprintf("update routeTable: %v", newTable))
 	}
+	s.routeTable = newTable
+}
+
+// 查询function对应的所有pod的ip地址
+func (s *server) getRouteIPs(key string) ([]string, bool) {
+	s.routeLock.RLock()
+	defer s.routeLock.RUnlock()
+	ips, ok := s.routeTable[key]
+	return ips, ok
 }
 
 // // 周期运行函数检查调用的function情况
@@ -127,16 +124,29 @@ func (s *server) updateRouteTableFromAPIServer() {
 // }
 
 func (s *server) Run() {
-	// 周期性的更新routeTable
-	go executor.Period(RouterUpdate_Delay, RouterUpdate_WaitTime, s.updateRouteTableFromAPIServer, RouterUpdate_ifLoop)
-
-	// 周期性的检查function的情况，如果有新创建的function，那么就创建一个新的pod
-	go s.funcController.Run()
-
-	// 周期性的检查workflow的情况，如果有新创建的workflow，那么就创建一个新的pod
-	go s.workflowController.Run()
+	// 通过informer监听Function Pod的变化，更新routeTable
+	s.informerFactory.Start(make(chan struct{}))
+
+	// 启动多个副本的时候所有副本都处理函数调用，只有leader运行function和workflow的controller
+	// 失去leader之后直接退出，由外部重启之后重新参与选举
+	go leaderelection.RunOrDie(leaderelection.DefaultLeaderElectionConfig(leaderelection.LeaseNameServerlessController), leaderelection.LeaderCallbacks{
+		OnStartedLeading: func(stopCh <-chan struct{}) {
+			// 周期性的检查function的情况，如果有新创建的function，那么就创建一个新的pod
+			go s.funcController.Run()
+
+			// 周期性的检查workflow的情况，如果有新创建的workflow，那么就创建一个新的pod
+			go s.workflowController.Run()
+		},
+		OnStoppedLeading: func() {
+			k8log.FatalLog("serveless", "leader election lost")
+		},
+	}, make(chan struct{}))
 
 	// 初始化服务器
+	// 函数的镜像通过docker构建和推送
+	healthz.InstallHandlers(s.httpServer, "serveless", nil, []healthz.HealthChecker{
+		healthz.NamedCheck("docker", dockerclient.Ping),
+	})
 	s.httpServer.POST("/:funcNamespace/:funcName", s.handleFuncRequest)
 	s.httpServer.GET("/:funcNamespace/:funcName", s.checkFunction)
 	s.httpServer.Run(":" + strconv.Itoa(config.Serveless_Server_Port))
@@ -6,4 +6,6 @@ var (
 	WorkflowController_Delay    = 0 * time.Second
 	WorkflowController_Waittime = []time.Duration{20 * time.Second}
 	WorkflowController_ifLoop   = true
+	// 同时执行的workflow的数量
+	WorkflowController_Workers = 5
 )
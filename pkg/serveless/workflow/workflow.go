@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"miniK8s/pkg/apiObject"
+	"miniK8s/pkg/client/cache"
 	"miniK8s/pkg/config"
+	"miniK8s/pkg/workqueue"
 	"miniK8s/util/executor"
 	netrequest "miniK8s/util/netRequest"
 	"miniK8s/util/stringutil"
@@ -19,10 +21,14 @@ type WorkflowController interface {
 }
 
 type workflowController struct {
+	// 还没有执行完的workflow的 namespace/name，同一个workflow不会被同时执行两次
+	queue workqueue.RateLimitingInterface
 }
 
 func NewWorkflowController() WorkflowController {
-	return &workflowController{}
+	return &workflowController{
+		queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
 }
 
 func GetAllWorkflowsFromAPIServer() ([]apiObject.WorkflowStore, error) {
@@ -43,6 +49,7 @@ func GetAllWorkflowsFromAPIServer() ([]apiObject.WorkflowStore, error) {
 	return allWorkflows, nil
 }
 
+// 把还没有执行完的workflow加入队列，正在执行的workflow不会重复加入
 func (w *workflowController) routine() {
 	allFlows, err := GetAllWorkflowsFromAPIServer()
 
@@ -55,19 +62,52 @@ func (w *workflowController) routine() {
 		if flow.Status.Result != "" {
 			continue
 		}
+		w.queue.Add(flow.GetNamespace() + "/" + flow.GetName())
+	}
+}
 
-		// 先更新workflow的phase为running
-		if flow.Status.Phase == "" {
-			w.WriteBackResultToServer(apiObject.WorkflowRunning, "", flow.GetNamespace(), flow.GetName())
-		}
+// 执行一个workflow，func node没有实例的时候返回错误，由队列退避之后重试
+func (w *workflowController) syncWorkflow(item interface{}) error {
+	key := item.(string)
+	namespace, name := cache.SplitMetaNamespaceKey(key)
 
-		// 如果workflow的某个func node无实例，进行创建，等待下一次的routine执行
-		if !(w.checkWorkflowNode(flow)) {
-			continue
+	// 重新读取workflow，上一次处理的时候可能已经执行完了
+	url := config.GetAPIServerURLPrefix() + config.WorkflowSpecURL
+	url = stringutil.Replace(url, config.URL_PARAM_NAMESPACE_PART, namespace)
+	url = stringutil.Replace(url, config.URL_PARAM_NAME_PART, name)
+
+	flow := apiObject.WorkflowStore{}
+	code, err := netrequest.GetRequestByTarget(url, &flow, "data")
+	if err != nil {
+		return err
+	}
+	// workflow已经被删除了
+	if code == http.StatusNotFound {
+		return nil
+	}
+	if code != http.StatusOK {
+		return errors.New("get workflow " + key + " from apiserver failed, code " + strconv.Itoa(code))
+	}
+
+	if flow.Status.Result != "" {
+		return nil
+	}
+
+	// 先更新workflow的phase为running
+	if flow.Status.Phase == "" {
+		if err := w.WriteBackResultToServer(apiObject.WorkflowRunning, "", flow.GetNamespace(), flow.GetName()); err != nil {
+			return err
 		}
+	}
 
-		go w.executeWorkflow(flow)
+	// 如果workflow的某个func node无实例，进行创建，等实例启动之后重试
+	if !(w.checkWorkflowNode(flow)) {
+		return errors.New("function instances of workflow " + key + " are not ready")
 	}
+
+	// 执行过程中的错误会作为结果写回，不再重试，避免函数被重复调用
+	w.executeWorkflow(flow)
+	return nil
 }
 
 func (w *workflowController) executeWorkflow(workflow apiObject.WorkflowStore) {
@@ -147,7 +187,7 @@ func (w *workflowController) executeWorkflow(workflow apiObject.WorkflowStore) {
 	w.WriteBackResultToServer(apiObject.WorkflowCompleted, lastStepResult, workflow.GetNamespace(), workflow.GetName())
 }
 
-func (w *workflowController) WriteBackResultToServer(phase string, result string, namespace string, name string) {
+func (w *workflowController) WriteBackResultToServer(phase string, result string, namespace string, name string) error {
 	statusURL := config.GetAPIServerURLPrefix() + config.WorkflowSpecStatusURL
 	statusURL = stringutil.Replace(statusURL, config.URL_PARAM_NAMESPACE_PART, namespace)
 	statusURL = stringutil.Replace(statusURL, config.URL_PARAM_NAME_PART, name)
@@ -161,13 +201,15 @@ func (w *workflowController) WriteBackResultToServer(phase string, result string
 
 	if err != nil {
 		fmt.Println("put request failed + ", err.Error())
-		return
+		return err
 	}
 
 	if code != http.StatusOK {
 		fmt.Println("put request failed expected code 200, get " + strconv.Itoa(code))
-		return
+		return errors.New("put request failed expected code 200, get " + strconv.Itoa(code))
 	}
+
+	return nil
 }
 
 // 检查当前的workflow的每个func node是否都存在pod实例，若不存在则创建并返回false
@@ -192,7 +234,8 @@ func (w *workflowController) checkWorkflowNode(workflow apiObject.WorkflowStore)
 }
 
 func (w *workflowController) Run() {
-	executor.Period(WorkflowController_Delay, WorkflowController_Waittime, w.routine, WorkflowController_ifLoop)
+	go executor.Period(WorkflowController_Delay, WorkflowController_Waittime, w.routine, WorkflowController_ifLoop)
+	workqueue.Run("workflowController", w.queue, WorkflowController_Workers, workqueue.DefaultMaxRetries, w.syncWorkflow, nil)
 }
 
 func (w *workflowController) CompareCheck(checkType apiObject.ChoiceCheckType, lastStepResult string, CompareValue string, varName string) (bool, error) {
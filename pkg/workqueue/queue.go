@@ -0,0 +1,114 @@
+package workqueue
+
+import (
+	"sync"
+)
+
+// Interface 是一个对item去重的FIFO队列
+// 同一个item在队列里面只会出现一次；正在处理的item再次加入的时候，等处理完(Done)之后才会重新放回队列，
+// 所以同一个item不会被多个worker同时处理
+type Interface interface {
+	Add(item interface{})
+	Len() int
+	// 阻塞直到有item可以处理，队列关闭之后shutdown为true
+	Get() (item interface{}, shutdown bool)
+	// 处理完一个item之后必须调用
+	Done(item interface{})
+	// 关闭之后不再接受新的item，已经在队列里面的item仍然可以取出
+	ShutDown()
+	ShuttingDown() bool
+}
+
+type queue struct {
+	cond *sync.Cond
+
+	// 等待处理的item，按照加入的顺序
+	items []interface{}
+	// 需要处理的item的集合，包括正在处理中、处理完之后需要重新放回队列的item
+	dirty map[interface{}]struct{}
+	// 正在被worker处理的item
+	processing map[interface{}]struct{}
+
+	shuttingDown bool
+}
+
+// item需要能够作为map的key，一般使用 namespace/name 这样的字符串
+func New() Interface {
+	return newQueue()
+}
+
+func newQueue() *queue {
+	return &queue{
+		cond:       sync.NewCond(&sync.Mutex{}),
+		items:      make([]interface{}, 0),
+		dirty:      make(map[interface{}]struct{}),
+		processing: make(map[interface{}]struct{}),
+	}
+}
+
+func (q *queue) Add(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	if q.shuttingDown {
+		return
+	}
+	if _, ok := q.dirty[item]; ok {
+		return
+	}
+	q.dirty[item] = struct{}{}
+	// 正在处理的item等Done的时候再放回队列
+	if _, ok := q.processing[item]; ok {
+		return
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+}
+
+func (q *queue) Len() int {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return len(q.items)
+}
+
+func (q *queue) Get() (interface{}, bool) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	for len(q.items) == 0 && !q.shuttingDown {
+		q.cond.Wait()
+	}
+	if len(q.items) == 0 {
+		return nil, true
+	}
+
+	item := q.items[0]
+	q.items[0] = nil
+	q.items = q.items[1:]
+
+	q.processing[item] = struct{}{}
+	delete(q.dirty, item)
+	return item, false
+}
+
+func (q *queue) Done(item interface{}) {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	delete(q.processing, item)
+	// 处理期间又被加入过，重新放回队列
+	if _, ok := q.dirty[item]; ok {
+		q.items = append(q.items, item)
+		q.cond.Signal()
+	}
+}
+
+func (q *queue) ShutDown() {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	q.shuttingDown = true
+	q.cond.Broadcast()
+}
+
+func (q *queue) ShuttingDown() bool {
+	q.cond.L.Lock()
+	defer q.cond.L.Unlock()
+	return q.shuttingDown
+}
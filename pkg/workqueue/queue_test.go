@@ -0,0 +1,135 @@
+package workqueue
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueueDeduplicate(t *testing.T) {
+	q := New()
+	q.Add("a")
+	q.Add("b")
+	q.Add("a")
+	if q.Len() != 2 {
+		t.Fatalf("expected 2 items but got %d", q.Len())
+	}
+
+	item, _ := q.Get()
+	if item != "a" {
+		t.Fatalf("expected a but got %v", item)
+	}
+	// 处理期间再次加入的item不会被其他worker取出，Done之后才放回队列
+	q.Add("a")
+	if q.Len() != 1 {
+		t.Fatalf("expected a not to be queued while processing, got %d items", q.Len())
+	}
+	q.Done("a")
+	if q.Len() != 2 {
+		t.Fatalf("expected a to be queued after done, got %d items", q.Len())
+	}
+
+	item, _ = q.Get()
+	q.Done(item)
+	item, _ = q.Get()
+	if item != "a" {
+		t.Fatalf("expected a but got %v", item)
+	}
+	q.Done(item)
+
+	q.ShutDown()
+	q.Add("c")
+	if _, shutdown := q.Get(); !shutdown {
+		t.Errorf("expected the queue to be shut down")
+	}
+}
+
+func TestExponentialFailureRateLimiter(t *testing.T) {
+	limiter := NewItemExponentialFailureRateLimiter(time.Millisecond, 5*time.Millisecond)
+	expected := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 5 * time.Millisecond, 5 * time.Millisecond}
+	for i, e := range expected {
+		if got := limiter.When("a"); got != e {
+			t.Errorf("expected delay %v for failure %d but got %v", e, i, got)
+		}
+	}
+	// 每个item单独计算
+	if got := limiter.When("b"); got != time.Millisecond {
+		t.Errorf("expected delay 1ms for b but got %v", got)
+	}
+	if limiter.NumRequeues("a") != 5 {
+		t.Errorf("expected 5 requeues but got %d", limiter.NumRequeues("a"))
+	}
+	limiter.Forget("a")
+	if limiter.NumRequeues("a") != 0 || limiter.When("a") != time.Millisecond {
+		t.Errorf("expected the failures to be forgotten")
+	}
+}
+
+func TestRunRetry(t *testing.T) {
+	q := NewRateLimitingQueue(NewItemExponentialFailureRateLimiter(time.Millisecond, 10*time.Millisecond))
+
+	var lock sync.Mutex
+	attempts := map[string]int{}
+	done := make(chan string, 10)
+	process := func(item interface{}) error {
+		key := item.(string)
+		lock.Lock()
+		attempts[key]++
+		n := attempts[key]
+		lock.Unlock()
+
+		switch {
+		// flaky前两次失败，第三次成功
+		case key == "flaky" && n < 3:
+			return errors.New("flaky")
+		// broken一直失败，重试3次之后放弃
+		case key == "broken":
+			if n == 4 {
+				done <- key
+			}
+			return errors.New("broken")
+		}
+		done <- key
+		return nil
+	}
+
+	stopCh := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		Run("test", q, 2, 3, process, stopCh)
+		close(stopped)
+	}()
+
+	q.Add("ok")
+	q.Add("flaky")
+	q.Add("broken")
+
+	finished := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		select {
+		case key := <-done:
+			finished[key] = true
+		case <-time.After(time.Second):
+			t.Fatalf("expected all items to be processed, got %v", finished)
+		}
+	}
+
+	// 放弃之后不再重试
+	time.Sleep(50 * time.Millisecond)
+	lock.Lock()
+	if attempts["ok"] != 1 || attempts["flaky"] != 3 || attempts["broken"] != 4 {
+		t.Errorf("unexpected attempts %v", attempts)
+	}
+	lock.Unlock()
+	if q.NumRequeues("flaky") != 0 || q.NumRequeues("broken") != 0 {
+		t.Errorf("expected the failures to be forgotten")
+	}
+
+	close(stopCh)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatalf("expected Run to return after stop")
+	}
+}
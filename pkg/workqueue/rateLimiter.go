@@ -0,0 +1,73 @@
+package workqueue
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	// 第一次重试的等待时间，之后每次失败翻倍
+	DefaultBaseDelay = 5 * time.Millisecond
+	// 重试等待时间的上限
+	DefaultMaxDelay = 1000 * time.Second
+)
+
+// RateLimiter 决定一个处理失败的item要等多久再重试
+type RateLimiter interface {
+	// 记录一次失败，返回这次需要等待的时间
+	When(item interface{}) time.Duration
+	// item处理成功，清除失败的记录
+	Forget(item interface{})
+	// item连续失败的次数
+	NumRequeues(item interface{}) int
+}
+
+// 每个item单独计算的指数退避，等待时间是 baseDelay * 2^失败次数，不超过maxDelay
+type itemExponentialFailureRateLimiter struct {
+	lock     sync.Mutex
+	failures map[interface{}]int
+
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func NewItemExponentialFailureRateLimiter(baseDelay time.Duration, maxDelay time.Duration) RateLimiter {
+	return &itemExponentialFailureRateLimiter{
+		failures:  make(map[interface{}]int),
+		baseDelay: baseDelay,
+		maxDelay:  maxDelay,
+	}
+}
+
+// controller默认使用的RateLimiter
+func DefaultControllerRateLimiter() RateLimiter {
+	return NewItemExponentialFailureRateLimiter(DefaultBaseDelay, DefaultMaxDelay)
+}
+
+func (r *itemExponentialFailureRateLimiter) When(item interface{}) time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	exp := r.failures[item]
+	r.failures[item] = exp + 1
+
+	// 失败次数很多的时候避免溢出
+	backoff := float64(r.baseDelay.Nanoseconds()) * math.Pow(2, float64(exp))
+	if backoff > float64(r.maxDelay.Nanoseconds()) {
+		return r.maxDelay
+	}
+	return time.Duration(backoff)
+}
+
+func (r *itemExponentialFailureRateLimiter) Forget(item interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.failures, item)
+}
+
+func (r *itemExponentialFailureRateLimiter) NumRequeues(item interface{}) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.failures[item]
+}
@@ -0,0 +1,55 @@
+package workqueue
+
+import (
+	"time"
+)
+
+// RateLimitingInterface 在去重队列的基础上支持延迟加入和按照RateLimiter退避重试
+type RateLimitingInterface interface {
+	Interface
+	// 等待duration之后再加入队列
+	AddAfter(item interface{}, duration time.Duration)
+	// 按照RateLimiter计算的等待时间重新加入队列，item的失败次数加一
+	AddRateLimited(item interface{})
+	// item处理成功或者放弃重试，清除失败的次数
+	Forget(item interface{})
+	NumRequeues(item interface{}) int
+}
+
+type rateLimitingQueue struct {
+	*queue
+	rateLimiter RateLimiter
+}
+
+func NewRateLimitingQueue(rateLimiter RateLimiter) RateLimitingInterface {
+	return &rateLimitingQueue{
+		queue:       newQueue(),
+		rateLimiter: rateLimiter,
+	}
+}
+
+func (q *rateLimitingQueue) AddAfter(item interface{}, duration time.Duration) {
+	if q.ShuttingDown() {
+		return
+	}
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	// 等待期间item不在队列里面，这段时间内的Add会让item提前被处理
+	time.AfterFunc(duration, func() {
+		q.Add(item)
+	})
+}
+
+func (q *rateLimitingQueue) AddRateLimited(item interface{}) {
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+func (q *rateLimitingQueue) Forget(item interface{}) {
+	q.rateLimiter.Forget(item)
+}
+
+func (q *rateLimitingQueue) NumRequeues(item interface{}) int {
+	return q.rateLimiter.NumRequeues(item)
+}
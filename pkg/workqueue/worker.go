@@ -0,0 +1,57 @@
+package workqueue
+
+import (
+	"fmt"
+	"miniK8s/pkg/k8log"
+	"sync"
+)
+
+// 一个item连续失败这么多次之后放弃，等下一次事件或者周期性的检查再重新加入
+var DefaultMaxRetries = 15
+
+// ProcessFunc 处理一个item，返回错误的时候按照退避时间重试
+type ProcessFunc func(item interface{}) error
+
+// Run 启动workers个协程并行处理队列里面的item，阻塞直到stopCh关闭
+// stopCh为nil的时候一直运行到进程退出；stopCh关闭之后关闭队列，等正在处理的item完成之后返回
+// 处理成功的item清除失败次数；失败的item退避之后重试，超过maxRetries次之后丢弃
+func Run(name string, queue RateLimitingInterface, workers int, maxRetries int, process ProcessFunc, stopCh <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for processNextItem(name, queue, maxRetries, process) {
+			}
+		}()
+	}
+
+	<-stopCh
+	queue.ShutDown()
+	wg.Wait()
+}
+
+// 队列关闭之后返回false
+func processNextItem(name string, queue RateLimitingInterface, maxRetries int, process ProcessFunc) bool {
+	item, shutdown := queue.Get()
+	if shutdown {
+		return false
+	}
+	defer queue.Done(item)
+
+	err := process(item)
+	if err == nil {
+		queue.Forget(item)
+		return true
+	}
+
+	if queue.NumRequeues(item) < maxRetries {
+		k8log.WarnLog(name, fmt.Sprintf("process %v failed, retry later: %s", item, err.Error()))
+		queue.AddRateLimited(item)
+		return true
+	}
+
+	k8log.ErrorLog(name, fmt.Sprintf("process %v failed %d times, drop it: %s", item, maxRetries, err.Error()))
+	queue.Forget(item)
+	return true
+}
@@ -0,0 +1,463 @@
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 支持两种patch，和k8s保持一致
+// merge patch(RFC 7386)：{"spec": {"replicas": 3}}，值为null的字段会被删除，数组整体替换
+// json patch(RFC 6902)：[{"op": "replace", "path": "/spec/replicas", "value": 3}]
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var ErrUnsupportedPatchType = errors.New("unsupported patch type, use " + MergePatchType + " or " + JSONPatchType)
+
+// 根据Content-Type把patch应用到doc上面，返回新的json
+func Apply(patchType string, doc []byte, patch []byte) ([]byte, error) {
+	// Content-Type后面可能带有charset
+	if i := strings.Index(patchType, ";"); i >= 0 {
+		patchType = patchType[:i]
+	}
+	switch strings.TrimSpace(patchType) {
+	case MergePatchType:
+		return MergePatch(doc, patch)
+	case JSONPatchType:
+		return JSONPatch(doc, patch)
+	default:
+		return nil, ErrUnsupportedPatchType
+	}
+}
+
+// 解析json，数字保持原样，避免int64被转换成float64丢失精度
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ==============================================
+// merge patch
+// ==============================================
+
+func MergePatch(doc []byte, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %v", err)
+	}
+	return json.Marshal(mergeValue(target, p))
+}
+
+// patch不是对象的时候直接替换target，否则逐个字段合并
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = mergeValue(t[key], value)
+	}
+	return t
+}
+
+// ==============================================
+// json patch
+// ==============================================
+
+type operation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+func JSONPatch(doc []byte, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %v", err)
+	}
+
+	ops := make([]operation, 0)
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("invalid json patch: %v", err)
+	}
+
+	// 任何一个操作失败，整个patch都不生效
+	for i, op := range ops {
+		target, err = applyOperation(target, &op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %v", i, op.Op, err)
+		}
+	}
+	return json.Marshal(target)
+}
+
+func applyOperation(doc interface{}, op *operation) (interface{}, error) {
+	if op.Path == nil {
+		return nil, errors.New("missing path")
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := func() (interface{}, error) {
+		if op.Value == nil {
+			return nil, errors.New("missing value")
+		}
+		return decode(*op.Value)
+	}
+	from := func() ([]string, error) {
+		if op.From == nil {
+			return nil, errors.New("missing from")
+		}
+		return parsePointer(*op.From)
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "move":
+		f, err := from()
+		if err != nil {
+			return nil, err
+		}
+		// 不能把对象移动到它自己的子节点下面
+		if len(path) > len(f) && strings.Join(path[:len(f)], "/") == strings.Join(f, "/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		doc, v, err := remove(doc, f)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "copy":
+		f, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := get(doc, f)
+		if err != nil {
+			return nil, err
+		}
+		// 复制一份，避免两个位置共用同一个对象
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = decode(data); err != nil {
+			return nil, err
+		}
+		return add(doc, path, v)
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(actual, v) {
+			return nil, fmt.Errorf("test failed at %q", *op.Path)
+		}
+		return doc, nil
+	default:
+		return nil, errors.New("unknown op")
+	}
+}
+
+// 解析json pointer(RFC 6901)，比如/metadata/labels/app~1name
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid path %q", pointer)
+	}
+	parts := strings.Split(pointer[1:], "/")
+	for i, part := range parts {
+		part = strings.ReplaceAll(part, "~1", "/")
+		parts[i] = strings.ReplaceAll(part, "~0", "~")
+	}
+	return parts, nil
+}
+
+// 数组的下标，allowEnd为true的时候允许等于数组的长度或者是"-"，表示在最后添加
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > length || (index == length && !allowEnd) {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func get(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q not found", token)
+			}
+			doc = v
+		case []interface{}:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("path %q not found", token)
+		}
+	}
+	return doc, nil
+}
+
+// 在path的位置添加value，对象的字段已经存在的时候替换，数组在下标的位置插入
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node, nil)
+		copy(node[index+1:], node[index:])
+		node[index] = value
+		// 数组的长度变了，需要写回父节点
+		return set(doc, path[:len(path)-1], node)
+	default:
+		return nil, fmt.Errorf("cannot add to %q", strings.Join(path, "/"))
+	}
+}
+
+// 删除path位置的值，返回删除之后的文档和被删除的值
+func remove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		v, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %q not found", last)
+		}
+		delete(node, last)
+		return doc, v, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		v := node[index]
+		node = append(node[:index], node[index+1:]...)
+		doc, err = set(doc, path[:len(path)-1], node)
+		return doc, v, err
+	default:
+		return nil, nil, fmt.Errorf("path %q not found", last)
+	}
+}
+
+// 把path位置的值替换为value，path必须存在
+func set(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	default:
+		return nil, fmt.Errorf("path %q not found", last)
+	}
+	return doc, nil
+}
+
+// 比较两个json值是否相等，数字按照字面值比较
+func equal(a interface{}, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key, value := range av {
+			other, ok := bv[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		if av == bv {
+			return true
+		}
+		af, aerr := av.Float64()
+		bf, berr := bv.Float64()
+		return aerr == nil && berr == nil && af == bf
+	default:
+		return a == b
+	}
+}
+
+// ==============================================
+// three-way merge
+// ==============================================
+
+// 根据上次apply的配置(original)、这次apply的配置(modified)和服务器上的对象(current)生成merge patch
+// modified里面和current不一样的字段会被设置，original里面有但是modified里面没有的字段会被删除
+// 其他的字段(比如控制器设置的)保持不变
+func CreateThreeWayMergePatch(original []byte, modified []byte, current []byte) ([]byte, error) {
+	o := make(map[string]interface{})
+	if len(bytes.TrimSpace(original)) != 0 {
+		v, err := decode(original)
+		if err != nil {
+			return nil, fmt.Errorf("invalid original: %v", err)
+		}
+		// 上次apply的配置不是对象的时候当作没有
+		if m, ok := v.(map[string]interface{}); ok {
+			o = m
+		}
+	}
+	m, err := decode(modified)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modified: %v", err)
+	}
+	c, err := decode(current)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current: %v", err)
+	}
+
+	mm, ok := m.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("modified is not an object")
+	}
+	cm, _ := c.(map[string]interface{})
+	return json.Marshal(threeWayDiff(o, mm, cm))
+}
+
+func threeWayDiff(original map[string]interface{}, modified map[string]interface{}, current map[string]interface{}) map[string]interface{} {
+	patch := make(map[string]interface{})
+	for key, value := range modified {
+		cur, exist := current[key]
+		if value == nil {
+			if exist {
+				patch[key] = nil
+			}
+			continue
+		}
+
+		// 两边都是对象的时候逐个字段比较，否则整体替换
+		vm, vok := value.(map[string]interface{})
+		cm, cok := cur.(map[string]interface{})
+		if vok && cok {
+			om, _ := original[key].(map[string]interface{})
+			if sub := threeWayDiff(om, vm, cm); len(sub) != 0 {
+				patch[key] = sub
+			}
+			continue
+		}
+		if !exist || !equal(value, cur) {
+			patch[key] = value
+		}
+	}
+
+	for key := range original {
+		if _, ok := modified[key]; ok {
+			continue
+		}
+		if _, exist := current[key]; exist {
+			patch[key] = nil
+		}
+	}
+	return patch
+}
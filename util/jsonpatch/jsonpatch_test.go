@@ -0,0 +1,139 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// 比较两个json是否等价
+func assertJSONEqual(t *testing.T, expected string, actual []byte) {
+	t.Helper()
+	var e, a interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		t.Fatalf("invalid expected json: %v", err)
+	}
+	if err := json.Unmarshal(actual, &a); err != nil {
+		t.Fatalf("invalid actual json: %v", err)
+	}
+	if !reflect.DeepEqual(e, a) {
+		t.Errorf("expected %s but got %s", expected, string(actual))
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	doc := `{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"}},"spec":{"replicas":1,"ports":[80,443]}}`
+
+	tests := []struct {
+		patch    string
+		expected string
+	}{
+		{`{"spec":{"replicas":3}}`, `{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"}},"spec":{"replicas":3,"ports":[80,443]}}`},
+		{`{"metadata":{"labels":{"tier":null,"env":"prod"}}}`, `{"metadata":{"name":"rs","labels":{"app":"web","env":"prod"}},"spec":{"replicas":1,"ports":[80,443]}}`},
+		// 数组整体替换
+		{`{"spec":{"ports":[8080]}}`, `{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"}},"spec":{"replicas":1,"ports":[8080]}}`},
+		{`{"metadata":{"annotations":{"a":"b"}}}`, `{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"},"annotations":{"a":"b"}},"spec":{"replicas":1,"ports":[80,443]}}`},
+	}
+
+	for _, test := range tests {
+		res, err := Apply(MergePatchType, []byte(doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("patch %s: %v", test.patch, err)
+			continue
+		}
+		assertJSONEqual(t, test.expected, res)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	doc := `{"metadata":{"labels":{"app":"web"}},"spec":{"replicas":1,"ports":[80,443]}}`
+
+	tests := []struct {
+		patch    string
+		expected string
+	}{
+		{`[{"op":"replace","path":"/spec/replicas","value":3}]`, `{"metadata":{"labels":{"app":"web"}},"spec":{"replicas":3,"ports":[80,443]}}`},
+		{`[{"op":"add","path":"/metadata/labels/app.kubernetes.io~1name","value":"x"}]`, `{"metadata":{"labels":{"app":"web","app.kubernetes.io/name":"x"}},"spec":{"replicas":1,"ports":[80,443]}}`},
+		{`[{"op":"add","path":"/spec/ports/1","value":8080},{"op":"add","path":"/spec/ports/-","value":9090}]`, `{"metadata":{"labels":{"app":"web"}},"spec":{"replicas":1,"ports":[80,8080,443,9090]}}`},
+		{`[{"op":"remove","path":"/spec/ports/0"}]`, `{"metadata":{"labels":{"app":"web"}},"spec":{"replicas":1,"ports":[443]}}`},
+		{`[{"op":"move","from":"/metadata/labels/app","path":"/metadata/labels/name"}]`, `{"metadata":{"labels":{"name":"web"}},"spec":{"replicas":1,"ports":[80,443]}}`},
+		{`[{"op":"copy","from":"/spec/replicas","path":"/spec/min"}]`, `{"metadata":{"labels":{"app":"web"}},"spec":{"replicas":1,"min":1,"ports":[80,443]}}`},
+		{`[{"op":"test","path":"/spec/replicas","value":1},{"op":"replace","path":"/spec/replicas","value":2}]`, `{"metadata":{"labels":{"app":"web"}},"spec":{"replicas":2,"ports":[80,443]}}`},
+	}
+
+	for _, test := range tests {
+		res, err := Apply(JSONPatchType+"; charset=utf-8", []byte(doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("patch %s: %v", test.patch, err)
+			continue
+		}
+		assertJSONEqual(t, test.expected, res)
+	}
+}
+
+func TestJSONPatchFailed(t *testing.T) {
+	doc := `{"spec":{"replicas":1,"ports":[80]}}`
+
+	patches := []string{
+		// test失败的时候前面的操作也不生效
+		`[{"op":"replace","path":"/spec/replicas","value":2},{"op":"test","path":"/spec/replicas","value":1}]`,
+		`[{"op":"remove","path":"/spec/missing"}]`,
+		`[{"op":"replace","path":"/spec/missing","value":1}]`,
+		`[{"op":"add","path":"/spec/ports/5","value":1}]`,
+		`[{"op":"add","path":"/missing/child","value":1}]`,
+		`[{"op":"move","from":"/spec","path":"/spec/child"}]`,
+		`[{"op":"add","path":"spec"}]`,
+		`[{"op":"unknown","path":"/spec"}]`,
+		`{"op":"add"}`,
+	}
+	for _, patch := range patches {
+		if _, err := JSONPatch([]byte(doc), []byte(patch)); err == nil {
+			t.Errorf("patch %s should fail", patch)
+		}
+	}
+
+	if _, err := Apply("application/json", []byte(doc), []byte(`{}`)); err != ErrUnsupportedPatchType {
+		t.Errorf("expected ErrUnsupportedPatchType but got %v", err)
+	}
+}
+
+func TestCreateThreeWayMergePatch(t *testing.T) {
+	// status和uuid是服务器设置的，不在apply的配置里面
+	current := `{"metadata":{"name":"rs","uuid":"1","labels":{"app":"web","tier":"fe","owner":"hpa"}},"spec":{"replicas":3,"ports":[80]},"status":{"ready":3}}`
+
+	tests := []struct {
+		original string
+		modified string
+		expected string
+	}{
+		// 没有变化
+		{`{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"}},"spec":{"replicas":3,"ports":[80]}}`,
+			`{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"}},"spec":{"replicas":3,"ports":[80]}}`,
+			`{}`},
+		// 从配置里面去掉的字段被删除，不在配置里面的owner保留
+		{`{"metadata":{"name":"rs","labels":{"app":"web","tier":"fe"}},"spec":{"replicas":3,"ports":[80]}}`,
+			`{"metadata":{"name":"rs","labels":{"app":"web"}},"spec":{"replicas":5,"ports":[80,443]}}`,
+			`{"metadata":{"labels":{"tier":null}},"spec":{"replicas":5,"ports":[80,443]}}`},
+		// 没有上次apply的配置的时候不删除任何字段
+		{``,
+			`{"metadata":{"name":"rs","labels":{"env":"prod"}}}`,
+			`{"metadata":{"labels":{"env":"prod"}}}`},
+		// 已经被别人删除的字段不需要再删除
+		{`{"spec":{"replicas":3,"min":1}}`,
+			`{"spec":{}}`,
+			`{"spec":{"replicas":null}}`},
+	}
+
+	for _, test := range tests {
+		res, err := CreateThreeWayMergePatch([]byte(test.original), []byte(test.modified), []byte(current))
+		if err != nil {
+			t.Errorf("modified %s: %v", test.modified, err)
+			continue
+		}
+		assertJSONEqual(t, test.expected, res)
+	}
+
+	if _, err := CreateThreeWayMergePatch(nil, []byte(`[1]`), []byte(current)); err == nil {
+		t.Errorf("modified which is not an object should fail")
+	}
+}
@@ -0,0 +1,292 @@
+package jsonschema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// 一个简化版的JSON Schema校验器，给CRD校验自定义对象使用
+// 支持的关键字：type、properties、required、additionalProperties(只支持false)、
+// items、enum、minimum、maximum、minLength、maxLength、pattern
+
+// 某个字段没有通过校验
+type FieldError struct {
+	// 字段的路径，比如spec.replicas、spec.containers[0].name
+	Field string
+	// 没有通过校验的原因
+	Reason string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Reason
+}
+
+var supportedTypes = []string{"object", "array", "string", "number", "integer", "boolean", "null"}
+
+// 检查schema本身是不是合法的，注册CRD的时候调用
+func CheckSchema(schema map[string]interface{}) error {
+	return checkSchema(schema, "schema")
+}
+
+func checkSchema(schema map[string]interface{}, field string) error {
+	if t, ok := schema["type"]; ok {
+		typeName, isString := t.(string)
+		if !isString || !contains(supportedTypes, typeName) {
+			return fmt.Errorf("%s.type: unsupported type %v", field, t)
+		}
+	}
+
+	if props, ok := schema["properties"]; ok {
+		propMap, isMap := props.(map[string]interface{})
+		if !isMap {
+			return fmt.Errorf("%s.properties: should be an object", field)
+		}
+		for name, prop := range propMap {
+			sub, isMap := prop.(map[string]interface{})
+			if !isMap {
+				return fmt.Errorf("%s.properties.%s: should be an object", field, name)
+			}
+			if err := checkSchema(sub, field+".properties."+name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if items, ok := schema["items"]; ok {
+		sub, isMap := items.(map[string]interface{})
+		if !isMap {
+			return fmt.Errorf("%s.items: should be an object", field)
+		}
+		if err := checkSchema(sub, field+".items"); err != nil {
+			return err
+		}
+	}
+
+	if required, ok := schema["required"]; ok {
+		if _, err := toStringSlice(required); err != nil {
+			return fmt.Errorf("%s.required: %s", field, err.Error())
+		}
+	}
+
+	if enum, ok := schema["enum"]; ok {
+		if _, isSlice := enum.([]interface{}); !isSlice {
+			return fmt.Errorf("%s.enum: should be an array", field)
+		}
+	}
+
+	if pattern, ok := schema["pattern"]; ok {
+		str, isString := pattern.(string)
+		if !isString {
+			return fmt.Errorf("%s.pattern: should be a string", field)
+		}
+		if _, err := regexp.Compile(str); err != nil {
+			return fmt.Errorf("%s.pattern: %s", field, err.Error())
+		}
+	}
+
+	for _, key := range []string{"minimum", "maximum", "minLength", "maxLength"} {
+		if v, ok := schema[key]; ok {
+			if _, isNumber := toFloat(v); !isNumber {
+				return fmt.Errorf("%s.%s: should be a number", field, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// 用schema校验value，field是value的路径，返回所有没有通过校验的字段
+// value应该是json解析出来的对象，也就是map[string]interface{}、[]interface{}、string、float64、bool或者nil
+func Validate(schema map[string]interface{}, value interface{}, field string) []FieldError {
+	errs := make([]FieldError, 0)
+
+	if t, ok := schema["type"].(string); ok {
+		if !matchType(t, value) {
+			return append(errs, FieldError{Field: field, Reason: fmt.Sprintf("should be %s, got %s", t, typeOf(value))})
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, FieldError{Field: field, Reason: fmt.Sprintf("should be one of %v", enum)})
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		errs = append(errs, validateObject(schema, v, field)...)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				errs = append(errs, Validate(items, item, fmt.Sprintf("%s[%d]", field, i))...)
+			}
+		}
+	case string:
+		if min, ok := toFloat(schema["minLength"]); ok && float64(len(v)) < min {
+			errs = append(errs, FieldError{Field: field, Reason: fmt.Sprintf("length should be at least %v", min)})
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(len(v)) > max {
+			errs = append(errs, FieldError{Field: field, Reason: fmt.Sprintf("length should be at most %v", max)})
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(v) {
+				errs = append(errs, FieldError{Field: field, Reason: "should match pattern " + pattern})
+			}
+		}
+	default:
+		if number, isNumber := toFloat(value); isNumber {
+			if min, ok := toFloat(schema["minimum"]); ok && number < min {
+				errs = append(errs, FieldError{Field: field, Reason: fmt.Sprintf("should be at least %v", min)})
+			}
+			if max, ok := toFloat(schema["maximum"]); ok && number > max {
+				errs = append(errs, FieldError{Field: field, Reason: fmt.Sprintf("should be at most %v", max)})
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateObject(schema map[string]interface{}, obj map[string]interface{}, field string) []FieldError {
+	errs := make([]FieldError, 0)
+
+	if required, err := toStringSlice(schema["required"]); err == nil {
+		for _, name := range required {
+			if _, ok := obj[name]; !ok {
+				errs = append(errs, FieldError{Field: field + "." + name, Reason: "required"})
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+
+	// 按照字段名排序，保证返回的错误顺序是固定的
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sub, ok := props[name].(map[string]interface{})
+		if !ok {
+			if additional, isBool := schema["additionalProperties"].(bool); isBool && !additional {
+				errs = append(errs, FieldError{Field: field + "." + name, Reason: "unknown field"})
+			}
+			continue
+		}
+		errs = append(errs, Validate(sub, obj[name], field+"."+name)...)
+	}
+
+	return errs
+}
+
+func matchType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+// json解析出来的数字是float64，yaml解析出来的可能是int，统一转换为float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func equal(a interface{}, b interface{}) bool {
+	fa, aIsNumber := toFloat(a)
+	fb, bIsNumber := toFloat(b)
+	if aIsNumber && bIsNumber {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toStringSlice(value interface{}) ([]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("should be an array of string")
+	}
+	res := make([]string, 0, len(list))
+	for _, v := range list {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("should be an array of string")
+		}
+		res = append(res, str)
+	}
+	return res, nil
+}
+
+func contains(list []string, target string) bool {
+	for _, str := range list {
+		if str == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["image", "replicas"],
+	"additionalProperties": false,
+	"properties": {
+		"image": {"type": "string", "minLength": 1},
+		"replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+		"mode": {"type": "string", "enum": ["train", "eval"]},
+		"args": {"type": "array", "items": {"type": "string"}}
+	}
+}`
+
+func parse(t *testing.T, str string) map[string]interface{} {
+	res := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(str), &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestCheckSchema(t *testing.T) {
+	if err := CheckSchema(parse(t, testSchema)); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSchema(parse(t, `{"type": "int"}`)); err == nil {
+		t.Error("type int should be rejected")
+	}
+	if err := CheckSchema(parse(t, `{"properties": {"a": {"pattern": "("}}}`)); err == nil {
+		t.Error("invalid pattern should be rejected")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema := parse(t, testSchema)
+
+	ok := parse(t, `{"image": "pytorch", "replicas": 2, "mode": "train", "args": ["--epochs", "3"]}`)
+	if errs := Validate(schema, ok, "spec"); len(errs) != 0 {
+		t.Fatalf("expected no errors but got %v", errs)
+	}
+
+	bad := parse(t, `{"replicas": 1.5, "mode": "serve", "args": ["a", 1], "extra": true}`)
+	errs := Validate(schema, bad, "spec")
+
+	expected := map[string]bool{
+		"spec.image":    true,
+		"spec.replicas": true,
+		"spec.mode":     true,
+		"spec.args[1]":  true,
+		"spec.extra":    true,
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %v errors but got %v", len(expected), errs)
+	}
+	for _, err := range errs {
+		if !expected[err.Field] {
+			t.Errorf("unexpected error %v", err)
+		}
+	}
+}
@@ -6,12 +6,11 @@ import (
 
 // 对于指定的端点发送一个DELETE请求
 func DelRequest(uri string) (int, error) {
-	req, err := http.NewRequest(http.MethodDelete, uri, nil)
+	req, err := newRequest(http.MethodDelete, uri, nil)
 	if err != nil {
 		return 0, err
 	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := doRequest(req)
 	if err != nil {
 		return 0, err
 	}
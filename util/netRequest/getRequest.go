@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 
 	"net/http"
 )
@@ -81,7 +82,11 @@ func GetRequestByTarget(uri string, target interface{}, key string) (int, error)
 }
 
 func GetRequest(uri string) (int, map[string]interface{}, error) {
-	response, err := http.Get(uri)
+	request, err := newRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	response, err := doRequest(request)
 	if err != nil {
 		// k8log.ErrorLog("netrequest", "GetRequestByTarget failed, for get failed, err: "+err.Error())
 		return 0, nil, err
@@ -98,3 +103,72 @@ func GetRequest(uri string) (int, map[string]interface{}, error) {
 
 	return response.StatusCode, result, nil
 }
+
+// 分页获取list类型的数据，每次最多获取pageSize个，直到服务器返回的continue为空
+// 所有页的数据会合并之后反序列化到target指向的切片中
+func GetAllPagesByTarget(uri string, target interface{}, pageSize int) (int, error) {
+	items := make([]json.RawMessage, 0)
+	continueToken := ""
+
+	for {
+		pageURI, err := url.Parse(uri)
+		if err != nil {
+			return 0, err
+		}
+		query := pageURI.Query()
+		query.Set("limit", fmt.Sprint(pageSize))
+		if continueToken != "" {
+			query.Set("continue", continueToken)
+		}
+		pageURI.RawQuery = query.Encode()
+
+		code, res, err := GetRequest(pageURI.String())
+		if err != nil {
+			return code, err
+		}
+		if code != http.StatusOK {
+			return code, nil
+		}
+
+		data, ok := res["data"]
+		if !ok || data == nil {
+			return code, errors.New("resp[data] is nil")
+		}
+
+		page := make([]json.RawMessage, 0)
+		if err := json.Unmarshal([]byte(fmt.Sprint(data)), &page); err != nil {
+			return 0, err
+		}
+		items = append(items, page...)
+
+		// continue为空说明已经是最后一页
+		continueToken, _ = res["continue"].(string)
+		if continueToken == "" {
+			break
+		}
+	}
+
+	allItems, err := json.Marshal(items)
+	if err != nil {
+		return 0, err
+	}
+	return http.StatusOK, json.Unmarshal(allItems, target)
+}
+
+// 发送GET请求，直接返回response，用于watch这种持续推送数据的请求
+// 状态码是200的时候，response.Body由调用者负责关闭
+func GetStream(uri string) (*http.Response, error) {
+	request, err := newRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return response, fmt.Errorf("get stream failed, code: %d", response.StatusCode)
+	}
+	return response, nil
+}
@@ -0,0 +1,43 @@
+package netrequest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetAllPagesByTarget(t *testing.T) {
+	// 模拟APIServer，一共3页数据
+	pages := map[string]map[string]interface{}{
+		"":      {"data": `[{"name":"a"},{"name":"b"}]`, "continue": "page2"},
+		"page2": {"data": `[{"name":"c"},{"name":"d"}]`, "continue": "page3"},
+		"page3": {"data": `[{"name":"e"}]`, "continue": ""},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != "2" {
+			t.Errorf("expected limit 2 but got %v", r.URL.Query().Get("limit"))
+		}
+		page, ok := pages[r.URL.Query().Get("continue")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	target := []struct {
+		Name string `json:"name"`
+	}{}
+	code, err := GetAllPagesByTarget(server.URL+"/api/v1/nodes", &target, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != http.StatusOK {
+		t.Fatalf("expected status %v but got %v", http.StatusOK, code)
+	}
+	if len(target) != 5 || target[4].Name != "e" {
+		t.Errorf("expected 5 items but got %v", target)
+	}
+}
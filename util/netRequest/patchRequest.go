@@ -0,0 +1,33 @@
+package netrequest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// Patch请求，patchType是application/merge-patch+json或者application/json-patch+json
+func PatchRequest(uri string, patchType string, patch []byte) (int, interface{}, error) {
+	request, err := newRequest(http.MethodPatch, uri, bytes.NewBuffer(patch))
+	if err != nil {
+		return 0, nil, err
+	}
+	request.Header.Set("Content-Type", patchType)
+
+	response, err := doRequest(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	var bodyJson interface{}
+	if err := json.NewDecoder(response.Body).Decode(&bodyJson); err != nil {
+		// 路由不存在(资源不支持更新)的时候返回的不是json
+		if response.StatusCode != http.StatusOK {
+			return response.StatusCode, nil, nil
+		}
+		return 0, nil, err
+	}
+
+	return response.StatusCode, bodyJson, nil
+}
@@ -14,7 +14,13 @@ func PostRequestByTarget(uri string, target interface{}) (int, interface{}, erro
 		k8log.ErrorLog("postRequest", "PostRequestByTarget: Marshal object failed "+err.Error())
 		return 0, nil, err
 	}
-	response, err := http.Post(uri, "application/json", bytes.NewBuffer(jsonData))
+	request, err := newRequest(http.MethodPost, uri, bytes.NewBuffer(jsonData))
+	if err != nil {
+		k8log.ErrorLog("postRequest", "PostRequestByTarget: New request failed "+err.Error())
+		return 0, nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := doRequest(request)
 	if err != nil {
 		k8log.ErrorLog("postRequest", "PostRequestByTarget: Post object failed "+err.Error())
 		return 0, nil, err
@@ -31,10 +37,9 @@ func PostRequestByTarget(uri string, target interface{}) (int, interface{}, erro
 }
 
 func PostString(uri string, str string) (*http.Response, error) {
-	cli := http.Client{}
-	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewReader([]byte(str)))
+	req, err := newRequest(http.MethodPost, uri, bytes.NewReader([]byte(str)))
 	if err != nil {
 		return nil, err
 	}
-	return cli.Do(req)
+	return doRequest(req)
 }
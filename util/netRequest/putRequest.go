@@ -13,14 +13,13 @@ func PutRequestByTarget(uri string, target interface{}) (int, interface{}, error
 		return 0, nil, err
 	}
 
-	request, err := http.NewRequest("PUT", uri, bytes.NewBuffer(jsonData))
+	request, err := newRequest(http.MethodPut, uri, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return 0, nil, err
 	}
 	request.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	response, err := doRequest(request)
 	if err != nil {
 		return 0, nil, err
 	}
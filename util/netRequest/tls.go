@@ -0,0 +1,53 @@
+package netrequest
+
+import (
+	"crypto/tls"
+	"miniK8s/pkg/config"
+	"net/http"
+	"sync"
+)
+
+// 发往APIServer的请求共用一个http.Client，开启TLS的时候带上集群CA和客户端证书
+var (
+	httpClient *http.Client
+	clientLock sync.Mutex
+)
+
+// 设置之后不再从文件里面读取CA和客户端证书，传入nil表示不使用TLS
+func SetTLSConfig(tlsConfig *tls.Config) {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	httpClient = newHTTPClient(tlsConfig)
+}
+
+// 没有调用过SetTLSConfig的时候，按照config.LoadClientTLSConfig读取证书
+func getClient() (*http.Client, error) {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	if httpClient == nil {
+		tlsConfig, err := config.LoadClientTLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		httpClient = newHTTPClient(tlsConfig)
+	}
+	return httpClient, nil
+}
+
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	if tlsConfig == nil {
+		return &http.Client{}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}
+}
+
+// 使用共用的http.Client发送请求
+func doRequest(req *http.Request) (*http.Response, error) {
+	client, err := getClient()
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
+}
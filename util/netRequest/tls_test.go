@@ -0,0 +1,32 @@
+package netrequest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": "ok"})
+	}))
+	defer server.Close()
+	defer SetTLSConfig(nil)
+
+	// 不信任服务端证书的时候请求失败
+	SetTLSConfig(&tls.Config{RootCAs: x509.NewCertPool()})
+	if _, _, err := GetRequest(server.URL); err == nil {
+		t.Errorf("request should fail when the server certificate is not trusted")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	SetTLSConfig(&tls.Config{RootCAs: pool})
+	code, _, err := GetRequest(server.URL)
+	if err != nil || code != http.StatusOK {
+		t.Errorf("request with CA bundle failed, code %v err %v", code, err)
+	}
+}
@@ -0,0 +1,83 @@
+package netrequest
+
+import (
+	"io"
+	"miniK8s/pkg/config"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// 发往APIServer的请求都会在请求头里面带上这个token
+// 其他地址(比如用户函数的pod)不能带token，节点的token有集群管理员的权限
+var (
+	bearerToken string
+	tokenLoaded bool
+	tokenLock   sync.Mutex
+	// config.GetAPIServerURLPrefix()之外的APIServer地址
+	apiServerURLPrefixes []string
+)
+
+// 设置之后不再从文件里面读取，kubectl用这个函数设置用户的token
+func SetBearerToken(token string) {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+	bearerToken = token
+	tokenLoaded = true
+}
+
+// 访问不在配置里面的APIServer地址的时候需要先注册，否则请求不会带上token
+func AddAPIServerURLPrefix(prefix string) {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+	prefix = strings.TrimSuffix(prefix, "/")
+	for _, p := range apiServerURLPrefixes {
+		if p == prefix {
+			return
+		}
+	}
+	apiServerURLPrefixes = append(apiServerURLPrefixes, prefix)
+}
+
+// uri是否发往APIServer，前缀后面必须是路径、查询参数或者结束，避免 :8090 匹配到 :80901
+func isAPIServerURL(uri string) bool {
+	tokenLock.Lock()
+	prefixes := append([]string{config.GetAPIServerURLPrefix()}, apiServerURLPrefixes...)
+	tokenLock.Unlock()
+	for _, prefix := range prefixes {
+		if !strings.HasPrefix(uri, prefix) {
+			continue
+		}
+		rest := uri[len(prefix):]
+		if rest == "" || rest[0] == '/' || rest[0] == '?' {
+			return true
+		}
+	}
+	return false
+}
+
+// 没有调用过SetBearerToken的时候，按照节点组件的方式读取token
+func getBearerToken() string {
+	tokenLock.Lock()
+	defer tokenLock.Unlock()
+	if !tokenLoaded {
+		bearerToken = config.LoadToken(config.NodeTokenFile)
+		tokenLoaded = true
+	}
+	return bearerToken
+}
+
+// 创建一个请求，发往APIServer的请求带上Authorization请求头
+func newRequest(method string, uri string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, uri, body)
+	if err != nil {
+		return nil, err
+	}
+	if !isAPIServerURL(uri) {
+		return req, nil
+	}
+	if token := getBearerToken(); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req, nil
+}
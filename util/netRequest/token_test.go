@@ -0,0 +1,65 @@
+package netrequest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearerToken(t *testing.T) {
+	SetBearerToken("test-token")
+	defer SetBearerToken("")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "unauthorized"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": "ok"})
+	}))
+	defer server.Close()
+	AddAPIServerURLPrefix(server.URL)
+
+	code, _, err := GetRequest(server.URL)
+	if err != nil || code != http.StatusOK {
+		t.Errorf("get request should carry the token, code %v err %v", code, err)
+	}
+	code, _, err = PostRequestByTarget(server.URL, map[string]string{"a": "b"})
+	if err != nil || code != http.StatusOK {
+		t.Errorf("post request should carry the token, code %v err %v", code, err)
+	}
+	code, _, err = PutRequestByTarget(server.URL, map[string]string{"a": "b"})
+	if err != nil || code != http.StatusOK {
+		t.Errorf("put request should carry the token, code %v err %v", code, err)
+	}
+	code, err = DelRequest(server.URL)
+	if err != nil || code != http.StatusOK {
+		t.Errorf("delete request should carry the token, code %v err %v", code, err)
+	}
+
+	// 其他地址的请求不带token，比如用户函数的pod
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("request to %s should not carry the token", r.Host)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": "ok"})
+	}))
+	defer other.Close()
+	if _, err := PostString(other.URL+"/default/func", "{}"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := GetRequest(other.URL); err != nil {
+		t.Fatal(err)
+	}
+	if isAPIServerURL(server.URL + "1/api") {
+		t.Errorf("expected a different port not to match the apiserver prefix")
+	}
+
+	SetBearerToken("wrong-token")
+	code, _, _ = GetRequest(server.URL)
+	if code != http.StatusUnauthorized {
+		t.Errorf("expected status %v but got %v", http.StatusUnauthorized, code)
+	}
+}
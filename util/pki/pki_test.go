@@ -0,0 +1,123 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func parseCert(t *testing.T, certPEM []byte) *x509.Certificate {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatalf("failed to decode certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestIssueCert(t *testing.T) {
+	caCert, caKey, err := NewCA("minik8s-ca")
+	if err != nil {
+		t.Fatalf("NewCA failed: %v", err)
+	}
+	pool, err := NewCertPool(caCert)
+	if err != nil {
+		t.Fatalf("NewCertPool failed: %v", err)
+	}
+
+	nodeCert, _, err := IssueCert(caCert, caKey, &CertRequest{
+		CommonName:   "node1",
+		Organization: []string{"system:nodes"},
+		Usage:        UsageClient,
+	})
+	if err != nil {
+		t.Fatalf("IssueCert failed: %v", err)
+	}
+
+	cert := parseCert(t, nodeCert)
+	if cert.Subject.CommonName != "node1" || len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "system:nodes" {
+		t.Errorf("unexpected subject %v", cert.Subject)
+	}
+	opts := x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}
+	if _, err := cert.Verify(opts); err != nil {
+		t.Errorf("client certificate verify failed: %v", err)
+	}
+
+	// 别的CA签发的证书不能通过验证
+	otherCert, otherKey, _ := NewCA("other-ca")
+	fakeCert, _, err := IssueCert(otherCert, otherKey, &CertRequest{CommonName: "node1", Usage: UsageClient})
+	if err != nil {
+		t.Fatalf("IssueCert failed: %v", err)
+	}
+	if _, err := parseCert(t, fakeCert).Verify(opts); err == nil {
+		t.Errorf("certificate issued by other CA should not be verified")
+	}
+
+	if _, _, err := IssueCert(caCert, caKey, &CertRequest{CommonName: "node1", Usage: "unknown"}); err == nil {
+		t.Errorf("expected error for unknown usage")
+	}
+	if _, _, err := IssueCert(nodeCert, caKey, &CertRequest{CommonName: "node2", Usage: UsageClient}); err == nil {
+		t.Errorf("expected error when signing with a non-CA certificate")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	caCert, caKey, err := NewCA("minik8s-ca")
+	if err != nil {
+		t.Fatalf("NewCA failed: %v", err)
+	}
+	pool, _ := NewCertPool(caCert)
+
+	serverCert, serverKey, err := IssueCert(caCert, caKey, &CertRequest{
+		CommonName: "apiserver",
+		Hosts:      []string{"127.0.0.1", "localhost"},
+		Usage:      UsageServer,
+	})
+	if err != nil {
+		t.Fatalf("IssueCert failed: %v", err)
+	}
+	serverPair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatalf("X509KeyPair failed: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	clientCert, clientKey, err := IssueCert(caCert, caKey, &CertRequest{CommonName: "node1", Usage: UsageClient})
+	if err != nil {
+		t.Fatalf("IssueCert failed: %v", err)
+	}
+	clientPair, _ := tls.X509KeyPair(clientCert, clientKey)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      pool,
+		Certificates: []tls.Certificate{clientPair},
+	}}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+
+	// 没有客户端证书的时候握手失败
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("request without client certificate should fail")
+	}
+}
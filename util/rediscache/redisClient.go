@@ -21,6 +21,9 @@ type RedisCache interface {
 	GetAllObject(valueType interface{}) (map[string]interface{}, error)
 	Update(key string, value interface{}) error
 
+	// 检查redis是否可用
+	Ping() error
+
 	// 判断是否存在Redis缓存里面
 	ifExists(key string) (bool, error)
 }
@@ -178,6 +181,10 @@ func (r *rediscache) Update(key string, value interface{}) error {
 	return result.Err()
 }
 
+func (r *rediscache) Ping() error {
+	return r.redisClient.Ping(context.Background()).Err()
+}
+
 func (r *rediscache) ifExists(key string) (bool, error) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
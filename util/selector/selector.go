@@ -0,0 +1,238 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// 支持的运算符，和k8s的labelSelector语法保持一致
+// app=web,tier!=db,env in (a,b),env notin (c),app,!app
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// 一个要求，比如app=web
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// 多个要求之间是与的关系，空的Selector匹配所有对象
+type Selector []Requirement
+
+var setRequirementRegexp = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// 解析labelSelector，比如app=web,tier!=db,env in (a,b)
+func ParseLabelSelector(str string) (Selector, error) {
+	sel := Selector{}
+	for _, part := range splitRequirements(str) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		req := Requirement{}
+		if match := setRequirementRegexp.FindStringSubmatch(part); match != nil {
+			req.Key = match[1]
+			req.Operator = Operator(match[2])
+			for _, v := range strings.Split(match[3], ",") {
+				if v = strings.TrimSpace(v); v != "" {
+					req.Values = append(req.Values, v)
+				}
+			}
+			if len(req.Values) == 0 {
+				return nil, fmt.Errorf("invalid selector %q: empty value set", part)
+			}
+		} else if strings.ContainsAny(part, "=!") && !strings.HasPrefix(part, "!") {
+			r, err := parseEqualityRequirement(part)
+			if err != nil {
+				return nil, err
+			}
+			req = r
+		} else if strings.HasPrefix(part, "!") {
+			req.Key = strings.TrimSpace(strings.TrimPrefix(part, "!"))
+			req.Operator = DoesNotExist
+		} else {
+			req.Key = part
+			req.Operator = Exists
+		}
+
+		if req.Key == "" || strings.ContainsAny(req.Key, " =!(),") {
+			return nil, fmt.Errorf("invalid selector %q: bad key", part)
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// 解析fieldSelector，只支持=、==、!=，比如spec.nodeName=node1,status.phase=Running
+func ParseFieldSelector(str string) (Selector, error) {
+	sel := Selector{}
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		req, err := parseEqualityRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// 解析key=value、key==value、key!=value
+func parseEqualityRequirement(part string) (Requirement, error) {
+	req := Requirement{}
+	var kv []string
+	switch {
+	case strings.Contains(part, "!="):
+		kv = strings.SplitN(part, "!=", 2)
+		req.Operator = NotEquals
+	case strings.Contains(part, "=="):
+		kv = strings.SplitN(part, "==", 2)
+		req.Operator = Equals
+	case strings.Contains(part, "="):
+		kv = strings.SplitN(part, "=", 2)
+		req.Operator = Equals
+	default:
+		return req, fmt.Errorf("invalid selector %q: missing operator", part)
+	}
+	req.Key = strings.TrimSpace(kv[0])
+	req.Values = []string{strings.TrimSpace(kv[1])}
+	if req.Key == "" {
+		return req, fmt.Errorf("invalid selector %q: empty key", part)
+	}
+	if strings.ContainsAny(req.Values[0], "=!") {
+		return req, fmt.Errorf("invalid selector %q: bad value", part)
+	}
+	return req, nil
+}
+
+// 按照逗号切分，但是忽略括号里面的逗号
+func splitRequirements(str string) []string {
+	parts := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, ch := range str {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, str[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, str[start:])
+	return parts
+}
+
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+// 检查labels是否满足所有的要求
+func (s Selector) MatchLabels(labels map[string]string) bool {
+	for _, req := range s {
+		value, exists := labels[req.Key]
+		if !req.match(value, exists) {
+			return false
+		}
+	}
+	return true
+}
+
+// 检查对象的字段是否满足所有的要求
+// obj是对象json反序列化之后的map，key是用.分隔的字段路径，比如status.phase
+func (s Selector) MatchFields(obj map[string]interface{}) bool {
+	for _, req := range s {
+		value, exists := lookupField(obj, req.Key)
+		if !req.match(value, exists) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Requirement) match(value string, exists bool) bool {
+	switch r.Operator {
+	case Equals:
+		return exists && value == r.Values[0]
+	case NotEquals:
+		return !exists || value != r.Values[0]
+	case In:
+		return exists && containsString(r.Values, value)
+	case NotIn:
+		return !exists || !containsString(r.Values, value)
+	case Exists:
+		return exists
+	case DoesNotExist:
+		return !exists
+	}
+	return false
+}
+
+// 根据字段路径找到对应的值，找不到的时候返回false
+func lookupField(obj map[string]interface{}, fieldPath string) (string, bool) {
+	var cur interface{} = obj
+	for _, field := range strings.Split(fieldPath, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		cur, ok = m[field]
+		if !ok {
+			return "", false
+		}
+	}
+	if cur == nil {
+		return "", true
+	}
+	switch v := cur.(type) {
+	case map[string]interface{}, []interface{}:
+		return "", false
+	case string:
+		return v, true
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+func containsString(slice []string, s string) bool {
+	for _, v := range slice {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// 把matchLabels转换成labelSelector字符串，key按照字典序排列
+func FormatLabelSelector(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+labels[key])
+	}
+	return strings.Join(parts, ",")
+}
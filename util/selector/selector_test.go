@@ -0,0 +1,78 @@
+package selector
+
+import "testing"
+
+func TestParseLabelSelector(t *testing.T) {
+	sel, err := ParseLabelSelector("app=web,tier!=db,env in (a, b),!canary,release")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sel) != 5 {
+		t.Fatalf("expected 5 requirements but got %v", len(sel))
+	}
+
+	labels := map[string]string{"app": "web", "tier": "fe", "env": "b", "release": "1"}
+	if !sel.MatchLabels(labels) {
+		t.Errorf("labels %v should match", labels)
+	}
+
+	labels["env"] = "c"
+	if sel.MatchLabels(labels) {
+		t.Errorf("labels %v should not match", labels)
+	}
+
+	labels["env"] = "a"
+	labels["canary"] = "true"
+	if sel.MatchLabels(labels) {
+		t.Errorf("labels %v should not match", labels)
+	}
+}
+
+func TestParseLabelSelectorInvalid(t *testing.T) {
+	for _, str := range []string{"=web", "env in ()", "a=b=c"} {
+		if _, err := ParseLabelSelector(str); err == nil {
+			t.Errorf("selector %q should be invalid", str)
+		}
+	}
+}
+
+func TestEmptySelector(t *testing.T) {
+	sel, err := ParseLabelSelector("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sel.Empty() || !sel.MatchLabels(nil) {
+		t.Errorf("empty selector should match everything")
+	}
+}
+
+func TestMatchFields(t *testing.T) {
+	sel, err := ParseFieldSelector("spec.nodeName=node1,status.phase!=Failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := map[string]interface{}{
+		"spec":   map[string]interface{}{"nodeName": "node1"},
+		"status": map[string]interface{}{"phase": "Running"},
+	}
+	if !sel.MatchFields(obj) {
+		t.Errorf("object should match")
+	}
+
+	obj["spec"] = map[string]interface{}{"nodeName": "node2"}
+	if sel.MatchFields(obj) {
+		t.Errorf("object should not match")
+	}
+
+	if _, err := ParseFieldSelector("status.phase in (Running)"); err == nil {
+		t.Errorf("field selector should not support set operators")
+	}
+}
+
+func TestFormatLabelSelector(t *testing.T) {
+	got := FormatLabelSelector(map[string]string{"tier": "fe", "app": "web"})
+	if got != "app=web,tier=fe" {
+		t.Errorf("FormatLabelSelector() = %v", got)
+	}
+}